@@ -42,7 +42,18 @@ INSERT IGNORE INTO master_statuses (id, name) VALUES
 (1, 'pending'),
 (2, 'running'),
 (3, 'succeed'),
-(4, 'failed');
+(4, 'failed'),
+(5, 'cancelled');
+
+CREATE TABLE IF NOT EXISTS master_process_kinds (
+  id INT PRIMARY KEY,
+  name VARCHAR(255)
+);
+
+INSERT IGNORE INTO master_process_kinds (id, name) VALUES
+(1, 'render'),
+(2, 'ensemble'),
+(3, 'sweep');
 
 CREATE TABLE IF NOT EXISTS master_object_types (
   id INT PRIMARY KEY,
@@ -62,8 +73,9 @@ CREATE TABLE IF NOT EXISTS objects (
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 
-  -- bucket_path_sha256 VARCHAR(64) GENERATED ALWAYS AS (SHA2(CONCAT(bucket, '___', path), 256)) STORED,
-  -- UNIQUE INDEX bucket_path_sha256_idx (bucket_path_sha256),
+  -- the path is too long to be a key
+  bucket_path_sha256 VARCHAR(64) GENERATED ALWAYS AS (SHA2(CONCAT(bucket, '___', path), 256)) STORED,
+  UNIQUE INDEX bucket_path_sha256_idx (bucket_path_sha256),
   INDEX bucket_prefix_idx (bucket(64)),
   INDEX path_prefix_idx (path(256)),
   CONSTRAINT fk_type_id FOREIGN KEY (type_id) REFERENCES master_object_types(id)
@@ -72,14 +84,25 @@ CREATE TABLE IF NOT EXISTS objects (
 CREATE TABLE IF NOT EXISTS process_details (
   id INT AUTO_INCREMENT PRIMARY KEY,
   command VARCHAR(4096),
+  options JSON,
   title VARCHAR(4096) NOT NULL,
+  -- the original musicxml file name except extension, the results are named after it
+  basename VARCHAR(4096) NOT NULL,
+  notes VARCHAR(4096) NOT NULL DEFAULT '',
   score_object_id INT NOT NULL,
   log_object_id INT,
   result_object_id INT,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  -- the options to search
+  model VARCHAR(255) GENERATED ALWAYS AS (JSON_VALUE(options, '$.model')) STORED,
+  support_model VARCHAR(255) GENERATED ALWAYS AS (JSON_VALUE(options, '$.supportModel')) STORED,
+  transpose INT GENERATED ALWAYS AS (JSON_VALUE(options, '$.transpose' RETURNING SIGNED)) STORED,
 
   INDEX title_prefix_idx (title(64)),
+  INDEX model_idx (model),
+  INDEX support_model_idx (support_model),
+  INDEX transpose_idx (transpose),
   CONSTRAINT fk_score_object_ids FOREIGN KEY (score_object_id) REFERENCES objects(id),
   CONSTRAINT fk_log_object_ids FOREIGN KEY (log_object_id) REFERENCES objects(id),
   CONSTRAINT fk_result_object_ids FOREIGN KEY (result_object_id) REFERENCES objects(id)
@@ -89,19 +112,134 @@ CREATE TABLE IF NOT EXISTS processes (
   id INT AUTO_INCREMENT PRIMARY KEY,
   request_id VARCHAR(255),
   status_id INT NOT NULL,
+  kind_id INT NOT NULL DEFAULT 1,
+  group_id INT,
+  parent_id INT,
+  starred BOOLEAN NOT NULL DEFAULT FALSE,
   details_id INT NOT NULL,
   started_at TIMESTAMP,
   completed_at TIMESTAMP,
+  pruned_at TIMESTAMP,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 
   INDEX status_id_idx (status_id),
+  INDEX group_id_idx (group_id),
+  INDEX parent_id_idx (parent_id),
   INDEX created_at_idx (created_at),
+  INDEX completed_at_idx (completed_at),
   UNIQUE INDEX request_id_idx (request_id),
   UNIQUE INDEX details_id_idx (details_id),
   CONSTRAINT fk_status_id FOREIGN KEY (status_id) REFERENCES master_statuses(id),
+  CONSTRAINT fk_kind_id FOREIGN KEY (kind_id) REFERENCES master_process_kinds(id),
+  CONSTRAINT fk_group_id FOREIGN KEY (group_id) REFERENCES processes(id),
+  CONSTRAINT fk_parent_id FOREIGN KEY (parent_id) REFERENCES processes(id),
   CONSTRAINT fk_details_id FOREIGN KEY (details_id) REFERENCES process_details(id)
 );
+
+CREATE TABLE IF NOT EXISTS process_tags (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  process_id INT NOT NULL,
+  -- lowercased by the server, compared as is
+  name VARCHAR(64) COLLATE utf8mb4_bin NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+  INDEX name_idx (name),
+  UNIQUE INDEX process_id_name_idx (process_id, name),
+  CONSTRAINT fk_process_id FOREIGN KEY (process_id) REFERENCES processes(id)
+);
+{{- end }}
+
+{{- define "pneutrinoutil.mysql.migrateSQL" -}}
+-- Upgrade the tables created by the older versions, as tables.sql creates only the missing tables.
+-- Can be run repeatedly.
+DROP PROCEDURE IF EXISTS alter_table;
+DROP PROCEDURE IF EXISTS add_column;
+DROP PROCEDURE IF EXISTS add_index;
+DROP PROCEDURE IF EXISTS add_constraint;
+
+DELIMITER //
+CREATE PROCEDURE alter_table(IN tbl VARCHAR(64), IN clause TEXT)
+BEGIN
+  SET @ddl = CONCAT('ALTER TABLE ', tbl, ' ', clause);
+  PREPARE stmt FROM @ddl;
+  EXECUTE stmt;
+  DEALLOCATE PREPARE stmt;
+END //
+
+CREATE PROCEDURE add_column(IN tbl VARCHAR(64), IN col VARCHAR(64), IN def TEXT)
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = tbl AND column_name = col
+  ) THEN
+    CALL alter_table(tbl, CONCAT('ADD COLUMN ', col, ' ', def));
+  END IF;
+END //
+
+CREATE PROCEDURE add_index(IN tbl VARCHAR(64), IN idx VARCHAR(64), IN def TEXT)
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = tbl AND index_name = idx
+  ) THEN
+    CALL alter_table(tbl, CONCAT('ADD INDEX ', idx, ' ', def));
+  END IF;
+END //
+
+CREATE PROCEDURE add_constraint(IN tbl VARCHAR(64), IN con VARCHAR(64), IN def TEXT)
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.table_constraints
+    WHERE table_schema = DATABASE() AND table_name = tbl AND constraint_name = con
+  ) THEN
+    CALL alter_table(tbl, CONCAT('ADD CONSTRAINT ', con, ' ', def));
+  END IF;
+END //
+DELIMITER ;
+
+CALL add_column('objects', 'bucket_path_sha256', 'VARCHAR(64) GENERATED ALWAYS AS (SHA2(CONCAT(bucket, ''___'', path), 256)) STORED');
+-- the transcoded wavs were cached twice by the concurrent requests
+DELETE o FROM objects o
+  JOIN objects k ON k.bucket_path_sha256 = o.bucket_path_sha256 AND k.id < o.id
+  WHERE NOT EXISTS (
+    SELECT 1 FROM process_details d
+    WHERE o.id IN (d.score_object_id, d.log_object_id, d.result_object_id)
+  );
+CALL add_constraint('objects', 'bucket_path_sha256_idx', 'UNIQUE (bucket_path_sha256)');
+
+CALL add_column('process_details', 'options', 'JSON');
+CALL add_column('process_details', 'basename', 'VARCHAR(4096) NOT NULL');
+CALL add_column('process_details', 'notes', 'VARCHAR(4096) NOT NULL DEFAULT ''''');
+CALL add_column('process_details', 'model', 'VARCHAR(255) GENERATED ALWAYS AS (JSON_VALUE(options, ''$.model'')) STORED');
+CALL add_column('process_details', 'support_model', 'VARCHAR(255) GENERATED ALWAYS AS (JSON_VALUE(options, ''$.supportModel'')) STORED');
+CALL add_column('process_details', 'transpose', 'INT GENERATED ALWAYS AS (JSON_VALUE(options, ''$.transpose'' RETURNING SIGNED)) STORED');
+CALL add_index('process_details', 'model_idx', '(model)');
+CALL add_index('process_details', 'support_model_idx', '(support_model)');
+CALL add_index('process_details', 'transpose_idx', '(transpose)');
+-- the titles were the basenames before they became editable
+UPDATE process_details SET basename = title WHERE basename = '';
+
+CALL add_column('processes', 'kind_id', 'INT NOT NULL DEFAULT 1');
+CALL add_column('processes', 'group_id', 'INT');
+CALL add_column('processes', 'parent_id', 'INT');
+CALL add_column('processes', 'starred', 'BOOLEAN NOT NULL DEFAULT FALSE');
+CALL add_column('processes', 'pruned_at', 'TIMESTAMP');
+CALL add_index('processes', 'group_id_idx', '(group_id)');
+CALL add_index('processes', 'parent_id_idx', '(parent_id)');
+CALL add_index('processes', 'completed_at_idx', '(completed_at)');
+CALL add_constraint('processes', 'fk_kind_id', 'FOREIGN KEY (kind_id) REFERENCES master_process_kinds(id)');
+CALL add_constraint('processes', 'fk_group_id', 'FOREIGN KEY (group_id) REFERENCES processes(id)');
+CALL add_constraint('processes', 'fk_parent_id', 'FOREIGN KEY (parent_id) REFERENCES processes(id)');
+
+-- the tags were case-insensitive before lowercased
+ALTER TABLE process_tags MODIFY name VARCHAR(64) COLLATE utf8mb4_bin NOT NULL;
+UPDATE process_tags SET name = LOWER(name) WHERE name <> LOWER(name);
+
+DROP PROCEDURE alter_table;
+DROP PROCEDURE add_column;
+DROP PROCEDURE add_index;
+DROP PROCEDURE add_constraint;
 {{- end }}
 
 {{- define "pneutrinoutil.mysql.setupSh" -}}
@@ -132,6 +270,7 @@ set -o pipefail
 run "${root}/db.sql"
 run "${root}/users.sql"
 run "${root}/tables.sql" "$mysql_db"
+run "${root}/migrate.sql" "$mysql_db"
 
 client <<< "SHOW DATABASES;"
 client -D "$mysql_db" <<< "SHOW TABLES;"
@@ -150,6 +289,8 @@ users.sql: |
   {{- include "pneutrinoutil.mysql.usersSQL" . | nindent 4 }}
 tables.sql: |
   {{- include "pneutrinoutil.mysql.tablesSQL" . | nindent 4 }}
+migrate.sql: |
+  {{- include "pneutrinoutil.mysql.migrateSQL" . | nindent 4 }}
 setup.sh: |
   {{- include "pneutrinoutil.mysql.setupSh" . | nindent 4 }}
 {{- end }}
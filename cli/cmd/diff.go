@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/berquerant/pneutrinoutil/cli/results"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().String("wav", "", "write the residual wav, A minus aligned B, into the file")
+	diffCmd.Flags().String("json", "", "write the report as json into the file")
+	diffCmd.Flags().Float64("minCents", 10, "print the notes whose f0 differ more than the cents")
+	diffCmd.Flags().Float64("timingTolerance", 0.01, "print the labels whose start times shift more than the seconds")
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff RUN RUN",
+	Short: "Compare the audio of the runs",
+	Long: `Compare the audio of the runs
+
+Align the wav of the second run to the first one, then report the RMS difference,
+the f0 difference per note and the changed phoneme timings.
+RUN is the name of the result directory or the path.
+The f0 per note and the timings are available if the results have the f0 and the timing label.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		renders := make([]*compare.Render, 2)
+		for i, x := range args {
+			r, err := results.Find(resultDir(cmd), x)
+			if err != nil {
+				return err
+			}
+			if renders[i], err = compare.ReadRender(compare.NewResultRenderPaths(r.Dir, r.Element.Basename)); err != nil {
+				return err
+			}
+		}
+
+		var (
+			wavPath, _         = cmd.Flags().GetString("wav")
+			jsonPath, _        = cmd.Flags().GetString("json")
+			minCents, _        = cmd.Flags().GetFloat64("minCents")
+			timingTolerance, _ = cmd.Flags().GetFloat64("timingTolerance")
+		)
+		d, residual := compare.NewDiff(renders[0], renders[1], timingTolerance)
+		if wavPath != "" {
+			if err := audio.WriteFile(wavPath, residual); err != nil {
+				return err
+			}
+		}
+		if jsonPath != "" {
+			if err := writeFileFunc(jsonPath, func(f *os.File) error {
+				enc := json.NewEncoder(f)
+				enc.SetIndent("", "  ")
+				return enc.Encode(d)
+			}); err != nil {
+				return err
+			}
+		}
+		return d.Write(os.Stdout, minCents)
+	},
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/berquerant/pneutrinoutil/cli/doctor"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Uint64("minFreeSpace", 1024, "least free space of the workDir in MiB")
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the NEUTRINO installation",
+	Long: `Check the NEUTRINO installation
+
+Check the layout of the NEUTRINO directory, the permissions of the binaries, the shared libraries,
+the version of NEUTRINO, info.toml of every model and the free space of the workDir.
+Exit with non-zero status if any error is found.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			neutrinoDir, _  = cmd.Flags().GetString("neutrinoDir")
+			workDir, _      = cmd.Flags().GetString("workDir")
+			minFreeSpace, _ = cmd.Flags().GetUint64("minFreeSpace")
+		)
+		r := doctor.Installation{
+			NeutrinoDir:  neutrinoDir,
+			WorkDir:      workDir,
+			MinFreeSpace: minFreeSpace << 20,
+		}.Check(cmd.Context())
+		return writeReport(r)
+	},
+}
+
+func writeReport(r *doctor.Report) error {
+	if err := r.Write(os.Stdout); err != nil {
+		return err
+	}
+	if n := r.Count(doctor.SeverityError); n > 0 {
+		return fmt.Errorf("%w: %d errors found", ErrCheck, n)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/berquerant/pneutrinoutil/cli/ctl"
@@ -30,6 +31,10 @@ func NewConfig(cmd *cobra.Command, args []string) (*ctl.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// override by profile
+	if err := ApplyProfile(cmd, c); err != nil {
+		return nil, err
+	}
 	// override by file
 	if len(args) == 1 {
 		if err := func() error {
@@ -60,3 +65,21 @@ func NewConfig(cmd *cobra.Command, args []string) (*ctl.Config, error) {
 	}
 	return c, nil
 }
+
+// ApplyProfile overrides c by the profile specified by --profile.
+func ApplyProfile(cmd *cobra.Command, c *ctl.Config) error {
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		return nil
+	}
+	path, _ := cmd.Flags().GetString("profiles")
+	if path == "" {
+		workDir, _ := cmd.Flags().GetString("workDir")
+		path = filepath.Join(workDir, "profiles.yml")
+	}
+	profiles, err := ctl.ReadProfiles(path)
+	if err != nil {
+		return fmt.Errorf("%w: read profiles %s", err, path)
+	}
+	return profiles.Apply(name, c)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/hook"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.Flags().String("hook", "", "hook command, required")
+	hookCmd.Flags().StringSlice("events", nil, "events to call the hook")
+	hookCmd.Flags().String("state", "", "state directory of the pipeline, required")
+	hookCmd.Flags().String("resultDir", "", "result directory")
+	hookCmd.Flags().String("outputDir", "./output", "directory of the intermediate files")
+	hookCmd.Flags().String("basename", "", "basename of the score")
+	hookCmd.Flags().String("event", "", "event occurred, required")
+	hookCmd.Flags().String("stage", "", "stage of the event")
+	hookCmd.Flags().Int("status", 0, "exit status of the pipeline on failure")
+}
+
+var hookCmd = &cobra.Command{
+	Use:    "hook",
+	Short:  "Record the lifecycle event and call the hook",
+	Hidden: true, // called from the generated script
+	Long: `Record the lifecycle event and call the hook
+
+The hook receives the json document of the event via stdin and the result directory as the 1st argument.
+The config in the document is read from HookConfig.
+Exits with non-zero status to stop the pipeline if the hook fails or cannot be called at start or preStage.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		err := runHook(cmd)
+		if err == nil || errors.Is(err, ErrCheck) {
+			return err
+		}
+		eventName, _ := cmd.Flags().GetString("event")
+		if event := hook.Event(eventName); !slices.Contains(hook.Events, event) || event.CanVeto() {
+			// the stage should not run without the hook
+			return errors.Join(ErrCheck, err)
+		}
+		return err
+	},
+}
+
+func runHook(cmd *cobra.Command) error {
+	var (
+		hookCommand, _ = cmd.Flags().GetString("hook")
+		eventNames, _  = cmd.Flags().GetStringSlice("events")
+		stateDir, _    = cmd.Flags().GetString("state")
+		resultDir, _   = cmd.Flags().GetString("resultDir")
+		outputDir, _   = cmd.Flags().GetString("outputDir")
+		basename, _    = cmd.Flags().GetString("basename")
+		eventName, _   = cmd.Flags().GetString("event")
+		stage, _       = cmd.Flags().GetString("stage")
+		status, _      = cmd.Flags().GetInt("status")
+	)
+	if hookCommand == "" || stateDir == "" || eventName == "" {
+		return fmt.Errorf("%w: require hook, state and event", ErrArgument)
+	}
+	events, err := hook.ParseEvents(eventNames)
+	if err != nil {
+		return errors.Join(ErrArgument, err)
+	}
+	event := hook.Event(eventName)
+	if !slices.Contains(hook.Events, event) {
+		return fmt.Errorf("%w: unknown event %s", ErrArgument, eventName)
+	}
+
+	var (
+		state  = hook.NewState(stateDir)
+		record = &hook.Record{
+			Event: event,
+			Stage: stage,
+			Time:  time.Now(),
+		}
+	)
+	records, err := state.Records()
+	if err != nil {
+		return err
+	}
+	records = append(records, record)
+
+	var hookErr error
+	if slices.Contains(events, event) {
+		d, err := hook.NewDocument(records, status)
+		if err != nil {
+			return err
+		}
+		d.ResultDir = resultDir
+		if d.Files, err = hook.Files(outputDir, basename, resultDir); err != nil {
+			return err
+		}
+		if x := os.Getenv("HookConfig"); x != "" {
+			d.Config = json.RawMessage(x)
+		}
+		hookErr = hook.Run(cmd.Context(), hookCommand, d, os.Stdout, os.Stderr)
+		record.Vetoed = hookErr != nil && event.CanVeto()
+	}
+	if err := state.Append(record); err != nil {
+		return err
+	}
+
+	switch {
+	case hookErr == nil:
+		return nil
+	case record.Vetoed:
+		return fmt.Errorf("%w: vetoed by the hook at %s %s: %w", ErrCheck, event, stage, hookErr)
+	default:
+		slog.Warn("hook failed and ignored", slog.String("event", eventName), slog.String("stage", stage), logx.Err(hookErr))
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/manifest"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+	manifestCmd.Flags().String("resultDir", "", "result directory, required")
+	manifestCmd.Flags().String("score", "", "rendered score, required")
+	manifestCmd.Flags().StringSlice("labels", nil, "label files")
+	manifestCmd.Flags().String("commandLine", "", "arguments of pneutrinoutil as json")
+	manifestCmd.Flags().StringSlice("env", nil, "names of additional environment variables allowed to read")
+}
+
+var manifestCmd = &cobra.Command{
+	Use:    "manifest",
+	Short:  "Write the manifest into the result directory",
+	Hidden: true, // called from the generated script
+	Long: `Write the manifest into the result directory
+
+The manifest records the hashes of the score, the labels, the outputs, the binaries and the models,
+the versions, the command line and the environment whitelist to reproduce the render.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			resultDir, _   = cmd.Flags().GetString("resultDir")
+			score, _       = cmd.Flags().GetString("score")
+			labels, _      = cmd.Flags().GetStringSlice("labels")
+			commandLine, _ = cmd.Flags().GetString("commandLine")
+			env, _         = cmd.Flags().GetStringSlice("env")
+			neutrinoDir, _ = cmd.Flags().GetString("neutrinoDir")
+		)
+		if resultDir == "" || score == "" {
+			return fmt.Errorf("%w: require resultDir and score", ErrArgument)
+		}
+
+		b, err := os.ReadFile(filepath.Join(resultDir, "config.yml"))
+		if err != nil {
+			return err
+		}
+		var c ctl.Config
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return err
+		}
+		models := []string{c.ModelDir}
+		if c.SupportModelDir != "" {
+			models = append(models, c.SupportModelDir)
+		}
+		var args []string
+		if commandLine != "" {
+			if err := json.Unmarshal([]byte(commandLine), &args); err != nil {
+				return fmt.Errorf("%w: commandLine", err)
+			}
+		}
+
+		m, err := manifest.New(&manifest.Input{
+			NeutrinoDir:     neutrinoDir,
+			NeutrinoVersion: c.NeutrinoVersion,
+			Models:          models,
+			Score:           score,
+			Labels:          labels,
+			ResultDir:       resultDir,
+			CommandLine:     args,
+			Env:             env,
+		}, time.Now())
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(resultDir, manifest.FileName))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		return m.Write(f)
+	},
+}
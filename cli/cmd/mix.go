@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(mixCmd)
+	mixCmd.Flags().String("input", "", "vocal wav")
+	mixCmd.Flags().String("output", "", "output wav")
+
+	var c ctl.Config
+	if err := c.SetFlags(mixCmd.Flags()); err != nil {
+		panic(err)
+	}
+}
+
+var mixCmd = &cobra.Command{
+	Use:    "mix",
+	Short:  "Mix the generated wav with the accompaniment",
+	Hidden: true, // called from the generated script
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			input, _  = cmd.Flags().GetString("input")
+			output, _ = cmd.Flags().GetString("output")
+		)
+		if input == "" || output == "" {
+			return fmt.Errorf("%w: require input and output", ErrArgument)
+		}
+
+		c, err := NewConfig(cmd, nil)
+		if err != nil {
+			return err
+		}
+		if c.Accompaniment == "" {
+			return fmt.Errorf("%w: require accompaniment", ErrArgument)
+		}
+
+		vocal, err := audio.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		accompaniment, err := audio.ReadFile(c.Accompaniment)
+		if err != nil {
+			return err
+		}
+		tracks, err := c.MixTracks(vocal, accompaniment)
+		if err != nil {
+			return err
+		}
+		w, err := audio.Mix(tracks...)
+		if err != nil {
+			return err
+		}
+		slog.Info("mix", "input", input, "accompaniment", c.Accompaniment, "output", output, "duration", w.Duration())
+		return audio.WriteFile(output, w)
+	},
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/berquerant/pneutrinoutil/pkg/musicxml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(partCmd)
+	partCmd.Flags().String("input", "", "input musicxml")
+	partCmd.Flags().String("part", "", "id or name of the part to extract; list parts if empty")
+	partCmd.Flags().String("output", "", "output musicxml; stdout if empty")
+}
+
+var partCmd = &cobra.Command{
+	Use:   "part",
+	Short: "Extract a part from the musicxml",
+	Long: `Extract a part from the musicxml
+
+e.g.
+pneutrinoutil part --input some.musicxml
+lists parts of some.musicxml as json
+pneutrinoutil part --input some.musicxml --part P1 --output p1.musicxml
+writes the score that contains only the part P1`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			input, _  = cmd.Flags().GetString("input")
+			part, _   = cmd.Flags().GetString("part")
+			output, _ = cmd.Flags().GetString("output")
+		)
+		if input == "" {
+			return fmt.Errorf("%w: require input", ErrArgument)
+		}
+		score, err := os.ReadFile(input)
+		if err != nil {
+			return err
+		}
+
+		if part == "" {
+			parts, err := musicxml.Parts(score)
+			if err != nil {
+				return err
+			}
+			b, err := json.Marshal(parts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s", b)
+			return nil
+		}
+
+		b, err := musicxml.ExtractPart(score, part)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = os.Stdout.Write(b)
+			return err
+		}
+		return os.WriteFile(output, b, 0644)
+	},
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(postprocessCmd)
+	postprocessCmd.Flags().String("input", "", "input wav")
+	postprocessCmd.Flags().String("output", "", "output wav")
+
+	var c ctl.Config
+	if err := c.SetFlags(postprocessCmd.Flags()); err != nil {
+		panic(err)
+	}
+}
+
+var postprocessCmd = &cobra.Command{
+	Use:    "postprocess",
+	Short:  "Normalize, trim silence and fade the generated wav",
+	Hidden: true, // called from the generated script
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			input, _  = cmd.Flags().GetString("input")
+			output, _ = cmd.Flags().GetString("output")
+		)
+		if input == "" || output == "" {
+			return fmt.Errorf("%w: require input and output", ErrArgument)
+		}
+
+		c, err := NewConfig(cmd, nil)
+		if err != nil {
+			return err
+		}
+		params, err := c.PostProcessParams()
+		if err != nil {
+			return err
+		}
+
+		w, err := audio.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		if err := audio.PostProcess(w, params); err != nil {
+			return err
+		}
+		slog.Info("postprocess", "input", input, "output", output, "peak", w.Peak())
+		return audio.WriteFile(output, w)
+	},
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(regressCmd)
+	regressCmd.Flags().StringSlice("corpus", nil, "musicxml files or directories of musicxml files to render")
+	for _, side := range []string{"baseline", "candidate"} {
+		regressCmd.Flags().String(side+"NeutrinoDir", "", "NEUTRINO directory of the "+side+"; default is --neutrinoDir")
+		regressCmd.Flags().String(side+"Model", "", "singer of the "+side+"; default is --model")
+		regressCmd.Flags().String(side+"SupportModel", "", "support singer of the "+side+"; default is --supportModel")
+	}
+	regressCmd.Flags().Float64("maxDurationDiff", 0.1, "flag the scores whose durations differ more than the seconds, disabled if 0")
+	regressCmd.Flags().Float64("maxF0Cents", 50, "flag the scores whose f0 deviate more than the cents on average, disabled if 0")
+	regressCmd.Flags().Float64("maxSpectralDistance", 6, "flag the scores whose mean log-spectral distance exceeds the dB, disabled if 0")
+	regressCmd.Flags().Float64("maxTimingShift", 0.05, "flag the scores whose phoneme timings shift more than the seconds, disabled if 0")
+	regressCmd.Flags().Int("concurrency", 1, "number of scores rendered at the same time")
+	regressCmd.Flags().StringSlice("env", nil, "names of additional environment variables to allow reading; all allows everythings")
+	regressCmd.Flags().StringP("shell", "s", "bash", "shell command to execute")
+
+	var c ctl.Config
+	if err := c.SetFlags(regressCmd.Flags()); err != nil {
+		panic(err)
+	}
+}
+
+var regressCmd = &cobra.Command{
+	Use:   "regress [CONFIG_YML|CONFIG_JSON]",
+	Short: "Render a corpus with the baseline and the candidate and compare the outputs",
+	Long: `Render a corpus with the baseline and the candidate and compare the outputs
+
+Render every score of the corpus with the baseline and the candidate NEUTRINO directory or singer,
+then compare the duration, the f0 deviation in cents, the log-spectral distance and the shifts of the timing labels.
+Scores that changed beyond the thresholds are flagged.
+Results are written into $workDir/regress/regress__YYYYmmddHHMMSS_TIMESTAMP_PID with regress.json.
+Exit with non-zero status if any scores are flagged.
+
+e.g.
+pneutrinoutil regress --corpus songs/ --baselineNeutrinoDir NEUTRINO-v2 --candidateNeutrinoDir NEUTRINO-v3
+compares the renders of NEUTRINO v2 and v3 of the musicxml files in songs/`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		c, err := NewConfig(cmd, args)
+		if err != nil {
+			return err
+		}
+		corpus, _ := cmd.Flags().GetStringSlice("corpus")
+		scores, err := listCorpus(corpus)
+		if err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return fmt.Errorf("%w: require corpus", ErrArgument)
+		}
+		threshold, err := newRegressThreshold(cmd)
+		if err != nil {
+			return err
+		}
+
+		var (
+			workDir, _     = cmd.Flags().GetString("workDir")
+			concurrency, _ = cmd.Flags().GetInt("concurrency")
+		)
+		// absolute because the generated scripts are executed on the NEUTRINO directory
+		regressDir, err := filepath.Abs(filepath.Join(workDir, "regress", pathx.NewResultElement("regress", now, now.Unix(), os.Getpid()).String()))
+		if err != nil {
+			return err
+		}
+		for _, d := range []string{"score", "config", "log"} {
+			if err := pathx.EnsureDir(filepath.Join(regressDir, d)); err != nil {
+				return err
+			}
+		}
+
+		var (
+			r = &regressor{
+				cmd:        cmd,
+				base:       c,
+				regressDir: regressDir,
+				threshold:  threshold,
+			}
+			report = &compare.Regression{
+				Baseline:  newRegressTarget(cmd, c, "baseline"),
+				Candidate: newRegressTarget(cmd, c, "candidate"),
+				Threshold: *threshold,
+				Entries:   make([]*compare.RegressionEntry, len(scores)),
+			}
+			sem = make(chan struct{}, max(concurrency, 1))
+			wg  sync.WaitGroup
+		)
+		for i, score := range scores {
+			wg.Go(func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				report.Entries[i] = r.regress(cmd.Context(), i, score, &report.Baseline, &report.Candidate)
+			})
+		}
+		wg.Wait()
+
+		if err := writeFileFunc(filepath.Join(regressDir, compare.RegressionJSONFileName), func(f *os.File) error {
+			return report.WriteJSON(f)
+		}); err != nil {
+			return err
+		}
+		if err := report.WriteText(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println(filepath.Join(regressDir, compare.RegressionJSONFileName))
+		if n := report.Flagged(); n > 0 {
+			return fmt.Errorf("%w: %d of %d scores flagged", ErrCheck, n, len(report.Entries))
+		}
+		return nil
+	},
+}
+
+// listCorpus returns the musicxml files, directories are expanded to the musicxml files in them.
+func listCorpus(corpus []string) ([]string, error) {
+	var xs []string
+	for _, x := range corpus {
+		switch pathx.Exist(x) {
+		case pathx.Efile:
+			xs = append(xs, x)
+		case pathx.Edir:
+			matched, err := filepath.Glob(filepath.Join(x, "*.musicxml"))
+			if err != nil {
+				return nil, err
+			}
+			xs = append(xs, matched...)
+		default:
+			return nil, fmt.Errorf("%w: corpus %s not found", ErrArgument, x)
+		}
+	}
+	return xs, nil
+}
+
+func newRegressThreshold(cmd *cobra.Command) (*compare.Threshold, error) {
+	var t compare.Threshold
+	t.Duration, _ = cmd.Flags().GetFloat64("maxDurationDiff")
+	t.F0Cents, _ = cmd.Flags().GetFloat64("maxF0Cents")
+	t.SpectralDistance, _ = cmd.Flags().GetFloat64("maxSpectralDistance")
+	t.TimingShift, _ = cmd.Flags().GetFloat64("maxTimingShift")
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrArgument, err)
+	}
+	return &t, nil
+}
+
+func newRegressTarget(cmd *cobra.Command, c *ctl.Config, side string) compare.Target {
+	t := compare.Target{
+		Model:        c.ModelDir,
+		SupportModel: c.SupportModelDir,
+	}
+	t.NeutrinoDir, _ = cmd.Flags().GetString("neutrinoDir")
+	for _, x := range []struct {
+		name string
+		dest *string
+	}{
+		{name: side + "NeutrinoDir", dest: &t.NeutrinoDir},
+		{name: side + "Model", dest: &t.Model},
+		{name: side + "SupportModel", dest: &t.SupportModel},
+	} {
+		if cmd.Flags().Changed(x.name) {
+			*x.dest, _ = cmd.Flags().GetString(x.name)
+		}
+	}
+	return t
+}
+
+type regressor struct {
+	cmd        *cobra.Command
+	base       *ctl.Config
+	regressDir string
+	threshold  *compare.Threshold
+}
+
+// regress renders the score with the baseline and the candidate and returns the entry of the report.
+// Each render has its own basename not to share the intermediate files in the NEUTRINO directory.
+func (r *regressor) regress(ctx context.Context, index int, score string, baseline, candidate *compare.Target) *compare.RegressionEntry {
+	var (
+		name     = fmt.Sprintf("%s_%d", strings.TrimSuffix(filepath.Base(score), filepath.Ext(score)), index)
+		entry    = &compare.RegressionEntry{Score: score}
+		logAttrs = []any{"name", name, "score", score}
+	)
+	slog.Info("regress start", logAttrs...)
+	renders := make([]*compare.Render, 2)
+	for i, x := range []struct {
+		side   string
+		target *compare.Target
+		dest   *string
+	}{
+		{side: "baseline", target: baseline, dest: &entry.Baseline},
+		{side: "candidate", target: candidate, dest: &entry.Candidate},
+	} {
+		sideName := name + "_" + x.side
+		resultDir, err := r.render(ctx, sideName, score, x.target)
+		if err != nil {
+			slog.Error("regress failed", append(logAttrs, "side", x.side, "err", err)...)
+			entry.Error = fmt.Sprintf("%s: %v", x.side, err)
+			return entry
+		}
+		*x.dest = resultDir
+		if renders[i], err = compare.ReadRender(compare.NewResultRenderPaths(resultDir, sideName)); err != nil {
+			slog.Error("regress failed", append(logAttrs, "side", x.side, "err", err)...)
+			entry.Error = fmt.Sprintf("%s: %v", x.side, err)
+			return entry
+		}
+	}
+
+	entry.Result = compare.Compare(renders[0], renders[1], r.threshold.TimingShift)
+	entry.Exceeded = r.threshold.Exceeded(entry.Result)
+	slog.Info("regress done", append(logAttrs, "exceeded", entry.Exceeded)...)
+	return entry
+}
+
+// render renders the score with the target and returns the result directory.
+func (r *regressor) render(ctx context.Context, name, score string, target *compare.Target) (string, error) {
+	b, err := os.ReadFile(score)
+	if err != nil {
+		return "", err
+	}
+	scorePath := filepath.Join(r.regressDir, "score", name+".musicxml")
+	if err := os.WriteFile(scorePath, b, 0644); err != nil {
+		return "", err
+	}
+
+	c := *r.base
+	c.Score = scorePath
+	c.ModelDir = target.Model
+	c.SupportModelDir = target.SupportModel
+	if b, err = yaml.Marshal(&c); err != nil {
+		return "", err
+	}
+	configPath := filepath.Join(r.regressDir, "config", name+".yml")
+	if err := os.WriteFile(configPath, b, 0644); err != nil {
+		return "", err
+	}
+
+	logFile, err := os.Create(filepath.Join(r.regressDir, "log", name+".log"))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = logFile.Close() }()
+
+	var (
+		shell, _ = r.cmd.Flags().GetString("shell")
+		env, _   = r.cmd.Flags().GetStringSlice("env")
+	)
+	if err := runSelf(ctx, logFile, configPath, r.regressDir, target.NeutrinoDir, shell, env); err != nil {
+		return "", err
+	}
+	return findSelfResult(r.regressDir, name)
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/hook"
+	"github.com/berquerant/pneutrinoutil/cli/manifest"
+	"github.com/berquerant/pneutrinoutil/pkg/client"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/server/handler"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/pflag"
+)
+
+// localFlags are the flags of the local pipeline, the server does not use them.
+var localFlags = []string{"dry", "list-tasks", "include", "exclude", "env", "shell"}
+
+// checkRemoteFlags rejects the flags that the server ignores.
+func checkRemoteFlags(flags *pflag.FlagSet) error {
+	var xs []string
+	for _, x := range localFlags {
+		if flags.Changed(x) {
+			xs = append(xs, "--"+x)
+		}
+	}
+	if len(xs) > 0 {
+		return fmt.Errorf("%w: %s not available with the server", ErrArgument, strings.Join(xs, ", "))
+	}
+	return nil
+}
+
+// remote submits the score to pneutrinoutil-server instead of running NEUTRINO,
+// and downloads the results into the local result directory.
+type remote struct {
+	client    *client.Client
+	config    *ctl.Config
+	basename  string
+	resultDir string
+	play      string
+	hook      string
+	events    []hook.Event
+	records   []*hook.Record
+}
+
+func (r *remote) run(ctx context.Context) error {
+	if len(r.config.Stages) > 0 {
+		return fmt.Errorf("%w: stages are not available with the server", ErrArgument)
+	}
+	if err := os.MkdirAll(r.resultDir, 0755); err != nil {
+		return err
+	}
+	if err := r.callHook(ctx, hook.EventStart, ""); err != nil {
+		_ = r.callHook(ctx, hook.EventFailure, "")
+		return err
+	}
+
+	rid, err := r.start(ctx)
+	if err != nil {
+		_ = r.callHook(ctx, hook.EventFailure, err.Error())
+		return err
+	}
+	slog.Info("submitted", slog.String("server", r.client.BaseURL), slog.String("rid", rid))
+
+	d, err := r.client.Watch(ctx, rid, func(d *handler.GetDetailResponseData) {
+		slog.Info("status", slog.String("rid", rid), slog.String("status", d.Status))
+	})
+	if err != nil {
+		_ = r.callHook(ctx, hook.EventFailure, err.Error())
+		return err
+	}
+
+	if d.Status != domain.ProcessStatusSucceed.String() {
+		// the log and the config tell why it failed
+		r.downloadLog(ctx, rid)
+		_ = r.downloadConfig(ctx, rid)
+		_ = r.callHook(ctx, hook.EventFailure, fmt.Sprintf("process %s is %s on the server", rid, d.Status))
+		return fmt.Errorf("%w: process %s is %s, result dir %s", ErrCheck, rid, d.Status, r.resultDir)
+	}
+
+	if err := r.download(ctx, rid); err != nil {
+		_ = r.callHook(ctx, hook.EventFailure, err.Error())
+		return err
+	}
+	slog.Info("downloaded", slog.String("rid", rid), slog.String("dir", r.resultDir))
+	_ = r.callHook(ctx, hook.EventSuccess, "")
+	return r.playWav(ctx)
+}
+
+func (r *remote) start(ctx context.Context) (string, error) {
+	score, err := os.Open(r.config.Score)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = score.Close() }()
+	req := &client.StartRequest{
+		Score:  &client.File{Name: filepath.Base(r.config.Score), Content: score},
+		Config: r.config.JobOptions(),
+	}
+	if x := r.config.Accompaniment; x != "" {
+		f, err := os.Open(x)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = f.Close() }()
+		req.Accompaniment = &client.File{Name: filepath.Base(x), Content: f}
+	}
+	return r.client.Start(ctx, req)
+}
+
+// download saves the results like the local result directory.
+func (r *remote) download(ctx context.Context, rid string) error {
+	formats, err := r.config.OutputFormats()
+	if err != nil {
+		return err
+	}
+	files := map[string]func() (io.ReadCloser, error){
+		r.basename + ".wav": func() (io.ReadCloser, error) {
+			return r.client.Wav(ctx, rid, "")
+		},
+		r.basename + ".musicxml": func() (io.ReadCloser, error) {
+			return r.client.MusicXML(ctx, rid)
+		},
+		pathx.TimingLabelFileName(r.basename): func() (io.ReadCloser, error) {
+			return r.client.Label(ctx, rid)
+		},
+		manifest.FileName: func() (io.ReadCloser, error) {
+			return r.client.Manifest(ctx, rid)
+		},
+	}
+	if r.config.Accompaniment != "" {
+		files[pathx.MixFileName(r.basename)] = func() (io.ReadCloser, error) {
+			return r.client.Mix(ctx, rid)
+		}
+	}
+	for _, f := range formats {
+		files[f.FileName(r.basename)] = func() (io.ReadCloser, error) {
+			return r.client.Wav(ctx, rid, f.String())
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := r.save(name, files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := r.downloadConfig(ctx, rid); err != nil {
+		return err
+	}
+	r.downloadLog(ctx, rid)
+	return nil
+}
+
+// downloadLog saves the log, the process may have no log.
+func (r *remote) downloadLog(ctx context.Context, rid string) {
+	if err := r.save("process.log", func() (io.ReadCloser, error) {
+		return r.client.Log(ctx, rid)
+	}); err != nil {
+		slog.Warn("failed to download log", slog.String("rid", rid), logx.Err(err))
+	}
+}
+
+func (r *remote) downloadConfig(ctx context.Context, rid string) error {
+	c, err := r.client.Config(ctx, rid)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// the config of the server is in the hook documents after this
+	r.config = c
+	return os.WriteFile(filepath.Join(r.resultDir, "config.yml"), b, 0644)
+}
+
+func (r *remote) save(name string, f func() (io.ReadCloser, error)) error {
+	body, err := f()
+	if err != nil {
+		return fmt.Errorf("%w: download %s", err, name)
+	}
+	defer func() { _ = body.Close() }()
+	out, err := os.Create(filepath.Join(r.resultDir, name))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	_, err = io.Copy(out, body)
+	return err
+}
+
+// callHook records the event and calls the hook if the event is enabled.
+// Returns an error only if the hook vetoed the process.
+// cause overrides the error of the document if not empty.
+func (r *remote) callHook(ctx context.Context, event hook.Event, cause string) error {
+	record := &hook.Record{
+		Event: event,
+		Time:  time.Now(),
+	}
+	r.records = append(r.records, record)
+	if r.hook == "" || !slices.Contains(r.events, event) {
+		return nil
+	}
+
+	d, err := hook.NewDocument(r.records, 0)
+	if err != nil {
+		return err
+	}
+	if cause != "" {
+		d.Error = cause
+	}
+	d.ResultDir = r.resultDir
+	if d.Files, err = hook.Files("", r.basename, r.resultDir); err != nil {
+		return err
+	}
+	if d.Config, err = json.Marshal(r.config); err != nil {
+		return err
+	}
+	hookErr := hook.Run(ctx, r.hook, d, os.Stdout, os.Stderr)
+	switch {
+	case hookErr == nil:
+		return nil
+	case event.CanVeto():
+		record.Vetoed = true
+		return fmt.Errorf("%w: vetoed by the hook at %s: %w", ErrCheck, event, hookErr)
+	default:
+		slog.Warn("hook failed and ignored", slog.String("event", string(event)), logx.Err(hookErr))
+		return nil
+	}
+}
+
+// playWav plays the mix if exists like the local pipeline.
+func (r *remote) playWav(ctx context.Context) error {
+	if r.play == "" {
+		return nil
+	}
+	wav := filepath.Join(r.resultDir, r.basename+".wav")
+	if x := filepath.Join(r.resultDir, pathx.MixFileName(r.basename)); pathx.Exist(x) == pathx.Efile {
+		wav = x
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", strings.TrimSpace(r.play)+` "$1"`, "sh", wav)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
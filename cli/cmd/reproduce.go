@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/manifest"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(reproduceCmd)
+	reproduceCmd.Flags().StringP("shell", "s", "bash", "shell command to execute")
+}
+
+var reproduceCmd = &cobra.Command{
+	Use:   "reproduce RESULT_DIR",
+	Short: "Render the result again and report whether the outputs differ",
+	Long: `Render the result again and report whether the outputs differ
+
+Render the score in the result directory with config.yml and compare the new manifest with manifest.json.
+Results are written into $workDir/reproduce/BASENAME__YYYYmmddHHMMSS_TIMESTAMP_PID.
+NEUTRINO directory recorded in the manifest is used unless --neutrinoDir is given.
+Exit with non-zero status if the outputs differ.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		resultDir := args[0]
+		original, err := manifest.Read(filepath.Join(resultDir, manifest.FileName))
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(filepath.Join(resultDir, "config.yml"))
+		if err != nil {
+			return err
+		}
+		var c ctl.Config
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return err
+		}
+		basename := c.Basename()
+		// the score in the result directory is the rendered one, the part is already extracted
+		if c.Score, err = filepath.Abs(filepath.Join(resultDir, basename+".musicxml")); err != nil {
+			return err
+		}
+		c.Part = ""
+
+		var (
+			workDir, _     = cmd.Flags().GetString("workDir")
+			neutrinoDir, _ = cmd.Flags().GetString("neutrinoDir")
+			shell, _       = cmd.Flags().GetString("shell")
+		)
+		if !cmd.Flags().Changed("neutrinoDir") {
+			neutrinoDir = original.Neutrino.Dir
+		}
+		// absolute because the generated script is executed on the NEUTRINO directory
+		reproduceDir, err := filepath.Abs(filepath.Join(workDir, "reproduce", pathx.NewResultElement(basename, now, now.Unix(), os.Getpid()).String()))
+		if err != nil {
+			return err
+		}
+		if err := pathx.EnsureDir(reproduceDir); err != nil {
+			return err
+		}
+		if b, err = yaml.Marshal(&c); err != nil {
+			return err
+		}
+		configPath := filepath.Join(reproduceDir, "config.yml")
+		if err := os.WriteFile(configPath, b, 0644); err != nil {
+			return err
+		}
+
+		if err := runSelf(cmd.Context(), os.Stderr, configPath, reproduceDir, neutrinoDir, shell, original.Env); err != nil {
+			return fmt.Errorf("%w: failed to render", err)
+		}
+
+		reproducedDir, err := findSelfResult(reproduceDir, basename)
+		if err != nil {
+			return err
+		}
+		reproduced, err := manifest.Read(filepath.Join(reproducedDir, manifest.FileName))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--- %s\n+++ %s\n", resultDir, reproducedDir)
+		r := manifest.Compare(original, reproduced)
+		if err := r.Write(os.Stdout); err != nil {
+			return err
+		}
+		if r.OutputsDiffer() {
+			return fmt.Errorf("%w: %d outputs differ", ErrCheck, len(r.Outputs))
+		}
+		return nil
+	},
+}
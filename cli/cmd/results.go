@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/results"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(resultsCmd)
+	resultsCmd.AddCommand(resultsListCmd, resultsShowCmd, resultsPruneCmd, resultsDiffCmd)
+
+	for _, c := range []*cobra.Command{resultsListCmd, resultsPruneCmd} {
+		c.Flags().String("basename", "", "select the runs of the basename")
+		c.Flags().String("since", "", "select the runs started at or after the time; RFC3339 or YYYY-mm-dd")
+		c.Flags().String("until", "", "select the runs started at or before the time; RFC3339 or YYYY-mm-dd")
+		c.Flags().String("model", "", "select the runs whose model or supportModel is the value")
+	}
+	resultsPruneCmd.Flags().Int("keepLast", 0, "keep the last N runs of each basename, disabled if 0")
+	resultsPruneCmd.Flags().Duration("olderThan", 0, "remove the runs older than the duration, e.g. 720h, disabled if 0")
+	resultsPruneCmd.Flags().Bool("dry", false, "print the runs to be removed")
+}
+
+var resultsCmd = &cobra.Command{
+	Use:   "results",
+	Short: "Manage the results of the past runs",
+	Long: `Manage the results of the past runs
+
+Results are $workDir/result/BASENAME__YYYYmmddHHMMSS_TIMESTAMP_SALT.
+RUN is the name of the result directory or the path.`,
+}
+
+func resultDir(cmd *cobra.Command) string {
+	workDir, _ := cmd.Flags().GetString("workDir")
+	return filepath.Join(workDir, "result")
+}
+
+func newResultsFilter(cmd *cobra.Command) (results.Filter, error) {
+	var f results.Filter
+	f.Basename, _ = cmd.Flags().GetString("basename")
+	f.Model, _ = cmd.Flags().GetString("model")
+	for _, x := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{name: "since", dest: &f.Since},
+		{name: "until", dest: &f.Until},
+	} {
+		v, _ := cmd.Flags().GetString(x.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseResultsTime(v)
+		if err != nil {
+			return f, fmt.Errorf("%w: %s", ErrArgument, x.name)
+		}
+		*x.dest = t
+	}
+	return f, nil
+}
+
+func parseResultsTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+func listResults(cmd *cobra.Command) ([]*results.Run, error) {
+	f, err := newResultsFilter(cmd)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := results.List(resultDir(cmd))
+	if err != nil {
+		return nil, err
+	}
+	return results.FilterRuns(runs, f), nil
+}
+
+var resultsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the runs from oldest to newest",
+	Long: `List the runs from oldest to newest
+
+Print the name, the start time, the model, the support model, the transpose and the description of the runs separated by tab.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		runs, err := listResults(cmd)
+		if err != nil {
+			return err
+		}
+		for _, r := range runs {
+			var model, supportModel, transpose, desc string
+			if c := r.Config; c != nil {
+				model, supportModel, transpose, desc = c.ModelDir, c.SupportModelDir, fmt.Sprint(c.Transpose), c.Description
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n", r.Name(), r.CreatedAt().Format(time.RFC3339), model, supportModel, transpose, desc)
+		}
+		return nil
+	},
+}
+
+var resultsShowCmd = &cobra.Command{
+	Use:   "show RUN",
+	Short: "Show the config and the files of the run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := results.Find(resultDir(cmd), args[0])
+		if err != nil {
+			return err
+		}
+		files, err := r.Files()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("dir: %s\nbasename: %s\ncreatedAt: %s\n", r.Dir, r.Element.Basename, r.CreatedAt().Format(time.RFC3339))
+		fmt.Println("files:")
+		for _, f := range files {
+			fmt.Printf("  %s\t%d\n", f.Name, f.Size)
+		}
+		if b, err := os.ReadFile(filepath.Join(r.Dir, results.ConfigFileName)); err == nil {
+			fmt.Printf("config:\n%s", b)
+		}
+		return nil
+	},
+}
+
+var resultsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the runs",
+	Long: `Remove the runs
+
+Remove the selected runs that satisfy all of --keepLast and --olderThan.
+
+e.g.
+pneutrinoutil results prune --keepLast 3 --olderThan 720h --dry
+prints the runs older than 30 days except the last 3 runs of each basename`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		runs, err := listResults(cmd)
+		if err != nil {
+			return err
+		}
+		var p results.PrunePolicy
+		p.KeepLast, _ = cmd.Flags().GetInt("keepLast")
+		p.OlderThan, _ = cmd.Flags().GetDuration("olderThan")
+		targets, err := p.Prune(runs, time.Now())
+		if err != nil {
+			return err
+		}
+		dry, _ := cmd.Flags().GetBool("dry")
+		for _, r := range targets {
+			fmt.Println(r.Dir)
+			if dry {
+				continue
+			}
+			if err := os.RemoveAll(r.Dir); err != nil {
+				return err
+			}
+			slog.Debug("removed", "dir", r.Dir)
+		}
+		return nil
+	},
+}
+
+var resultsDiffCmd = &cobra.Command{
+	Use:   "diff RUN RUN",
+	Short: "Compare the configs and the files of the runs",
+	Long: `Compare the configs and the files of the runs
+
+The basename in the file names is replaced with ${BASENAME} to compare the runs of the different scores.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := results.Find(resultDir(cmd), args[0])
+		if err != nil {
+			return err
+		}
+		b, err := results.Find(resultDir(cmd), args[1])
+		if err != nil {
+			return err
+		}
+		d, err := results.NewDiff(a, b)
+		if err != nil {
+			return err
+		}
+		return d.Write(os.Stdout, a, b)
+	},
+}
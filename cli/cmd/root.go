@@ -12,7 +12,9 @@ import (
 
 	"github.com/berquerant/execx"
 	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/hook"
 	"github.com/berquerant/pneutrinoutil/cli/task"
+	"github.com/berquerant/pneutrinoutil/pkg/client"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
 	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/berquerant/pneutrinoutil/pkg/version"
@@ -22,11 +24,16 @@ import (
 func Main(ctx context.Context) {
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		slog.Error("main", logx.Err(err))
+		if errors.Is(err, ErrCheck) {
+			os.Exit(1)
+		}
 	}
 }
 
 var (
 	ErrArgument = errors.New("Argument")
+	// ErrCheck means that the command found problems, exits with non-zero status.
+	ErrCheck = errors.New("Check")
 )
 
 func InitFlags(cmd *cobra.Command) {
@@ -38,9 +45,13 @@ func InitFlags(cmd *cobra.Command) {
 		"working directory; $HOME/.pneutrinoutil or .pneutrinoutil if no $HOME",
 	)
 	cmd.PersistentFlags().StringP("neutrinoDir", "n", "./dist/NEUTRINO", "NEUTRINO directory")
+	cmd.PersistentFlags().String("profile", "", "name of the profile to use as the base of the config")
+	cmd.PersistentFlags().String("profiles", "", "profiles file; $workDir/profiles.yml if empty")
 	cmd.Flags().Bool("dry", false, "dryrun")
 	cmd.Flags().String("play", "", "play command generated wav after running, wav file will be passed to 1st argument")
-	cmd.Flags().String("hook", "", "command to be executed after running, result dir will be passed to 1st argument")
+	cmd.Flags().String("hook", "", "command to be executed at the lifecycle events, json document of the event will be passed to stdin and result dir will be passed to 1st argument")
+	cmd.Flags().StringSlice("hookEvents", []string{string(hook.EventSuccess)}, "events to call the hook: start, preStage, postStage, success, failure or all; the hook can stop the pipeline at start and preStage")
+	cmd.Flags().String("server", "", "URL of pneutrinoutil-server including the version, e.g. http://127.0.0.1:9101/v1; submit the score to the server instead of running NEUTRINO and download the results into the result dir, the hook is called only at start, success and failure, the flags of the local pipeline like --include are not available")
 	cmd.Flags().Bool("list-tasks", false, "list task names")
 	cmd.Flags().StringSlice("env", nil, "names of additional environment variables to allow reading; all allows everythings")
 	cmd.Flags().StringP("shell", "s", "bash", "shell command to execute")
@@ -63,6 +74,8 @@ var rootCmd = &cobra.Command{
 	Short: `Generate .wav from .musicxml using NEUTRINO`,
 	Long: `Generate .wav from .musicxml using NEUTRINO
 
+Config values are overridden in order of defaults, --profile, the config file and the flags.
+
 e.g.
 pneutrinoutil --neutrinoDir /path/to/NEUTRINO --workDir /path/to/install-result --score /path/to/some.musicxml`,
 	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
@@ -83,20 +96,47 @@ pneutrinoutil --neutrinoDir /path/to/NEUTRINO --workDir /path/to/install-result
 		if err != nil {
 			return err
 		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
 
 		var (
-			dir        = NewDir(cmd, now)
-			play, _    = cmd.Flags().GetString("play")
-			hook, _    = cmd.Flags().GetString("hook")
-			include, _ = cmd.Flags().GetStringSlice("include")
-			exclude, _ = cmd.Flags().GetStringSlice("exclude")
+			dir            = NewDir(cmd, now)
+			play, _        = cmd.Flags().GetString("play")
+			hookCommand, _ = cmd.Flags().GetString("hook")
+			hookEvents, _  = cmd.Flags().GetStringSlice("hookEvents")
+			include, _     = cmd.Flags().GetStringSlice("include")
+			exclude, _     = cmd.Flags().GetStringSlice("exclude")
+			server, _      = cmd.Flags().GetString("server")
 		)
 
+		events, err := hook.ParseEvents(hookEvents)
+		if err != nil {
+			return errors.Join(ErrArgument, err)
+		}
+
+		if server != "" {
+			if err := checkRemoteFlags(cmd.Flags()); err != nil {
+				return err
+			}
+			r := &remote{
+				client:    client.New(server),
+				config:    c,
+				basename:  c.Basename(),
+				resultDir: dir.LocalResultDestDir(c.Basename()),
+				play:      play,
+				hook:      hookCommand,
+				events:    events,
+			}
+			return r.run(cmd.Context())
+		}
+
 		if err := c.SetInfo(cmd.Context(), dir.NeutrinoDir()); err != nil {
 			return err
 		}
 
-		tasks, err := task.NewGenerator(dir, c, play, hook).ExecutableTasks()
+		generator := task.NewGenerator(dir, c, play, hookCommand)
+		tasks, err := generator.ExecutableTasks()
 		if err != nil {
 			return err
 		}
@@ -110,10 +150,13 @@ pneutrinoutil --neutrinoDir /path/to/NEUTRINO --workDir /path/to/install-result
 			return nil
 		}
 
-		tasks.Entrypoint = prepareTaskEntrypoint(taskNames, include, exclude)
+		tasks.Entrypoint = generator.HookEntrypoint(prepareTaskEntrypoint(taskNames, include, exclude))
 
 		environWhiteList, _ := cmd.Flags().GetStringSlice("env")
 		tasks.Env.Merge(prepareAdditionalEnviron(environWhiteList))
+		// recorded in the manifest
+		tasks.Env.Set("EnvWhiteList", strings.Join(environWhiteList, ","))
+		tasks.Env.Set("HookEvents", strings.Join(hookEvents, ","))
 
 		tasks.Env.Set("PWD", dir.NeutrinoDir())
 
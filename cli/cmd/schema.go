@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/info"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Dump JSON Schema of config.yml",
+	Long: `Dump JSON Schema of config.yml
+
+model and supportModel are restricted to the models in the NEUTRINO directory.
+
+e.g. with yaml-language-server
+# yaml-language-server: $schema=/path/to/schema.json`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		neutrinoDir, _ := cmd.Flags().GetString("neutrinoDir")
+		models, err := info.ModelIDs(neutrinoDir)
+		if err != nil {
+			slog.Warn("models are not restricted", logx.Err(err))
+		}
+		s, err := ctl.NewSchema(models)
+		if err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	},
+}
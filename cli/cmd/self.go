@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runSelf renders the config by pneutrinoutil as a child process.
+// Results are written into workDir/result.
+func runSelf(ctx context.Context, out io.Writer, configPath, workDir, neutrinoDir, shell string, env []string) error {
+	self, _ := os.Executable()
+	args := []string{
+		configPath,
+		"--workDir", workDir,
+		"--neutrinoDir", neutrinoDir,
+		"--shell", shell,
+	}
+	if len(env) > 0 {
+		args = append(args, "--env", strings.Join(env, ","))
+	}
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// findSelfResult returns the result directory of the basename rendered by runSelf.
+func findSelfResult(workDir, basename string) (string, error) {
+	matched, err := filepath.Glob(filepath.Join(workDir, "result", basename+"__*"))
+	if err != nil || len(matched) != 1 {
+		return "", fmt.Errorf("result not found in %s", workDir)
+	}
+	return matched[0], nil
+}
@@ -17,11 +17,17 @@ func init() {
 var skeletonCmd = &cobra.Command{
 	Use:   "skeleton",
 	Short: "Dump default config.yml",
+	Long: `Dump default config.yml
+
+Dump the resolved profile over the defaults if --profile is given.`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		c, err := ctl.NewDefaultConfig()
 		if err != nil {
 			return err
 		}
+		if err := ApplyProfile(cmd, c); err != nil {
+			return err
+		}
 
 		var b []byte
 		if x, _ := cmd.Flags().GetBool("json"); x {
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+	sweepCmd.Flags().String("spec", "", "yaml or json file of the value lists to sweep; {model: [], supportModel: [], transpose: []}")
+	sweepCmd.Flags().StringSlice("models", nil, "singers to sweep, override the spec")
+	sweepCmd.Flags().StringSlice("supportModels", nil, "support singers to sweep, override the spec")
+	sweepCmd.Flags().IntSlice("transposes", nil, "transpose values to sweep, override the spec")
+	sweepCmd.Flags().Int("concurrency", 1, "number of combinations rendered at the same time")
+	sweepCmd.Flags().Bool("dry", false, "print the combinations")
+	sweepCmd.Flags().StringSlice("env", nil, "names of additional environment variables to allow reading; all allows everythings")
+	sweepCmd.Flags().StringP("shell", "s", "bash", "shell command to execute")
+
+	var c ctl.Config
+	if err := c.SetFlags(sweepCmd.Flags()); err != nil {
+		panic(err)
+	}
+}
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep [CONFIG_YML|CONFIG_JSON]",
+	Short: "Render every combination of singers and transpose values",
+	Long: `Render every combination of singers and transpose values
+
+Values of the lists that are not specified are taken from the config.
+Results are written into $workDir/sweep/BASENAME__YYYYmmddHHMMSS_TIMESTAMP_PID with index.json and index.html.
+
+e.g.
+pneutrinoutil sweep --score some.musicxml --models MERROW,KIRITAN --transposes 0,-12 --concurrency 2
+renders 4 wavs`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		c, err := NewConfig(cmd, args)
+		if err != nil {
+			return err
+		}
+		if c.Score == "" {
+			return fmt.Errorf("%w: require score", ErrArgument)
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		spec, err := newSweepSpec(cmd)
+		if err != nil {
+			return err
+		}
+		combinations := spec.Combinations(sweep.Combination{
+			Model:        c.ModelDir,
+			SupportModel: c.SupportModelDir,
+			Transpose:    c.Transpose,
+		})
+
+		if dry, _ := cmd.Flags().GetBool("dry"); dry {
+			for _, x := range combinations {
+				fmt.Println(x)
+			}
+			return nil
+		}
+
+		var (
+			workDir, _     = cmd.Flags().GetString("workDir")
+			concurrency, _ = cmd.Flags().GetInt("concurrency")
+		)
+		// absolute because the generated scripts are executed on the NEUTRINO directory
+		sweepDir, err := filepath.Abs(filepath.Join(workDir, "sweep", pathx.NewResultElement(c.Basename(), now, now.Unix(), os.Getpid()).String()))
+		if err != nil {
+			return err
+		}
+		r := &sweeper{
+			cmd:      cmd,
+			base:     c,
+			sweepDir: sweepDir,
+		}
+		for _, d := range []string{"score", "config", "log"} {
+			if err := pathx.EnsureDir(filepath.Join(sweepDir, d)); err != nil {
+				return err
+			}
+		}
+
+		var (
+			index = &sweep.Index{
+				Basename: c.Basename(),
+				Entries:  make([]*sweep.Entry, len(combinations)),
+			}
+			sem = make(chan struct{}, max(concurrency, 1))
+			wg  sync.WaitGroup
+		)
+		for i, x := range combinations {
+			wg.Go(func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				index.Entries[i] = r.render(cmd.Context(), i, x)
+			})
+		}
+		wg.Wait()
+
+		for _, x := range []struct {
+			name  string
+			write func(*os.File) error
+		}{
+			{name: sweep.IndexJSONFileName, write: func(f *os.File) error { return index.WriteJSON(f) }},
+			{name: sweep.IndexHTMLFileName, write: func(f *os.File) error { return index.WriteHTML(f) }},
+		} {
+			if err := writeFileFunc(filepath.Join(sweepDir, x.name), x.write); err != nil {
+				return err
+			}
+		}
+		fmt.Println(filepath.Join(sweepDir, sweep.IndexHTMLFileName))
+
+		var failed int
+		for _, x := range index.Entries {
+			if !x.OK {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d combinations failed", failed, len(index.Entries))
+		}
+		return nil
+	},
+}
+
+func newSweepSpec(cmd *cobra.Command) (*sweep.Spec, error) {
+	spec := &sweep.Spec{}
+	if x, _ := cmd.Flags().GetString("spec"); x != "" {
+		b, err := os.ReadFile(x)
+		if err != nil {
+			return nil, err
+		}
+		if spec, err = sweep.ParseSpec(b); err != nil {
+			return nil, err
+		}
+	}
+	var flagSpec sweep.Spec
+	flagSpec.Model, _ = cmd.Flags().GetStringSlice("models")
+	flagSpec.SupportModel, _ = cmd.Flags().GetStringSlice("supportModels")
+	flagSpec.Transpose, _ = cmd.Flags().GetIntSlice("transposes")
+	merged := spec.Merge(flagSpec)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+func writeFileFunc(path string, write func(*os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return write(f)
+}
+
+type sweeper struct {
+	cmd      *cobra.Command
+	base     *ctl.Config
+	sweepDir string
+}
+
+// render runs pneutrinoutil with the combination and returns the entry of the index.
+// Each combination has its own basename not to share the intermediate files in the NEUTRINO directory.
+func (s *sweeper) render(ctx context.Context, index int, x *sweep.Combination) *sweep.Entry {
+	name := fmt.Sprintf("%s_%d", s.base.Basename(), index)
+	entry := &sweep.Entry{
+		Combination: *x,
+		ID:          name,
+	}
+	logAttrs := []any{"name", name, "combination", x.String()}
+	slog.Info("sweep start", logAttrs...)
+	if err := s.run(ctx, name, x); err != nil {
+		slog.Error("sweep failed", append(logAttrs, "err", err)...)
+		entry.Error = err.Error()
+		return entry
+	}
+
+	result, err := findSelfResult(s.sweepDir, name)
+	if err != nil {
+		slog.Error("sweep result not found", append(logAttrs, "err", err)...)
+		entry.Error = "result not found"
+		return entry
+	}
+	if pathx.Exist(filepath.Join(result, name+".wav")) != pathx.Efile {
+		slog.Error("sweep wav not found", append(logAttrs, "result", result)...)
+		entry.Error = "wav not found"
+		return entry
+	}
+	resultDir, _ := filepath.Rel(s.sweepDir, result)
+	entry.OK = true
+	entry.Wav = filepath.Join(resultDir, name+".wav")
+	entry.Config = filepath.Join(resultDir, "config.yml")
+	slog.Info("sweep succeed", append(logAttrs, "result", result)...)
+	return entry
+}
+
+func (s *sweeper) run(ctx context.Context, name string, x *sweep.Combination) error {
+	score, err := os.ReadFile(s.base.Score)
+	if err != nil {
+		return err
+	}
+	scorePath := filepath.Join(s.sweepDir, "score", name+".musicxml")
+	if err := os.WriteFile(scorePath, score, 0644); err != nil {
+		return err
+	}
+
+	c := *s.base
+	c.Score = scorePath
+	c.ModelDir = x.Model
+	c.SupportModelDir = x.SupportModel
+	c.Transpose = x.Transpose
+	c.Description = strings.TrimSpace(fmt.Sprintf("%s %s", s.base.Description, x))
+	b, err := yaml.Marshal(&c)
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(s.sweepDir, "config", name+".yml")
+	if err := os.WriteFile(configPath, b, 0644); err != nil {
+		return err
+	}
+
+	logFile, err := os.Create(filepath.Join(s.sweepDir, "log", name+".log"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = logFile.Close() }()
+
+	var (
+		neutrinoDir, _ = s.cmd.Flags().GetString("neutrinoDir")
+		shell, _       = s.cmd.Flags().GetString("shell")
+		env, _         = s.cmd.Flags().GetStringSlice("env")
+	)
+	return runSelf(ctx, logFile, configPath, s.sweepDir, neutrinoDir, shell, env)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(transcodeCmd)
+	transcodeCmd.Flags().String("input", "", "input wav")
+	transcodeCmd.Flags().String("formats", "", "output formats separated by comma; codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac,wav:48000:24")
+	transcodeCmd.Flags().String("outputDir", "", "output directory; directory of the input if empty")
+}
+
+var transcodeCmd = &cobra.Command{
+	Use:   "transcode",
+	Short: "Convert wav into other formats",
+	Long: `Convert wav into other formats
+
+e.g.
+pneutrinoutil transcode --input some.wav --formats flac,wav:48000:24
+writes some.flac and some.48000hz.24bit.wav next to some.wav`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		var (
+			input, _     = cmd.Flags().GetString("input")
+			formats, _   = cmd.Flags().GetString("formats")
+			outputDir, _ = cmd.Flags().GetString("outputDir")
+		)
+		if input == "" {
+			return fmt.Errorf("%w: require input", ErrArgument)
+		}
+		if outputDir == "" {
+			outputDir = filepath.Dir(input)
+		}
+		fs, err := audio.ParseOutputFormats(formats)
+		if err != nil {
+			return err
+		}
+		if len(fs) == 0 {
+			return fmt.Errorf("%w: require formats", ErrArgument)
+		}
+
+		w, err := audio.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		basename := pathx.Basename(input)
+		for _, f := range fs {
+			output := filepath.Join(outputDir, f.FileName(basename))
+			if filepath.Clean(output) == filepath.Clean(input) {
+				slog.Warn("transcode skipped, same as the input", "input", input, "format", f.String())
+				continue
+			}
+			slog.Info("transcode", "input", input, "output", output, "format", f.String())
+			if err := f.WriteFile(output, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/doctor"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	var c ctl.Config
+	if err := c.SetFlags(validateCmd.Flags()); err != nil {
+		panic(err)
+	}
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [CONFIG_YML|CONFIG_JSON]",
+	Short: "Check the config and the score against the NEUTRINO installation",
+	Long: `Check the config and the score against the NEUTRINO installation
+
+Check the values of the config, the score and the part, the accompaniment and the models.
+Exit with non-zero status if any error is found.
+
+e.g.
+pneutrinoutil validate config.yml --neutrinoDir /path/to/NEUTRINO`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := NewConfig(cmd, args)
+		if err != nil {
+			return err
+		}
+		neutrinoDir, _ := cmd.Flags().GetString("neutrinoDir")
+		r := doctor.Installation{
+			NeutrinoDir: neutrinoDir,
+		}.CheckConfig(c)
+		return writeReport(r)
+	},
+}
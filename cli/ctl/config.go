@@ -2,11 +2,16 @@ package ctl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"strconv"
 
+	"al.essio.dev/pkg/shellescape"
 	"github.com/berquerant/execx"
 	"github.com/berquerant/pneutrinoutil/cli/info"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
 	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/berquerant/structconfig"
 	"github.com/spf13/pflag"
@@ -28,10 +33,32 @@ type Config struct {
 	// Project settings
 	Score      string `json:"score" yaml:"score" name:"score" usage:"score file, required"`
 	NumThreads int    `json:"thread" yaml:"thread" name:"thread" usage:"number of parallel in session" default:"4"`
+	Part       string `json:"part" yaml:"part" name:"part" usage:"id or name of the part to render, the score is used as it is if empty"`
 	// NEUTRINO
 	ModelDir        string `json:"model" yaml:"model" name:"model" usage:"singer" default:"MERROW"`
 	SupportModelDir string `json:"supportModel" yaml:"supportModel" name:"supportModel" usage:"support singer"`
 	Transpose       int    `json:"transpose" yaml:"transpose" name:"transpose" usage:"change the key and estimate" default:"0"`
+	// Post-process
+	Normalize        string  `json:"normalize" yaml:"normalize" name:"normalize" usage:"normalize the wav; peak or loudness (EBU R128), disabled if empty"`
+	PeakLevel        float64 `json:"peakLevel" yaml:"peakLevel" name:"peakLevel" usage:"target level of peak normalization, also the peak ceiling of loudness normalization, in dBFS" default:"-1"`
+	LoudnessLevel    float64 `json:"loudnessLevel" yaml:"loudnessLevel" name:"loudnessLevel" usage:"target level of loudness normalization in LUFS" default:"-23"`
+	TrimSilence      bool    `json:"trimSilence" yaml:"trimSilence" name:"trimSilence" usage:"trim or pad leading and trailing silence to leadingSilence and trailingSilence"`
+	SilenceThreshold float64 `json:"silenceThreshold" yaml:"silenceThreshold" name:"silenceThreshold" usage:"level regarded as silence in dBFS" default:"-60"`
+	LeadingSilence   float64 `json:"leadingSilence" yaml:"leadingSilence" name:"leadingSilence" usage:"seconds of leading silence after trimming"`
+	TrailingSilence  float64 `json:"trailingSilence" yaml:"trailingSilence" name:"trailingSilence" usage:"seconds of trailing silence after trimming"`
+	FadeIn           float64 `json:"fadeIn" yaml:"fadeIn" name:"fadeIn" usage:"seconds of fade in"`
+	FadeOut          float64 `json:"fadeOut" yaml:"fadeOut" name:"fadeOut" usage:"seconds of fade out"`
+	// Transcode
+	Formats string `json:"formats" yaml:"formats" name:"formats" usage:"additional output formats separated by comma; codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac,wav:48000:24"`
+	// Mix
+	Accompaniment     string  `json:"accompaniment" yaml:"accompaniment" name:"accompaniment" usage:"accompaniment wav to mix with the vocal into ${BASENAME}.mix.wav, disabled if empty"`
+	MixOffset         float64 `json:"mixOffset" yaml:"mixOffset" name:"mixOffset" usage:"seconds to delay the vocal against the accompaniment, negative value delays the accompaniment"`
+	VocalGain         float64 `json:"vocalGain" yaml:"vocalGain" name:"vocalGain" usage:"gain of the vocal in the mix in dB"`
+	VocalPan          float64 `json:"vocalPan" yaml:"vocalPan" name:"vocalPan" usage:"pan of the vocal in the mix; -1 (left) to 1 (right)"`
+	AccompanimentGain float64 `json:"accompanimentGain" yaml:"accompanimentGain" name:"accompanimentGain" usage:"gain of the accompaniment in the mix in dB"`
+	AccompanimentPan  float64 `json:"accompanimentPan" yaml:"accompanimentPan" name:"accompanimentPan" usage:"pan of the accompaniment in the mix; -1 (left) to 1 (right)"`
+	// Stages are the user-defined stages, only available in the config file
+	Stages []*Stage `json:"stages,omitempty" yaml:"stages,omitempty" usage:"user-defined stages of the pipeline"`
 	// Info
 	NeutrinoVersion  string `json:"neutrinoVersion" yaml:"neutrinoVersion"`
 	ModelData        any    `json:"modelData" yaml:"modelData"`
@@ -58,12 +85,133 @@ func (c Config) Env() execx.Env {
 	return e
 }
 
+// PostProcessEnabled returns true if any post-process is required.
+func (c Config) PostProcessEnabled() bool {
+	return c.Normalize != "" || c.TrimSilence || c.FadeIn > 0 || c.FadeOut > 0
+}
+
+// PostProcessFlags returns the flags to pass the post-process settings to the postprocess command.
+func (c Config) PostProcessFlags() []string {
+	flags := []string{
+		"--normalize=" + shellescape.Quote(c.Normalize),
+		fmt.Sprintf("--peakLevel=%v", c.PeakLevel),
+		fmt.Sprintf("--loudnessLevel=%v", c.LoudnessLevel),
+		fmt.Sprintf("--trimSilence=%v", c.TrimSilence),
+		fmt.Sprintf("--silenceThreshold=%v", c.SilenceThreshold),
+		fmt.Sprintf("--leadingSilence=%v", c.LeadingSilence),
+		fmt.Sprintf("--trailingSilence=%v", c.TrailingSilence),
+		fmt.Sprintf("--fadeIn=%v", c.FadeIn),
+		fmt.Sprintf("--fadeOut=%v", c.FadeOut),
+	}
+	return flags
+}
+
+func (c Config) PostProcessParams() (*audio.PostProcessParams, error) {
+	normalize, err := audio.ParseNormalizeMethod(c.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	return &audio.PostProcessParams{
+		Normalize:        normalize,
+		PeakLevel:        c.PeakLevel,
+		LoudnessLevel:    c.LoudnessLevel,
+		TrimSilence:      c.TrimSilence,
+		SilenceThreshold: c.SilenceThreshold,
+		LeadingSilence:   c.LeadingSilence,
+		TrailingSilence:  c.TrailingSilence,
+		FadeIn:           c.FadeIn,
+		FadeOut:          c.FadeOut,
+	}, nil
+}
+
+// MixFlags returns the flags to pass the mix settings except the accompaniment to the mix command.
+func (c Config) MixFlags() []string {
+	return []string{
+		fmt.Sprintf("--mixOffset=%v", c.MixOffset),
+		fmt.Sprintf("--vocalGain=%v", c.VocalGain),
+		fmt.Sprintf("--vocalPan=%v", c.VocalPan),
+		fmt.Sprintf("--accompanimentGain=%v", c.AccompanimentGain),
+		fmt.Sprintf("--accompanimentPan=%v", c.AccompanimentPan),
+	}
+}
+
+var ErrInvalidPan = errors.New("InvalidPan")
+
+// MixTracks returns the tracks to mix the vocal with the accompaniment.
+func (c Config) MixTracks(vocal, accompaniment *audio.Wav) ([]*audio.Track, error) {
+	for _, x := range []float64{c.VocalPan, c.AccompanimentPan} {
+		if x < -1 || x > 1 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPan, x)
+		}
+	}
+	v := &audio.Track{
+		Wav:  vocal,
+		Gain: c.VocalGain,
+		Pan:  c.VocalPan,
+	}
+	a := &audio.Track{
+		Wav:  accompaniment,
+		Gain: c.AccompanimentGain,
+		Pan:  c.AccompanimentPan,
+	}
+	if c.MixOffset >= 0 {
+		v.Offset = c.MixOffset
+	} else {
+		a.Offset = -c.MixOffset
+	}
+	return []*audio.Track{v, a}, nil
+}
+
+func (c Config) OutputFormats() ([]*audio.OutputFormat, error) {
+	return audio.ParseOutputFormats(c.Formats)
+}
+
+// Validate checks the values that can be checked before running.
+func (c Config) Validate() error {
+	if _, err := c.PostProcessParams(); err != nil {
+		return err
+	}
+	if _, err := c.OutputFormats(); err != nil {
+		return err
+	}
+	if _, err := c.MixTracks(nil, nil); err != nil {
+		return err
+	}
+	return validateStages(c.Stages)
+}
+
 // ApplyFlagValues sets flag values to this.
 func (c *Config) ApplyFlagValues(fs *pflag.FlagSet) error {
 	sc := structconfig.New[Config]()
 	return sc.FromFlags(c, fs)
 }
 
+// FlagValues returns the values of the fields that have "name" struct tag as strings keyed by the names.
+func (c Config) FlagValues() map[string]string {
+	var (
+		r = map[string]string{}
+		t = reflect.TypeFor[Config]()
+		v = reflect.ValueOf(c)
+	)
+	for i := range t.NumField() {
+		name, ok := t.Field(i).Tag.Lookup("name")
+		if !ok {
+			continue
+		}
+		switch x := v.Field(i); x.Kind() {
+		case reflect.String:
+			r[name] = x.String()
+		case reflect.Int:
+			r[name] = strconv.FormatInt(x.Int(), 10)
+		case reflect.Float64:
+			r[name] = strconv.FormatFloat(x.Float(), 'f', -1, 64)
+		case reflect.Bool:
+			r[name] = strconv.FormatBool(x.Bool())
+		}
+	}
+	return r
+}
+
 // SetFlags sets command-line flags.
 // Flag name is from "name" struct tag.
 // Flag usage is from "usage" struct tag.
@@ -15,9 +15,34 @@ func TestConfig(t *testing.T) {
 			return
 		}
 		want := &ctl.Config{
-			NumThreads: 4,
-			ModelDir:   "MERROW",
+			NumThreads:       4,
+			ModelDir:         "MERROW",
+			PeakLevel:        -1,
+			LoudnessLevel:    -23,
+			SilenceThreshold: -60,
 		}
 		assert.Equal(t, want, got)
 	})
+
+	t.Run("FlagValues", func(t *testing.T) {
+		c := ctl.Config{
+			Score:       "score.musicxml",
+			NumThreads:  2,
+			ModelDir:    "MERROW",
+			Transpose:   -12,
+			PeakLevel:   -1.5,
+			TrimSilence: true,
+			Stages:      []*ctl.Stage{{Name: "x", Command: "true"}},
+		}
+		got := c.FlagValues()
+		assert.Equal(t, "score.musicxml", got["score"])
+		assert.Equal(t, "2", got["thread"])
+		assert.Equal(t, "MERROW", got["model"])
+		assert.Equal(t, "-12", got["transpose"])
+		assert.Equal(t, "-1.5", got["peakLevel"])
+		assert.Equal(t, "true", got["trimSilence"])
+		assert.Equal(t, "", got["supportModel"])
+		assert.NotContains(t, got, "stages")
+		assert.NotContains(t, got, "neutrinoVersion")
+	})
 }
@@ -0,0 +1,123 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var ErrJob = errors.New("Job")
+
+// jobFileKeys are the keys of the files given to the job by the server, not by the options.
+var jobFileKeys = []string{"score", "accompaniment"}
+
+// JobKeys returns the keys of the options of a job of the server.
+// They are the names of the command-line flags except the files.
+func JobKeys() []string {
+	var (
+		r []string
+		t = reflect.TypeFor[Config]()
+	)
+	for i := range t.NumField() {
+		name, ok := t.Field(i).Tag.Lookup("name")
+		if !ok || slices.Contains(jobFileKeys, name) {
+			continue
+		}
+		r = append(r, name)
+	}
+	return r
+}
+
+// JobOptions returns the options of a job of the server that reproduces this config.
+func (c Config) JobOptions() map[string]any {
+	b, _ := json.Marshal(c)
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	r := map[string]any{}
+	for _, k := range JobKeys() {
+		r[k] = m[k]
+	}
+	return r
+}
+
+// NewJobConfig returns the default config overridden by the options and the values of a job of the server.
+//
+// options is the json object keyed by JobKeys.
+// values are the strings keyed by JobKeys like the form values, override options.
+// The config is validated by Validate and the schema.
+func (s Schema) NewJobConfig(options []byte, values map[string]string) (*Config, error) {
+	c, err := NewDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	keys := JobKeys()
+
+	if len(bytes.TrimSpace(options)) > 0 {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(options, &m); err != nil {
+			return nil, fmt.Errorf("%w: options should be a json object: %w", ErrJob, err)
+		}
+		for k := range m {
+			if !slices.Contains(keys, k) {
+				return nil, fmt.Errorf("%w: unknown option %s", ErrJob, k)
+			}
+		}
+		d := json.NewDecoder(bytes.NewReader(options))
+		d.DisallowUnknownFields()
+		if err := d.Decode(c); err != nil {
+			return nil, fmt.Errorf("%w: invalid options: %w", ErrJob, err)
+		}
+	}
+
+	if len(values) > 0 {
+		m := map[string]any{}
+		for k, v := range values {
+			p, ok := s.Properties[k]
+			if !ok || !slices.Contains(keys, k) {
+				return nil, fmt.Errorf("%w: unknown option %s", ErrJob, k)
+			}
+			x, err := p.parse(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, k)
+			}
+			m[k] = x
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, fmt.Errorf("%w: invalid values: %w", ErrJob, err)
+		}
+	}
+
+	if err := s.ValidateConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ValidateConfig validates the options of a job of the server in c.
+func (s Schema) ValidateConfig(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if len(c.Stages) > 0 {
+		return fmt.Errorf("%w: stages are not available", ErrJob)
+	}
+	var (
+		values = c.FlagValues()
+		args   []string
+	)
+	for _, k := range JobKeys() {
+		args = append(args, fmt.Sprintf("--%s=%s", k, values[k]))
+	}
+	if err := s.ValidateArgs(args); err != nil {
+		return fmt.Errorf("%w: %s", ErrJob, strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	return nil
+}
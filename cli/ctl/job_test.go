@@ -0,0 +1,151 @@
+package ctl_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob(t *testing.T) {
+	schema, err := ctl.NewSchema([]string{"MERROW", "KIRITAN"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		keys := ctl.JobKeys()
+		assert.Contains(t, keys, "thread")
+		assert.Contains(t, keys, "desc")
+		assert.NotContains(t, keys, "score")
+		assert.NotContains(t, keys, "accompaniment")
+		assert.NotContains(t, keys, "stages")
+		for _, k := range keys {
+			_, ok := schema.Properties[k]
+			assert.True(t, ok, k)
+		}
+	})
+
+	for _, tc := range []struct {
+		title   string
+		options string
+		values  map[string]string
+		want    func(*ctl.Config)
+		err     bool
+	}{
+		{
+			title: "default",
+			want:  func(*ctl.Config) {},
+		},
+		{
+			title:   "options",
+			options: `{"thread": 2, "desc": "d", "model": "KIRITAN", "transpose": -12, "trimSilence": true}`,
+			want: func(c *ctl.Config) {
+				c.NumThreads = 2
+				c.Description = "d"
+				c.ModelDir = "KIRITAN"
+				c.Transpose = -12
+				c.TrimSilence = true
+			},
+		},
+		{
+			title:   "values override options",
+			options: `{"transpose": -12, "fadeIn": 1}`,
+			values:  map[string]string{"transpose": "12", "peakLevel": "-3.5"},
+			want: func(c *ctl.Config) {
+				c.Transpose = 12
+				c.FadeIn = 1
+				c.PeakLevel = -3.5
+			},
+		},
+		{
+			title:   "not an object",
+			options: `[]`,
+			err:     true,
+		},
+		{
+			title:   "score",
+			options: `{"score": "/etc/passwd"}`,
+			err:     true,
+		},
+		{
+			title:  "accompaniment value",
+			values: map[string]string{"accompaniment": "x.wav"},
+			err:    true,
+		},
+		{
+			title:   "stages",
+			options: `{"stages": [{"name": "x", "command": "true"}]}`,
+			err:     true,
+		},
+		{
+			title:   "info",
+			options: `{"neutrinoVersion": "v"}`,
+			err:     true,
+		},
+		{
+			title:   "unknown",
+			options: `{"unknown": 1}`,
+			err:     true,
+		},
+		{
+			title:   "type",
+			options: `{"thread": "many"}`,
+			err:     true,
+		},
+		{
+			title:  "value type",
+			values: map[string]string{"transpose": "high"},
+			err:    true,
+		},
+		{
+			title:   "unknown model",
+			options: `{"model": "NOBODY"}`,
+			err:     true,
+		},
+		{
+			title:   "invalid config",
+			options: `{"normalize": "loud"}`,
+			err:     true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := schema.NewJobConfig([]byte(tc.options), tc.values)
+			if tc.err {
+				assert.NotNil(t, err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			want, err := ctl.NewDefaultConfig()
+			if !assert.Nil(t, err) {
+				return
+			}
+			tc.want(want)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("options roundtrip", func(t *testing.T) {
+		c, err := ctl.NewDefaultConfig()
+		if !assert.Nil(t, err) {
+			return
+		}
+		c.Score = "score.musicxml"
+		c.NumThreads = 3
+		c.SupportModelDir = "KIRITAN"
+		c.FadeOut = 0.5
+		b, err := json.Marshal(c.JobOptions())
+		if !assert.Nil(t, err) {
+			return
+		}
+		got, err := schema.NewJobConfig(b, nil)
+		if !assert.Nil(t, err) {
+			return
+		}
+		c.Score = ""
+		assert.Equal(t, c, got)
+	})
+}
@@ -0,0 +1,125 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+var (
+	ErrProfileNotFound = errors.New("ProfileNotFound")
+	ErrInvalidProfile  = errors.New("InvalidProfile")
+)
+
+// ProfileExtendsKey is the key of the profile to inherit values from.
+const ProfileExtendsKey = "extends"
+
+// Profiles are named sets of config values, e.g.
+//
+//	base:
+//	  thread: 8
+//	  accompaniment: ${HOME}/backing.wav
+//	kiritan:
+//	  extends: base
+//	  model: KIRITAN
+//
+// Keys are the same as the config file.
+// Environment variables in the string values are expanded.
+type Profiles map[string]map[string]any
+
+// ParseProfiles parses yaml or json profiles.
+func ParseProfiles(b []byte) (Profiles, error) {
+	var p Profiles
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, errors.Join(ErrInvalidProfile, err)
+	}
+	return p, nil
+}
+
+// ReadProfiles reads the profiles file.
+func ReadProfiles(path string) (Profiles, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProfiles(b)
+}
+
+// Resolve returns the values of the profile merged with the profiles it extends.
+func (p Profiles) Resolve(name string) (map[string]any, error) {
+	return p.resolve(name, nil)
+}
+
+func (p Profiles) resolve(name string, visited []string) (map[string]any, error) {
+	for _, x := range visited {
+		if x == name {
+			return nil, fmt.Errorf("%w: cyclic extends: %s", ErrInvalidProfile, strings.Join(append(visited, name), " -> "))
+		}
+	}
+	profile, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	r := map[string]any{}
+	if x, ok := profile[ProfileExtendsKey]; ok {
+		parent, ok := x.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: %s should be a string", ErrInvalidProfile, name, ProfileExtendsKey)
+		}
+		v, err := p.resolve(parent, append(visited, name))
+		if err != nil {
+			return nil, err
+		}
+		r = v
+	}
+	for k, v := range profile {
+		if k == ProfileExtendsKey {
+			continue
+		}
+		r[k] = expandEnv(v)
+	}
+	return r, nil
+}
+
+// expandEnv replaces ${var} or $var in the string values.
+func expandEnv(v any) any {
+	switch v := v.(type) {
+	case string:
+		return os.ExpandEnv(v)
+	case []any:
+		r := make([]any, len(v))
+		for i, x := range v {
+			r[i] = expandEnv(x)
+		}
+		return r
+	case map[string]any:
+		r := maps.Clone(v)
+		for k, x := range r {
+			r[k] = expandEnv(x)
+		}
+		return r
+	default:
+		return v
+	}
+}
+
+// Apply overrides c by the values of the profile.
+func (p Profiles) Apply(name string, c *Config) error {
+	v, err := p.Resolve(name)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalWithOptions(b, c, yaml.DisallowUnknownField()); err != nil {
+		return fmt.Errorf("%w: %s", errors.Join(ErrInvalidProfile, err), name)
+	}
+	return nil
+}
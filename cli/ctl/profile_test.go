@@ -0,0 +1,106 @@
+package ctl_test
+
+import (
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Setenv("PNEUTRINOUTIL_TEST_SCORE", "/scores")
+	profiles, err := ctl.ParseProfiles([]byte(`base:
+  thread: 8
+  score: ${PNEUTRINOUTIL_TEST_SCORE}/song.musicxml
+kiritan:
+  extends: base
+  model: KIRITAN
+  transpose: -12
+duet:
+  extends: kiritan
+  thread: 2
+cycle1:
+  extends: cycle2
+cycle2:
+  extends: cycle1
+orphan:
+  extends: none
+unknown:
+  models: KIRITAN
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("resolve", func(t *testing.T) {
+		got, err := profiles.Resolve("duet")
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, map[string]any{
+			"thread":    uint64(2),
+			"score":     "/scores/song.musicxml",
+			"model":     "KIRITAN",
+			"transpose": int64(-12),
+		}, got)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		c, err := ctl.NewDefaultConfig()
+		if !assert.Nil(t, err) {
+			return
+		}
+		if !assert.Nil(t, profiles.Apply("duet", c)) {
+			return
+		}
+		want, _ := ctl.NewDefaultConfig()
+		want.NumThreads = 2
+		want.Score = "/scores/song.musicxml"
+		want.ModelDir = "KIRITAN"
+		want.Transpose = -12
+		assert.Equal(t, want, c)
+	})
+
+	for _, tc := range []struct {
+		title string
+		name  string
+		err   error
+	}{
+		{title: "not found", name: "none", err: ctl.ErrProfileNotFound},
+		{title: "extends not found", name: "orphan", err: ctl.ErrProfileNotFound},
+		{title: "cycle", name: "cycle1", err: ctl.ErrInvalidProfile},
+		{title: "unknown key", name: "unknown", err: ctl.ErrInvalidProfile},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			c, _ := ctl.NewDefaultConfig()
+			assert.ErrorIs(t, profiles.Apply(tc.name, c), tc.err)
+		})
+	}
+}
+
+func TestProfilesExample(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	// the example of the doc of Profiles
+	profiles, err := ctl.ParseProfiles([]byte(`base:
+  thread: 8
+  accompaniment: ${HOME}/backing.wav
+kiritan:
+  extends: base
+  model: KIRITAN
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	c, err := ctl.NewDefaultConfig()
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Nil(t, profiles.Apply("kiritan", c)) {
+		return
+	}
+	want, _ := ctl.NewDefaultConfig()
+	want.NumThreads = 8
+	want.Accompaniment = "/home/user/backing.wav"
+	want.ModelDir = "KIRITAN"
+	assert.Equal(t, want, c)
+}
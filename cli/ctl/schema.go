@@ -0,0 +1,150 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrSchema = errors.New("Schema")
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the JSON Schema of Config.
+type Schema struct {
+	Schema               string                     `json:"$schema"`
+	Title                string                     `json:"title"`
+	Type                 string                     `json:"type"`
+	Properties           map[string]*SchemaProperty `json:"properties"`
+	AdditionalProperties bool                       `json:"additionalProperties"`
+}
+
+type SchemaProperty struct {
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Default     any      `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	// ReadOnly is true if the value is set by pneutrinoutil, not by the config.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// NewSchema returns the JSON Schema of Config.
+// Types, descriptions and defaults are from "name", "usage" and "default" struct tags.
+// model and supportModel are restricted to models if not empty.
+func NewSchema(models []string) (*Schema, error) {
+	s := &Schema{
+		Schema:     jsonSchemaDraft,
+		Title:      "pneutrinoutil config",
+		Type:       "object",
+		Properties: map[string]*SchemaProperty{},
+	}
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name, ok := f.Tag.Lookup("name")
+		if !ok {
+			if f.Type.Kind() == reflect.Slice {
+				// available only in the config file
+				s.Properties[key] = &SchemaProperty{
+					Type:        "array",
+					Description: f.Tag.Get("usage"),
+				}
+				continue
+			}
+			s.Properties[key] = &SchemaProperty{
+				ReadOnly: true,
+			}
+			continue
+		}
+		p := &SchemaProperty{
+			Description: f.Tag.Get("usage"),
+		}
+		switch f.Type.Kind() {
+		case reflect.String:
+			p.Type = "string"
+		case reflect.Int:
+			p.Type = "integer"
+		case reflect.Float64:
+			p.Type = "number"
+		case reflect.Bool:
+			p.Type = "boolean"
+		default:
+			return nil, fmt.Errorf("%w: unsupported type %s of %s", ErrSchema, f.Type, name)
+		}
+		if x, ok := f.Tag.Lookup("default"); ok {
+			v, err := p.parse(x)
+			if err != nil {
+				return nil, fmt.Errorf("%w: default of %s", err, name)
+			}
+			p.Default = v
+		}
+		s.Properties[name] = p
+	}
+	if len(models) > 0 {
+		s.Properties["model"].Enum = slices.Clone(models)
+		// empty means no support singer
+		s.Properties["supportModel"].Enum = append([]string{""}, models...)
+	}
+	return s, nil
+}
+
+func (p SchemaProperty) parse(v string) (any, error) {
+	var (
+		r   any
+		err error
+	)
+	switch p.Type {
+	case "integer":
+		r, err = strconv.Atoi(v)
+	case "number":
+		r, err = strconv.ParseFloat(v, 64)
+	case "boolean":
+		r, err = strconv.ParseBool(v)
+	default:
+		r = v
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not %s", ErrSchema, v, p.Type)
+	}
+	return r, nil
+}
+
+// ValidateArgs validates the command-line arguments like --key=value.
+func (s Schema) ValidateArgs(args []string) error {
+	var errs []error
+	for _, arg := range args {
+		k, v, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !ok || !strings.HasPrefix(arg, "--") {
+			errs = append(errs, fmt.Errorf("%w: %s should be --key=value", ErrSchema, arg))
+			continue
+		}
+		if err := s.validate(k, v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s Schema) validate(key, value string) error {
+	p, ok := s.Properties[key]
+	if !ok || p.ReadOnly {
+		return fmt.Errorf("%w: unknown key %s", ErrSchema, key)
+	}
+	if p.Type == "array" {
+		return fmt.Errorf("%w: %s is only available in the config file", ErrSchema, key)
+	}
+	if _, err := p.parse(value); err != nil {
+		return fmt.Errorf("%w: %s", err, key)
+	}
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, value) {
+		return fmt.Errorf("%w: %s should be one of %s", ErrSchema, key, strings.Join(p.Enum, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package ctl_test
+
+import (
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	s, err := ctl.NewSchema([]string{"MERROW", "KIRITAN"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("properties", func(t *testing.T) {
+		assert.Equal(t, &ctl.SchemaProperty{
+			Type:        "integer",
+			Description: "number of parallel in session",
+			Default:     4,
+		}, s.Properties["thread"])
+		assert.Equal(t, &ctl.SchemaProperty{
+			Type:        "string",
+			Description: "singer",
+			Default:     "MERROW",
+			Enum:        []string{"MERROW", "KIRITAN"},
+		}, s.Properties["model"])
+		assert.Equal(t, []string{"", "MERROW", "KIRITAN"}, s.Properties["supportModel"].Enum)
+		assert.Equal(t, -1.0, s.Properties["peakLevel"].Default)
+		assert.Equal(t, "boolean", s.Properties["trimSilence"].Type)
+		assert.True(t, s.Properties["neutrinoVersion"].ReadOnly)
+		assert.Equal(t, "array", s.Properties["stages"].Type)
+	})
+
+	t.Run("no models", func(t *testing.T) {
+		s, err := ctl.NewSchema(nil)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Nil(t, s.Properties["model"].Enum)
+	})
+
+	for _, tc := range []struct {
+		title string
+		args  []string
+		err   bool
+	}{
+		{
+			title: "empty",
+		},
+		{
+			title: "valid",
+			args:  []string{"--model=KIRITAN", "--transpose=-12", "--peakLevel=-0.5", "--trimSilence=true", "--formats=flac"},
+		},
+		{
+			title: "unknown model",
+			args:  []string{"--model=NONE"},
+			err:   true,
+		},
+		{
+			title: "not integer",
+			args:  []string{"--transpose=1.5"},
+			err:   true,
+		},
+		{
+			title: "unknown key",
+			args:  []string{"--models=KIRITAN"},
+			err:   true,
+		},
+		{
+			title: "read only",
+			args:  []string{"--neutrinoVersion=v3"},
+			err:   true,
+		},
+		{
+			title: "config file only",
+			args:  []string{"--stages=x"},
+			err:   true,
+		},
+		{
+			title: "not key value",
+			args:  []string{"transpose"},
+			err:   true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			err := s.ValidateArgs(tc.args)
+			if tc.err {
+				assert.ErrorIs(t, err, ctl.ErrSchema)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
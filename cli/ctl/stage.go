@@ -0,0 +1,63 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var ErrInvalidStage = errors.New("InvalidStage")
+
+// Stage is a user-defined stage of the pipeline.
+//
+// The command is executed on the NEUTRINO directory like the built-in stages,
+// the variables of the pipeline, e.g. ${BASENAME}, ${ModelDir} and ${ResultDestDir}, are expanded.
+// Intermediate files are ./output/${BASENAME}.*, the result directory is created by the init stage.
+type Stage struct {
+	// Name is the name of the task, selectable by --include and --exclude.
+	Name string `json:"name" yaml:"name"`
+	// Command is the shell script of the stage.
+	Command string `json:"command" yaml:"command"`
+	// Before is the name of the stage to run this stage before.
+	Before string `json:"before,omitempty" yaml:"before,omitempty"`
+	// After is the name of the stage to run this stage after.
+	// The stage is appended to the pipeline if both Before and After are empty.
+	After string `json:"after,omitempty" yaml:"after,omitempty"`
+	// Inputs are the files the command requires, checked before the command.
+	// The variables in the paths are expanded like the command.
+	Inputs []string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// Outputs are the files the command creates, checked after the command.
+	Outputs []string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	// Fatal stops the pipeline if the stage fails, otherwise the failure is logged and ignored.
+	Fatal bool `json:"fatal,omitempty" yaml:"fatal,omitempty"`
+}
+
+// stageNameRegexp matches the names available as shell functions.
+var stageNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (s Stage) Validate() error {
+	if !stageNameRegexp.MatchString(s.Name) {
+		return fmt.Errorf("%w: name %q should match %s", ErrInvalidStage, s.Name, stageNameRegexp)
+	}
+	if s.Command == "" {
+		return fmt.Errorf("%w: %s: require command", ErrInvalidStage, s.Name)
+	}
+	if s.Before != "" && s.After != "" {
+		return fmt.Errorf("%w: %s: before and after are exclusive", ErrInvalidStage, s.Name)
+	}
+	return nil
+}
+
+func validateStages(stages []*Stage) error {
+	names := map[string]bool{}
+	for _, x := range stages {
+		if err := x.Validate(); err != nil {
+			return err
+		}
+		if names[x.Name] {
+			return fmt.Errorf("%w: %s: duplicated name", ErrInvalidStage, x.Name)
+		}
+		names[x.Name] = true
+	}
+	return nil
+}
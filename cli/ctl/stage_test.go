@@ -0,0 +1,64 @@
+package ctl_test
+
+import (
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStage(t *testing.T) {
+	for _, tc := range []struct {
+		title  string
+		stages []*ctl.Stage
+		err    bool
+	}{
+		{
+			title: "no stages",
+		},
+		{
+			title: "valid",
+			stages: []*ctl.Stage{
+				{Name: "effect", Command: "sox in.wav out.wav reverb", After: "NEUTRINO"},
+				{Name: "upload_result", Command: "true"},
+			},
+		},
+		{
+			title:  "invalid name",
+			stages: []*ctl.Stage{{Name: "my-stage", Command: "true"}},
+			err:    true,
+		},
+		{
+			title:  "no command",
+			stages: []*ctl.Stage{{Name: "effect"}},
+			err:    true,
+		},
+		{
+			title:  "before and after",
+			stages: []*ctl.Stage{{Name: "effect", Command: "true", Before: "cleanup", After: "NEUTRINO"}},
+			err:    true,
+		},
+		{
+			title: "duplicated",
+			stages: []*ctl.Stage{
+				{Name: "effect", Command: "true"},
+				{Name: "effect", Command: "false"},
+			},
+			err: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			c, err := ctl.NewDefaultConfig()
+			if !assert.Nil(t, err) {
+				return
+			}
+			c.Stages = tc.stages
+			err = c.Validate()
+			if tc.err {
+				assert.ErrorIs(t, err, ctl.ErrInvalidStage)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/info"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/musicxml"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+)
+
+// CheckConfig checks the config and the score against the installation.
+func (i Installation) CheckConfig(c *ctl.Config) *Report {
+	var r Report
+	if err := c.Validate(); err != nil {
+		r.Error("config", "fix the value in the config or the flag", "%v", err)
+	} else {
+		r.OK("config", "values are valid")
+	}
+	i.checkScore(&r, c)
+	if c.Accompaniment != "" {
+		checkAccompaniment(&r, c.Accompaniment)
+	}
+	if i.CheckLayout(&r) {
+		i.checkConfigModels(&r, c)
+	}
+	return &r
+}
+
+func (i Installation) checkScore(r *Report, c *ctl.Config) {
+	const check = "score"
+	if c.Score == "" {
+		r.Error(check, "set --score or score in the config", "score is empty")
+		return
+	}
+	b, err := os.ReadFile(c.Score)
+	if err != nil {
+		r.Error(check, "", "%v", err)
+		return
+	}
+	parts, err := musicxml.Parts(b)
+	if err != nil {
+		r.Error(check, "export the score as uncompressed partwise musicxml", "%s: %v", c.Score, err)
+		return
+	}
+	if c.Part == "" {
+		if len(parts) > 1 {
+			r.Warn(check, "set --part to render one of them", "%s has %d parts", c.Score, len(parts))
+			return
+		}
+		r.OK(check, "%s", c.Score)
+		return
+	}
+	if _, err := musicxml.FindPart(parts, c.Part); err != nil {
+		names := make([]string, len(parts))
+		for i, p := range parts {
+			names[i] = p.ID + "(" + p.Name + ")"
+		}
+		r.Error(check, "set --part to one of "+strings.Join(names, ", "), "%s: %v", c.Score, err)
+		return
+	}
+	r.OK(check, "%s part %s", c.Score, c.Part)
+}
+
+func checkAccompaniment(r *Report, path string) {
+	const check = "accompaniment"
+	if pathx.Exist(path) != pathx.Efile {
+		r.Error(check, "", "%s not found", path)
+		return
+	}
+	if _, err := audio.ReadFile(path); err != nil {
+		r.Error(check, "convert the accompaniment into PCM wav", "%s: %v", path, err)
+		return
+	}
+	r.OK(check, "%s", path)
+}
+
+func (i Installation) checkConfigModels(r *Report, c *ctl.Config) {
+	const check = "model"
+	models, err := info.ModelIDs(i.NeutrinoDir)
+	if err != nil {
+		r.Error(check, "", "%v", err)
+		return
+	}
+	hint := "use one of " + strings.Join(models, ", ")
+	for _, x := range []struct {
+		key   string
+		model string
+	}{
+		{key: "model", model: c.ModelDir},
+		{key: "supportModel", model: c.SupportModelDir},
+	} {
+		if x.model == "" {
+			continue
+		}
+		if !slices.Contains(models, x.model) {
+			r.Error(check, hint, "%s %s not found in %s", x.key, x.model, i.modelDir())
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(i.modelDir(), x.model, "info.toml")); err != nil {
+			r.Warn(check, "reinstall the model", "%s %s: %v", x.key, x.model, err)
+			continue
+		}
+		r.OK(check, "%s %s", x.key, x.model)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build linux || darwin
+
+package doctor
+
+import "syscall"
+
+func freeSpace(dir string) (uint64, error) {
+	var s syscall.Statfs_t
+	if err := syscall.Statfs(dir, &s); err != nil {
+		return 0, err
+	}
+	return uint64(s.Bavail) * uint64(s.Bsize), nil
+}
@@ -0,0 +1,143 @@
+package doctor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/doctor"
+	"github.com/stretchr/testify/assert"
+)
+
+const score = `<?xml version="1.0" encoding="UTF-8"?>
+<score-partwise version="4.0">
+  <part-list>
+    <score-part id="P1"><part-name>Soprano</part-name></score-part>
+    <score-part id="P2"><part-name>Alto</part-name></score-part>
+  </part-list>
+</score-partwise>
+`
+
+// newInstallation creates a fake NEUTRINO directory.
+func newInstallation(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, d := range []string{"bin", "model/MERROW", "model/BROKEN"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, d), 0755))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "bin", "neutrino"), []byte("#!/bin/sh\necho 'NEUTRINO - v3.0.0'\necho usage\n"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "bin", "musicXMLtoLabel"), []byte("#!/bin/sh\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "bin", "libneutrino.so"), nil, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "model", "MERROW", "info.toml"), []byte("name = \"MERROW\"\n"), 0644))
+	return dir
+}
+
+func findings(r *doctor.Report, check string, severity doctor.Severity) []string {
+	var xs []string
+	for _, x := range r.Findings {
+		if x.Check == check && x.Severity == severity {
+			xs = append(xs, x.Message)
+		}
+	}
+	return xs
+}
+
+func TestInstallation(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		r := doctor.Installation{NeutrinoDir: filepath.Join(t.TempDir(), "none")}.Check(context.TODO())
+		assert.Equal(t, 1, r.Count(doctor.SeverityError))
+		assert.Len(t, findings(r, "layout", doctor.SeverityError), 1)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		dir := newInstallation(t)
+		r := doctor.Installation{
+			NeutrinoDir: dir,
+			WorkDir:     filepath.Join(dir, "work", "not", "created"),
+		}.Check(context.TODO())
+		assert.Equal(t, []string{filepath.Join(dir, "bin", "musicXMLtoLabel") + " is not executable"}, findings(r, "binary", doctor.SeverityError))
+		assert.Equal(t, []string{"MERROW"}, findings(r, "model", doctor.SeverityOK))
+		assert.Len(t, findings(r, "model", doctor.SeverityWarn), 1)
+		assert.Len(t, findings(r, "disk", doctor.SeverityOK), 1)
+		assert.Equal(t, 1, r.Count(doctor.SeverityError))
+
+		assert.Nil(t, os.Chmod(filepath.Join(dir, "bin", "musicXMLtoLabel"), 0755))
+		r = doctor.Installation{NeutrinoDir: dir, WorkDir: dir}.Check(context.TODO())
+		assert.Equal(t, []string{"v3.0.0"}, findings(r, "version", doctor.SeverityOK))
+		assert.Equal(t, 0, r.Count(doctor.SeverityError))
+	})
+
+	t.Run("disk", func(t *testing.T) {
+		dir := newInstallation(t)
+		r := doctor.Installation{NeutrinoDir: dir, WorkDir: dir, MinFreeSpace: 1 << 62}.Check(context.TODO())
+		assert.Len(t, findings(r, "disk", doctor.SeverityError), 1)
+	})
+}
+
+func TestCheckConfig(t *testing.T) {
+	dir := newInstallation(t)
+	scorePath := filepath.Join(t.TempDir(), "score.musicxml")
+	assert.Nil(t, os.WriteFile(scorePath, []byte(score), 0644))
+	inst := doctor.Installation{NeutrinoDir: dir}
+
+	for _, tc := range []struct {
+		title  string
+		config func(*ctl.Config)
+		errors map[string]int
+	}{
+		{
+			title:  "valid",
+			config: func(c *ctl.Config) { c.Part = "Alto" },
+		},
+		{
+			title:  "no score",
+			config: func(c *ctl.Config) { c.Score = "" },
+			errors: map[string]int{"score": 1},
+		},
+		{
+			title:  "part not found",
+			config: func(c *ctl.Config) { c.Part = "Tenor" },
+			errors: map[string]int{"score": 1},
+		},
+		{
+			title: "model not found",
+			config: func(c *ctl.Config) {
+				c.ModelDir = "NONE"
+				c.SupportModelDir = "NONE2"
+			},
+			errors: map[string]int{"model": 2},
+		},
+		{
+			title:  "invalid value",
+			config: func(c *ctl.Config) { c.Formats = "mp3" },
+			errors: map[string]int{"config": 1},
+		},
+		{
+			title:  "accompaniment not found",
+			config: func(c *ctl.Config) { c.Accompaniment = filepath.Join(dir, "none.wav") },
+			errors: map[string]int{"accompaniment": 1},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			c, err := ctl.NewDefaultConfig()
+			if !assert.Nil(t, err) {
+				return
+			}
+			c.Score = scorePath
+			tc.config(c)
+			r := inst.CheckConfig(c)
+			got := map[string]int{}
+			for _, x := range r.Findings {
+				if x.Severity == doctor.SeverityError {
+					got[x.Check]++
+				}
+			}
+			if tc.errors == nil {
+				tc.errors = map[string]int{}
+			}
+			assert.Equal(t, tc.errors, got)
+		})
+	}
+}
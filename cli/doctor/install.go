@@ -0,0 +1,187 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/cli/info"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+)
+
+// Binaries are the executables in $neutrinoDir/bin called by the generated script.
+var Binaries = []string{"neutrino", "musicXMLtoLabel"}
+
+// Installation is the NEUTRINO installation to check.
+type Installation struct {
+	NeutrinoDir string
+	WorkDir     string
+	// MinFreeSpace is the least free bytes of the filesystem of WorkDir.
+	MinFreeSpace uint64
+}
+
+func (i Installation) binDir() string   { return filepath.Join(i.NeutrinoDir, "bin") }
+func (i Installation) modelDir() string { return filepath.Join(i.NeutrinoDir, "model") }
+
+// Check checks the installation and returns the findings.
+func (i Installation) Check(ctx context.Context) *Report {
+	var r Report
+	if !i.CheckLayout(&r) {
+		// the other checks make no sense
+		return &r
+	}
+	if i.checkBinaries(&r) {
+		i.checkLibraries(ctx, &r)
+		i.checkVersion(ctx, &r)
+	}
+	i.checkModels(&r)
+	i.checkDiskSpace(&r)
+	return &r
+}
+
+// CheckLayout checks the directories of the installation.
+func (i Installation) CheckLayout(r *Report) bool {
+	const check = "layout"
+	hint := "set --neutrinoDir to the NEUTRINO directory, or download NEUTRINO as README says"
+	if pathx.Exist(i.NeutrinoDir) != pathx.Edir {
+		r.Error(check, hint, "NEUTRINO directory %s not found", i.NeutrinoDir)
+		return false
+	}
+	ok := true
+	for _, d := range []string{i.binDir(), i.modelDir()} {
+		if pathx.Exist(d) != pathx.Edir {
+			r.Error(check, hint, "%s not found", d)
+			ok = false
+		}
+	}
+	if ok {
+		r.OK(check, "%s", i.NeutrinoDir)
+	}
+	return ok
+}
+
+func (i Installation) checkBinaries(r *Report) bool {
+	const check = "binary"
+	ok := true
+	for _, name := range Binaries {
+		path := filepath.Join(i.binDir(), name)
+		s, err := os.Stat(path)
+		if err != nil {
+			r.Error(check, "reinstall NEUTRINO", "%s not found", path)
+			ok = false
+			continue
+		}
+		if !s.Mode().IsRegular() {
+			r.Error(check, "reinstall NEUTRINO", "%s is not a file", path)
+			ok = false
+			continue
+		}
+		if s.Mode().Perm()&0111 == 0 {
+			r.Error(check, fmt.Sprintf("chmod 755 %s/*", i.binDir()), "%s is not executable", path)
+			ok = false
+			continue
+		}
+		r.OK(check, "%s", path)
+	}
+	return ok
+}
+
+func (i Installation) checkLibraries(ctx context.Context, r *Report) {
+	const check = "library"
+	entries, err := os.ReadDir(i.binDir())
+	if err != nil {
+		r.Error(check, "", "%v", err)
+		return
+	}
+	var libs []string
+	for _, x := range entries {
+		if name := x.Name(); strings.HasSuffix(name, ".dylib") || strings.Contains(name, ".so") {
+			libs = append(libs, name)
+		}
+	}
+	if len(libs) == 0 {
+		r.Warn(check, "reinstall NEUTRINO", "no shared libraries in %s", i.binDir())
+	} else {
+		r.OK(check, "%d shared libraries in %s", len(libs), i.binDir())
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	ldd, err := exec.LookPath("ldd")
+	if err != nil {
+		return
+	}
+	for _, name := range Binaries {
+		var (
+			path   = filepath.Join(i.binDir(), name)
+			stdout bytes.Buffer
+			cmd    = exec.CommandContext(ctx, ldd, path)
+		)
+		cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+i.binDir())
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil {
+			r.Warn(check, "", "ldd %s failed: %v", path, err)
+			continue
+		}
+		for line := range strings.SplitSeq(stdout.String(), "\n") {
+			if strings.Contains(line, "not found") {
+				r.Error(check, "install the library or reinstall NEUTRINO", "%s: %s", name, strings.TrimSpace(line))
+			}
+		}
+	}
+}
+
+func (i Installation) checkVersion(ctx context.Context, r *Report) {
+	const check = "version"
+	v, err := info.GetNeutrinoVersion(ctx, i.NeutrinoDir)
+	if err != nil {
+		r.Error(check, "run bin/neutrino on the NEUTRINO directory to see what is wrong", "%v", err)
+		return
+	}
+	r.OK(check, "%s", v)
+}
+
+func (i Installation) checkModels(r *Report) {
+	const check = "model"
+	models, err := info.ModelIDs(i.NeutrinoDir)
+	if err != nil {
+		r.Error(check, "", "%v", err)
+		return
+	}
+	if len(models) == 0 {
+		r.Error(check, "download singer voice models into "+i.modelDir(), "no models in %s", i.modelDir())
+		return
+	}
+	for _, m := range models {
+		if _, err := info.ReadModelInfo(filepath.Join(i.modelDir(), m)); err != nil {
+			r.Warn(check, "reinstall the model", "%s: failed to read info.toml: %v", m, err)
+			continue
+		}
+		r.OK(check, "%s", m)
+	}
+}
+
+func (i Installation) checkDiskSpace(r *Report) {
+	const check = "disk"
+	// workDir may not be created yet
+	dir := i.WorkDir
+	for pathx.Exist(dir) != pathx.Edir && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		r.Warn(check, "", "failed to get free space of %s: %v", dir, err)
+		return
+	}
+	if free < i.MinFreeSpace {
+		r.Error(check, "free up space or change --workDir", "%s has only %d MiB free, require %d MiB", dir, free>>20, i.MinFreeSpace>>20)
+		return
+	}
+	r.OK(check, "%s has %d MiB free", dir, free>>20)
+}
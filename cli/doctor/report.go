@@ -0,0 +1,90 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarn
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "OK"
+	case SeverityWarn:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Finding is the result of a check.
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+	// Hint is what to do to fix the finding.
+	Hint string
+}
+
+// Report is the list of findings.
+type Report struct {
+	Findings []*Finding
+}
+
+func (r *Report) add(severity Severity, check, hint, format string, v ...any) {
+	r.Findings = append(r.Findings, &Finding{
+		Check:    check,
+		Severity: severity,
+		// joined errors are multiline
+		Message: strings.ReplaceAll(fmt.Sprintf(format, v...), "\n", ": "),
+		Hint:    hint,
+	})
+}
+
+func (r *Report) OK(check, format string, v ...any) { r.add(SeverityOK, check, "", format, v...) }
+func (r *Report) Warn(check, hint, format string, v ...any) {
+	r.add(SeverityWarn, check, hint, format, v...)
+}
+func (r *Report) Error(check, hint, format string, v ...any) {
+	r.add(SeverityError, check, hint, format, v...)
+}
+
+// Count returns the number of the findings of the severity.
+func (r Report) Count(severity Severity) int {
+	var n int
+	for _, x := range r.Findings {
+		if x.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Write writes the findings line by line, e.g.
+//
+//	[ERROR] binary: bin/neutrino is not executable
+//	        hint: chmod 755 /path/to/NEUTRINO/bin/*
+func (r Report) Write(w io.Writer) error {
+	for _, x := range r.Findings {
+		if _, err := fmt.Fprintf(w, "[%s] %s: %s\n", x.Severity, x.Check, x.Message); err != nil {
+			return err
+		}
+		if x.Hint != "" {
+			if _, err := fmt.Fprintf(w, "        hint: %s\n", x.Hint); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d errors, %d warnings\n", r.Count(SeverityError), r.Count(SeverityWarn))
+	return err
+}
@@ -0,0 +1,254 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrHook = errors.New("Hook")
+
+// Event is a lifecycle point of the pipeline.
+type Event string
+
+const (
+	// EventStart is before the first stage.
+	EventStart Event = "start"
+	// EventPreStage is before each stage.
+	EventPreStage Event = "preStage"
+	// EventPostStage is after each stage succeeded.
+	EventPostStage Event = "postStage"
+	// EventSuccess is after all the stages succeeded.
+	EventSuccess Event = "success"
+	// EventFailure is after the pipeline failed.
+	EventFailure Event = "failure"
+)
+
+var Events = []Event{
+	EventStart,
+	EventPreStage,
+	EventPostStage,
+	EventSuccess,
+	EventFailure,
+}
+
+// CanVeto returns true if the hook can stop the pipeline at the event by the non-zero exit status.
+func (e Event) CanVeto() bool { return e == EventStart || e == EventPreStage }
+
+// ParseEvents parses the event names.
+// all means all the events.
+func ParseEvents(names []string) ([]Event, error) {
+	r := []Event{}
+	for _, x := range names {
+		if x == "all" {
+			return Events, nil
+		}
+		e := Event(x)
+		if !slices.Contains(Events, e) {
+			return nil, fmt.Errorf("%w: unknown event %s", ErrHook, x)
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
+
+// Record is an event occurred in the pipeline.
+type Record struct {
+	Event Event     `json:"event"`
+	Stage string    `json:"stage,omitempty"`
+	Time  time.Time `json:"time"`
+	// Vetoed is true if the hook stopped the pipeline at the event.
+	Vetoed bool `json:"vetoed,omitempty"`
+}
+
+// State is the directory shared by the hook invocations of the running pipeline.
+type State struct {
+	dir string
+}
+
+func NewState(dir string) *State {
+	return &State{dir: dir}
+}
+
+func (s State) recordFile() string { return filepath.Join(s.dir, "events.jsonl") }
+
+// Records returns the events occurred so far.
+func (s State) Records() ([]*Record, error) {
+	f, err := os.Open(s.recordFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		r       []*Record
+		scanner = bufio.NewScanner(f)
+	)
+	for scanner.Scan() {
+		var x Record
+		if err := json.Unmarshal(scanner.Bytes(), &x); err != nil {
+			return nil, fmt.Errorf("%w: read records: %w", ErrHook, err)
+		}
+		r = append(r, &x)
+	}
+	return r, scanner.Err()
+}
+
+func (s State) Append(r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.recordFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Document is passed to the hook via stdin.
+type Document struct {
+	Event Event  `json:"event"`
+	Stage string `json:"stage,omitempty"`
+	// Config is the config of the pipeline.
+	Config    json.RawMessage `json:"config,omitempty"`
+	ResultDir string          `json:"resultDir"`
+	StartedAt time.Time       `json:"startedAt"`
+	Time      time.Time       `json:"time"`
+	// Elapsed is the seconds since the start.
+	Elapsed float64 `json:"elapsed"`
+	// Stages are the timings of the stages started so far.
+	Stages []*StageTiming `json:"stages"`
+	// Files are the intermediate files and the results produced so far.
+	Files []string `json:"files"`
+	// Error is the cause of the failure.
+	Error string `json:"error,omitempty"`
+}
+
+type StageTiming struct {
+	Name      string     `json:"name"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	// Elapsed is the seconds the stage took, or has taken if not ended.
+	Elapsed float64 `json:"elapsed"`
+}
+
+// NewDocument returns the document of the last record.
+// status is the exit status of the pipeline on failure.
+func NewDocument(records []*Record, status int) (*Document, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no records", ErrHook)
+	}
+	var (
+		last = records[len(records)-1]
+		d    = &Document{
+			Event:     last.Event,
+			Stage:     last.Stage,
+			StartedAt: records[0].Time,
+			Time:      last.Time,
+			Elapsed:   last.Time.Sub(records[0].Time).Seconds(),
+			Stages:    []*StageTiming{},
+			Files:     []string{},
+		}
+		current *StageTiming
+	)
+	for _, r := range records {
+		switch r.Event {
+		case EventPreStage:
+			current = &StageTiming{
+				Name:      r.Stage,
+				StartedAt: r.Time,
+			}
+			d.Stages = append(d.Stages, current)
+		case EventPostStage:
+			if current != nil && current.Name == r.Stage {
+				current.EndedAt = new(r.Time)
+				current.Elapsed = r.Time.Sub(current.StartedAt).Seconds()
+				current = nil
+			}
+		}
+	}
+	if current != nil {
+		current.Elapsed = last.Time.Sub(current.StartedAt).Seconds()
+	}
+
+	if last.Event == EventFailure {
+		d.Error = failureCause(records[:len(records)-1], last.Stage, status)
+	}
+	return d, nil
+}
+
+func failureCause(records []*Record, stage string, status int) string {
+	if len(records) > 0 {
+		if x := records[len(records)-1]; x.Vetoed {
+			if x.Event == EventStart {
+				return "vetoed by the hook at start"
+			}
+			return fmt.Sprintf("vetoed by the hook at %s of %s", x.Event, x.Stage)
+		}
+	}
+	if stage == "" {
+		return fmt.Sprintf("exited with status %d", status)
+	}
+	return fmt.Sprintf("stage %s exited with status %d", stage, status)
+}
+
+// Files returns the intermediate files of the basename in outputDir and the files in resultDir as absolute paths.
+// The intermediate files are not included if outputDir is empty.
+func Files(outputDir, basename, resultDir string) ([]string, error) {
+	r := []string{}
+	if outputDir != "" {
+		xs, err := filepath.Glob(filepath.Join(outputDir, basename+".*"))
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, xs...)
+	}
+	if err := filepath.WalkDir(resultDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			r = append(r, path)
+		}
+		return nil
+	}); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for i, x := range r {
+		if a, err := filepath.Abs(x); err == nil {
+			r[i] = a
+		}
+	}
+	return r, nil
+}
+
+// Run executes the hook by sh.
+// The hook receives the document via stdin and the result directory as the 1st argument.
+func Run(ctx context.Context, hook string, d *Document, stdout, stderr io.Writer) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", strings.TrimSpace(hook)+` "$1"`, "sh", d.ResultDir)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
@@ -0,0 +1,104 @@
+package hook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/hook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvents(t *testing.T) {
+	got, err := hook.ParseEvents([]string{"start", "failure"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []hook.Event{hook.EventStart, hook.EventFailure}, got)
+
+	got, err = hook.ParseEvents([]string{"all"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, hook.Events, got)
+
+	_, err = hook.ParseEvents([]string{"end"})
+	assert.ErrorIs(t, err, hook.ErrHook)
+}
+
+func TestDocument(t *testing.T) {
+	var (
+		t0  = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		at  = func(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+		run = []*hook.Record{
+			{Event: hook.EventStart, Time: at(0)},
+			{Event: hook.EventPreStage, Stage: "init", Time: at(1)},
+			{Event: hook.EventPostStage, Stage: "init", Time: at(3)},
+			{Event: hook.EventPreStage, Stage: "NEUTRINO", Time: at(3)},
+		}
+	)
+
+	t.Run("running", func(t *testing.T) {
+		got, err := hook.NewDocument(run, 0)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, hook.EventPreStage, got.Event)
+		assert.Equal(t, "NEUTRINO", got.Stage)
+		assert.Equal(t, 3.0, got.Elapsed)
+		assert.Equal(t, []*hook.StageTiming{
+			{Name: "init", StartedAt: at(1), EndedAt: new(at(3)), Elapsed: 2},
+			{Name: "NEUTRINO", StartedAt: at(3)},
+		}, got.Stages)
+		assert.Empty(t, got.Error)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		got, err := hook.NewDocument(append(run[:4:4], &hook.Record{Event: hook.EventFailure, Stage: "NEUTRINO", Time: at(10)}), 2)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "stage NEUTRINO exited with status 2", got.Error)
+		assert.Equal(t, 7.0, got.Stages[1].Elapsed)
+		assert.Nil(t, got.Stages[1].EndedAt)
+	})
+
+	t.Run("vetoed", func(t *testing.T) {
+		records := []*hook.Record{
+			{Event: hook.EventStart, Time: at(0)},
+			{Event: hook.EventPreStage, Stage: "init", Time: at(1), Vetoed: true},
+			{Event: hook.EventFailure, Stage: "init", Time: at(2)},
+		}
+		got, err := hook.NewDocument(records, 1)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "vetoed by the hook at preStage of init", got.Error)
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		_, err := hook.NewDocument(nil, 0)
+		assert.ErrorIs(t, err, hook.ErrHook)
+	})
+}
+
+func TestState(t *testing.T) {
+	s := hook.NewState(t.TempDir())
+	got, err := s.Records()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Empty(t, got)
+
+	want := []*hook.Record{
+		{Event: hook.EventStart, Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Event: hook.EventPreStage, Stage: "init", Time: time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC), Vetoed: true},
+	}
+	for _, x := range want {
+		assert.Nil(t, s.Append(x))
+	}
+	got, err = s.Records()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, want, got)
+}
@@ -13,35 +13,47 @@ import (
 var ErrModels = errors.New("Models")
 
 func getModels(neutrinoDir string) ([]Model, error) {
-	x, err := filepath.Abs(neutrinoDir)
+	ids, err := ModelIDs(neutrinoDir)
 	if err != nil {
-		return nil, errors.Join(ErrModels, err)
+		return nil, err
 	}
-	dir := filepath.Join(x, "model")
-	entries, err := os.ReadDir(dir)
+	x, err := filepath.Abs(neutrinoDir)
 	if err != nil {
 		return nil, errors.Join(ErrModels, err)
 	}
+	dir := filepath.Join(x, "model")
 
-	var models []Model
-	for _, x := range entries {
-		if !x.IsDir() {
-			continue
-		}
+	models := make([]Model, len(ids))
+	for i, id := range ids {
 		m := Model{
-			ID: x.Name(),
+			ID: id,
 		}
-		data, err := ReadModelInfo(filepath.Join(dir, x.Name()))
+		data, err := ReadModelInfo(filepath.Join(dir, id))
 		if err == nil {
 			m.Data = data
 		} else {
 			slog.Warn("failed to read model info", slog.String("model", m.ID), logx.Err(err))
 		}
-		models = append(models, m)
+		models[i] = m
 	}
 	return models, nil
 }
 
+// ModelIDs returns the names of the model directories in the NEUTRINO directory.
+func ModelIDs(neutrinoDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(neutrinoDir, "model"))
+	if err != nil {
+		return nil, errors.Join(ErrModels, err)
+	}
+	var ids []string
+	for _, x := range entries {
+		if x.IsDir() {
+			ids = append(ids, x.Name())
+		}
+	}
+	return ids, nil
+}
+
 func ReadModelInfo(modelDir string) (map[string]any, error) {
 	var m map[string]any
 	if _, err := toml.DecodeFile(filepath.Join(modelDir, "info.toml"), &m); err != nil {
@@ -22,19 +22,37 @@ func GetNeutrinoVersion(ctx context.Context, dir string) (string, error) {
 	if _, err := os.Stat(bin); err != nil {
 		bin = filepath.Join(x, "bin", "neutrino")
 	}
+	if _, err := os.Stat(bin); err != nil {
+		return "", fmt.Errorf("%w: binary not found in %s", errors.Join(ErrNeutrinoVersion, err), filepath.Join(x, "bin"))
+	}
 	dyld := filepath.Join(x, "bin")
 
+	var stderr bytes.Buffer
 	cmd := exec.CommandContext(ctx, bin)
 	cmd.Env = append(cmd.Env, "DYLD_LIBRARY_PATH="+dyld)
-	output, _ := cmd.Output()
+	cmd.Stderr = &stderr
+	// NEUTRINO prints the version with the usage, the exit status is not reliable
+	output, runErr := cmd.Output()
+	unexpected := func() error {
+		err := fmt.Errorf("%w: failed to get version from the output of %s: %q", ErrNeutrinoVersion, bin, firstLine(output))
+		if runErr != nil {
+			err = fmt.Errorf("%w: %w: stderr: %q", err, runErr, firstLine(stderr.Bytes()))
+		}
+		return err
+	}
 	ss := bytes.SplitN(output, []byte("\n"), 2)
 	if len(ss) != 2 {
-		return "", fmt.Errorf("%w: failed to get output", ErrNeutrinoVersion)
+		return "", unexpected()
 	}
 	s := string(ss[0])
 	vs := strings.SplitN(s, "-", 2)
 	if len(vs) != 2 {
-		return "", fmt.Errorf("%w: failed to get output", ErrNeutrinoVersion)
+		return "", unexpected()
 	}
 	return strings.TrimSpace(vs[1]), nil
 }
+
+func firstLine(b []byte) string {
+	s, _, _ := strings.Cut(string(b), "\n")
+	return strings.TrimSpace(s)
+}
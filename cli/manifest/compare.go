@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Difference is the different item of the manifests.
+type Difference struct {
+	// Kind is the kind of the item, e.g. output, binary, model.
+	Kind string
+	Name string
+	// A is the value of the original manifest, empty if missing.
+	A string
+	// B is the value of the other manifest, empty if missing.
+	B string
+}
+
+// Comparison is the result of comparing the manifests.
+type Comparison struct {
+	// Environment are the differences of the installation and the inputs.
+	Environment []*Difference
+	// Outputs are the differences of the outputs.
+	Outputs []*Difference
+}
+
+func (c Comparison) OutputsDiffer() bool { return len(c.Outputs) > 0 }
+
+// Compare compares the manifest a with b.
+func Compare(a, b *Manifest) *Comparison {
+	var c Comparison
+	env := func(kind, name, x, y string) {
+		if x != y {
+			c.Environment = append(c.Environment, &Difference{Kind: kind, Name: name, A: x, B: y})
+		}
+	}
+	env("version", "neutrino", a.Neutrino.Version, b.Neutrino.Version)
+	env("version", "pneutrinoutil", a.Pneutrinoutil.Version, b.Pneutrinoutil.Version)
+	env("revision", "pneutrinoutil", a.Pneutrinoutil.Revision, b.Pneutrinoutil.Revision)
+	if a.Score != nil && b.Score != nil {
+		env("score", a.Score.Name, a.Score.SHA256, b.Score.SHA256)
+	}
+	c.Environment = append(c.Environment, compareHashes("binary", a.Neutrino.Binaries, b.Neutrino.Binaries)...)
+	c.Environment = append(c.Environment, compareHashes("model", a.Models, b.Models)...)
+	c.Environment = append(c.Environment, compareHashes("label", a.Labels, b.Labels)...)
+	c.Outputs = compareHashes("output", a.Outputs, b.Outputs)
+	return &c
+}
+
+func compareHashes(kind string, a, b []*Hash) []*Difference {
+	toMap := func(xs []*Hash) map[string]string {
+		d := make(map[string]string, len(xs))
+		for _, x := range xs {
+			d[x.Name] = x.SHA256
+		}
+		return d
+	}
+	var (
+		da    = toMap(a)
+		db    = toMap(b)
+		names = slices.Sorted(maps.Keys(da))
+	)
+	for k := range db {
+		if _, ok := da[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	slices.Sort(names)
+
+	var xs []*Difference
+	for _, k := range names {
+		if da[k] != db[k] {
+			xs = append(xs, &Difference{Kind: kind, Name: k, A: da[k], B: db[k]})
+		}
+	}
+	return xs
+}
+
+// Write writes the differences line by line.
+func (c Comparison) Write(w io.Writer) error {
+	for _, x := range append(append([]*Difference{}, c.Environment...), c.Outputs...) {
+		if _, err := fmt.Fprintf(w, "%s %s: %s -> %s\n", x.Kind, x.Name, orMissing(x.A), orMissing(x.B)); err != nil {
+			return err
+		}
+	}
+	result := "outputs are identical"
+	if c.OutputsDiffer() {
+		result = fmt.Sprintf("%d outputs differ", len(c.Outputs))
+	}
+	_, err := fmt.Fprintln(w, result)
+	return err
+}
+
+func orMissing(s string) string {
+	if s == "" {
+		return "(missing)"
+	}
+	return s
+}
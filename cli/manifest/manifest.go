@@ -0,0 +1,216 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/version"
+)
+
+var ErrManifest = errors.New("Manifest")
+
+// FileName is the manifest in the result directory.
+const FileName = "manifest.json"
+
+// Manifest records what is required to reproduce the render.
+type Manifest struct {
+	CreatedAt     time.Time     `json:"createdAt"`
+	Pneutrinoutil Pneutrinoutil `json:"pneutrinoutil"`
+	Neutrino      Neutrino      `json:"neutrino"`
+	Models        []*Hash       `json:"models"`
+	Score         *Hash         `json:"score"`
+	Labels        []*Hash       `json:"labels"`
+	// Outputs are the files in the result directory.
+	Outputs []*Hash `json:"outputs"`
+	// CommandLine is the arguments of the pneutrinoutil process.
+	CommandLine []string `json:"commandLine"`
+	// Env is the names of the additional environment variables allowed to read.
+	Env []string `json:"env"`
+}
+
+type Pneutrinoutil struct {
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+}
+
+type Neutrino struct {
+	Version string `json:"version"`
+	Dir     string `json:"dir"`
+	// Binaries are the files in the bin directory.
+	Binaries []*Hash `json:"binaries"`
+}
+
+// Hash is the SHA-256 of the file or the directory.
+type Hash struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Input is the source of the manifest.
+type Input struct {
+	NeutrinoDir     string
+	NeutrinoVersion string
+	// Models are the names of the model directories.
+	Models []string
+	// Score is the path of the rendered score.
+	Score string
+	// Labels are the paths of the label files.
+	Labels      []string
+	ResultDir   string
+	CommandLine []string
+	Env         []string
+}
+
+// ignoredOutputs are the files in the result directory not to be compared.
+var ignoredOutputs = []string{FileName, "config.yml", "PWD"}
+
+// New calculates the hashes of the files and returns the manifest.
+func New(in *Input, now time.Time) (*Manifest, error) {
+	neutrinoDir, err := filepath.Abs(in.NeutrinoDir)
+	if err != nil {
+		return nil, errors.Join(ErrManifest, err)
+	}
+	m := &Manifest{
+		CreatedAt: now,
+		Pneutrinoutil: Pneutrinoutil{
+			Version:  version.Version,
+			Revision: version.Revision,
+		},
+		Neutrino: Neutrino{
+			Version: in.NeutrinoVersion,
+			Dir:     neutrinoDir,
+		},
+		CommandLine: in.CommandLine,
+		Env:         in.Env,
+	}
+
+	if m.Neutrino.Binaries, err = hashDirFiles(filepath.Join(neutrinoDir, "bin"), nil); err != nil {
+		return nil, fmt.Errorf("%w: binaries", err)
+	}
+	for _, x := range in.Models {
+		h, err := hashDir(filepath.Join(neutrinoDir, "model", x))
+		if err != nil {
+			return nil, fmt.Errorf("%w: model %s", err, x)
+		}
+		m.Models = append(m.Models, &Hash{Name: x, SHA256: h})
+	}
+	if m.Score, err = hashFileAs(in.Score, filepath.Base(in.Score)); err != nil {
+		return nil, fmt.Errorf("%w: score", err)
+	}
+	for _, x := range in.Labels {
+		name, err := filepath.Rel(neutrinoDir, absOr(x))
+		if err != nil {
+			name = x
+		}
+		h, err := hashFileAs(x, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: label", err)
+		}
+		m.Labels = append(m.Labels, h)
+	}
+	if m.Outputs, err = hashDirFiles(in.ResultDir, ignoredOutputs); err != nil {
+		return nil, fmt.Errorf("%w: outputs", err)
+	}
+	return m, nil
+}
+
+// Read reads the manifest file.
+func Read(path string) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Join(ErrManifest, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Join(ErrManifest, err)
+	}
+	return &m, nil
+}
+
+func (m Manifest) Write(w io.Writer) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func absOr(path string) string {
+	if x, err := filepath.Abs(path); err == nil {
+		return x
+	}
+	return path
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFileAs(path, name string) (*Hash, error) {
+	h, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Hash{Name: name, SHA256: h}, nil
+}
+
+// hashDirFiles returns the hashes of the files in the dir recursively, except the names of the top level in ignore.
+func hashDirFiles(dir string, ignore []string) ([]*Hash, error) {
+	var xs []*Hash
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ignore, name) {
+			return nil
+		}
+		h, err := hashFileAs(path, name)
+		if err != nil {
+			return err
+		}
+		xs = append(xs, h)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return xs, nil
+}
+
+// hashDir returns the hash of the names and the contents of the files in the dir.
+func hashDir(dir string) (string, error) {
+	xs, err := hashDirFiles(dir, nil)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, x := range xs {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\n", x.Name, x.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
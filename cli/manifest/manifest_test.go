@@ -0,0 +1,117 @@
+package manifest_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func newManifest(t *testing.T, wav string) *manifest.Manifest {
+	t.Helper()
+	var (
+		neutrinoDir = t.TempDir()
+		resultDir   = t.TempDir()
+	)
+	writeFiles(t, neutrinoDir, map[string]string{
+		"bin/neutrino":                  "neutrino",
+		"model/MERROW/info.toml":        "name = 'MERROW'",
+		"model/MERROW/voice.bin":        "voice",
+		"score/musicxml/x.musicxml":     "score",
+		"score/label/full/x.lab":        "full",
+		"score/label/timing/x.lab":      "timing",
+		"model/KIRITAN/info.toml":       "not used",
+		"score/musicxml/other.musicxml": "not used",
+	})
+	writeFiles(t, resultDir, map[string]string{
+		"x.wav":      wav,
+		"x.musicxml": "score",
+		"config.yml": "model: MERROW",
+		"PWD":        resultDir,
+	})
+	m, err := manifest.New(&manifest.Input{
+		NeutrinoDir:     neutrinoDir,
+		NeutrinoVersion: "v3.0.0",
+		Models:          []string{"MERROW"},
+		Score:           filepath.Join(neutrinoDir, "score/musicxml/x.musicxml"),
+		Labels: []string{
+			filepath.Join(neutrinoDir, "score/label/full/x.lab"),
+			filepath.Join(neutrinoDir, "score/label/timing/x.lab"),
+		},
+		ResultDir:   resultDir,
+		CommandLine: []string{"pneutrinoutil", "--score", "x.musicxml"},
+		Env:         []string{"HOME"},
+	}, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return m
+}
+
+func TestManifest(t *testing.T) {
+	m := newManifest(t, "wav")
+
+	t.Run("new", func(t *testing.T) {
+		names := func(xs []*manifest.Hash) []string {
+			r := make([]string, len(xs))
+			for i, x := range xs {
+				r[i] = x.Name
+			}
+			return r
+		}
+		assert.Equal(t, []string{"neutrino"}, names(m.Neutrino.Binaries))
+		assert.Equal(t, []string{"MERROW"}, names(m.Models))
+		assert.Equal(t, "x.musicxml", m.Score.Name)
+		score := sha256.Sum256([]byte("score"))
+		assert.Equal(t, hex.EncodeToString(score[:]), m.Score.SHA256)
+		assert.Equal(t, []string{"score/label/full/x.lab", "score/label/timing/x.lab"}, names(m.Labels))
+		assert.Equal(t, []string{"x.musicxml", "x.wav"}, names(m.Outputs))
+		assert.Equal(t, "v3.0.0", m.Neutrino.Version)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), manifest.FileName)
+		var buf bytes.Buffer
+		if !assert.Nil(t, m.Write(&buf)) {
+			return
+		}
+		assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+		got, err := manifest.Read(path)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, m, got)
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		same := manifest.Compare(m, newManifest(t, "wav"))
+		assert.False(t, same.OutputsDiffer())
+		assert.Empty(t, same.Environment)
+
+		other := newManifest(t, "other wav")
+		other.Neutrino.Version = "v3.1.0"
+		diff := manifest.Compare(m, other)
+		assert.True(t, diff.OutputsDiffer())
+		assert.Equal(t, []*manifest.Difference{
+			{Kind: "output", Name: "x.wav", A: m.Outputs[1].SHA256, B: other.Outputs[1].SHA256},
+		}, diff.Outputs)
+		assert.Equal(t, []*manifest.Difference{
+			{Kind: "version", Name: "neutrino", A: "v3.0.0", B: "v3.1.0"},
+		}, diff.Environment)
+	})
+}
@@ -0,0 +1,187 @@
+package results
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Diff is the difference between 2 runs.
+type Diff struct {
+	Config []*ConfigDiff
+	// OnlyA are the files only in A.
+	OnlyA []string
+	// OnlyB are the files only in B.
+	OnlyB []string
+	// Changed are the files in both but the contents are different.
+	Changed []string
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Config) == 0 && len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Changed) == 0
+}
+
+// ConfigDiff is the different value of config.yml.
+type ConfigDiff struct {
+	Key string
+	A   any
+	B   any
+}
+
+// basenameVar replaces the basename in the file names to compare runs of different basenames.
+const basenameVar = "${BASENAME}"
+
+// NewDiff compares the configs and the files of the runs.
+func NewDiff(a, b *Run) (*Diff, error) {
+	var d Diff
+	ca, err := readConfigMap(a)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := readConfigMap(b)
+	if err != nil {
+		return nil, err
+	}
+	keys := slices.Sorted(maps.Keys(ca))
+	for k := range cb {
+		if _, ok := ca[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if !reflect.DeepEqual(ca[k], cb[k]) {
+			d.Config = append(d.Config, &ConfigDiff{
+				Key: k,
+				A:   ca[k],
+				B:   cb[k],
+			})
+		}
+	}
+
+	fa, err := fileMap(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := fileMap(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range slices.Sorted(maps.Keys(fa)) {
+		y, ok := fb[k]
+		if !ok {
+			d.OnlyA = append(d.OnlyA, k)
+			continue
+		}
+		if k == ConfigFileName {
+			// compared by keys
+			continue
+		}
+		same, err := sameFile(fa[k], y)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(fb)) {
+		if _, ok := fa[k]; !ok {
+			d.OnlyB = append(d.OnlyB, k)
+		}
+	}
+	return &d, nil
+}
+
+// Write writes the diff like diff -u.
+func (d Diff) Write(w io.Writer, a, b *Run) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", a.Name(), b.Name())
+	for _, x := range d.Config {
+		if x.A != nil {
+			fmt.Fprintf(&buf, "-%s: %v\n", x.Key, x.A)
+		}
+		if x.B != nil {
+			fmt.Fprintf(&buf, "+%s: %v\n", x.Key, x.B)
+		}
+	}
+	for _, x := range d.OnlyA {
+		fmt.Fprintf(&buf, "Only in %s: %s\n", a.Name(), x)
+	}
+	for _, x := range d.OnlyB {
+		fmt.Fprintf(&buf, "Only in %s: %s\n", b.Name(), x)
+	}
+	for _, x := range d.Changed {
+		fmt.Fprintf(&buf, "Files differ: %s\n", x)
+	}
+	_, err := io.Copy(w, &buf)
+	return err
+}
+
+func readConfigMap(r *Run) (map[string]any, error) {
+	b, err := os.ReadFile(filepath.Join(r.Dir, ConfigFileName))
+	if os.IsNotExist(err) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, r.Name())
+	}
+	return m, nil
+}
+
+// fileMap returns the normalized file names to the paths.
+func fileMap(r *Run) (map[string]string, error) {
+	files, err := r.Files()
+	if err != nil {
+		return nil, err
+	}
+	d := make(map[string]string, len(files))
+	for _, f := range files {
+		name := f.Name
+		if x, ok := strings.CutPrefix(name, r.Element.Basename+"."); ok {
+			name = basenameVar + "." + x
+		}
+		d[name] = filepath.Join(r.Dir, f.Name)
+	}
+	return d, nil
+}
+
+func sameFile(a, b string) (bool, error) {
+	x, err := fileHash(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := fileHash(b)
+	if err != nil {
+		return false, err
+	}
+	return x == y, nil
+}
+
+func fileHash(path string) ([sha256.Size]byte, error) {
+	var r [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return r, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return r, err
+	}
+	copy(r[:], h.Sum(nil))
+	return r, nil
+}
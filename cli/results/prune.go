@@ -0,0 +1,59 @@
+package results
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrPolicy = errors.New("Policy")
+
+// PrunePolicy decides the runs to be removed.
+// A run is removed if it satisfies all the enabled conditions.
+type PrunePolicy struct {
+	// KeepLast keeps the last N runs of each basename, disabled if 0.
+	KeepLast int
+	// OlderThan removes the runs older than the duration, disabled if 0.
+	OlderThan time.Duration
+}
+
+func (p PrunePolicy) Validate() error {
+	if p.KeepLast < 0 || p.OlderThan < 0 {
+		return errors.Join(ErrPolicy, errors.New("negative value"))
+	}
+	if p.KeepLast == 0 && p.OlderThan == 0 {
+		return errors.Join(ErrPolicy, errors.New("require keep last or older than"))
+	}
+	return nil
+}
+
+// Prune returns the runs to be removed.
+// runs should be sorted from oldest to newest.
+func (p PrunePolicy) Prune(runs []*Run, now time.Time) ([]*Run, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	keep := map[*Run]bool{}
+	if p.KeepLast > 0 {
+		seen := map[string]int{}
+		for i := len(runs) - 1; i >= 0; i-- {
+			r := runs[i]
+			if seen[r.Element.Basename] < p.KeepLast {
+				keep[r] = true
+			}
+			seen[r.Element.Basename]++
+		}
+	}
+
+	var xs []*Run
+	for _, r := range runs {
+		if keep[r] {
+			continue
+		}
+		if p.OlderThan > 0 && now.Sub(r.CreatedAt()) <= p.OlderThan {
+			continue
+		}
+		xs = append(xs, r)
+	}
+	return xs, nil
+}
@@ -0,0 +1,173 @@
+package results
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/goccy/go-yaml"
+)
+
+var ErrRunNotFound = errors.New("RunNotFound")
+
+// ConfigFileName is the config of the run in the result directory.
+const ConfigFileName = "config.yml"
+
+// Run is the result directory of a run, $workDir/result/BASENAME__YYYYmmddHHMMSS_TIMESTAMP_SALT.
+type Run struct {
+	Element *pathx.ResultElement
+	Dir     string
+	// Config is nil if config.yml is not available.
+	Config *ctl.Config
+}
+
+func (r Run) Name() string { return filepath.Base(r.Dir) }
+
+// CreatedAt returns the time when the run started.
+func (r Run) CreatedAt() time.Time { return time.Unix(r.Element.Timestamp, 0) }
+
+// NewRun reads the result directory.
+func NewRun(dir string) (*Run, error) {
+	elem, err := pathx.ParseResultElement(filepath.Base(dir))
+	if err != nil {
+		return nil, err
+	}
+	if pathx.Exist(dir) != pathx.Edir {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, dir)
+	}
+	r := &Run{
+		Element: elem,
+		Dir:     dir,
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, ConfigFileName)); err == nil {
+		var c ctl.Config
+		if err := yaml.Unmarshal(b, &c); err == nil {
+			r.Config = &c
+		}
+	}
+	return r, nil
+}
+
+// List returns the runs in the result directory, from oldest to newest.
+// Entries that are not results are ignored.
+func List(resultDir string) ([]*Run, error) {
+	entries, err := os.ReadDir(resultDir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Run
+	for _, x := range entries {
+		if !x.IsDir() {
+			continue
+		}
+		r, err := NewRun(filepath.Join(resultDir, x.Name()))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, r)
+	}
+	slices.SortStableFunc(runs, func(a, b *Run) int {
+		return cmp.Or(
+			cmp.Compare(a.Element.Timestamp, b.Element.Timestamp),
+			cmp.Compare(a.Element.Salt, b.Element.Salt),
+		)
+	})
+	return runs, nil
+}
+
+// Find returns the run specified by the name of the result directory or the path.
+func Find(resultDir, nameOrPath string) (*Run, error) {
+	if pathx.Exist(nameOrPath) == pathx.Edir {
+		return NewRun(nameOrPath)
+	}
+	r, err := NewRun(filepath.Join(resultDir, nameOrPath))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errors.Join(ErrRunNotFound, err), nameOrPath)
+	}
+	return r, nil
+}
+
+// File is a file in the result directory.
+type File struct {
+	// Name is the relative path from the result directory.
+	Name string
+	Size int64
+}
+
+// Files returns the files in the result directory.
+func (r Run) Files() ([]*File, error) {
+	var files []*File
+	if err := filepath.WalkDir(r.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(r.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, &File{
+			Name: name,
+			Size: info.Size(),
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Filter selects runs.
+// Empty fields match any runs.
+type Filter struct {
+	Basename string
+	Since    time.Time
+	Until    time.Time
+	// Model matches model or supportModel in config.yml.
+	Model string
+}
+
+func (f Filter) Match(r *Run) bool {
+	if f.Basename != "" && f.Basename != r.Element.Basename {
+		return false
+	}
+	if !f.Since.IsZero() && r.CreatedAt().Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.CreatedAt().After(f.Until) {
+		return false
+	}
+	if f.Model != "" {
+		if r.Config == nil {
+			return false
+		}
+		if f.Model != r.Config.ModelDir && f.Model != r.Config.SupportModelDir {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterRuns returns the runs that match f.
+func FilterRuns(runs []*Run, f Filter) []*Run {
+	var xs []*Run
+	for _, r := range runs {
+		if f.Match(r) {
+			xs = append(xs, r)
+		}
+	}
+	return xs
+}
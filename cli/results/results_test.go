@@ -0,0 +1,158 @@
+package results_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/results"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// newRun creates a result directory started days ago.
+func newRun(t *testing.T, resultDir, basename string, days int, config string, files map[string]string) string {
+	t.Helper()
+	at := now.AddDate(0, 0, -days)
+	dir := filepath.Join(resultDir, pathx.NewResultElement(basename, at, at.Unix(), days).String())
+	assert.Nil(t, os.MkdirAll(dir, 0755))
+	if config != "" {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, results.ConfigFileName), []byte(config), 0644))
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func names(runs []*results.Run) []string {
+	xs := make([]string, len(runs))
+	for i, r := range runs {
+		xs[i] = r.Element.Basename + "@" + r.CreatedAt().UTC().Format(time.DateOnly)
+	}
+	return xs
+}
+
+func TestList(t *testing.T) {
+	resultDir := t.TempDir()
+	newRun(t, resultDir, "a", 1, "model: KIRITAN\n", nil)
+	newRun(t, resultDir, "a", 10, "model: MERROW\nsupportModel: KIRITAN\n", nil)
+	newRun(t, resultDir, "b", 5, "", nil)
+	assert.Nil(t, os.MkdirAll(filepath.Join(resultDir, "not_a_result"), 0755))
+
+	runs, err := results.List(resultDir)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"a@2026-09-21", "b@2026-09-26", "a@2026-09-30"}, names(runs))
+
+	for _, tc := range []struct {
+		title  string
+		filter results.Filter
+		want   []string
+	}{
+		{title: "all", want: []string{"a@2026-09-21", "b@2026-09-26", "a@2026-09-30"}},
+		{title: "basename", filter: results.Filter{Basename: "a"}, want: []string{"a@2026-09-21", "a@2026-09-30"}},
+		{title: "since", filter: results.Filter{Since: now.AddDate(0, 0, -5)}, want: []string{"b@2026-09-26", "a@2026-09-30"}},
+		{title: "until", filter: results.Filter{Until: now.AddDate(0, 0, -5)}, want: []string{"a@2026-09-21", "b@2026-09-26"}},
+		{title: "model", filter: results.Filter{Model: "KIRITAN"}, want: []string{"a@2026-09-21", "a@2026-09-30"}},
+		{title: "model without config", filter: results.Filter{Model: "MERROW"}, want: []string{"a@2026-09-21"}},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, names(results.FilterRuns(runs, tc.filter)))
+		})
+	}
+
+	t.Run("find", func(t *testing.T) {
+		r, err := results.Find(resultDir, runs[1].Name())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, runs[1].Dir, r.Dir)
+		_, err = results.Find(resultDir, "none__20260101000000_1_1")
+		assert.ErrorIs(t, err, results.ErrRunNotFound)
+	})
+}
+
+func TestPrune(t *testing.T) {
+	resultDir := t.TempDir()
+	for _, x := range []struct {
+		basename string
+		days     int
+	}{
+		{"a", 1}, {"a", 2}, {"a", 30}, {"a", 40}, {"b", 50},
+	} {
+		newRun(t, resultDir, x.basename, x.days, "", nil)
+	}
+	runs, err := results.List(resultDir)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for _, tc := range []struct {
+		title  string
+		policy results.PrunePolicy
+		want   []string
+		err    error
+	}{
+		{title: "no policy", err: results.ErrPolicy},
+		{title: "keep last", policy: results.PrunePolicy{KeepLast: 2}, want: []string{"a@2026-08-22", "a@2026-09-01"}},
+		{title: "older than", policy: results.PrunePolicy{OlderThan: 24 * 35 * time.Hour}, want: []string{"b@2026-08-12", "a@2026-08-22"}},
+		{title: "both", policy: results.PrunePolicy{KeepLast: 1, OlderThan: 24 * 35 * time.Hour}, want: []string{"a@2026-08-22"}},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := tc.policy.Prune(runs, now)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, names(got))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	resultDir := t.TempDir()
+	a, err := results.NewRun(newRun(t, resultDir, "a", 1, "model: MERROW\ntranspose: 0\n", map[string]string{
+		"a.wav":      "wav",
+		"a.musicxml": "score",
+		"a.flac":     "flac",
+	}))
+	if !assert.Nil(t, err) {
+		return
+	}
+	b, err := results.NewRun(newRun(t, resultDir, "b", 2, "model: KIRITAN\ntranspose: 0\nformats: mix\n", map[string]string{
+		"b.wav":      "wav2",
+		"b.musicxml": "score",
+		"b.mix.wav":  "mix",
+	}))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	got, err := results.NewDiff(a, b)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, &results.Diff{
+		Config: []*results.ConfigDiff{
+			{Key: "formats", B: "mix"},
+			{Key: "model", A: "MERROW", B: "KIRITAN"},
+		},
+		OnlyA:   []string{"${BASENAME}.flac"},
+		OnlyB:   []string{"${BASENAME}.mix.wav"},
+		Changed: []string{"${BASENAME}.wav"},
+	}, got)
+
+	same, err := results.NewDiff(a, a)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, same.Empty())
+}
@@ -1,13 +1,16 @@
 package task
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"al.essio.dev/pkg/shellescape"
 	"github.com/berquerant/execx"
 	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/goccy/go-yaml"
 )
 
@@ -41,29 +44,49 @@ func (g Generator) dyldLibraryPath() string {
 	return strings.Join(xs, ":") + ":"
 }
 
+// self returns the path of the running pneutrinoutil executable to run stages implemented in go.
+func (Generator) self() string {
+	if x, err := os.Executable(); err == nil {
+		return x
+	}
+	return os.Args[0]
+}
+
+// commandLine returns the arguments of the running pneutrinoutil as json to be recorded in the manifest.
+func (Generator) commandLine() string {
+	b, _ := json.Marshal(os.Args)
+	return string(b)
+}
+
 func (g Generator) env() execx.Env {
 	e := g.c.Env()
 	e.Set("ResultDestDir", g.dir.ResultDestDir())
 	e.Set("Score", g.c.Score)
+	e.Set("Accompaniment", g.c.Accompaniment)
 	e.Set("Play", g.play)
 	e.Set("Hook", g.hook)
+	if g.hook != "" {
+		b, _ := json.Marshal(g.c)
+		e.Set("HookConfig", string(b))
+	}
 	e.Set("DYLD_LIBRARY_PATH", g.dyldLibraryPath())
 	e.Set("HOME", os.Getenv("HOME"))
+	e.Set("Self", g.self())
 	e.Merge(g.dir.Env())
 	return e
 }
 
-func (g Generator) executableTasksV3() *execx.ExecutableTasks {
+func (g Generator) executableTasksV3() (*execx.ExecutableTasks, error) {
 	tasks := execx.NewTasks().
 		Add(execx.NewTask(
 			"init",
 			fmt.Sprintf(`export DYLD_LIBRARY_PATH="$DYLD_LIBRARY_PATH"
 chmod 755 %[1]s/*
 xattr -dr com.apple.quarantine "%[1]s"
-cp -f "${Score}" "%[2]s/"
+%[2]s
 mkdir -p "${ResultDestDir}"`,
 				g.dir.BinDir(),
-				g.dir.MusicXMLDir(),
+				g.copyScore(),
 			))).
 		Add(execx.NewTask(
 			"MusicXMLtoLabel",
@@ -102,21 +125,39 @@ mkdir -p "${ResultDestDir}"`,
 					}
 					return ""
 				}(),
-			))).
+			)))
+	if g.c.PostProcessEnabled() {
+		tasks = tasks.Add(g.postProcessTask())
+	}
+	if g.c.Accompaniment != "" {
+		tasks = tasks.Add(g.mixTask())
+	}
+	if g.c.Formats != "" {
+		tasks = tasks.Add(g.transcodeTask())
+	}
+	tasks = tasks.
 		Add(execx.NewTask(
 			"cleanup",
 			fmt.Sprintf(
 				`cp %[1]s/${BASENAME}.* "%[2]s/${BASENAME}.musicxml" "${ResultDestDir}/"
-cat <<EOS > "${ResultDestDir}/config.yml"
+cp "%[7]s/${BASENAME}.lab" "${ResultDestDir}/%[9]s"
+cat <<'EOS' > "${ResultDestDir}/config.yml"
 %[3]s
 EOS
 echo "%[4]s" > "${ResultDestDir}/PWD"
+"${Self}" manifest \
+  --neutrinoDir . \
+  --resultDir "${ResultDestDir}" \
+  --score "%[2]s/${BASENAME}.musicxml" \
+  --labels "%[5]s/${BASENAME}.lab,%[6]s/${BASENAME}.lab,%[7]s/${BASENAME}.lab" \
+  --commandLine %[8]s \
+  --env "${EnvWhiteList}"
 
-if [ -n "$Hook" ] ; then
-  $Hook "${ResultDestDir}"
-fi
 if [ -n "$Play" ] ; then
   result_wav="${ResultDestDir}/${BASENAME}.wav"
+  if [ -f "${ResultDestDir}/%[10]s" ] ; then
+    result_wav="${ResultDestDir}/%[10]s"
+  fi
   $Play "${result_wav}"
 fi`,
 				g.dir.OutputDir(),
@@ -126,14 +167,80 @@ fi`,
 					return b
 				}(),
 				g.dir.PWD(),
+				g.dir.FullDir(),
+				g.dir.MonoDir(),
+				g.dir.TimingDir(),
+				shellescape.Quote(g.commandLine()),
+				pathx.TimingLabelFileName("${BASENAME}"),
+				pathx.MixFileName("${BASENAME}"),
 			)))
 
-	return execx.NewExecutableTasks(tasks, g.env())
+	tasks, err := g.insertStages(tasks)
+	if err != nil {
+		return nil, err
+	}
+	// execx uses the scripts as a format, keep the percent signs in the config and the stages
+	for _, t := range tasks {
+		t.Script = strings.ReplaceAll(t.Script, "%", "%%")
+	}
+	return execx.NewExecutableTasks(tasks, g.env()), nil
+}
+
+// copyScore returns the script to put the score to be rendered into the musicxml dir.
+func (g Generator) copyScore() string {
+	if g.c.Part == "" {
+		return fmt.Sprintf(`cp -f "${Score}" "%s/"`, g.dir.MusicXMLDir())
+	}
+	return fmt.Sprintf(`"${Self}" part --input "${Score}" --part %s --output "%s/${BASENAME}.musicxml"`,
+		shellescape.Quote(g.c.Part),
+		g.dir.MusicXMLDir(),
+	)
+}
+
+func (g Generator) postProcessTask() *execx.Task {
+	return execx.NewTask(
+		"postprocess",
+		fmt.Sprintf(
+			`mv -f "%[1]s/${BASENAME}.wav" "%[1]s/${BASENAME}.raw.wav"
+"${Self}" postprocess \
+  --input "%[1]s/${BASENAME}.raw.wav" \
+  --output "%[1]s/${BASENAME}.wav" \
+  %[2]s`,
+			g.dir.OutputDir(),
+			strings.Join(g.c.PostProcessFlags(), " "),
+		))
+}
+
+func (g Generator) mixTask() *execx.Task {
+	return execx.NewTask(
+		"mix",
+		fmt.Sprintf(
+			`"${Self}" mix \
+  --input "%[1]s/${BASENAME}.wav" \
+  --accompaniment "${Accompaniment}" \
+  --output "%[1]s/%[3]s" \
+  %[2]s`,
+			g.dir.OutputDir(),
+			strings.Join(g.c.MixFlags(), " "),
+			pathx.MixFileName("${BASENAME}"),
+		))
+}
+
+func (g Generator) transcodeTask() *execx.Task {
+	return execx.NewTask(
+		"transcode",
+		fmt.Sprintf(
+			`"${Self}" transcode \
+  --input "%s/${BASENAME}.wav" \
+  --formats %s`,
+			g.dir.OutputDir(),
+			shellescape.Quote(g.c.Formats),
+		))
 }
 
 func (g Generator) ExecutableTasks() (*execx.ExecutableTasks, error) {
 	if strings.Contains(g.c.NeutrinoVersion, "v3.") {
-		return g.executableTasksV3(), nil
+		return g.executableTasksV3()
 	}
 	return nil, fmt.Errorf("failed to generate executable tasks")
 }
@@ -60,3 +60,12 @@ func (d Dir) ResultDestDir() string {
 		pathx.NewResultElement("${BASENAME}", d.now, d.now.Unix(), int(d.salt())).String(),
 	)
 }
+
+// LocalResultDestDir returns ResultDestDir of the basename without the variables of the script.
+func (d Dir) LocalResultDestDir(basename string) string {
+	return d.join(
+		d.workDir,
+		"result",
+		pathx.NewResultElement(basename, d.now, d.now.Unix(), int(d.salt())).String(),
+	)
+}
@@ -0,0 +1,57 @@
+package task
+
+import "fmt"
+
+// HookEntrypoint wraps the entrypoint to call the hook at the lifecycle events.
+// The first line of the entrypoint should be the shell options, the rest should be the stages.
+//
+// The hook is called at start, before and after each stage, on success and on failure.
+// A failure of the hook at start or before a stage stops the pipeline.
+// The config is passed to the hook command via HookConfig because the entrypoint is used as a format.
+func (g Generator) HookEntrypoint(entrypoint []string) []string {
+	if g.hook == "" || len(entrypoint) == 0 {
+		return entrypoint
+	}
+	r := []string{
+		entrypoint[0],
+		fmt.Sprintf(`pneutrinoutil_hook_state="$(mktemp -d)"
+pneutrinoutil_hook() {
+  "${Self}" hook \
+    --hook "${Hook}" \
+    --events "${HookEvents}" \
+    --state "${pneutrinoutil_hook_state}" \
+    --resultDir "${ResultDestDir}" \
+    --outputDir %s \
+    --basename "${BASENAME}" \
+    --event "$1" \
+    --stage "$2" \
+    --status "$3"
+}
+pneutrinoutil_hook_exit() {
+  pneutrinoutil_status=$?
+  set +e
+  if [ "$pneutrinoutil_status" -ne 0 ] ; then
+    pneutrinoutil_hook failure "$pneutrinoutil_stage" "$pneutrinoutil_status"
+  fi
+  rm -rf "${pneutrinoutil_hook_state}"
+  exit "$pneutrinoutil_status"
+}
+trap pneutrinoutil_hook_exit EXIT
+pneutrinoutil_stage=""
+pneutrinoutil_hook start "" 0`,
+			g.dir.OutputDir(),
+		),
+	}
+	for _, x := range entrypoint[1:] {
+		r = append(r,
+			fmt.Sprintf("pneutrinoutil_stage=%s", x),
+			fmt.Sprintf("pneutrinoutil_hook preStage %s 0", x),
+			x,
+			fmt.Sprintf("pneutrinoutil_hook postStage %s 0", x),
+		)
+	}
+	return append(r,
+		`pneutrinoutil_stage=""`,
+		`pneutrinoutil_hook success "" 0`,
+	)
+}
@@ -0,0 +1,86 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/berquerant/execx"
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+)
+
+var ErrStage = errors.New("Stage")
+
+// insertStages inserts the user-defined stages into the tasks in order.
+// A stage can be positioned relative to the built-in stages and the preceding user-defined stages.
+func (g Generator) insertStages(tasks execx.Tasks) (execx.Tasks, error) {
+	for _, s := range g.c.Stages {
+		index := func(name string) int {
+			return slices.IndexFunc(tasks, func(t *execx.Task) bool { return t.Name == name })
+		}
+		if index(s.Name) >= 0 {
+			return nil, fmt.Errorf("%w: %s: duplicated name", ErrStage, s.Name)
+		}
+		t := execx.NewTask(s.Name, stageScript(s))
+		switch {
+		case s.Before != "":
+			i := index(s.Before)
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %s: before %s not found", ErrStage, s.Name, s.Before)
+			}
+			tasks = slices.Insert(tasks, i, t)
+		case s.After != "":
+			i := index(s.After)
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %s: after %s not found", ErrStage, s.Name, s.After)
+			}
+			tasks = slices.Insert(tasks, i+1, t)
+		default:
+			tasks = tasks.Add(t)
+		}
+	}
+	return tasks, nil
+}
+
+// pathQuoter escapes the characters special in double quotes except $.
+var pathQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+
+// quotePath double-quotes the path of the inputs and the outputs of the stage
+// to expand the variables like ${BASENAME} while keeping the spaces.
+func quotePath(s string) string {
+	return `"` + pathQuoter.Replace(s) + `"`
+}
+
+// stageScript returns the body of the task of the stage.
+// The stage runs in a subshell not to leak the changes of the directory and the variables.
+func stageScript(s *ctl.Stage) string {
+	var b strings.Builder
+	b.WriteString("(\nset -e\n")
+	for _, x := range s.Inputs {
+		fmt.Fprintf(&b, "if [ ! -e %[1]s ] ; then echo %[2]s %[1]s >&2 ; exit 1 ; fi\n",
+			quotePath(x), shellescape.Quote("stage "+s.Name+": input not found:"))
+	}
+	b.WriteString(s.Command)
+	b.WriteString("\n")
+	for _, x := range s.Outputs {
+		fmt.Fprintf(&b, "if [ ! -e %[1]s ] ; then echo %[2]s %[1]s >&2 ; exit 1 ; fi\n",
+			quotePath(x), shellescape.Quote("stage "+s.Name+": output not found:"))
+	}
+	b.WriteString(")")
+	if s.Fatal {
+		return b.String()
+	}
+	// errexit is ignored in the subshell if the subshell is a condition
+	return fmt.Sprintf(`set +e
+%s
+pneutrinoutil_status=$?
+set -e
+if [ "$pneutrinoutil_status" -ne 0 ] ; then
+  echo %s "$pneutrinoutil_status" >&2
+fi`,
+		b.String(),
+		shellescape.Quote("stage "+s.Name+" failed and ignored, status:"),
+	)
+}
@@ -0,0 +1,257 @@
+package task
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/berquerant/execx"
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertStages(t *testing.T) {
+	builtin := func() execx.Tasks {
+		return execx.NewTasks().
+			Add(execx.NewTask("init", "")).
+			Add(execx.NewTask("musicXMLToLabel", "")).
+			Add(execx.NewTask("NEUTRINO", ""))
+	}
+	names := func(tasks execx.Tasks) []string {
+		r := make([]string, len(tasks))
+		for i, x := range tasks {
+			r[i] = x.Name
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		title  string
+		stages []*ctl.Stage
+		want   []string
+		err    error
+	}{
+		{
+			title: "no stages",
+			want:  []string{"init", "musicXMLToLabel", "NEUTRINO"},
+		},
+		{
+			title: "append",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true"},
+				{Name: "b", Command: "true"},
+			},
+			want: []string{"init", "musicXMLToLabel", "NEUTRINO", "a", "b"},
+		},
+		{
+			title: "before",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", Before: "musicXMLToLabel"},
+				{Name: "b", Command: "true", Before: "init"},
+			},
+			want: []string{"b", "init", "a", "musicXMLToLabel", "NEUTRINO"},
+		},
+		{
+			title: "after",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", After: "init"},
+				{Name: "b", Command: "true", After: "NEUTRINO"},
+			},
+			want: []string{"init", "a", "musicXMLToLabel", "NEUTRINO", "b"},
+		},
+		{
+			title: "relative to the preceding stage",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", After: "init"},
+				{Name: "b", Command: "true", After: "a"},
+				{Name: "c", Command: "true", Before: "a"},
+				{Name: "d", Command: "true"},
+			},
+			want: []string{"init", "c", "a", "b", "musicXMLToLabel", "NEUTRINO", "d"},
+		},
+		{
+			title: "unknown before",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", Before: "unknown"},
+			},
+			err: ErrStage,
+		},
+		{
+			title: "unknown after",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", After: "unknown"},
+			},
+			err: ErrStage,
+		},
+		{
+			title: "before the following stage",
+			stages: []*ctl.Stage{
+				{Name: "a", Command: "true", Before: "b"},
+				{Name: "b", Command: "true"},
+			},
+			err: ErrStage,
+		},
+		{
+			title: "duplicated with the built-in stage",
+			stages: []*ctl.Stage{
+				{Name: "init", Command: "true"},
+			},
+			err: ErrStage,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			g := Generator{
+				c: &ctl.Config{
+					Stages: tc.stages,
+				},
+			}
+			got, err := g.insertStages(builtin())
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, names(got))
+		})
+	}
+}
+
+func TestStageScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	// run runs the stage in the pipeline like execx, the pipeline continues after the stage unless it fails.
+	run := func(t *testing.T, dir string, s *ctl.Stage) (string, error) {
+		script := "set -e\n" + stageScript(s) + "\necho continued\n"
+		cmd := exec.CommandContext(t.Context(), sh, "-c", script)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "BASENAME=my score")
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	touch := func(t *testing.T, path string) {
+		if !assert.Nil(t, os.WriteFile(path, nil, 0600)) {
+			t.FailNow()
+		}
+	}
+
+	for _, tc := range []struct {
+		title     string
+		stage     *ctl.Stage
+		files     []string // files to be created before the stage
+		wantErr   bool
+		contains  []string
+		exists    []string // files to exist after the stage
+		notExists []string // files not to exist after the stage
+	}{
+		{
+			title: "expand the variables in the inputs and the outputs",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `cp "${BASENAME}.wav" "${BASENAME}.copy.wav"`,
+				Inputs:  []string{"${BASENAME}.wav"},
+				Outputs: []string{"${BASENAME}.copy.wav"},
+				Fatal:   true,
+			},
+			files:    []string{"my score.wav"},
+			contains: []string{"continued"},
+			exists:   []string{"my score.copy.wav"},
+		},
+		{
+			title: "missing input",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `touch ran`,
+				Inputs:  []string{"${BASENAME}.wav"},
+				Fatal:   true,
+			},
+			wantErr:  true,
+			contains: []string{"stage s: input not found: my score.wav"},
+		},
+		{
+			title: "missing output",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `touch ran`,
+				Outputs: []string{"${BASENAME}.copy.wav"},
+				Fatal:   true,
+			},
+			wantErr:  true,
+			contains: []string{"stage s: output not found: my score.copy.wav"},
+			exists:   []string{"ran"},
+		},
+		{
+			title: "special characters in the path",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `true`,
+				Inputs:  []string{"a\"b`c\\d"},
+				Fatal:   true,
+			},
+			files:    []string{"a\"b`c\\d"},
+			contains: []string{"continued"},
+		},
+		{
+			title: "fatal",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `exit 3`,
+				Fatal:   true,
+			},
+			wantErr: true,
+		},
+		{
+			title: "not fatal",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: "false\ntouch ran",
+			},
+			contains: []string{
+				"stage s failed and ignored, status: 1",
+				"continued",
+			},
+			// the command stops at the first failure
+			notExists: []string{"ran"},
+		},
+		{
+			title: "not fatal missing output",
+			stage: &ctl.Stage{
+				Name:    "s",
+				Command: `true`,
+				Outputs: []string{"${BASENAME}.copy.wav"},
+			},
+			contains: []string{
+				"stage s: output not found: my score.copy.wav",
+				"stage s failed and ignored, status: 1",
+				"continued",
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, x := range tc.files {
+				touch(t, filepath.Join(dir, x))
+			}
+			got, err := run(t, dir, tc.stage)
+			if tc.wantErr {
+				assert.NotNil(t, err, got)
+				assert.NotContains(t, got, "continued")
+			} else {
+				assert.Nil(t, err, got)
+			}
+			for _, x := range tc.contains {
+				assert.Contains(t, got, x)
+			}
+			for _, x := range tc.exists {
+				assert.FileExists(t, filepath.Join(dir, x))
+			}
+			for _, x := range tc.notExists {
+				assert.NoFileExists(t, filepath.Join(dir, x))
+			}
+		})
+	}
+}
@@ -2,20 +2,13 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
-	"mime/multipart"
-	"net/http"
 	"os"
 	"os/signal"
-	"slices"
 	"strings"
-	"time"
 
+	"github.com/berquerant/pneutrinoutil/pkg/client"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
 	"github.com/berquerant/pneutrinoutil/pkg/version"
 	"github.com/spf13/cobra"
@@ -52,11 +45,17 @@ var rootCmd = &cobra.Command{
 		var (
 			ids     = map[string]string{}
 			url, _  = cmd.Flags().GetString("server")
+			c       = client.New(url)
 			scanner = bufio.NewScanner(os.Stdin)
 		)
 		for scanner.Scan() {
 			basename := scanner.Text()
-			rid, err := post(ctx, url, basename, content)
+			rid, err := c.Start(ctx, &client.StartRequest{
+				Score: &client.File{
+					Name:    basename + ".musicxml",
+					Content: strings.NewReader(content),
+				},
+			})
 			if err != nil {
 				return err
 			}
@@ -69,7 +68,7 @@ var rootCmd = &cobra.Command{
 			attr := []any{"basename", basename, "rid", rid}
 			logger.Info("wait", attr...)
 			eg.Go(func() error {
-				if err := wait(ctx, url, rid); err != nil {
+				if _, err := c.Wait(ctx, rid); err != nil {
 					logger.Error("wait", append(attr, "err", err.Error())...)
 					return err
 				}
@@ -92,77 +91,6 @@ func readContent(filenameOrContent string) (string, error) {
 	return string(b), nil
 }
 
-func wait(ctx context.Context, url, rid string) error {
-	type responseData struct {
-		OK   bool `json:"ok"`
-		Data struct {
-			Status string `json:"status"`
-		} `json:"data"`
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/proc/"+rid+"/detail", nil)
-			if err != nil {
-				return err
-			}
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				continue
-			}
-			b, err := io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-			if err != nil {
-				continue
-			}
-			var data responseData
-			if err := json.Unmarshal(b, &data); err != nil {
-				continue
-			}
-			if !data.OK {
-				continue
-			}
-			if slices.Contains([]string{"succeed", "failed"}, data.Data.Status) {
-				return nil
-			}
-			time.Sleep(time.Millisecond * 300)
-		}
-	}
-}
-
-func post(ctx context.Context, url, basename, content string) (string, error) {
-	var body bytes.Buffer
-	w := multipart.NewWriter(&body)
-	fw, err := w.CreateFormFile("score", basename+".musicxml")
-	if err != nil {
-		return "", err
-	}
-	if _, err := fmt.Fprint(fw, content); err != nil {
-		return "", err
-	}
-	if err := w.Close(); err != nil {
-		return "", err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/proc", &body)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("content-type", w.FormDataContentType())
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	if status := resp.StatusCode; status != http.StatusAccepted {
-		return "", fmt.Errorf("status: want %d got %d", http.StatusAccepted, status)
-	}
-	return resp.Header.Get("x-request-id"), nil
-}
-
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
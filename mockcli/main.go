@@ -1,14 +1,17 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"time"
 
 	cli "github.com/berquerant/pneutrinoutil/cli/cmd"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
 	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/goccy/go-yaml"
@@ -110,18 +113,78 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
-		// create empty wav files
+		// create a short sine wav file
 		wavPath := filepath.Join(resultDir, c.Basename()+".wav")
-		wav, err := create("wav", wavPath)
-		if err != nil {
+		logger.Info("create wav", slog.String("path", wavPath))
+		wav := newMockWav()
+		if err := audio.WriteFile(wavPath, wav); err != nil {
 			return err
 		}
-		_ = wav.Close()
 
-		return nil
+		// mix the wav with the accompaniment like the mix stage
+		if c.Accompaniment != "" {
+			accompaniment, err := audio.ReadFile(c.Accompaniment)
+			if err != nil {
+				return err
+			}
+			tracks, err := c.MixTracks(wav, accompaniment)
+			if err != nil {
+				return err
+			}
+			mix, err := audio.Mix(tracks...)
+			if err != nil {
+				return err
+			}
+			mixPath := filepath.Join(resultDir, pathx.MixFileName(c.Basename()))
+			logger.Info("create mix", slog.String("path", mixPath))
+			if err := audio.WriteFile(mixPath, mix); err != nil {
+				return err
+			}
+		}
+
+		// create the f0 and the timing label of the wav
+		f0Path := filepath.Join(resultDir, c.Basename()+".f0")
+		logger.Info("create f0", slog.String("path", f0Path))
+		if err := os.WriteFile(f0Path, newMockF0(), 0644); err != nil {
+			return err
+		}
+		labelPath := filepath.Join(resultDir, pathx.TimingLabelFileName(c.Basename()))
+		logger.Info("create timing label", slog.String("path", labelPath))
+		return os.WriteFile(labelPath, []byte(mockTimingLabel), 0644)
 	},
 }
 
+// mockTimingLabel is the timing label of the mock wav, a note of 0.8 seconds after 0.2 seconds of the pause.
+const mockTimingLabel = `0 2000000 pau
+2000000 10000000 a
+`
+
+// newMockF0 returns the f0 of the mock wav, 1 second of 5ms frames.
+func newMockF0() []byte {
+	const frames = 200
+	b := make([]byte, frames*8)
+	for i := range frames {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(440))
+	}
+	return b
+}
+
+func newMockWav() *audio.Wav {
+	const (
+		sampleRate = 48000
+		frequency  = 440
+	)
+	w := audio.NewWav(audio.Format{
+		SampleRate: sampleRate,
+		BitDepth:   16,
+		Channels:   1,
+	}, sampleRate)
+	for i := range w.Data[0] {
+		w.Data[0][i] = 0.5 * math.Sin(2*math.Pi*frequency*float64(i)/sampleRate)
+	}
+	return w
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
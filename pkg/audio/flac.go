@@ -0,0 +1,404 @@
+package audio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// FLAC encoder with fixed predictors and rice coded residuals.
+// See RFC 9639.
+
+const (
+	flacBlockSize          = 4096
+	flacMaxFixedOrder      = 4
+	flacMaxPartitionOrder  = 8
+	flacMaxRiceParameter   = 30 // 31 is the escape code of the 5 bit parameter
+	flacMetadataStreamInfo = 0
+)
+
+var flacMarker = []byte("fLaC")
+
+// EncodeFLAC writes this as a FLAC stream.
+// Float samples are not supported, convert the format into integer PCM first.
+func (w Wav) EncodeFLAC(wr io.Writer) error {
+	f := w.Format
+	if f.Float {
+		return fmt.Errorf("%w: flac from float samples", ErrUnsupportedDepth)
+	}
+	switch f.BitDepth {
+	case 8, 16, 24:
+	default:
+		return fmt.Errorf("%w: flac %d bits", ErrUnsupportedDepth, f.BitDepth)
+	}
+	if f.Channels < 1 || f.Channels > 8 || f.Channels != len(w.Data) {
+		return fmt.Errorf("%w: flac %d channels", ErrUnsupportedWav, f.Channels)
+	}
+	if f.SampleRate < 1 || f.SampleRate >= 1<<20 {
+		return fmt.Errorf("%w: flac sample rate %d", ErrUnsupportedWav, f.SampleRate)
+	}
+
+	var (
+		frames  = w.Frames()
+		samples = make([][]int64, f.Channels)
+		hash    = md5.New()
+		width   = f.BitDepth / 8
+		sample  = make([]byte, 8)
+	)
+	for ch, x := range w.Data {
+		samples[ch] = make([]int64, frames)
+		for i, v := range x {
+			samples[ch][i] = quantize(clip(v), f.BitDepth)
+		}
+	}
+	// MD5 of the interleaved little endian samples
+	for i := range frames {
+		for ch := range f.Channels {
+			binary.LittleEndian.PutUint64(sample, uint64(samples[ch][i]))
+			hash.Write(sample[:width])
+		}
+	}
+
+	var (
+		body                   bytes.Buffer
+		minFrameSize           = math.MaxInt
+		maxFrameSize           = 0
+		minBlockSize, maxBlock = flacBlockSize, flacBlockSize
+	)
+	for n, start := 0, 0; start < frames; n, start = n+1, start+flacBlockSize {
+		end := min(start+flacBlockSize, frames)
+		block := make([][]int64, f.Channels)
+		for ch := range f.Channels {
+			block[ch] = samples[ch][start:end]
+		}
+		b := encodeFLACFrame(block, f.BitDepth, uint64(n))
+		minFrameSize = min(minFrameSize, len(b))
+		maxFrameSize = max(maxFrameSize, len(b))
+		body.Write(b)
+	}
+	if frames < flacBlockSize {
+		// the last block may be shorter than the minimum block size
+		minBlockSize, maxBlock = max(frames, 16), max(frames, 16)
+	}
+	if maxFrameSize == 0 {
+		minFrameSize = 0
+	}
+
+	var info flacBitWriter
+	info.write(uint64(minBlockSize), 16)
+	info.write(uint64(maxBlock), 16)
+	info.write(uint64(minFrameSize), 24)
+	info.write(uint64(maxFrameSize), 24)
+	info.write(uint64(f.SampleRate), 20)
+	info.write(uint64(f.Channels-1), 3)
+	info.write(uint64(f.BitDepth-1), 5)
+	info.write(uint64(frames), 36)
+	info.buf = hash.Sum(info.buf)
+
+	var head bytes.Buffer
+	head.Write(flacMarker)
+	// last metadata block flag and block type
+	head.WriteByte(0x80 | flacMetadataStreamInfo)
+	head.Write([]byte{0, 0, byte(len(info.buf))})
+	head.Write(info.buf)
+
+	if _, err := wr.Write(head.Bytes()); err != nil {
+		return err
+	}
+	_, err := wr.Write(body.Bytes())
+	return err
+}
+
+// encodeFLACFrame encodes a block of samples with independent channels.
+func encodeFLACFrame(block [][]int64, bitDepth int, number uint64) []byte {
+	var (
+		w         flacBitWriter
+		blockSize = len(block[0])
+	)
+	// header
+	w.write(0b11111111111110, 14)
+	w.write(0, 1) // reserved
+	w.write(0, 1) // fixed block size stream
+	w.write(7, 4) // 16 bit block size - 1 at the end of the header
+	w.write(0, 4) // sample rate from STREAMINFO
+	w.write(uint64(len(block)-1), 4)
+	w.write(flacSampleSizeCode(bitDepth), 3)
+	w.write(0, 1) // reserved
+	w.writeUTF8(number)
+	w.write(uint64(blockSize-1), 16)
+	w.write(uint64(flacCRC8(w.buf)), 8)
+
+	for _, x := range block {
+		encodeFLACSubframe(&w, x, bitDepth)
+	}
+
+	w.align()
+	w.write(uint64(flacCRC16(w.buf)), 16)
+	return w.buf
+}
+
+func flacSampleSizeCode(bitDepth int) uint64 {
+	switch bitDepth {
+	case 8:
+		return 1
+	case 12:
+		return 2
+	case 16:
+		return 4
+	case 20:
+		return 5
+	case 24:
+		return 6
+	case 32:
+		return 7
+	default:
+		return 0 // from STREAMINFO
+	}
+}
+
+func encodeFLACSubframe(w *flacBitWriter, x []int64, bitDepth int) {
+	constant := true
+	for _, v := range x[1:] {
+		if v != x[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		w.write(0, 1)
+		w.write(0b000000, 6)
+		w.write(0, 1) // no wasted bits
+		w.writeSigned(x[0], bitDepth)
+		return
+	}
+
+	var (
+		best         *flacResidual
+		bestOrder    int
+		verbatimBits = len(x) * bitDepth
+	)
+	for order := 0; order <= min(flacMaxFixedOrder, len(x)-1); order++ {
+		r := newFLACResidual(fixedResidual(x, order), len(x), order)
+		if best == nil || r.bits+order*bitDepth < best.bits+bestOrder*bitDepth {
+			best = r
+			bestOrder = order
+		}
+	}
+	if best == nil || best.bits+bestOrder*bitDepth >= verbatimBits {
+		w.write(0, 1)
+		w.write(0b000001, 6)
+		w.write(0, 1)
+		for _, v := range x {
+			w.writeSigned(v, bitDepth)
+		}
+		return
+	}
+
+	w.write(0, 1)
+	w.write(uint64(0b001000|bestOrder), 6)
+	w.write(0, 1)
+	for _, v := range x[:bestOrder] {
+		w.writeSigned(v, bitDepth)
+	}
+	best.write(w)
+}
+
+// fixedResidual returns the residuals of the fixed polynomial predictor of the order.
+func fixedResidual(x []int64, order int) []int64 {
+	r := make([]int64, len(x)-order)
+	for i := order; i < len(x); i++ {
+		var p int64
+		switch order {
+		case 1:
+			p = x[i-1]
+		case 2:
+			p = 2*x[i-1] - x[i-2]
+		case 3:
+			p = 3*x[i-1] - 3*x[i-2] + x[i-3]
+		case 4:
+			p = 4*x[i-1] - 6*x[i-2] + 4*x[i-3] - x[i-4]
+		}
+		r[i-order] = x[i] - p
+	}
+	return r
+}
+
+// flacResidual is a rice coded residual with the best partition order.
+type flacResidual struct {
+	values         []uint64 // zigzag encoded
+	order          int      // predictor order
+	partitionOrder int
+	parameters     []int
+	bits           int
+}
+
+func newFLACResidual(residual []int64, blockSize, order int) *flacResidual {
+	values := make([]uint64, len(residual))
+	for i, v := range residual {
+		values[i] = uint64((v << 1) ^ (v >> 63))
+	}
+
+	var best *flacResidual
+	for p := 0; p <= flacMaxPartitionOrder; p++ {
+		if blockSize%(1<<p) != 0 || blockSize>>p <= order {
+			break
+		}
+		var (
+			partitions = 1 << p
+			parameters = make([]int, partitions)
+			total      = 2 + 4 // coding method and partition order
+			start      = 0
+		)
+		for i := range partitions {
+			n := blockSize >> p
+			if i == 0 {
+				n -= order
+			}
+			var sum uint64
+			for _, v := range values[start : start+n] {
+				sum += v
+			}
+			k := riceParameter(sum, n)
+			parameters[i] = k
+			total += 5 + n*(k+1) + int(riceQuotientSum(values[start:start+n], k))
+			start += n
+		}
+		if best == nil || total < best.bits {
+			best = &flacResidual{
+				values:         values,
+				order:          order,
+				partitionOrder: p,
+				parameters:     parameters,
+				bits:           total,
+			}
+		}
+	}
+	return best
+}
+
+// riceParameter estimates the optimal rice parameter from the mean of the values.
+func riceParameter(sum uint64, n int) int {
+	if n == 0 || sum < uint64(n) {
+		return 0
+	}
+	return min(bits.Len64(sum/uint64(n))-1, flacMaxRiceParameter)
+}
+
+func riceQuotientSum(values []uint64, k int) uint64 {
+	var s uint64
+	for _, v := range values {
+		s += v >> k
+	}
+	return s
+}
+
+func (r *flacResidual) write(w *flacBitWriter) {
+	w.write(1, 2) // 5 bit rice parameters
+	w.write(uint64(r.partitionOrder), 4)
+	var (
+		size  = (len(r.values) + r.order) >> r.partitionOrder
+		start = 0
+	)
+	for i, k := range r.parameters {
+		n := size
+		if i == 0 {
+			// the first partition excludes the warm-up samples
+			n -= r.order
+		}
+		w.write(uint64(k), 5)
+		for _, v := range r.values[start : start+n] {
+			w.writeUnary(v >> k)
+			w.write(v&(1<<k-1), k)
+		}
+		start += n
+	}
+}
+
+type flacBitWriter struct {
+	buf   []byte
+	cur   uint64
+	nbits int
+}
+
+// write writes the lowest n bits of v.
+func (w *flacBitWriter) write(v uint64, n int) {
+	for n > 0 {
+		m := min(n, 56-w.nbits)
+		w.cur = w.cur<<m | (v>>(n-m))&(1<<m-1)
+		w.nbits += m
+		n -= m
+		for w.nbits >= 8 {
+			w.nbits -= 8
+			w.buf = append(w.buf, byte(w.cur>>w.nbits))
+		}
+		w.cur &= 1<<w.nbits - 1
+	}
+}
+
+func (w *flacBitWriter) writeSigned(v int64, n int) {
+	w.write(uint64(v)&(1<<n-1), n)
+}
+
+func (w *flacBitWriter) writeUnary(q uint64) {
+	for q >= 32 {
+		w.write(0, 32)
+		q -= 32
+	}
+	w.write(1, int(q)+1)
+}
+
+// writeUTF8 writes v in the UTF-8 like coding of the frame number.
+func (w *flacBitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		w.write(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	w.write((0xff<<(8-n))&0xff|v>>(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		w.write(0x80|(v>>(6*i))&0x3f, 8)
+	}
+}
+
+func (w *flacBitWriter) align() {
+	if w.nbits > 0 {
+		w.write(0, 8-w.nbits)
+	}
+}
+
+func flacCRC8(b []byte) byte {
+	var crc byte
+	for _, x := range b {
+		crc ^= x
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func flacCRC16(b []byte) uint16 {
+	var crc uint16
+	for _, x := range b {
+		crc ^= uint16(x) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
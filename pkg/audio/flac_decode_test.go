@@ -0,0 +1,420 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidFLAC = errors.New("InvalidFLAC")
+
+// DecodeFLAC reads a FLAC stream to test the encoder.
+// Bit depths other than multiples of 8 are widened to the next multiple of 8.
+func DecodeFLAC(r io.Reader) (*Wav, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidFLAC, err)
+	}
+	if !bytes.HasPrefix(b, flacMarker) {
+		return nil, fmt.Errorf("%w: missing marker", ErrInvalidFLAC)
+	}
+	br := &flacBitReader{buf: b, pos: len(flacMarker) * 8}
+
+	var (
+		info *flacStreamInfo
+		last bool
+	)
+	for !last {
+		last = br.read(1) == 1
+		typ := br.read(7)
+		size := int(br.read(24))
+		if br.err != nil {
+			return nil, errors.Join(ErrInvalidFLAC, br.err)
+		}
+		if typ == flacMetadataStreamInfo {
+			info = &flacStreamInfo{}
+			br.read(16) // min block size
+			br.read(16) // max block size
+			br.read(24) // min frame size
+			br.read(24) // max frame size
+			info.sampleRate = int(br.read(20))
+			info.channels = int(br.read(3)) + 1
+			info.bitDepth = int(br.read(5)) + 1
+			info.frames = int(br.read(36))
+			br.skip(128) // md5
+			continue
+		}
+		br.skip(size * 8)
+	}
+	if br.err != nil {
+		return nil, errors.Join(ErrInvalidFLAC, br.err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("%w: missing STREAMINFO", ErrInvalidFLAC)
+	}
+
+	data := make([][]int64, info.channels)
+	for ch := range data {
+		data[ch] = make([]int64, 0, info.frames)
+	}
+	for br.remaining() > 0 {
+		block, err := decodeFLACFrame(br, info)
+		if err != nil {
+			return nil, err
+		}
+		for ch, x := range block {
+			data[ch] = append(data[ch], x...)
+		}
+	}
+
+	var (
+		bitDepth = (info.bitDepth + 7) / 8 * 8
+		scale    = float64(int64(1) << (info.bitDepth - 1))
+		w        = NewWav(Format{
+			SampleRate: info.sampleRate,
+			BitDepth:   bitDepth,
+			Channels:   info.channels,
+		}, 0)
+	)
+	for ch, x := range data {
+		w.Data[ch] = make([]float64, len(x))
+		for i, v := range x {
+			w.Data[ch][i] = float64(v) / scale
+		}
+	}
+	return w, nil
+}
+
+type flacStreamInfo struct {
+	sampleRate int
+	channels   int
+	bitDepth   int
+	frames     int
+}
+
+const (
+	flacChannelLeftSide  = 8
+	flacChannelSideRight = 9
+	flacChannelMidSide   = 10
+)
+
+func decodeFLACFrame(br *flacBitReader, info *flacStreamInfo) ([][]int64, error) {
+	start := br.pos / 8
+	if br.read(14) != 0b11111111111110 {
+		return nil, fmt.Errorf("%w: missing frame sync", ErrInvalidFLAC)
+	}
+	br.read(1) // reserved
+	br.read(1) // blocking strategy
+	var (
+		blockSizeCode  = br.read(4)
+		sampleRateCode = br.read(4)
+		channelCode    = int(br.read(4))
+		sampleSizeCode = br.read(3)
+	)
+	br.read(1) // reserved
+	br.readUTF8()
+
+	var blockSize int
+	switch {
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		blockSize = int(br.read(8)) + 1
+	case blockSizeCode == 7:
+		blockSize = int(br.read(16)) + 1
+	case blockSizeCode >= 8:
+		blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return nil, fmt.Errorf("%w: reserved block size", ErrInvalidFLAC)
+	}
+	switch sampleRateCode {
+	case 12:
+		br.read(8)
+	case 13, 14:
+		br.read(16)
+	case 15:
+		return nil, fmt.Errorf("%w: invalid sample rate", ErrInvalidFLAC)
+	}
+
+	bitDepth := info.bitDepth
+	switch sampleSizeCode {
+	case 1:
+		bitDepth = 8
+	case 2:
+		bitDepth = 12
+	case 4:
+		bitDepth = 16
+	case 5:
+		bitDepth = 20
+	case 6:
+		bitDepth = 24
+	case 7:
+		bitDepth = 32
+	}
+
+	if br.err != nil {
+		return nil, errors.Join(ErrInvalidFLAC, br.err)
+	}
+	if crc := byte(br.read(8)); crc != flacCRC8(br.buf[start:br.pos/8-1]) {
+		return nil, fmt.Errorf("%w: header crc mismatch", ErrInvalidFLAC)
+	}
+
+	channels := channelCode + 1
+	if channelCode >= flacChannelLeftSide {
+		if channelCode > flacChannelMidSide {
+			return nil, fmt.Errorf("%w: reserved channel assignment", ErrInvalidFLAC)
+		}
+		channels = 2
+	}
+	if channels != info.channels {
+		return nil, fmt.Errorf("%w: channels mismatch", ErrInvalidFLAC)
+	}
+
+	block := make([][]int64, channels)
+	for ch := range block {
+		depth := bitDepth
+		// the side channel has an extra bit
+		if (channelCode == flacChannelLeftSide || channelCode == flacChannelMidSide) && ch == 1 ||
+			channelCode == flacChannelSideRight && ch == 0 {
+			depth++
+		}
+		x, err := decodeFLACSubframe(br, blockSize, depth)
+		if err != nil {
+			return nil, err
+		}
+		block[ch] = x
+	}
+
+	switch channelCode {
+	case flacChannelLeftSide:
+		for i, side := range block[1] {
+			block[1][i] = block[0][i] - side
+		}
+	case flacChannelSideRight:
+		for i, side := range block[0] {
+			block[0][i] = side + block[1][i]
+		}
+	case flacChannelMidSide:
+		for i := range block[0] {
+			var (
+				mid  = block[0][i]<<1 | block[1][i]&1
+				side = block[1][i]
+			)
+			block[0][i] = (mid + side) >> 1
+			block[1][i] = (mid - side) >> 1
+		}
+	}
+
+	br.align()
+	end := br.pos / 8
+	if crc := uint16(br.read(16)); crc != flacCRC16(br.buf[start:end]) {
+		return nil, fmt.Errorf("%w: frame crc mismatch", ErrInvalidFLAC)
+	}
+	if br.err != nil {
+		return nil, errors.Join(ErrInvalidFLAC, br.err)
+	}
+	return block, nil
+}
+
+func decodeFLACSubframe(br *flacBitReader, blockSize, bitDepth int) ([]int64, error) {
+	br.read(1) // padding
+	typ := int(br.read(6))
+	var wasted int
+	if br.read(1) == 1 {
+		wasted = int(br.readUnary()) + 1
+		bitDepth -= wasted
+	}
+
+	x := make([]int64, blockSize)
+	switch {
+	case typ == 0b000000:
+		v := br.readSigned(bitDepth)
+		for i := range x {
+			x[i] = v
+		}
+	case typ == 0b000001:
+		for i := range x {
+			x[i] = br.readSigned(bitDepth)
+		}
+	case typ >= 0b001000 && typ <= 0b001100:
+		order := typ & 0b111
+		for i := range order {
+			x[i] = br.readSigned(bitDepth)
+		}
+		if err := decodeFLACResidual(br, x, order); err != nil {
+			return nil, err
+		}
+		for i := order; i < blockSize; i++ {
+			switch order {
+			case 1:
+				x[i] += x[i-1]
+			case 2:
+				x[i] += 2*x[i-1] - x[i-2]
+			case 3:
+				x[i] += 3*x[i-1] - 3*x[i-2] + x[i-3]
+			case 4:
+				x[i] += 4*x[i-1] - 6*x[i-2] + 4*x[i-3] - x[i-4]
+			}
+		}
+	case typ >= 0b100000:
+		order := typ&0b11111 + 1
+		for i := range order {
+			x[i] = br.readSigned(bitDepth)
+		}
+		precision := int(br.read(4)) + 1
+		if precision == 16 {
+			return nil, fmt.Errorf("%w: invalid lpc precision", ErrInvalidFLAC)
+		}
+		shift := br.readSigned(5)
+		if shift < 0 {
+			return nil, fmt.Errorf("%w: negative lpc shift", ErrInvalidFLAC)
+		}
+		coefs := make([]int64, order)
+		for i := range coefs {
+			coefs[i] = br.readSigned(precision)
+		}
+		if err := decodeFLACResidual(br, x, order); err != nil {
+			return nil, err
+		}
+		for i := order; i < blockSize; i++ {
+			var p int64
+			for j, c := range coefs {
+				p += c * x[i-1-j]
+			}
+			x[i] += p >> shift
+		}
+	default:
+		return nil, fmt.Errorf("%w: reserved subframe type %d", ErrInvalidFLAC, typ)
+	}
+
+	if wasted > 0 {
+		for i := range x {
+			x[i] <<= wasted
+		}
+	}
+	if br.err != nil {
+		return nil, errors.Join(ErrInvalidFLAC, br.err)
+	}
+	return x, nil
+}
+
+// decodeFLACResidual adds the residuals to x after the warm-up samples.
+func decodeFLACResidual(br *flacBitReader, x []int64, order int) error {
+	var paramBits int
+	switch br.read(2) {
+	case 0:
+		paramBits = 4
+	case 1:
+		paramBits = 5
+	default:
+		return fmt.Errorf("%w: reserved residual coding method", ErrInvalidFLAC)
+	}
+	var (
+		partitionOrder = int(br.read(4))
+		partitions     = 1 << partitionOrder
+		size           = len(x) >> partitionOrder
+		escape         = uint64(1)<<paramBits - 1
+		i              = order
+	)
+	if size<<partitionOrder != len(x) || size < order {
+		return fmt.Errorf("%w: invalid partition order", ErrInvalidFLAC)
+	}
+	for p := range partitions {
+		n := size
+		if p == 0 {
+			n -= order
+		}
+		k := br.read(paramBits)
+		if k == escape {
+			width := int(br.read(5))
+			for range n {
+				x[i] = br.readSigned(width)
+				i++
+			}
+			continue
+		}
+		for range n {
+			u := br.readUnary()<<k | br.read(int(k))
+			x[i] = int64(u>>1) ^ -int64(u&1)
+			i++
+		}
+	}
+	return br.err
+}
+
+type flacBitReader struct {
+	buf []byte
+	pos int // in bits
+	err error
+}
+
+func (r *flacBitReader) remaining() int {
+	return len(r.buf)*8 - r.pos
+}
+
+func (r *flacBitReader) read(n int) uint64 {
+	if n == 0 || r.err != nil {
+		return 0
+	}
+	if r.remaining() < n {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	var v uint64
+	for n > 0 {
+		var (
+			offset = r.pos % 8
+			m      = min(n, 8-offset)
+			b      = uint64(r.buf[r.pos/8]>>(8-offset-m)) & (1<<m - 1)
+		)
+		v = v<<m | b
+		r.pos += m
+		n -= m
+	}
+	return v
+}
+
+func (r *flacBitReader) readSigned(n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	v := r.read(n)
+	return int64(v<<(64-n)) >> (64 - n)
+}
+
+func (r *flacBitReader) readUnary() uint64 {
+	var q uint64
+	for r.err == nil && r.read(1) == 0 {
+		q++
+	}
+	return q
+}
+
+func (r *flacBitReader) readUTF8() {
+	first := r.read(8)
+	for mask := uint64(0x80); first&mask != 0 && mask > 1; mask >>= 1 {
+		if mask != 0x80 {
+			r.read(8)
+		}
+	}
+}
+
+func (r *flacBitReader) skip(n int) {
+	if r.err != nil {
+		return
+	}
+	if r.remaining() < n {
+		r.err = io.ErrUnexpectedEOF
+		return
+	}
+	r.pos += n
+}
+
+func (r *flacBitReader) align() {
+	if x := r.pos % 8; x > 0 {
+		r.pos += 8 - x
+	}
+}
@@ -0,0 +1,173 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Codec string
+
+const (
+	CodecWav  Codec = "wav"
+	CodecFLAC Codec = "flac"
+)
+
+// maxFLACBitDepth is the bit depth of FLAC transcoded from float or 32 bit samples.
+const maxFLACBitDepth = 24
+
+var ErrInvalidOutputFormat = errors.New("InvalidOutputFormat")
+
+// OutputFormat is a transcoding target.
+// Zero SampleRate and BitDepth keep the ones of the source.
+type OutputFormat struct {
+	Codec      Codec
+	SampleRate int
+	BitDepth   int
+}
+
+// ParseOutputFormat parses codec[:sampleRate[:bitDepth]], e.g. flac, wav:48000:24, flac::16.
+func ParseOutputFormat(s string) (*OutputFormat, error) {
+	xs := strings.Split(s, ":")
+	if len(xs) > 3 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOutputFormat, s)
+	}
+	f := &OutputFormat{
+		Codec: Codec(xs[0]),
+	}
+	parseInt := func(i int) (int, error) {
+		if len(xs) <= i || xs[i] == "" {
+			return 0, nil
+		}
+		v, err := strconv.Atoi(xs[i])
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", errors.Join(ErrInvalidOutputFormat, err), s)
+		}
+		return v, nil
+	}
+	var err error
+	if f.SampleRate, err = parseInt(1); err != nil {
+		return nil, err
+	}
+	if f.BitDepth, err = parseInt(2); err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, s)
+	}
+	return f, nil
+}
+
+// ParseOutputFormats parses comma separated output formats.
+func ParseOutputFormats(s string) ([]*OutputFormat, error) {
+	var r []*OutputFormat
+	for x := range strings.SplitSeq(s, ",") {
+		if x = strings.TrimSpace(x); x == "" {
+			continue
+		}
+		f, err := ParseOutputFormat(x)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, f)
+	}
+	return r, nil
+}
+
+func (f OutputFormat) validate() error {
+	if f.SampleRate < 0 || f.SampleRate >= 1<<20 {
+		return fmt.Errorf("%w: sample rate %d", ErrInvalidOutputFormat, f.SampleRate)
+	}
+	switch f.Codec {
+	case CodecWav:
+		switch f.BitDepth {
+		case 0, 8, 16, 24, 32:
+			return nil
+		}
+	case CodecFLAC:
+		switch f.BitDepth {
+		case 0, 8, 16, 24:
+			return nil
+		}
+	default:
+		return fmt.Errorf("%w: codec %s", ErrInvalidOutputFormat, f.Codec)
+	}
+	return fmt.Errorf("%w: %s %d bits", ErrInvalidOutputFormat, f.Codec, f.BitDepth)
+}
+
+func (f OutputFormat) String() string {
+	switch {
+	case f.BitDepth != 0:
+		return fmt.Sprintf("%s:%s:%d", f.Codec, f.sampleRateString(), f.BitDepth)
+	case f.SampleRate != 0:
+		return fmt.Sprintf("%s:%d", f.Codec, f.SampleRate)
+	default:
+		return string(f.Codec)
+	}
+}
+
+func (f OutputFormat) sampleRateString() string {
+	if f.SampleRate == 0 {
+		return ""
+	}
+	return strconv.Itoa(f.SampleRate)
+}
+
+// FileName returns the name of the transcoded file, e.g. basename.48000hz.24bit.flac.
+func (f OutputFormat) FileName(basename string) string {
+	xs := []string{basename}
+	if f.SampleRate != 0 {
+		xs = append(xs, fmt.Sprintf("%dhz", f.SampleRate))
+	}
+	if f.BitDepth != 0 {
+		xs = append(xs, fmt.Sprintf("%dbit", f.BitDepth))
+	}
+	return strings.Join(append(xs, string(f.Codec)), ".")
+}
+
+func (f OutputFormat) ContentType() string {
+	switch f.Codec {
+	case CodecFLAC:
+		return "audio/flac"
+	default:
+		return "audio/wav"
+	}
+}
+
+// Transcode returns a copy of w converted into the sample rate and the bit depth.
+func (f OutputFormat) Transcode(w *Wav) *Wav {
+	r := w.Clone()
+	r.Resample(f.SampleRate)
+	switch {
+	case f.BitDepth != 0:
+		r.Format.BitDepth = f.BitDepth
+		r.Format.Float = false
+	case f.Codec == CodecFLAC && (r.Format.Float || r.Format.BitDepth > maxFLACBitDepth):
+		r.Format.BitDepth = maxFLACBitDepth
+		r.Format.Float = false
+	}
+	return r
+}
+
+// Encode transcodes w and writes it in the codec.
+func (f OutputFormat) Encode(wr io.Writer, w *Wav) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	r := f.Transcode(w)
+	switch f.Codec {
+	case CodecFLAC:
+		return r.EncodeFLAC(wr)
+	default:
+		return r.Encode(wr)
+	}
+}
+
+// WriteFile transcodes w and writes it into path.
+func (f OutputFormat) WriteFile(path string, w *Wav) error {
+	return writeFile(path, func(wr io.Writer) error {
+		return f.Encode(wr, w)
+	})
+}
@@ -0,0 +1,274 @@
+package audio_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFLAC(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, tc := range []struct {
+		title  string
+		format audio.Format
+		frames int
+		sample func(ch, i int) float64
+	}{
+		{
+			title:  "sine",
+			format: audio.Format{SampleRate: 48000, BitDepth: 16, Channels: 1},
+			frames: 10000,
+			sample: func(_, i int) float64 { return 0.5 * math.Sin(2*math.Pi*440*float64(i)/48000) },
+		},
+		{
+			title:  "noise",
+			format: audio.Format{SampleRate: 44100, BitDepth: 24, Channels: 2},
+			frames: 5000,
+			sample: func(_, _ int) float64 { return rng.Float64()*2 - 1 },
+		},
+		{
+			title:  "silence and short",
+			format: audio.Format{SampleRate: 8000, BitDepth: 8, Channels: 2},
+			frames: 7,
+			sample: func(ch, _ int) float64 { return float64(ch) * 0.25 },
+		},
+		{
+			title:  "exact blocks",
+			format: audio.Format{SampleRate: 22050, BitDepth: 16, Channels: 3},
+			frames: 4096 * 2,
+			sample: func(ch, i int) float64 { return float64(i%(100*(ch+1)))/400 - 0.25 },
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			w := audio.NewWav(tc.format, tc.frames)
+			for ch := range w.Data {
+				for i := range w.Data[ch] {
+					w.Data[ch][i] = tc.sample(ch, i)
+				}
+			}
+			var buf bytes.Buffer
+			if !assert.Nil(t, w.EncodeFLAC(&buf)) {
+				return
+			}
+			got, err := audio.DecodeFLAC(&buf)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.format, got.Format)
+			assert.Equal(t, tc.frames, got.Frames())
+			delta := 1 / float64(int(1)<<(tc.format.BitDepth-1))
+			for ch := range w.Data {
+				assert.InDeltaSlice(t, w.Data[ch], got.Data[ch], delta)
+			}
+		})
+	}
+
+	t.Run("compressed", func(t *testing.T) {
+		w := newSine(48000, 1, 440, 0.5)
+		var flac, wav bytes.Buffer
+		assert.Nil(t, w.EncodeFLAC(&flac))
+		assert.Nil(t, w.Encode(&wav))
+		assert.Less(t, flac.Len(), wav.Len()/2)
+	})
+
+	t.Run("float", func(t *testing.T) {
+		w := audio.NewWav(audio.Format{SampleRate: 48000, BitDepth: 32, Channels: 1, Float: true}, 10)
+		assert.ErrorIs(t, w.EncodeFLAC(&bytes.Buffer{}), audio.ErrUnsupportedDepth)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := audio.DecodeFLAC(bytes.NewBufferString("not a flac file"))
+		assert.ErrorIs(t, err, audio.ErrInvalidFLAC)
+	})
+}
+
+// flacGoldens are the pairs of testdata/NAME.wav and testdata/NAME.flac encoded from it.
+//
+// The flacs were encoded by EncodeFLAC, not by the reference encoder,
+// so TestFLACGolden only detects the changes of the output (regression).
+// The conformance is checked by TestFLACReference, which decodes them with the reference flac.
+var flacGoldens = []string{"sine16", "stereo24"}
+
+// wavData returns the data chunk of the wav file, the interleaved little endian samples.
+func wavData(t *testing.T, b []byte) []byte {
+	t.Helper()
+	for i := 12; i+8 <= len(b); {
+		size := int(binary.LittleEndian.Uint32(b[i+4:]))
+		if string(b[i:i+4]) == "data" {
+			return b[i+8 : i+8+size]
+		}
+		i += 8 + size + size%2
+	}
+	t.Fatal("missing data chunk")
+	return nil
+}
+
+func TestFLACGolden(t *testing.T) {
+	for _, name := range flacGoldens {
+		t.Run(name, func(t *testing.T) {
+			wavBytes, err := os.ReadFile(filepath.Join("testdata", name+".wav"))
+			if !assert.Nil(t, err) {
+				return
+			}
+			flacBytes, err := os.ReadFile(filepath.Join("testdata", name+".flac"))
+			if !assert.Nil(t, err) {
+				return
+			}
+			w, err := audio.Decode(bytes.NewReader(wavBytes))
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			var buf bytes.Buffer
+			if !assert.Nil(t, w.EncodeFLAC(&buf)) {
+				return
+			}
+			assert.Equal(t, flacBytes, buf.Bytes(), "encoder output changed")
+			// the MD5 in the STREAMINFO, after the marker, the block header and the 18 bytes of the sizes and the format
+			sum := md5.Sum(wavData(t, wavBytes))
+			assert.Equal(t, sum[:], flacBytes[4+4+18:4+4+34])
+
+			got, err := audio.DecodeFLAC(bytes.NewReader(flacBytes))
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, w, got)
+		})
+	}
+}
+
+// TestFLACReference checks the goldens by the reference decoder if installed.
+func TestFLACReference(t *testing.T) {
+	bin, err := exec.LookPath("flac")
+	if err != nil {
+		t.Skip("flac not found")
+	}
+	for _, name := range flacGoldens {
+		t.Run(name, func(t *testing.T) {
+			var (
+				flacPath = filepath.Join("testdata", name+".flac")
+				wavPath  = filepath.Join("testdata", name+".wav")
+			)
+			// verify the CRCs and the MD5 of the samples
+			if out, err := exec.Command(bin, "--test", "--silent", flacPath).CombinedOutput(); !assert.Nil(t, err, "%s", out) {
+				return
+			}
+			decoded, err := exec.Command(bin, "--decode", "--silent", "--stdout", flacPath).Output()
+			if !assert.Nil(t, err) {
+				return
+			}
+			want, err := os.ReadFile(wavPath)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, wavData(t, want), wavData(t, decoded))
+		})
+	}
+}
+
+func TestResample(t *testing.T) {
+	for _, tc := range []struct {
+		from, to int
+	}{
+		{from: 44100, to: 48000},
+		{from: 48000, to: 44100},
+		{from: 48000, to: 16000},
+		{from: 22050, to: 48000},
+	} {
+		t.Run(fmt.Sprintf("%d to %d", tc.from, tc.to), func(t *testing.T) {
+			const frequency = 1000.0
+			w := newSine(tc.from, 0.5, frequency, 0.5)
+			w.Resample(tc.to)
+			assert.Equal(t, tc.to, w.Format.SampleRate)
+			assert.InDelta(t, tc.to/2, w.Frames(), 1)
+			// compare with the ideal sine except the edges
+			for i := tc.to / 10; i < w.Frames()-tc.to/10; i++ {
+				want := 0.5 * math.Sin(2*math.Pi*frequency*float64(i)/float64(tc.to))
+				if !assert.InDelta(t, want, w.Data[0][i], 1e-3, "index %d", i) {
+					return
+				}
+			}
+		})
+	}
+
+	t.Run("anti aliasing", func(t *testing.T) {
+		// 10 kHz is above the Nyquist frequency of 16 kHz
+		w := newSine(48000, 0.5, 10000, 0.5)
+		w.Resample(16000)
+		var peak float64
+		for _, v := range w.Data[0][1600 : w.Frames()-1600] {
+			peak = max(peak, math.Abs(v))
+		}
+		assert.Less(t, peak, 0.01)
+	})
+}
+
+func TestOutputFormat(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		for _, tc := range []struct {
+			input    string
+			want     *audio.OutputFormat
+			fileName string
+			err      bool
+		}{
+			{input: "flac", want: &audio.OutputFormat{Codec: audio.CodecFLAC}, fileName: "x.flac"},
+			{input: "wav:48000:24", want: &audio.OutputFormat{Codec: audio.CodecWav, SampleRate: 48000, BitDepth: 24}, fileName: "x.48000hz.24bit.wav"},
+			{input: "flac::16", want: &audio.OutputFormat{Codec: audio.CodecFLAC, BitDepth: 16}, fileName: "x.16bit.flac"},
+			{input: "wav:44100", want: &audio.OutputFormat{Codec: audio.CodecWav, SampleRate: 44100}, fileName: "x.44100hz.wav"},
+			{input: "mp3", err: true},
+			{input: "flac:48000:32", err: true},
+			{input: "wav:abc", err: true},
+			{input: "wav:1:2:3", err: true},
+		} {
+			t.Run(tc.input, func(t *testing.T) {
+				got, err := audio.ParseOutputFormat(tc.input)
+				if tc.err {
+					assert.ErrorIs(t, err, audio.ErrInvalidOutputFormat)
+					return
+				}
+				if !assert.Nil(t, err) {
+					return
+				}
+				assert.Equal(t, tc.want, got)
+				assert.Equal(t, tc.input, got.String())
+				assert.Equal(t, tc.fileName, got.FileName("x"))
+			})
+		}
+	})
+
+	t.Run("parse list", func(t *testing.T) {
+		got, err := audio.ParseOutputFormats("flac, wav:48000:24,")
+		assert.Nil(t, err)
+		assert.Equal(t, []*audio.OutputFormat{
+			{Codec: audio.CodecFLAC},
+			{Codec: audio.CodecWav, SampleRate: 48000, BitDepth: 24},
+		}, got)
+	})
+
+	t.Run("encode", func(t *testing.T) {
+		w := audio.NewWav(audio.Format{SampleRate: 44100, BitDepth: 32, Channels: 1, Float: true}, 44100)
+		f := audio.OutputFormat{Codec: audio.CodecFLAC, SampleRate: 48000}
+		var buf bytes.Buffer
+		if !assert.Nil(t, f.Encode(&buf, w)) {
+			return
+		}
+		got, err := audio.DecodeFLAC(&buf)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, audio.Format{SampleRate: 48000, BitDepth: 24, Channels: 1}, got.Format)
+		assert.Equal(t, 48000, got.Frames())
+		// the source is not modified
+		assert.Equal(t, 44100, w.Format.SampleRate)
+	})
+}
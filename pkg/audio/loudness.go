@@ -0,0 +1,166 @@
+package audio
+
+import (
+	"errors"
+	"math"
+)
+
+// Integrated loudness based on ITU-R BS.1770 / EBU R128.
+
+var ErrTooShort = errors.New("TooShort")
+
+const (
+	loudnessBlockSeconds = 0.4
+	loudnessStepSeconds  = 0.1
+	loudnessAbsoluteGate = -70.0
+	loudnessRelativeGate = -10.0
+	loudnessOffset       = -0.691
+	silenceLevel         = -math.MaxFloat64
+)
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// newKWeighting returns the pre-filter (high shelf) and the RLB filter (high pass) for the sample rate.
+func newKWeighting(sampleRate int) (*biquad, *biquad) {
+	fs := float64(sampleRate)
+
+	shelf := func() *biquad {
+		const (
+			f0 = 1681.974450955533
+			g  = 3.999843853973347
+			q  = 0.7071752369554196
+		)
+		var (
+			k  = math.Tan(math.Pi * f0 / fs)
+			vh = math.Pow(10, g/20)
+			vb = math.Pow(vh, 0.4996667741545416)
+			a0 = 1 + k/q + k*k
+		)
+		return &biquad{
+			b0: (vh + vb*k/q + k*k) / a0,
+			b1: 2 * (k*k - vh) / a0,
+			b2: (vh - vb*k/q + k*k) / a0,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}()
+
+	highPass := func() *biquad {
+		const (
+			f0 = 38.13547087602444
+			q  = 0.5003270373238773
+		)
+		var (
+			k  = math.Tan(math.Pi * f0 / fs)
+			a0 = 1 + k/q + k*k
+		)
+		return &biquad{
+			b0: 1,
+			b1: -2,
+			b2: 1,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}()
+
+	return shelf, highPass
+}
+
+// IntegratedLoudness returns the gated loudness of the whole wav in LUFS.
+// Returns -math.MaxFloat64 if the wav is silent.
+func (w Wav) IntegratedLoudness() (float64, error) {
+	var (
+		blockSize = int(loudnessBlockSeconds * float64(w.Format.SampleRate))
+		stepSize  = int(loudnessStepSeconds * float64(w.Format.SampleRate))
+		frames    = w.Frames()
+	)
+	if blockSize == 0 || frames < blockSize {
+		return 0, ErrTooShort
+	}
+
+	// squared K-weighted samples summed over channels
+	power := make([]float64, frames)
+	for _, ch := range w.Data {
+		shelf, highPass := newKWeighting(w.Format.SampleRate)
+		for i, x := range ch {
+			y := highPass.process(shelf.process(x))
+			power[i] += y * y
+		}
+	}
+
+	// cumulative sum to get block energies quickly
+	cum := make([]float64, frames+1)
+	for i, x := range power {
+		cum[i+1] = cum[i] + x
+	}
+	var blocks []float64
+	for start := 0; start+blockSize <= frames; start += stepSize {
+		blocks = append(blocks, (cum[start+blockSize]-cum[start])/float64(blockSize))
+	}
+
+	loudness := func(meanSquare float64) float64 {
+		if meanSquare <= 0 {
+			return silenceLevel
+		}
+		return loudnessOffset + 10*math.Log10(meanSquare)
+	}
+	gatedMean := func(gate float64) (float64, bool) {
+		var (
+			sum float64
+			n   int
+		)
+		for _, z := range blocks {
+			if loudness(z) > gate {
+				sum += z
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, false
+		}
+		return sum / float64(n), true
+	}
+
+	absMean, ok := gatedMean(loudnessAbsoluteGate)
+	if !ok {
+		return silenceLevel, nil
+	}
+	relMean, ok := gatedMean(loudness(absMean) + loudnessRelativeGate)
+	if !ok {
+		return silenceLevel, nil
+	}
+	return loudness(relMean), nil
+}
+
+// Peak returns the sample peak level in dBFS.
+// Returns -math.MaxFloat64 if the wav is silent.
+func (w Wav) Peak() float64 {
+	var peak float64
+	for _, ch := range w.Data {
+		for _, x := range ch {
+			peak = max(peak, math.Abs(x))
+		}
+	}
+	return AmplitudeToDB(peak)
+}
+
+func AmplitudeToDB(amp float64) float64 {
+	if amp <= 0 {
+		return silenceLevel
+	}
+	return 20 * math.Log10(amp)
+}
+
+func DBToAmplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
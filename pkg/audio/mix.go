@@ -0,0 +1,78 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// Track is an input of Mix.
+type Track struct {
+	Wav    *Wav
+	Offset float64 // seconds to delay the track, negative value cuts the head
+	Gain   float64 // dB
+	Pan    float64 // -1 (left) to 1 (right)
+}
+
+// Mix sums the tracks into a stereo wav of the format of the first track.
+// Tracks of other sample rates are resampled.
+// Mono tracks are panned by the constant power pan law, stereo tracks are balanced by the pan.
+func Mix(tracks ...*Track) (*Wav, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("%w: no tracks to mix", ErrInvalidWav)
+	}
+	var (
+		format = tracks[0].Wav.Format
+		frames int
+		inputs = make([]*Wav, len(tracks))
+		starts = make([]int, len(tracks))
+	)
+	format.Channels = 2
+	for i, t := range tracks {
+		if t.Pan < -1 || t.Pan > 1 {
+			return nil, fmt.Errorf("%w: pan %f out of range [-1, 1]", ErrInvalidWav, t.Pan)
+		}
+		switch len(t.Wav.Data) {
+		case 1, 2:
+		default:
+			return nil, fmt.Errorf("%w: mix %d channels", ErrUnsupportedWav, len(t.Wav.Data))
+		}
+		w := t.Wav
+		if w.Format.SampleRate != format.SampleRate {
+			w = w.Clone()
+			w.Resample(format.SampleRate)
+		}
+		inputs[i] = w
+		starts[i] = int(math.Round(t.Offset * float64(format.SampleRate)))
+		frames = max(frames, starts[i]+w.Frames())
+	}
+
+	r := NewWav(format, max(frames, 0))
+	for i, t := range tracks {
+		var (
+			w           = inputs[i]
+			left, right = panGains(t.Pan, len(w.Data) == 1)
+			amp         = DBToAmplitude(t.Gain)
+			lch, rch    = w.Data[0], w.Data[len(w.Data)-1]
+		)
+		for j := range w.Frames() {
+			k := starts[i] + j
+			if k < 0 {
+				continue
+			}
+			r.Data[0][k] += lch[j] * left * amp
+			r.Data[1][k] += rch[j] * right * amp
+		}
+	}
+	return r, nil
+}
+
+// panGains returns the gains of the left and the right channel.
+func panGains(pan float64, mono bool) (float64, float64) {
+	if mono {
+		// constant power, -3 dB at the center
+		theta := (pan + 1) * math.Pi / 4
+		return math.Cos(theta), math.Sin(theta)
+	}
+	// balance, unity at the center
+	return min(1, 1-pan), min(1, 1+pan)
+}
@@ -0,0 +1,71 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMix(t *testing.T) {
+	constant := func(format audio.Format, frames int, v ...float64) *audio.Wav {
+		w := audio.NewWav(format, frames)
+		for ch := range w.Data {
+			for i := range w.Data[ch] {
+				w.Data[ch][i] = v[ch]
+			}
+		}
+		return w
+	}
+	var (
+		mono   = audio.Format{SampleRate: 1000, BitDepth: 16, Channels: 1}
+		stereo = audio.Format{SampleRate: 1000, BitDepth: 24, Channels: 2}
+	)
+
+	t.Run("offset and gain", func(t *testing.T) {
+		got, err := audio.Mix(
+			&audio.Track{Wav: constant(mono, 100, 0.5), Offset: 0.05, Pan: -1},
+			&audio.Track{Wav: constant(stereo, 100, 0.2, 0.4), Offset: -0.02, Gain: -6.0206},
+		)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, audio.Format{SampleRate: 1000, BitDepth: 16, Channels: 2}, got.Format)
+		assert.Equal(t, 150, got.Frames())
+		// backing only, halved
+		assert.InDelta(t, 0.1, got.Data[0][0], 1e-4)
+		assert.InDelta(t, 0.2, got.Data[1][0], 1e-4)
+		// vocal panned left fully
+		assert.InDelta(t, 0.6, got.Data[0][60], 1e-4)
+		assert.InDelta(t, 0.2, got.Data[1][60], 1e-4)
+		// vocal only
+		assert.InDelta(t, 0.5, got.Data[0][100], 1e-4)
+		assert.InDelta(t, 0.0, got.Data[1][100], 1e-4)
+	})
+
+	t.Run("center pan", func(t *testing.T) {
+		got, err := audio.Mix(&audio.Track{Wav: constant(mono, 10, 1)})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.InDelta(t, math.Sqrt2/2, got.Data[0][0], 1e-9)
+		assert.InDelta(t, math.Sqrt2/2, got.Data[1][0], 1e-9)
+	})
+
+	t.Run("resample", func(t *testing.T) {
+		got, err := audio.Mix(
+			&audio.Track{Wav: constant(mono, 1000, 0)},
+			&audio.Track{Wav: constant(audio.Format{SampleRate: 500, BitDepth: 16, Channels: 1}, 1000, 0)},
+		)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2000, got.Frames())
+	})
+
+	t.Run("invalid pan", func(t *testing.T) {
+		_, err := audio.Mix(&audio.Track{Wav: constant(mono, 10, 1), Pan: 2})
+		assert.ErrorIs(t, err, audio.ErrInvalidWav)
+	})
+}
@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// Peaks is min/max waveform data.
+// The JSON representation is compatible with the audiowaveform data format version 2,
+// so it can be passed to waveform viewers as it is.
+type Peaks struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"` // number of pixels
+	Data            []int `json:"data"`   // min and max per channel per pixel
+}
+
+const peaksVersion = 2
+
+// PeaksResolutions are samples per pixel of the peaks generated for each result.
+var PeaksResolutions = []int{256, 1024, 4096}
+
+// NewPeaks computes min/max pairs for every samplesPerPixel samples.
+// bits is the resolution of the values, 8 or 16.
+func NewPeaks(w *Wav, samplesPerPixel, bits int) (*Peaks, error) {
+	if samplesPerPixel < 1 {
+		return nil, fmt.Errorf("invalid samples per pixel: %d", samplesPerPixel)
+	}
+	if bits != 8 && bits != 16 {
+		return nil, fmt.Errorf("invalid bits: %d", bits)
+	}
+
+	var (
+		frames   = w.Frames()
+		channels = len(w.Data)
+		length   = (frames + samplesPerPixel - 1) / samplesPerPixel
+		data     = make([]int, 0, length*channels*2)
+		scale    = float64(int(1)<<(bits-1) - 1)
+		toInt    = func(v float64) int { return int(math.Round(clip(v) * scale)) }
+	)
+	for i := range length {
+		var (
+			start = i * samplesPerPixel
+			end   = min(start+samplesPerPixel, frames)
+		)
+		for ch := range channels {
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, v := range w.Data[ch][start:end] {
+				lo = min(lo, v)
+				hi = max(hi, v)
+			}
+			data = append(data, toInt(lo), toInt(hi))
+		}
+	}
+
+	return &Peaks{
+		Version:         peaksVersion,
+		Channels:        channels,
+		SampleRate:      w.Format.SampleRate,
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            bits,
+		Length:          length,
+		Data:            data,
+	}, nil
+}
+
+// PeaksFileName is the name of the peaks file of the resolution in the result directory.
+func PeaksFileName(samplesPerPixel int) string {
+	return fmt.Sprintf("peaks_%d.json", samplesPerPixel)
+}
@@ -0,0 +1,41 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeaks(t *testing.T) {
+	w := audio.NewWav(audio.Format{SampleRate: 8, BitDepth: 16, Channels: 2}, 5)
+	copy(w.Data[0], []float64{0, 1, -0.5, 0.5, 0})
+	copy(w.Data[1], []float64{-1, 0, 0, 0, 0.5})
+
+	t.Run("8bits", func(t *testing.T) {
+		got, err := audio.NewPeaks(w, 2, 8)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, &audio.Peaks{
+			Version:         2,
+			Channels:        2,
+			SampleRate:      8,
+			SamplesPerPixel: 2,
+			Bits:            8,
+			Length:          3,
+			Data: []int{
+				0, 127, -127, 0,
+				-64, 64, 0, 0,
+				0, 0, 64, 64,
+			},
+		}, got)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := audio.NewPeaks(w, 0, 8)
+		assert.NotNil(t, err)
+		_, err = audio.NewPeaks(w, 2, 12)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,161 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Gain changes the level by db.
+func (w *Wav) Gain(db float64) {
+	amp := DBToAmplitude(db)
+	for _, ch := range w.Data {
+		for i := range ch {
+			ch[i] *= amp
+		}
+	}
+}
+
+// TrimSilence removes leading and trailing samples quieter than thresholdDB in all channels.
+// Does nothing if the whole wav is silent.
+func (w *Wav) TrimSilence(thresholdDB float64) {
+	var (
+		threshold = DBToAmplitude(thresholdDB)
+		frames    = w.Frames()
+		loud      = func(i int) bool {
+			for _, ch := range w.Data {
+				if math.Abs(ch[i]) > threshold {
+					return true
+				}
+			}
+			return false
+		}
+		start = 0
+		end   = frames
+	)
+	for start < frames && !loud(start) {
+		start++
+	}
+	if start == frames {
+		return
+	}
+	for end > start && !loud(end-1) {
+		end--
+	}
+	for i, ch := range w.Data {
+		w.Data[i] = ch[start:end]
+	}
+}
+
+// PadSilence adds leading and trailing silence in seconds.
+func (w *Wav) PadSilence(leading, trailing float64) {
+	var (
+		head = w.secondsToFrames(leading)
+		tail = w.secondsToFrames(trailing)
+	)
+	if head == 0 && tail == 0 {
+		return
+	}
+	for i, ch := range w.Data {
+		x := make([]float64, head+len(ch)+tail)
+		copy(x[head:], ch)
+		w.Data[i] = x
+	}
+}
+
+// FadeIn applies a linear fade in of seconds.
+func (w *Wav) FadeIn(seconds float64) {
+	n := min(w.secondsToFrames(seconds), w.Frames())
+	for _, ch := range w.Data {
+		for i := range n {
+			ch[i] *= float64(i) / float64(n)
+		}
+	}
+}
+
+// FadeOut applies a linear fade out of seconds.
+func (w *Wav) FadeOut(seconds float64) {
+	var (
+		frames = w.Frames()
+		n      = min(w.secondsToFrames(seconds), frames)
+	)
+	for _, ch := range w.Data {
+		for i := range n {
+			ch[frames-1-i] *= float64(i) / float64(n)
+		}
+	}
+}
+
+func (w Wav) secondsToFrames(seconds float64) int {
+	if seconds <= 0 {
+		return 0
+	}
+	return int(math.Round(seconds * float64(w.Format.SampleRate)))
+}
+
+type NormalizeMethod string
+
+const (
+	NormalizeNone     NormalizeMethod = ""
+	NormalizePeak     NormalizeMethod = "peak"
+	NormalizeLoudness NormalizeMethod = "loudness" // EBU R128
+)
+
+var ErrInvalidNormalizeMethod = errors.New("InvalidNormalizeMethod")
+
+func ParseNormalizeMethod(s string) (NormalizeMethod, error) {
+	switch x := NormalizeMethod(s); x {
+	case NormalizeNone, NormalizePeak, NormalizeLoudness:
+		return x, nil
+	default:
+		return NormalizeNone, fmt.Errorf("%w: %s", ErrInvalidNormalizeMethod, s)
+	}
+}
+
+type PostProcessParams struct {
+	Normalize        NormalizeMethod
+	PeakLevel        float64 // dBFS, also the ceiling of the loudness normalization
+	LoudnessLevel    float64 // LUFS
+	TrimSilence      bool
+	SilenceThreshold float64 // dBFS
+	LeadingSilence   float64 // seconds
+	TrailingSilence  float64 // seconds
+	FadeIn           float64 // seconds
+	FadeOut          float64 // seconds
+}
+
+// PostProcess trims silence, applies fades and then normalizes the level.
+func PostProcess(w *Wav, p *PostProcessParams) error {
+	if p.TrimSilence {
+		w.TrimSilence(p.SilenceThreshold)
+		w.PadSilence(p.LeadingSilence, p.TrailingSilence)
+	}
+	w.FadeIn(p.FadeIn)
+	w.FadeOut(p.FadeOut)
+
+	switch p.Normalize {
+	case NormalizeNone:
+		return nil
+	case NormalizePeak:
+		if peak := w.Peak(); peak > silenceLevel {
+			w.Gain(p.PeakLevel - peak)
+		}
+		return nil
+	case NormalizeLoudness:
+		loudness, err := w.IntegratedLoudness()
+		if err != nil {
+			return fmt.Errorf("%w: measure loudness", err)
+		}
+		if loudness > silenceLevel {
+			gain := p.LoudnessLevel - loudness
+			// not to clip the peaks, the loudness may be lower than the target
+			if peak := w.Peak(); peak+gain > p.PeakLevel {
+				gain = p.PeakLevel - peak
+			}
+			w.Gain(gain)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidNormalizeMethod, p.Normalize)
+	}
+}
@@ -0,0 +1,125 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSine(sampleRate int, seconds, frequency, amplitude float64) *audio.Wav {
+	w := audio.NewWav(audio.Format{
+		SampleRate: sampleRate,
+		BitDepth:   16,
+		Channels:   1,
+	}, int(seconds*float64(sampleRate)))
+	for i := range w.Data[0] {
+		w.Data[0][i] = amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate))
+	}
+	return w
+}
+
+func TestLoudness(t *testing.T) {
+	t.Run("sine", func(t *testing.T) {
+		// 997 Hz sine of -20 dBFS in a single channel is -23.0 LUFS
+		w := newSine(48000, 3, 997, 0.1)
+		got, err := w.IntegratedLoudness()
+		assert.Nil(t, err)
+		assert.InDelta(t, -23.0, got, 0.1)
+		assert.InDelta(t, -20.0, w.Peak(), 0.01)
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		w := newSine(48000, 0.1, 997, 0.1)
+		_, err := w.IntegratedLoudness()
+		assert.ErrorIs(t, err, audio.ErrTooShort)
+	})
+}
+
+func TestPostProcess(t *testing.T) {
+	const sampleRate = 1000
+
+	t.Run("trim and pad", func(t *testing.T) {
+		w := audio.NewWav(audio.Format{SampleRate: sampleRate, BitDepth: 16, Channels: 1}, 1000)
+		for i := 300; i < 600; i++ {
+			w.Data[0][i] = 0.5
+		}
+		assert.Nil(t, audio.PostProcess(w, &audio.PostProcessParams{
+			TrimSilence:      true,
+			SilenceThreshold: -60,
+			LeadingSilence:   0.1,
+			TrailingSilence:  0.2,
+		}))
+		assert.Equal(t, 100+300+200, w.Frames())
+		assert.Equal(t, 0.0, w.Data[0][99])
+		assert.Equal(t, 0.5, w.Data[0][100])
+		assert.Equal(t, 0.5, w.Data[0][399])
+		assert.Equal(t, 0.0, w.Data[0][400])
+	})
+
+	t.Run("fade", func(t *testing.T) {
+		w := audio.NewWav(audio.Format{SampleRate: sampleRate, BitDepth: 16, Channels: 1}, 1000)
+		for i := range w.Data[0] {
+			w.Data[0][i] = 1
+		}
+		assert.Nil(t, audio.PostProcess(w, &audio.PostProcessParams{
+			FadeIn:  0.1,
+			FadeOut: 0.2,
+		}))
+		assert.Equal(t, 0.0, w.Data[0][0])
+		assert.InDelta(t, 0.5, w.Data[0][50], 1e-9)
+		assert.Equal(t, 1.0, w.Data[0][500])
+		assert.InDelta(t, 0.5, w.Data[0][899], 1e-9)
+		assert.Equal(t, 0.0, w.Data[0][999])
+	})
+
+	t.Run("peak", func(t *testing.T) {
+		w := newSine(48000, 1, 440, 0.25)
+		assert.Nil(t, audio.PostProcess(w, &audio.PostProcessParams{
+			Normalize: audio.NormalizePeak,
+			PeakLevel: -1,
+		}))
+		assert.InDelta(t, -1, w.Peak(), 0.01)
+	})
+
+	t.Run("loudness", func(t *testing.T) {
+		w := newSine(48000, 3, 997, 0.01)
+		assert.Nil(t, audio.PostProcess(w, &audio.PostProcessParams{
+			Normalize:     audio.NormalizeLoudness,
+			PeakLevel:     -1,
+			LoudnessLevel: -16,
+		}))
+		got, err := w.IntegratedLoudness()
+		assert.Nil(t, err)
+		assert.InDelta(t, -16, got, 0.01)
+	})
+
+	t.Run("loudness limited by peak", func(t *testing.T) {
+		// quiet sine with loud clicks, the crest factor is about 37 dB
+		w := newSine(48000, 3, 997, 0.01)
+		for i := 0; i < w.Frames(); i += 4800 {
+			w.Data[0][i] = 0.5
+		}
+		loudness, err := w.IntegratedLoudness()
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Nil(t, audio.PostProcess(w, &audio.PostProcessParams{
+			Normalize:     audio.NormalizeLoudness,
+			PeakLevel:     -1,
+			LoudnessLevel: -16,
+		}))
+		assert.InDelta(t, -1, w.Peak(), 0.01)
+		got, err := w.IntegratedLoudness()
+		assert.Nil(t, err)
+		// the gain is limited to the headroom of the clicks
+		assert.InDelta(t, loudness+(-1-audio.AmplitudeToDB(0.5)), got, 0.01)
+		assert.Less(t, got, -16.0)
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		_, err := audio.ParseNormalizeMethod("rms")
+		assert.ErrorIs(t, err, audio.ErrInvalidNormalizeMethod)
+	})
+}
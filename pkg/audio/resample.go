@@ -0,0 +1,109 @@
+package audio
+
+import "math"
+
+const (
+	// resampleZeroCrossings is the number of zero crossings of the sinc on each side.
+	resampleZeroCrossings = 32
+	// resampleMaxPhases limits the size of the precomputed filter table.
+	resampleMaxPhases = 4096
+)
+
+// Resample converts the sample rate with a windowed sinc interpolation.
+// The cutoff is lowered to the new Nyquist frequency on downsampling to avoid aliasing.
+func (w *Wav) Resample(rate int) {
+	from := w.Format.SampleRate
+	if rate <= 0 || from <= 0 || rate == from {
+		return
+	}
+
+	var (
+		g = gcd(from, rate)
+		// output sample j is at input position j * down / up
+		up     = rate / g
+		down   = from / g
+		cutoff = min(1, float64(rate)/float64(from))
+		radius = int(math.Ceil(resampleZeroCrossings / cutoff))
+		taps   = 2 * radius
+		frames = w.Frames()
+		n      = (frames*up + down - 1) / down
+	)
+
+	// filter returns the taps for the input samples base-radius+1 .. base+radius
+	// when the output is at base + phase / up.
+	newFilter := func(phase int) []float64 {
+		var (
+			frac = float64(phase) / float64(up)
+			h    = make([]float64, taps)
+			sum  float64
+		)
+		for k := range h {
+			d := frac + float64(radius-1-k)
+			h[k] = cutoff * sinc(cutoff*d) * blackman(d/float64(radius))
+			sum += h[k]
+		}
+		// unity gain at DC
+		for k := range h {
+			h[k] /= sum
+		}
+		return h
+	}
+	var cache [][]float64
+	if up <= resampleMaxPhases {
+		cache = make([][]float64, up)
+	}
+	filter := func(phase int) []float64 {
+		if cache == nil {
+			return newFilter(phase)
+		}
+		if cache[phase] == nil {
+			cache[phase] = newFilter(phase)
+		}
+		return cache[phase]
+	}
+
+	for ch, x := range w.Data {
+		y := make([]float64, n)
+		for j := range y {
+			var (
+				pos   = j * down
+				base  = pos / up
+				phase = pos % up
+				h     = filter(phase)
+				v     float64
+			)
+			for k, a := range h {
+				i := base - radius + 1 + k
+				if i < 0 || i >= frames {
+					continue
+				}
+				v += x[i] * a
+			}
+			y[j] = v
+		}
+		w.Data[ch] = y
+	}
+	w.Format.SampleRate = rate
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is the Blackman window over [-1, 1].
+func blackman(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
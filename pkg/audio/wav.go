@@ -0,0 +1,362 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	formatPCM        = 1
+	formatIEEEFloat  = 3
+	formatExtensible = 0xFFFE
+)
+
+var (
+	ErrInvalidWav       = errors.New("InvalidWav")
+	ErrUnsupportedWav   = errors.New("UnsupportedWav")
+	ErrUnsupportedDepth = errors.New("UnsupportedDepth")
+)
+
+// Format is the layout of the samples.
+type Format struct {
+	SampleRate int
+	BitDepth   int
+	Channels   int
+	Float      bool // IEEE float samples instead of integer PCM
+}
+
+// Wav is a decoded wav.
+// Data holds samples per channel, normalized to [-1, 1].
+type Wav struct {
+	Format Format
+	Data   [][]float64
+}
+
+// NewWav returns a silent wav with frames samples per channel.
+func NewWav(format Format, frames int) *Wav {
+	data := make([][]float64, format.Channels)
+	for i := range data {
+		data[i] = make([]float64, frames)
+	}
+	return &Wav{
+		Format: format,
+		Data:   data,
+	}
+}
+
+// Frames returns the number of samples per channel.
+func (w Wav) Frames() int {
+	if len(w.Data) == 0 {
+		return 0
+	}
+	return len(w.Data[0])
+}
+
+// Duration returns the length in seconds.
+func (w Wav) Duration() float64 {
+	if w.Format.SampleRate == 0 {
+		return 0
+	}
+	return float64(w.Frames()) / float64(w.Format.SampleRate)
+}
+
+// Clone returns a deep copy.
+func (w Wav) Clone() *Wav {
+	data := make([][]float64, len(w.Data))
+	for i, x := range w.Data {
+		data[i] = append([]float64(nil), x...)
+	}
+	return &Wav{
+		Format: w.Format,
+		Data:   data,
+	}
+}
+
+// Mono returns the average of all channels.
+func (w Wav) Mono() []float64 {
+	r := make([]float64, w.Frames())
+	if len(w.Data) == 0 {
+		return r
+	}
+	for _, ch := range w.Data {
+		for i, x := range ch {
+			r[i] += x
+		}
+	}
+	n := float64(len(w.Data))
+	for i := range r {
+		r[i] /= n
+	}
+	return r
+}
+
+func ReadFile(path string) (*Wav, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	w, err := Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%w: read wav %s", err, path)
+	}
+	return w, nil
+}
+
+func WriteFile(path string, w *Wav) error {
+	return writeFile(path, w.Encode)
+}
+
+func writeFile(path string, encode func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := encode(bw); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: write %s", err, path)
+	}
+	if err := bw.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Decode reads a RIFF WAVE stream.
+func Decode(r io.Reader) (*Wav, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.Join(ErrInvalidWav, err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: not a RIFF WAVE", ErrInvalidWav)
+	}
+
+	var (
+		format    *Format
+		formatTag uint16
+	)
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if format != nil {
+				return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWav)
+			}
+			return nil, errors.Join(ErrInvalidWav, err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, errors.Join(ErrInvalidWav, err)
+			}
+			f, tag, err := parseFmtChunk(body)
+			if err != nil {
+				return nil, err
+			}
+			format = f
+			formatTag = tag
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWav)
+			}
+			body, err := io.ReadAll(io.LimitReader(r, int64(size)))
+			if err != nil {
+				return nil, errors.Join(ErrInvalidWav, err)
+			}
+			data, err := decodeSamples(body, *format, formatTag)
+			if err != nil {
+				return nil, err
+			}
+			return &Wav{
+				Format: *format,
+				Data:   data,
+			}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+				return nil, errors.Join(ErrInvalidWav, err)
+			}
+		}
+		if size%2 == 1 {
+			// chunks are word aligned
+			if _, err := io.CopyN(io.Discard, r, 1); err != nil {
+				return nil, errors.Join(ErrInvalidWav, err)
+			}
+		}
+	}
+}
+
+func parseFmtChunk(body []byte) (*Format, uint16, error) {
+	if len(body) < 16 {
+		return nil, 0, fmt.Errorf("%w: short fmt chunk", ErrInvalidWav)
+	}
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	if tag == formatExtensible {
+		if len(body) < 26 {
+			return nil, 0, fmt.Errorf("%w: short extensible fmt chunk", ErrInvalidWav)
+		}
+		// the first 2 bytes of the sub format GUID is the actual format tag
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+	f := &Format{
+		Channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+		BitDepth:   int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	switch tag {
+	case formatPCM:
+		switch f.BitDepth {
+		case 8, 16, 24, 32:
+		default:
+			return nil, 0, fmt.Errorf("%w: pcm %d bits", ErrUnsupportedDepth, f.BitDepth)
+		}
+	case formatIEEEFloat:
+		f.Float = true
+		switch f.BitDepth {
+		case 32, 64:
+		default:
+			return nil, 0, fmt.Errorf("%w: float %d bits", ErrUnsupportedDepth, f.BitDepth)
+		}
+	default:
+		return nil, 0, fmt.Errorf("%w: format tag %d", ErrUnsupportedWav, tag)
+	}
+	if f.Channels < 1 {
+		return nil, 0, fmt.Errorf("%w: no channels", ErrInvalidWav)
+	}
+	return f, tag, nil
+}
+
+func decodeSamples(body []byte, f Format, tag uint16) ([][]float64, error) {
+	var (
+		width  = f.BitDepth / 8
+		frames = len(body) / (width * f.Channels)
+		data   = make([][]float64, f.Channels)
+	)
+	for i := range data {
+		data[i] = make([]float64, frames)
+	}
+
+	for i := range frames {
+		for ch := range f.Channels {
+			p := body[(i*f.Channels+ch)*width:]
+			var v float64
+			switch {
+			case tag == formatIEEEFloat && width == 4:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+			case tag == formatIEEEFloat && width == 8:
+				v = math.Float64frombits(binary.LittleEndian.Uint64(p))
+			case width == 1:
+				// 8 bit pcm is unsigned
+				v = (float64(p[0]) - 128) / 128
+			case width == 2:
+				v = float64(int16(binary.LittleEndian.Uint16(p))) / (1 << 15)
+			case width == 3:
+				x := int32(uint32(p[0])<<8|uint32(p[1])<<16|uint32(p[2])<<24) >> 8
+				v = float64(x) / (1 << 23)
+			case width == 4:
+				v = float64(int32(binary.LittleEndian.Uint32(p))) / (1 << 31)
+			}
+			data[ch][i] = v
+		}
+	}
+	return data, nil
+}
+
+// Encode writes this as a RIFF WAVE stream.
+// Samples are clipped to [-1, 1].
+func (w Wav) Encode(wr io.Writer) error {
+	f := w.Format
+	if f.Channels != len(w.Data) {
+		return fmt.Errorf("%w: want %d channels got %d", ErrInvalidWav, f.Channels, len(w.Data))
+	}
+	tag := uint16(formatPCM)
+	if f.Float {
+		tag = formatIEEEFloat
+		if f.BitDepth != 32 && f.BitDepth != 64 {
+			return fmt.Errorf("%w: float %d bits", ErrUnsupportedDepth, f.BitDepth)
+		}
+	} else {
+		switch f.BitDepth {
+		case 8, 16, 24, 32:
+		default:
+			return fmt.Errorf("%w: pcm %d bits", ErrUnsupportedDepth, f.BitDepth)
+		}
+	}
+
+	var (
+		width      = f.BitDepth / 8
+		blockAlign = width * f.Channels
+		dataSize   = blockAlign * w.Frames()
+		buf        bytes.Buffer
+		le         = binary.LittleEndian
+		put16      = func(v uint16) { buf.Write(le.AppendUint16(nil, v)) }
+		put32      = func(v uint32) { buf.Write(le.AppendUint32(nil, v)) }
+	)
+	buf.Grow(44 + dataSize)
+	buf.WriteString("RIFF")
+	put32(uint32(36 + dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	put32(16)
+	put16(tag)
+	put16(uint16(f.Channels))
+	put32(uint32(f.SampleRate))
+	put32(uint32(f.SampleRate * blockAlign))
+	put16(uint16(blockAlign))
+	put16(uint16(f.BitDepth))
+	buf.WriteString("data")
+	put32(uint32(dataSize))
+
+	for i := range w.Frames() {
+		for ch := range f.Channels {
+			v := clip(w.Data[ch][i])
+			switch {
+			case f.Float && width == 4:
+				put32(math.Float32bits(float32(v)))
+			case f.Float && width == 8:
+				buf.Write(le.AppendUint64(nil, math.Float64bits(v)))
+			case width == 1:
+				buf.WriteByte(byte(quantize(v, 8) + 128))
+			case width == 2:
+				put16(uint16(int16(quantize(v, 16))))
+			case width == 3:
+				x := uint32(int32(quantize(v, 24)))
+				buf.Write([]byte{byte(x), byte(x >> 8), byte(x >> 16)})
+			case width == 4:
+				put32(uint32(int32(quantize(v, 32))))
+			}
+		}
+	}
+	if dataSize%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	_, err := wr.Write(buf.Bytes())
+	return err
+}
+
+func clip(v float64) float64 {
+	return max(-1, min(1, v))
+}
+
+// quantize converts a normalized sample into a signed integer of bits width.
+func quantize(v float64, bits int) int64 {
+	var (
+		scale = float64(int64(1) << (bits - 1))
+		x     = int64(math.Round(v * scale))
+		hi    = int64(1)<<(bits-1) - 1
+		lo    = -int64(1) << (bits - 1)
+	)
+	return max(lo, min(hi, x))
+}
@@ -0,0 +1,62 @@
+package audio_test
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWav(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 0.25, -1, 0.999}
+
+	for _, tc := range []struct {
+		format audio.Format
+		delta  float64
+	}{
+		{format: audio.Format{SampleRate: 48000, BitDepth: 8, Channels: 1}, delta: 1.0 / 127},
+		{format: audio.Format{SampleRate: 48000, BitDepth: 16, Channels: 1}, delta: 1.0 / 32767},
+		{format: audio.Format{SampleRate: 44100, BitDepth: 24, Channels: 2}, delta: 1.0 / 8388607},
+		{format: audio.Format{SampleRate: 44100, BitDepth: 32, Channels: 1}, delta: 1e-9},
+		{format: audio.Format{SampleRate: 96000, BitDepth: 32, Channels: 2, Float: true}, delta: 1e-7},
+		{format: audio.Format{SampleRate: 96000, BitDepth: 64, Channels: 1, Float: true}, delta: 0},
+	} {
+		t.Run(fmt.Sprintf("%+v", tc.format), func(t *testing.T) {
+			w := audio.NewWav(tc.format, len(samples))
+			for ch := range tc.format.Channels {
+				copy(w.Data[ch], samples)
+			}
+
+			var buf bytes.Buffer
+			if !assert.Nil(t, w.Encode(&buf)) {
+				return
+			}
+			got, err := audio.Decode(&buf)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.format, got.Format)
+			assert.Equal(t, len(samples), got.Frames())
+			for ch := range tc.format.Channels {
+				assert.InDeltaSlice(t, samples, got.Data[ch], tc.delta)
+			}
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.wav")
+		w := audio.NewWav(audio.Format{SampleRate: 8000, BitDepth: 16, Channels: 1}, 8000)
+		assert.Nil(t, audio.WriteFile(path, w))
+		got, err := audio.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 1.0, got.Duration())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := audio.Decode(bytes.NewBufferString("not a wav file"))
+		assert.ErrorIs(t, err, audio.ErrInvalidWav)
+	})
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/server/handler"
+)
+
+var ErrClient = errors.New("Client")
+
+const (
+	defaultMaxRetries    = 3
+	defaultRetryInterval = 500 * time.Millisecond
+	defaultPollInterval  = 300 * time.Millisecond
+)
+
+// Client is the client of pneutrinoutil-server.
+type Client struct {
+	// BaseURL is the URI of the server including the version, e.g. http://127.0.0.1:9101/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// MaxRetries is the number of the retries of GET requests on 5xx or network errors.
+	// Requests to start processes are not retried not to start them twice.
+	MaxRetries int
+	// RetryInterval is the wait before the first retry, doubled on every retry.
+	RetryInterval time.Duration
+	// PollInterval is the interval of the polling by Wait.
+	PollInterval time.Duration
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:    http.DefaultClient,
+		MaxRetries:    defaultMaxRetries,
+		RetryInterval: defaultRetryInterval,
+		PollInterval:  defaultPollInterval,
+	}
+}
+
+// StatusError is the unsuccessful response of the server.
+type StatusError struct {
+	Status int
+	// Message is the error of the response, or the body if not json.
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", ErrClient, e.Status, e.Message)
+}
+
+func (e *StatusError) Unwrap() error { return ErrClient }
+
+// StatusCode returns the status of the response if err is a StatusError, otherwise 0.
+func StatusCode(err error) int {
+	var sErr *StatusError
+	if errors.As(err, &sErr) {
+		return sErr.Status
+	}
+	return 0
+}
+
+func newStatusError(resp *http.Response) *StatusError {
+	b, _ := io.ReadAll(resp.Body)
+	var body handler.ErrorResponse
+	if err := json.Unmarshal(b, &body); err == nil && body.Error != "" {
+		return &StatusError{Status: resp.StatusCode, Message: body.Error}
+	}
+	return &StatusError{Status: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// get sends a GET request with retries.
+// Returns the response if the status is 2xx, the caller should close the body.
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	var (
+		wait    = c.RetryInterval
+		lastErr error
+	)
+	for i := range c.MaxRetries + 1 {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, errors.Join(ctx.Err(), lastErr)
+			case <-time.After(wait):
+				wait *= 2
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path, query), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode/100 == 2 {
+			return resp, nil
+		}
+		sErr := newStatusError(resp)
+		_ = resp.Body.Close()
+		if resp.StatusCode < 500 {
+			return nil, sErr
+		}
+		lastErr = sErr
+	}
+	return nil, lastErr
+}
+
+// getJSON decodes the body of the GET request into v.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: decode %s: %w", ErrClient, path, err)
+	}
+	return nil
+}
+
+// getData decodes the data of handler.SuccessResponse of the GET request.
+func getData[T any](ctx context.Context, c *Client, path string, query url.Values) (*T, error) {
+	var body handler.SuccessResponse[T]
+	if err := c.getJSON(ctx, path, query, &body); err != nil {
+		return nil, err
+	}
+	if !body.OK {
+		return nil, fmt.Errorf("%w: %s: not ok", ErrClient, path)
+	}
+	return &body.Data, nil
+}
+
+// sendData decodes the data of handler.SuccessResponse of the request without retries.
+func sendData[T any](ctx context.Context, c *Client, method, path, contentType string, body io.Reader) (*T, error) {
+	resp, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var v handler.SuccessResponse[T]
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: decode %s: %w", ErrClient, path, err)
+	}
+	if !v.OK {
+		return nil, fmt.Errorf("%w: %s: not ok", ErrClient, path)
+	}
+	return &v.Data, nil
+}
+
+// File is a file to upload.
+type File struct {
+	Name    string
+	Content io.Reader
+}
+
+// postForm sends a multipart form and returns the request id of the new process.
+func (c *Client) postForm(ctx context.Context, path string, files map[string]*File, values map[string]string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for field, f := range files {
+		if f == nil {
+			continue
+		}
+		fw, err := w.CreateFormFile(field, f.Name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(fw, f.Content); err != nil {
+			return "", err
+		}
+	}
+	for k, v := range values {
+		if err := w.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return c.post(ctx, path, w.FormDataContentType(), &body)
+}
+
+// postJSON sends v as json and returns the request id of the new process.
+func (c *Client) postJSON(ctx context.Context, path string, v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return c.post(ctx, path, "application/json", bytes.NewReader(b))
+}
+
+// post sends the body and returns the request id of the new process.
+func (c *Client) post(ctx context.Context, path, contentType string, body io.Reader) (string, error) {
+	resp, err := c.send(ctx, http.MethodPost, path, contentType, body)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusAccepted {
+		return "", fmt.Errorf("%w: %s: unexpected status %d", ErrClient, path, resp.StatusCode)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	rid := resp.Header.Get("x-request-id")
+	if rid == "" {
+		return "", fmt.Errorf("%w: %s: no request id", ErrClient, path)
+	}
+	return rid, nil
+}
+
+// send sends a request without retries.
+// Returns the response if the status is 2xx, the caller should close the body.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, nil), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("content-type", contentType)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, newStatusError(resp)
+	}
+	return resp, nil
+}
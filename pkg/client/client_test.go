@@ -0,0 +1,331 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/client"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/berquerant/pneutrinoutil/server/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClient(t *testing.T, h http.HandlerFunc) *client.Client {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := client.New(srv.URL + "/v1/")
+	c.RetryInterval = time.Millisecond
+	c.PollInterval = time.Millisecond
+	return c
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retry on 5xx", func(t *testing.T) {
+		var count atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/proc/rid/detail", r.URL.Path)
+			if count.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, `{"ok":true,"data":{"rid":"rid","status":"running"}}`)
+		})
+		got, err := c.Detail(ctx, "rid")
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "running", got.Status)
+		assert.Equal(t, int32(3), count.Load())
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		var count atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, _ *http.Request) {
+			count.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"ok":false,"error":"broken"}`)
+		})
+		c.MaxRetries = 2
+		_, err := c.Log(ctx, "rid")
+		assert.ErrorIs(t, err, client.ErrClient)
+		assert.Equal(t, http.StatusInternalServerError, client.StatusCode(err))
+		assert.ErrorContains(t, err, "broken")
+		assert.Equal(t, int32(3), count.Load())
+	})
+
+	t.Run("no retry on 4xx", func(t *testing.T) {
+		var count atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, _ *http.Request) {
+			count.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		})
+		_, err := c.Config(ctx, "rid")
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+		assert.Equal(t, int32(1), count.Load())
+	})
+
+	t.Run("start", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/proc", r.URL.Path)
+			f, h, err := r.FormFile("score")
+			if !assert.Nil(t, err) {
+				return
+			}
+			defer f.Close()
+			b, _ := io.ReadAll(f)
+			assert.Equal(t, "score.musicxml", h.Filename)
+			assert.Equal(t, "content", string(b))
+			assert.Equal(t, "-12", r.FormValue("transpose"))
+			_, _, err = r.FormFile("accompaniment")
+			assert.ErrorIs(t, err, http.ErrMissingFile)
+			w.Header().Set("x-request-id", "newrid")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		got, err := c.Start(ctx, &client.StartRequest{
+			Score:  &client.File{Name: "score.musicxml", Content: strings.NewReader("content")},
+			Values: map[string]string{"transpose": "-12"},
+		})
+		if assert.Nil(t, err) {
+			assert.Equal(t, "newrid", got)
+		}
+	})
+
+	t.Run("start with config", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.JSONEq(t, `{"thread":2}`, r.FormValue("config"))
+			assert.Equal(t, "-12", r.FormValue("transpose"))
+			w.Header().Set("x-request-id", "newrid")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		_, err := c.Start(ctx, &client.StartRequest{
+			Score:  &client.File{Name: "score.musicxml", Content: strings.NewReader("content")},
+			Config: map[string]any{"thread": 2},
+			Values: map[string]string{"transpose": "-12"},
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("start from score", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/proc", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("content-type"))
+			b, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"score":"rid","config":{"transpose":5}}`, string(b))
+			w.Header().Set("x-request-id", "newrid")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		got, err := c.StartFromScore(ctx, "rid", map[string]any{"transpose": 5})
+		if assert.Nil(t, err) {
+			assert.Equal(t, "newrid", got)
+		}
+	})
+
+	t.Run("rerun", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/proc/rid/rerun", r.URL.Path)
+			b, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"config":{"model":"KIRITAN"}}`, string(b))
+			w.Header().Set("x-request-id", "newrid")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		got, err := c.Rerun(ctx, "rid", map[string]any{"model": "KIRITAN"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, "newrid", got)
+		}
+	})
+
+	t.Run("search page", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/proc/search", r.URL.Path)
+			q := r.URL.Query()
+			assert.Equal(t, "title", q.Get("sort"))
+			assert.Equal(t, "asc", q.Get("order"))
+			assert.Equal(t, "cur", q.Get("cursor"))
+			assert.Equal(t, "true", q.Get("total"))
+			_, _ = io.WriteString(w, `{"ok":true,"data":[{"request_id":"rid"}],"next":"next","total":3}`)
+		})
+		got, err := c.SearchPage(ctx, &client.SearchRequest{
+			Sort:   "title",
+			Order:  "asc",
+			Cursor: "cur",
+			Total:  true,
+		})
+		if assert.Nil(t, err) {
+			assert.Equal(t, &handler.SearchProcessResponse{
+				OK: true,
+				Data: handler.SearchProcessResponseData{
+					{RequestID: "rid"},
+				},
+				Next:  "next",
+				Total: new(3),
+			}, got)
+		}
+	})
+
+	t.Run("search filters", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, []string{"rid1", "rid2"}, q["ids"])
+			assert.Equal(t, "Abc", q.Get("contains"))
+			assert.Equal(t, "MERROW", q.Get("model"))
+			assert.Equal(t, "NAKUMO", q.Get("supportModel"))
+			assert.Equal(t, "0", q.Get("transpose"))
+			assert.Equal(t, "2025-11-15T06:33:34Z", q.Get("completedStart"))
+			assert.False(t, q.Has("completedEnd"))
+			assert.Equal(t, "90", q.Get("minDuration"))
+			assert.False(t, q.Has("maxDuration"))
+			assert.Equal(t, "false", q.Get("wav"))
+			assert.Equal(t, []string{"draft", "solo"}, q["tags"])
+			_, _ = io.WriteString(w, `{"ok":true,"data":[]}`)
+		})
+		_, err := c.Search(ctx, &client.SearchRequest{
+			IDs:            []string{"rid1", "rid2"},
+			Contains:       "Abc",
+			Model:          "MERROW",
+			SupportModel:   "NAKUMO",
+			Transpose:      new(0),
+			CompletedStart: new(time.Unix(1763188414, 0).UTC()),
+			MinDuration:    90 * time.Second,
+			Wav:            new(false),
+			Tags:           []string{"draft", "solo"},
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/proc/rid/cancel", r.URL.Path)
+			w.WriteHeader(http.StatusConflict)
+			_, _ = io.WriteString(w, `{"ok":false,"error":"process is already completed"}`)
+		})
+		err := c.Cancel(ctx, "rid")
+		assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+		assert.ErrorContains(t, err, "already completed")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			assert.Equal(t, "/v1/proc/rid", r.URL.Path)
+			_, _ = io.WriteString(w, `{"ok":true,"data":{"processes":["rid"],"objects":3,"size_bytes":100}}`)
+		})
+		got, err := c.Delete(ctx, "rid")
+		if assert.Nil(t, err) {
+			assert.Equal(t, &handler.DeleteResponseData{
+				Processes: []string{"rid"},
+				Objects:   3,
+				SizeBytes: 100,
+			}, got)
+		}
+	})
+
+	t.Run("delete list", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/proc/delete", r.URL.Path)
+			var req handler.DeleteRequest
+			if assert.Nil(t, json.NewDecoder(r.Body).Decode(&req)) {
+				assert.Equal(t, []string{"rid1", "rid2"}, req.IDs)
+			}
+			w.WriteHeader(http.StatusConflict)
+			_, _ = io.WriteString(w, `{"ok":false,"error":"process is pending or running"}`)
+		})
+		_, err := c.DeleteList(ctx, "rid1", "rid2")
+		assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+	})
+
+	t.Run("star", func(t *testing.T) {
+		for _, tc := range []struct {
+			starred bool
+			method  string
+		}{
+			{starred: true, method: http.MethodPut},
+			{starred: false, method: http.MethodDelete},
+		} {
+			c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tc.method, r.Method)
+				assert.Equal(t, "/v1/proc/rid/star", r.URL.Path)
+				_, _ = io.WriteString(w, `{"ok":true,"data":"starred"}`)
+			})
+			assert.Nil(t, c.Star(ctx, "rid", tc.starred))
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/v1/proc/rid", r.URL.Path)
+			b, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"title":"new title","tags":[]}`, string(b))
+			_, _ = io.WriteString(w, `{"ok":true,"data":"edited"}`)
+		})
+		assert.Nil(t, c.Edit(ctx, "rid", &handler.EditRequest{
+			Title: new("new title"),
+			Tags:  &[]string{},
+		}))
+	})
+
+	t.Run("retention report disabled", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/retention/report", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"ok":false,"error":"retention is disabled"}`)
+		})
+		_, err := c.RetentionReport(ctx)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+	})
+
+	t.Run("retention runs", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/retention/runs", r.URL.Path)
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			_, _ = io.WriteString(w, `{"ok":true,"data":[{"dry_run":false,"started_at":"2025-03-08T12:00:00Z","rules":[{"name":"failed","action":"delete","processes":["rid"],"objects":3,"size_bytes":100,"errors":0}],"processes":1,"objects":3,"size_bytes":100,"errors":0}]}`)
+		})
+		got, err := c.RetentionRuns(ctx, 10)
+		if assert.Nil(t, err) {
+			assert.Equal(t, handler.RetentionRunsResponseData{
+				{
+					StartedAt: time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC),
+					Rules: []*retention.RuleReport{
+						{
+							Name:      "failed",
+							Action:    retention.ActionDelete,
+							Processes: []string{"rid"},
+							Objects:   3,
+							SizeBytes: 100,
+						},
+					},
+					Processes: 1,
+					Objects:   3,
+					SizeBytes: 100,
+				},
+			}, got)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		var count atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, _ *http.Request) {
+			status := "running"
+			if count.Add(1) > 2 {
+				status = "failed"
+			}
+			_, _ = io.WriteString(w, `{"ok":true,"data":{"rid":"rid","status":"`+status+`"}}`)
+		})
+		got, err := c.Wait(ctx, "rid")
+		if assert.Nil(t, err) {
+			assert.Equal(t, "failed", got.Status)
+		}
+	})
+}
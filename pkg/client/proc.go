@@ -0,0 +1,496 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/berquerant/pneutrinoutil/server/handler"
+)
+
+// Health returns nil if the server is healthy.
+func (c *Client) Health(ctx context.Context) error {
+	_, err := getData[string](ctx, c, "/health", nil)
+	return err
+}
+
+func (c *Client) Version(ctx context.Context) (*handler.VersionResponseData, error) {
+	return getData[handler.VersionResponseData](ctx, c, "/version", nil)
+}
+
+func (c *Client) Debug(ctx context.Context) (*handler.DebugResponseData, error) {
+	return getData[handler.DebugResponseData](ctx, c, "/debug", nil)
+}
+
+// ConfigSchema returns the JSON Schema of the config.
+func (c *Client) ConfigSchema(ctx context.Context) (*ctl.Schema, error) {
+	var r ctl.Schema
+	if err := c.getJSON(ctx, "/schema/config", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// StartRequest is the request to start a process or a sweep process.
+type StartRequest struct {
+	Score *File // required
+	// Accompaniment is the wav to mix with the vocal, only available for a process.
+	Accompaniment *File
+	// Config is the options of the job keyed by ctl.JobKeys, e.g. ctl.Config.JobOptions.
+	Config map[string]any
+	// Values are the form values, e.g. model, transpose and, for a sweep process, spec, models and transposes.
+	// Values override Config.
+	Values map[string]string
+}
+
+func (r StartRequest) values() (map[string]string, error) {
+	return withConfig(r.Values, r.Config)
+}
+
+// withConfig returns the values with the form value config.
+func withConfig(values map[string]string, config map[string]any) (map[string]string, error) {
+	if config == nil {
+		return values, nil
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	r := maps.Clone(values)
+	if r == nil {
+		r = map[string]string{}
+	}
+	r["config"] = string(b)
+	return r, nil
+}
+
+// Start starts a process and returns the request id.
+func (c *Client) Start(ctx context.Context, r *StartRequest) (string, error) {
+	values, err := r.values()
+	if err != nil {
+		return "", err
+	}
+	return c.postForm(ctx, "/proc", map[string]*File{
+		"score":         r.Score,
+		"accompaniment": r.Accompaniment,
+	}, values)
+}
+
+// StartFromScore starts a process with the score of the process rid and returns the request id.
+// config is the options of the job keyed by ctl.JobKeys.
+func (c *Client) StartFromScore(ctx context.Context, rid string, config map[string]any) (string, error) {
+	req := handler.StartRequest{
+		Score: rid,
+	}
+	if config != nil {
+		b, err := json.Marshal(config)
+		if err != nil {
+			return "", err
+		}
+		req.Config = b
+	}
+	return c.postJSON(ctx, "/proc", req)
+}
+
+// Rerun starts a process with the score and the options of the process rid, and returns the request id.
+// config overrides the options, keyed by ctl.JobKeys.
+func (c *Client) Rerun(ctx context.Context, rid string, config map[string]any) (string, error) {
+	var req handler.RerunRequest
+	if config != nil {
+		b, err := json.Marshal(config)
+		if err != nil {
+			return "", err
+		}
+		req.Config = b
+	}
+	return c.postJSON(ctx, procPath(rid, "rerun"), req)
+}
+
+// StartSweep starts a sweep process and returns the request id.
+func (c *Client) StartSweep(ctx context.Context, r *StartRequest) (string, error) {
+	values, err := r.values()
+	if err != nil {
+		return "", err
+	}
+	return c.postForm(ctx, "/proc/sweep", map[string]*File{
+		"score": r.Score,
+	}, values)
+}
+
+// EnsembleRequest is the request to start an ensemble process.
+type EnsembleRequest struct {
+	Score  *File // required
+	Voices []*handler.EnsembleVoice
+	// Config is the options of the job keyed by ctl.JobKeys applied to all the voices.
+	Config map[string]any
+	// Values are the form values applied to all the voices, override Config.
+	Values map[string]string
+}
+
+// StartEnsemble starts an ensemble process and returns the request id.
+func (c *Client) StartEnsemble(ctx context.Context, r *EnsembleRequest) (string, error) {
+	voices, err := json.Marshal(r.Voices)
+	if err != nil {
+		return "", err
+	}
+	values, err := withConfig(r.Values, r.Config)
+	if err != nil {
+		return "", err
+	}
+	values = maps.Clone(values)
+	if values == nil {
+		values = map[string]string{}
+	}
+	values["voices"] = string(voices)
+	return c.postForm(ctx, "/proc/ensemble", map[string]*File{
+		"score": r.Score,
+	}, values)
+}
+
+// SearchRequest is the condition of the search.
+// The zero value is the server default.
+type SearchRequest struct {
+	Limit          int
+	IDs            []string // request ids
+	Status         string   // pending, running, succeed or failed
+	Prefix         string   // title prefix
+	Contains       string   // title substring, case-insensitive
+	Model          string
+	SupportModel   string
+	Transpose      *int
+	Start          *time.Time
+	End            *time.Time
+	CompletedStart *time.Time
+	CompletedEnd   *time.Time
+	MinDuration    time.Duration // truncated to seconds
+	MaxDuration    time.Duration // truncated to seconds
+	Wav            *bool         // whether the wav of the result exists
+	Tags           []string      // all the tags should be attached
+	Sort           string        // created, completed, duration or title
+	Order          string        // asc or desc
+	Cursor         string        // next of the previous page
+	Total          bool          // count the processes matched
+}
+
+func (r SearchRequest) query() url.Values {
+	q := url.Values{}
+	if r.Limit > 0 {
+		q.Set("limit", strconv.Itoa(r.Limit))
+	}
+	if r.Status != "" {
+		q.Set("status", r.Status)
+	}
+	if r.Prefix != "" {
+		q.Set("prefix", r.Prefix)
+	}
+	if r.Start != nil {
+		q.Set("start", r.Start.Format(time.RFC3339))
+	}
+	if r.End != nil {
+		q.Set("end", r.End.Format(time.RFC3339))
+	}
+	for _, x := range r.IDs {
+		q.Add("ids", x)
+	}
+	if r.Contains != "" {
+		q.Set("contains", r.Contains)
+	}
+	if r.Model != "" {
+		q.Set("model", r.Model)
+	}
+	if r.SupportModel != "" {
+		q.Set("supportModel", r.SupportModel)
+	}
+	if r.Transpose != nil {
+		q.Set("transpose", strconv.Itoa(*r.Transpose))
+	}
+	if r.CompletedStart != nil {
+		q.Set("completedStart", r.CompletedStart.Format(time.RFC3339))
+	}
+	if r.CompletedEnd != nil {
+		q.Set("completedEnd", r.CompletedEnd.Format(time.RFC3339))
+	}
+	if x := int(r.MinDuration.Seconds()); x > 0 {
+		q.Set("minDuration", strconv.Itoa(x))
+	}
+	if x := int(r.MaxDuration.Seconds()); x > 0 {
+		q.Set("maxDuration", strconv.Itoa(x))
+	}
+	if r.Wav != nil {
+		q.Set("wav", strconv.FormatBool(*r.Wav))
+	}
+	for _, x := range r.Tags {
+		q.Add("tags", x)
+	}
+	if r.Sort != "" {
+		q.Set("sort", r.Sort)
+	}
+	if r.Order != "" {
+		q.Set("order", r.Order)
+	}
+	if r.Cursor != "" {
+		q.Set("cursor", r.Cursor)
+	}
+	if r.Total {
+		q.Set("total", "true")
+	}
+	return q
+}
+
+// Search returns the processes in order of created_at desc by default.
+func (c *Client) Search(ctx context.Context, r *SearchRequest) (handler.SearchProcessResponseData, error) {
+	x, err := c.SearchPage(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return x.Data, nil
+}
+
+// SearchPage returns the page of the processes with the cursor of the next page.
+func (c *Client) SearchPage(ctx context.Context, r *SearchRequest) (*handler.SearchProcessResponse, error) {
+	if r == nil {
+		r = &SearchRequest{}
+	}
+	const path = "/proc/search"
+	var x handler.SearchProcessResponse
+	if err := c.getJSON(ctx, path, r.query(), &x); err != nil {
+		return nil, err
+	}
+	if !x.OK {
+		return nil, fmt.Errorf("%w: %s: not ok", ErrClient, path)
+	}
+	return &x, nil
+}
+
+func procPath(rid string, elem ...string) string {
+	p := "/proc/" + url.PathEscape(rid)
+	for _, x := range elem {
+		p += "/" + x
+	}
+	return p
+}
+
+func (c *Client) Detail(ctx context.Context, rid string) (*handler.GetDetailResponseData, error) {
+	return getData[handler.GetDetailResponseData](ctx, c, procPath(rid, "detail"), nil)
+}
+
+// Config returns the pneutrinoutil config of the process.
+func (c *Client) Config(ctx context.Context, rid string) (*ctl.Config, error) {
+	return getData[ctl.Config](ctx, c, procPath(rid, "config"), nil)
+}
+
+// download returns the body of the GET request, the caller should close it.
+func (c *Client) download(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// MusicXML downloads the score of the process.
+func (c *Client) MusicXML(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "musicxml"), nil)
+}
+
+// Wav downloads the wav of the process.
+// format is codec[:sampleRate[:bitDepth]], e.g. flac, wav:48000:24; the original if empty.
+func (c *Client) Wav(ctx context.Context, rid, format string) (io.ReadCloser, error) {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	return c.download(ctx, procPath(rid, "wav"), q)
+}
+
+// Mix downloads the wav of the process mixed with the accompaniment.
+func (c *Client) Mix(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "mix"), nil)
+}
+
+// Label downloads the timing label of the process.
+func (c *Client) Label(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "label"), nil)
+}
+
+// Manifest downloads the manifest of the process.
+func (c *Client) Manifest(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "manifest"), nil)
+}
+
+// Log downloads the log of the process.
+func (c *Client) Log(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "log"), nil)
+}
+
+// Peaks returns the waveform peaks of the process.
+// resolution is samples per pixel; the server default if 0.
+func (c *Client) Peaks(ctx context.Context, rid string, resolution int) (*audio.Peaks, error) {
+	q := url.Values{}
+	if resolution > 0 {
+		q.Set("resolution", strconv.Itoa(resolution))
+	}
+	var r audio.Peaks
+	if err := c.getJSON(ctx, procPath(rid, "peaks"), q, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Stem downloads the wav of the voice of the ensemble process.
+func (c *Client) Stem(ctx context.Context, rid string, index int) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "stem", strconv.Itoa(index)), nil)
+}
+
+// SweepIndex returns the index of the runs of the sweep process.
+func (c *Client) SweepIndex(ctx context.Context, rid string) (*sweep.Index, error) {
+	var r sweep.Index
+	if err := c.getJSON(ctx, procPath(rid, "sweep"), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// SweepIndexHTML downloads the index of the sweep process as html.
+func (c *Client) SweepIndexHTML(ctx context.Context, rid string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "sweep", "html"), nil)
+}
+
+// Diff compares the audio of the processes.
+// timingTolerance is in seconds; the server default if 0.
+func (c *Client) Diff(ctx context.Context, rid, other string, timingTolerance float64) (*compare.Diff, error) {
+	q := url.Values{}
+	if timingTolerance > 0 {
+		q.Set("timingTolerance", strconv.FormatFloat(timingTolerance, 'f', -1, 64))
+	}
+	return getData[compare.Diff](ctx, c, procPath(rid, "diff", url.PathEscape(other)), q)
+}
+
+// DiffWav downloads the residual wav of the processes.
+func (c *Client) DiffWav(ctx context.Context, rid, other string) (io.ReadCloser, error) {
+	return c.download(ctx, procPath(rid, "diff", url.PathEscape(other), "wav"), nil)
+}
+
+// Done returns true if the status is terminal.
+func Done(status string) bool {
+	x, ok := domain.ProcessStatusFromString(status)
+	return ok && x.Done()
+}
+
+// Cancel cancels the pending or running process.
+func (c *Client) Cancel(ctx context.Context, rid string) error {
+	resp, err := c.send(ctx, http.MethodPost, procPath(rid, "cancel"), "", nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// Delete deletes the completed process and its score, log and results.
+func (c *Client) Delete(ctx context.Context, rid string) (*handler.DeleteResponseData, error) {
+	return sendData[handler.DeleteResponseData](ctx, c, http.MethodDelete, procPath(rid), "", nil)
+}
+
+// DeleteList deletes the completed processes and their score, log and results.
+// Nothing is deleted if any process is missing or not completed.
+func (c *Client) DeleteList(ctx context.Context, rid ...string) (*handler.DeleteResponseData, error) {
+	b, err := json.Marshal(handler.DeleteRequest{
+		IDs: rid,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sendData[handler.DeleteResponseData](ctx, c, http.MethodPost, "/proc/delete", "application/json", bytes.NewReader(b))
+}
+
+// Star stars the process to keep it from the retention policies, or unstars it.
+func (c *Client) Star(ctx context.Context, rid string, starred bool) error {
+	method := http.MethodPut
+	if !starred {
+		method = http.MethodDelete
+	}
+	resp, err := c.send(ctx, method, procPath(rid, "star"), "", nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// Edit edits the title, the notes and the tags of the process.
+func (c *Client) Edit(ctx context.Context, rid string, r *handler.EditRequest) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(ctx, http.MethodPatch, procPath(rid), "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// RetentionReport returns what the retention policy would reclaim.
+func (c *Client) RetentionReport(ctx context.Context) (*retention.Report, error) {
+	return getData[retention.Report](ctx, c, "/retention/report", nil)
+}
+
+// RetentionRuns returns the reports of the retention runs, the latest first.
+// limit is the server default if 0.
+func (c *Client) RetentionRuns(ctx context.Context, limit int) (handler.RetentionRunsResponseData, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	r, err := getData[handler.RetentionRunsResponseData](ctx, c, "/retention/runs", q)
+	if err != nil {
+		return nil, err
+	}
+	return *r, nil
+}
+
+// Wait polls the process until it succeeds or fails, then returns the detail.
+// The caller should check the status of the detail.
+func (c *Client) Wait(ctx context.Context, rid string) (*handler.GetDetailResponseData, error) {
+	return c.Watch(ctx, rid, nil)
+}
+
+// Watch is Wait calling f with the detail when the status changes.
+func (c *Client) Watch(ctx context.Context, rid string, f func(*handler.GetDetailResponseData)) (*handler.GetDetailResponseData, error) {
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	var status string
+	for {
+		d, err := c.Detail(ctx, rid)
+		if err != nil {
+			return nil, fmt.Errorf("%w: wait %s", err, rid)
+		}
+		if f != nil && d.Status != status {
+			f(d)
+		}
+		status = d.Status
+		if Done(d.Status) {
+			return d, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
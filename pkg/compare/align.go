@@ -0,0 +1,135 @@
+package compare
+
+import (
+	"math"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+)
+
+const (
+	// MaxLag is the max offset in seconds searched to align the wavs.
+	MaxLag = 1.0
+	// alignRate is the approximate sample rate of the coarse search of the offset.
+	alignRate = 2000
+	// minCorrelation is the normalized cross-correlation required to shift the wavs.
+	minCorrelation = 0.5
+)
+
+// Lag returns the offset in frames of b against a that maximizes the cross-correlation within maxLag seconds.
+// Positive means b is delayed.
+// Returns 0 if the wavs are not correlated enough to be aligned.
+func Lag(a, b []float64, sampleRate int, maxLag float64) int {
+	if len(a) == 0 || len(b) == 0 || sampleRate <= 0 {
+		return 0
+	}
+	// search the decimated signals first, then refine around the offset
+	var (
+		factor = max(1, sampleRate/alignRate)
+		xa     = decimate(a, factor)
+		xb     = decimate(b, factor)
+		limit  = int(maxLag * float64(sampleRate) / float64(factor))
+		coarse = factor * correlationPeak(xa, xb, limit)
+		lag    = coarse
+		best   = math.Inf(-1)
+	)
+	for k := coarse - factor; k <= coarse+factor; k++ {
+		if v := correlationAt(a, b, k); v > best {
+			best, lag = v, k
+		}
+	}
+	if best < minCorrelation*math.Sqrt(correlationAt(a, a, 0)*correlationAt(b, b, 0)) {
+		return 0
+	}
+	return lag
+}
+
+// decimate returns the averages of every factor samples.
+func decimate(x []float64, factor int) []float64 {
+	r := make([]float64, (len(x)+factor-1)/factor)
+	for i, v := range x {
+		r[i/factor] += v
+	}
+	for i := range r {
+		r[i] /= float64(factor)
+	}
+	return r
+}
+
+// correlationAt returns sum(a[i] * b[i+lag]).
+func correlationAt(a, b []float64, lag int) float64 {
+	var s float64
+	for i := max(0, -lag); i < len(a) && i+lag < len(b); i++ {
+		s += a[i] * b[i+lag]
+	}
+	return s
+}
+
+// correlationPeak returns the lag within limit that maximizes the cross-correlation by fft.
+func correlationPeak(a, b []float64, limit int) int {
+	n := 1
+	for n < len(a)+len(b) {
+		n <<= 1
+	}
+	var (
+		ar, ai = make([]float64, n), make([]float64, n)
+		br, bi = make([]float64, n), make([]float64, n)
+	)
+	copy(ar, a)
+	copy(br, b)
+	fft(ar, ai)
+	fft(br, bi)
+	// conj(A) * B, then the inverse transform by the conjugate
+	for i := range n {
+		re := ar[i]*br[i] + ai[i]*bi[i]
+		im := ar[i]*bi[i] - ai[i]*br[i]
+		ar[i], ai[i] = re, -im
+	}
+	fft(ar, ai)
+
+	var (
+		lag  int
+		best = math.Inf(-1)
+	)
+	for k := -min(limit, n/2-1); k <= min(limit, n/2-1); k++ {
+		v := ar[(k+n)%n] // the real part of the conjugate is not affected
+		if v > best {
+			best, lag = v, k
+		}
+	}
+	return lag
+}
+
+// Residual returns the mono wav of a minus b delayed by lag frames, in the format of a.
+// b is resampled to the sample rate of a.
+func Residual(a, b *audio.Wav, lag int) *audio.Wav {
+	if b.Format.SampleRate != a.Format.SampleRate {
+		b = b.Clone()
+		b.Resample(a.Format.SampleRate)
+	}
+	var (
+		x      = a.Mono()
+		y      = b.Mono()
+		format = a.Format
+	)
+	format.Channels = 1
+	w := audio.NewWav(format, len(x))
+	for i, v := range x {
+		if j := i + lag; j >= 0 && j < len(y) {
+			v -= y[j]
+		}
+		w.Data[0][i] = v
+	}
+	return w
+}
+
+// RMS returns the root mean square of the samples.
+func RMS(x []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	var s float64
+	for _, v := range x {
+		s += v * v
+	}
+	return math.Sqrt(s / float64(len(x)))
+}
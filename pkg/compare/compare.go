@@ -0,0 +1,143 @@
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+)
+
+// Render is the outputs of a render to be compared.
+type Render struct {
+	Wav *audio.Wav
+	// F0 is nil if not available.
+	F0 []float64
+	// Labels are the timing labels, nil if not available.
+	Labels []*Label
+}
+
+// RenderPaths is the locations of the outputs of a render.
+// Empty paths are not read.
+type RenderPaths struct {
+	Wav    string
+	F0     string
+	Labels string
+}
+
+// ReadRender reads the outputs of a render.
+func ReadRender(p *RenderPaths) (*Render, error) {
+	var (
+		r   Render
+		err error
+	)
+	if r.Wav, err = audio.ReadFile(p.Wav); err != nil {
+		return nil, fmt.Errorf("%w: wav %s", err, filepath.Base(p.Wav))
+	}
+	if p.F0 != "" {
+		if r.F0, err = ReadF0File(p.F0); err != nil {
+			return nil, fmt.Errorf("%w: f0 %s", err, filepath.Base(p.F0))
+		}
+	}
+	if p.Labels != "" {
+		if r.Labels, err = ReadLabelFile(p.Labels); err != nil {
+			return nil, fmt.Errorf("%w: labels %s", err, filepath.Base(p.Labels))
+		}
+	}
+	return &r, nil
+}
+
+// Result is the differences of the renders.
+type Result struct {
+	Duration DurationDiff `json:"duration"`
+	// F0 is nil if the f0 of either is not available.
+	F0 *F0Deviation `json:"f0,omitempty"`
+	// SpectralDistance is the mean log-spectral distance in dB.
+	SpectralDistance float64 `json:"spectralDistance"`
+	// Timing is nil if the labels of either are not available.
+	Timing *TimingShift `json:"timing,omitempty"`
+}
+
+// DurationDiff is the lengths of the wavs in seconds.
+type DurationDiff struct {
+	A    float64 `json:"a"`
+	B    float64 `json:"b"`
+	Diff float64 `json:"diff"` // b - a
+}
+
+// Compare compares the render a with b.
+// timingTolerance is the shift of a label in seconds to be reported as changed.
+func Compare(a, b *Render, timingTolerance float64) *Result {
+	r := &Result{
+		Duration: DurationDiff{
+			A:    a.Wav.Duration(),
+			B:    b.Wav.Duration(),
+			Diff: b.Wav.Duration() - a.Wav.Duration(),
+		},
+		SpectralDistance: SpectralDistance(a.Wav, b.Wav),
+	}
+	if a.F0 != nil && b.F0 != nil {
+		r.F0 = NewF0Deviation(a.F0, b.F0)
+	}
+	if a.Labels != nil && b.Labels != nil {
+		r.Timing = NewTimingShift(a.Labels, b.Labels, timingTolerance)
+	}
+	return r
+}
+
+var ErrThreshold = errors.New("Threshold")
+
+// Threshold is the limits of the differences of the renders.
+// Zero disables the limit.
+type Threshold struct {
+	// Duration is the max absolute difference of the durations in seconds.
+	Duration float64 `json:"duration"`
+	// F0Cents is the max mean f0 deviation in cents.
+	F0Cents float64 `json:"f0Cents"`
+	// SpectralDistance is the max mean log-spectral distance in dB.
+	SpectralDistance float64 `json:"spectralDistance"`
+	// TimingShift is the max shift of a label in seconds.
+	TimingShift float64 `json:"timingShift"`
+}
+
+func (t Threshold) Validate() error {
+	for _, x := range []struct {
+		name  string
+		value float64
+	}{
+		{name: "duration", value: t.Duration},
+		{name: "f0Cents", value: t.F0Cents},
+		{name: "spectralDistance", value: t.SpectralDistance},
+		{name: "timingShift", value: t.TimingShift},
+	} {
+		if x.value < 0 || math.IsNaN(x.value) {
+			return fmt.Errorf("%w: %s should be positive or zero", ErrThreshold, x.name)
+		}
+	}
+	return nil
+}
+
+// Exceeded returns the reasons why the result is beyond the threshold.
+// Returns nil if the result is within the threshold.
+func (t Threshold) Exceeded(r *Result) []string {
+	var xs []string
+	if t.Duration > 0 && math.Abs(r.Duration.Diff) > t.Duration {
+		xs = append(xs, fmt.Sprintf("duration changed by %+.3fs", r.Duration.Diff))
+	}
+	if t.F0Cents > 0 && r.F0 != nil && r.F0.MeanCents > t.F0Cents {
+		xs = append(xs, fmt.Sprintf("f0 deviates by %.1f cents on average", r.F0.MeanCents))
+	}
+	if t.SpectralDistance > 0 && r.SpectralDistance > t.SpectralDistance {
+		xs = append(xs, fmt.Sprintf("spectral distance is %.2fdB", r.SpectralDistance))
+	}
+	if r.Timing != nil {
+		if r.Timing.PhonemeMismatch {
+			xs = append(xs, "phonemes changed")
+		}
+		if t.TimingShift > 0 && r.Timing.MaxShift > t.TimingShift {
+			xs = append(xs, fmt.Sprintf("timing shifted by %.3fs at most", r.Timing.MaxShift))
+		}
+	}
+	return xs
+}
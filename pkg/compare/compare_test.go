@@ -0,0 +1,212 @@
+package compare_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeF0(xs ...float64) []byte {
+	var b bytes.Buffer
+	for _, x := range xs {
+		_ = binary.Write(&b, binary.LittleEndian, x)
+	}
+	return b.Bytes()
+}
+
+func TestF0(t *testing.T) {
+	t.Run("decode", func(t *testing.T) {
+		got, err := compare.DecodeF0(encodeF0(0, 440, 880))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []float64{0, 440, 880}, got)
+		_, err = compare.DecodeF0([]byte{1, 2, 3})
+		assert.ErrorIs(t, err, compare.ErrInvalidF0)
+	})
+
+	t.Run("deviation", func(t *testing.T) {
+		got := compare.NewF0Deviation(
+			[]float64{0, 440, 440, 0, 440, 440},
+			[]float64{0, 440, 880, 440, 0},
+		)
+		assert.Equal(t, 2, got.VoicedFrames)
+		assert.Equal(t, 2, got.VoicingMismatches)
+		assert.InDelta(t, 600, got.MeanCents, 1e-9)
+		assert.InDelta(t, 1200, got.MaxCents, 1e-9)
+	})
+}
+
+func TestLabels(t *testing.T) {
+	a, err := compare.DecodeLabels(strings.NewReader("0 1000000 pau\n1000000 3000000 a\n3000000 4000000 pau\n"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []*compare.Label{
+		{Start: 0, End: 0.1, Phoneme: "pau"},
+		{Start: 0.1, End: 0.3, Phoneme: "a"},
+		{Start: 0.3, End: 0.4, Phoneme: "pau"},
+	}, a)
+
+	_, err = compare.DecodeLabels(strings.NewReader("0 pau\n"))
+	assert.ErrorIs(t, err, compare.ErrInvalidLabel)
+
+	t.Run("same", func(t *testing.T) {
+		got := compare.NewTimingShift(a, a, 0.01)
+		assert.Equal(t, &compare.TimingShift{Labels: 3}, got)
+	})
+
+	t.Run("shifted", func(t *testing.T) {
+		b, err := compare.DecodeLabels(strings.NewReader("0 1500000 pau\n1500000 3000000 a\n3000000 4000000 pau\n"))
+		if !assert.Nil(t, err) {
+			return
+		}
+		got := compare.NewTimingShift(a, b, 0.01)
+		assert.False(t, got.PhonemeMismatch)
+		assert.InDelta(t, 0.05, got.MaxShift, 1e-9)
+		if assert.Len(t, got.Changed, 1) {
+			assert.Equal(t, 1, got.Changed[0].Index)
+		}
+	})
+
+	t.Run("phonemes changed", func(t *testing.T) {
+		b, err := compare.DecodeLabels(strings.NewReader("0 1000000 pau\n1000000 3000000 i\n"))
+		if !assert.Nil(t, err) {
+			return
+		}
+		got := compare.NewTimingShift(a, b, 0.01)
+		assert.True(t, got.PhonemeMismatch)
+		assert.Equal(t, 2, got.Labels)
+	})
+}
+
+func sine(freq float64, seconds float64) *audio.Wav {
+	const rate = 8000
+	w := audio.NewWav(audio.Format{SampleRate: rate, BitDepth: 16, Channels: 1}, int(seconds*rate))
+	for i := range w.Data[0] {
+		w.Data[0][i] = 0.5 * math.Sin(2*math.Pi*freq*float64(i)/rate)
+	}
+	return w
+}
+
+func TestCompare(t *testing.T) {
+	var (
+		a = &compare.Render{Wav: sine(440, 1), F0: []float64{440, 440}}
+		b = &compare.Render{Wav: sine(880, 1.5), F0: []float64{440, 466.16}}
+	)
+
+	same := compare.Compare(a, a, 0.01)
+	assert.Equal(t, 0.0, same.SpectralDistance)
+	assert.Equal(t, 0.0, same.Duration.Diff)
+	assert.Nil(t, same.Timing)
+
+	got := compare.Compare(a, b, 0.01)
+	assert.InDelta(t, 0.5, got.Duration.Diff, 1e-9)
+	assert.Greater(t, got.SpectralDistance, 1.0)
+	assert.InDelta(t, 50, got.F0.MeanCents, 0.1)
+
+	for _, tc := range []struct {
+		title     string
+		threshold compare.Threshold
+		want      int
+	}{
+		{title: "disabled", want: 0},
+		{title: "duration", threshold: compare.Threshold{Duration: 0.1}, want: 1},
+		{title: "within", threshold: compare.Threshold{Duration: 1, F0Cents: 100, SpectralDistance: 1000}, want: 0},
+		{title: "all", threshold: compare.Threshold{Duration: 0.1, F0Cents: 10, SpectralDistance: 1}, want: 3},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Len(t, tc.threshold.Exceeded(got), tc.want)
+		})
+	}
+	assert.ErrorIs(t, compare.Threshold{F0Cents: -1}.Validate(), compare.ErrThreshold)
+}
+
+func TestLag(t *testing.T) {
+	const rate = 8000
+	var (
+		a = make([]float64, rate)
+		b = make([]float64, rate)
+	)
+	// a click train at irregular intervals
+	for _, i := range []int{1000, 1500, 2600, 4100, 5000} {
+		a[i] = 1
+		b[i+123] = 1
+	}
+	assert.Equal(t, 123, compare.Lag(a, b, rate, compare.MaxLag))
+	assert.Equal(t, -123, compare.Lag(b, a, rate, compare.MaxLag))
+	assert.Equal(t, 0, compare.Lag(a, a, rate, compare.MaxLag))
+	// not correlated
+	assert.Equal(t, 0, compare.Lag(sine(440, 1).Mono(), sine(466.16, 1).Mono(), rate, compare.MaxLag))
+}
+
+func TestNotes(t *testing.T) {
+	labels, err := compare.DecodeLabels(strings.NewReader(`0 1000000 pau
+1000000 1500000 k
+1500000 3000000 a
+3000000 4000000 N
+4000000 4500000 pau
+4500000 5000000 s
+5000000 6000000 i
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, [][]int{{1, 2}, {3}, {5, 6}}, compare.Notes(labels))
+
+	var (
+		a = make([]float64, 120)
+		b = make([]float64, 120)
+	)
+	for i := range a {
+		a[i] = 440
+		b[i] = 440
+	}
+	for i := 60; i < 80; i++ {
+		b[i] = 880 // N
+	}
+	for i := 100; i < 120; i++ {
+		b[i] = 0 // i
+	}
+	got := compare.NewNoteDiffs(labels, a, b, 0)
+	if !assert.Len(t, got, 3) {
+		return
+	}
+	assert.Equal(t, "k a", got[0].Lyric)
+	assert.InDelta(t, 0.1, got[0].Start, 1e-9)
+	assert.Equal(t, 0.0, got[0].Cents)
+	assert.InDelta(t, 1200, got[1].Cents, 1e-9)
+	assert.Equal(t, 0.0, got[2].B)
+	assert.Equal(t, 0.0, got[2].Cents)
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		labels = []*compare.Label{{Start: 0, End: 0.2, Phoneme: "pau"}, {Start: 0.2, End: 1, Phoneme: "a"}}
+		a      = &compare.Render{Wav: sine(440, 1), F0: make([]float64, 200), Labels: labels}
+	)
+	for i := range a.F0 {
+		a.F0[i] = 440
+	}
+
+	got, residual := compare.NewDiff(a, a, 0.01)
+	assert.Equal(t, 0.0, got.Lag)
+	assert.Equal(t, -150.0, got.RMS.Diff)
+	assert.Equal(t, 0.0, compare.RMS(residual.Data[0]))
+	if assert.Len(t, got.Notes, 1) {
+		assert.Equal(t, 0.0, got.Notes[0].Cents)
+	}
+	if assert.NotNil(t, got.Timing) {
+		assert.Empty(t, got.Timing.Changed)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, got.Write(&buf, 10))
+	assert.Contains(t, buf.String(), "notes: 0 of 1 differ more than 10 cents")
+}
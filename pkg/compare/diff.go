@@ -0,0 +1,211 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+)
+
+// NewResultRenderPaths returns the locations of the outputs in the result directory.
+// F0 and Labels are empty if missing.
+func NewResultRenderPaths(resultDir, basename string) *RenderPaths {
+	p := &RenderPaths{
+		Wav: filepath.Join(resultDir, basename+".wav"),
+	}
+	if x := filepath.Join(resultDir, basename+".f0"); pathx.Exist(x) == pathx.Efile {
+		p.F0 = x
+	}
+	if x := filepath.Join(resultDir, pathx.TimingLabelFileName(basename)); pathx.Exist(x) == pathx.Efile {
+		p.Labels = x
+	}
+	return p
+}
+
+// Diff is the differences of the renders aligned.
+type Diff struct {
+	// Lag is the offset of b against a in seconds to align the wavs, positive means b is delayed.
+	Lag float64 `json:"lag"`
+	RMS RMSDiff `json:"rms"`
+	// Notes are the f0 differences per note, nil if the f0 or the labels are not available.
+	Notes []*NoteDiff `json:"notes,omitempty"`
+	// Timing is the difference of the labels, nil if the labels of either are not available.
+	// The labels of b are shifted by the lag.
+	Timing *TimingShift `json:"timing,omitempty"`
+}
+
+// minDBFS is the level of the silence.
+const minDBFS = -150
+
+// RMSDiff is the levels of the wavs and the residual in dBFS, floored at -150dBFS.
+type RMSDiff struct {
+	A    float64 `json:"a"`
+	B    float64 `json:"b"`
+	Diff float64 `json:"diff"`
+}
+
+// NoteDiff is the f0 difference of a note.
+type NoteDiff struct {
+	Index int `json:"index"`
+	// Lyric is the phonemes of the note.
+	Lyric string `json:"lyric"`
+	// Start is the start time of the note of a in seconds.
+	Start float64 `json:"start"`
+	// End is the end time of the note of a in seconds.
+	End float64 `json:"end"`
+	// A is the mean f0 of a in Hz, 0 if unvoiced.
+	A float64 `json:"a"`
+	// B is the mean f0 of b in Hz, 0 if unvoiced.
+	B float64 `json:"b"`
+	// Cents is the interval from A to B, 0 if either is unvoiced.
+	Cents float64 `json:"cents"`
+}
+
+// NewDiff aligns the render b to a and compares them.
+// Returns the diff and the residual wav, a minus aligned b.
+// timingTolerance is the shift of a label in seconds to be reported as changed.
+func NewDiff(a, b *Render, timingTolerance float64) (*Diff, *audio.Wav) {
+	bw := b.Wav
+	if bw.Format.SampleRate != a.Wav.Format.SampleRate {
+		bw = bw.Clone()
+		bw.Resample(a.Wav.Format.SampleRate)
+	}
+	var (
+		rate     = a.Wav.Format.SampleRate
+		lag      = Lag(a.Wav.Mono(), bw.Mono(), rate, MaxLag)
+		residual = Residual(a.Wav, bw, lag)
+		d        = &Diff{
+			Lag: float64(lag) / float64(rate),
+			RMS: RMSDiff{
+				A:    max(audio.AmplitudeToDB(RMS(a.Wav.Mono())), minDBFS),
+				B:    max(audio.AmplitudeToDB(RMS(bw.Mono())), minDBFS),
+				Diff: max(audio.AmplitudeToDB(RMS(residual.Data[0])), minDBFS),
+			},
+		}
+	)
+	if a.F0 != nil && b.F0 != nil && a.Labels != nil {
+		d.Notes = NewNoteDiffs(a.Labels, a.F0, b.F0, d.Lag)
+	}
+	if a.Labels != nil && b.Labels != nil {
+		shifted := make([]*Label, len(b.Labels))
+		for i, x := range b.Labels {
+			shifted[i] = &Label{
+				Start:   x.Start - d.Lag,
+				End:     x.End - d.Lag,
+				Phoneme: x.Phoneme,
+			}
+		}
+		d.Timing = NewTimingShift(a.Labels, shifted, timingTolerance)
+	}
+	return d, residual
+}
+
+// vowels are the phonemes that carry the pitch of the notes, uppercases are devoiced.
+var vowels = []string{"a", "i", "u", "e", "o", "N", "A", "I", "U", "E", "O"}
+
+// Notes groups the labels into notes, a note is the consonants followed by a vowel.
+// Returns the indices of the labels of the notes, the last label of a note is the vowel.
+func Notes(labels []*Label) [][]int {
+	var (
+		xs   [][]int
+		note []int
+	)
+	for i, x := range labels {
+		note = append(note, i)
+		switch {
+		case slices.Contains(vowels, x.Phoneme):
+			xs = append(xs, note)
+			note = nil
+		case x.Phoneme == "pau" || x.Phoneme == "sil" || x.Phoneme == "br":
+			note = nil
+		}
+	}
+	return xs
+}
+
+// NewNoteDiffs compares the mean f0 of the notes of the labels.
+// The frames of b are shifted by lag seconds.
+func NewNoteDiffs(labels []*Label, a, b []float64, lag float64) []*NoteDiff {
+	var (
+		notes = Notes(labels)
+		xs    = make([]*NoteDiff, len(notes))
+		shift = int(math.Round(lag / F0FramePeriod))
+	)
+	for i, note := range notes {
+		var (
+			phonemes = make([]string, len(note))
+			vowel    = labels[note[len(note)-1]]
+		)
+		for j, k := range note {
+			phonemes[j] = labels[k].Phoneme
+		}
+		// the pitch of the note is the pitch of the vowel
+		start := int(vowel.Start / F0FramePeriod)
+		end := int(vowel.End / F0FramePeriod)
+		x := &NoteDiff{
+			Index: i,
+			Lyric: strings.Join(phonemes, " "),
+			Start: labels[note[0]].Start,
+			End:   vowel.End,
+			A:     meanVoiced(a, start, end),
+			B:     meanVoiced(b, start+shift, end+shift),
+		}
+		if x.A > 0 && x.B > 0 {
+			x.Cents = Cents(x.A, x.B)
+		}
+		xs[i] = x
+	}
+	return xs
+}
+
+// meanVoiced returns the mean of the positive values of f0[start:end], 0 if none.
+func meanVoiced(f0 []float64, start, end int) float64 {
+	var (
+		sum float64
+		n   int
+	)
+	for i := max(start, 0); i < min(end, len(f0)); i++ {
+		if f0[i] > 0 {
+			sum += f0[i]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// Write writes the summary, the notes whose f0 differ more than minCents and the changed labels.
+func (d Diff) Write(w io.Writer, minCents float64) error {
+	lines := []string{
+		fmt.Sprintf("lag: %+.4fs", d.Lag),
+		fmt.Sprintf("rms: a %.2fdBFS b %.2fdBFS diff %.2fdBFS", d.RMS.A, d.RMS.B, d.RMS.Diff),
+	}
+	if d.Notes != nil {
+		var changed []string
+		for _, x := range d.Notes {
+			if math.Abs(x.Cents) > minCents {
+				changed = append(changed, fmt.Sprintf("  %d\t%.3f\t%s\t%.1fHz -> %.1fHz\t%+.1f cents", x.Index, x.Start, x.Lyric, x.A, x.B, x.Cents))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("notes: %d of %d differ more than %g cents", len(changed), len(d.Notes), minCents))
+		lines = append(lines, changed...)
+	}
+	if t := d.Timing; t != nil {
+		lines = append(lines, fmt.Sprintf("timing: mean shift %.4fs max shift %.4fs, %d of %d labels changed", t.MeanShift, t.MaxShift, len(t.Changed), t.Labels))
+		if t.PhonemeMismatch {
+			lines = append(lines, "  phonemes changed")
+		}
+		for _, x := range t.Changed {
+			lines = append(lines, fmt.Sprintf("  %d\t%s %.3f -> %s %.3f\t%+.4fs", x.Index, x.A.Phoneme, x.A.Start, x.B.Phoneme, x.B.Start, x.Shift))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
@@ -0,0 +1,78 @@
+package compare
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+var ErrInvalidF0 = errors.New("InvalidF0")
+
+// F0FramePeriod is the frame period of the f0 files of NEUTRINO in seconds.
+const F0FramePeriod = 0.005
+
+// ReadF0File reads the f0 file of NEUTRINO, little-endian float64 Hz per frame.
+// 0 means unvoiced.
+func ReadF0File(path string) ([]float64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeF0(b)
+}
+
+func DecodeF0(b []byte) ([]float64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("%w: size %d is not a multiple of 8", ErrInvalidF0, len(b))
+	}
+	r := make([]float64, len(b)/8)
+	for i := range r {
+		r[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return r, nil
+}
+
+// F0Deviation is the difference of the pitch of the frames voiced in both.
+type F0Deviation struct {
+	// MeanCents is the mean of the absolute differences in cents.
+	MeanCents float64 `json:"meanCents"`
+	// MaxCents is the max of the absolute differences in cents.
+	MaxCents float64 `json:"maxCents"`
+	// VoicedFrames is the number of the frames compared.
+	VoicedFrames int `json:"voicedFrames"`
+	// VoicingMismatches is the number of the frames voiced in only one of them.
+	VoicingMismatches int `json:"voicingMismatches"`
+}
+
+// NewF0Deviation compares the f0 frame by frame up to the shorter one.
+func NewF0Deviation(a, b []float64) *F0Deviation {
+	var (
+		d   F0Deviation
+		sum float64
+	)
+	for i := range min(len(a), len(b)) {
+		x, y := a[i], b[i]
+		switch {
+		case x <= 0 && y <= 0:
+			continue
+		case x <= 0 || y <= 0:
+			d.VoicingMismatches++
+			continue
+		}
+		c := math.Abs(Cents(x, y))
+		sum += c
+		d.MaxCents = max(d.MaxCents, c)
+		d.VoicedFrames++
+	}
+	if d.VoicedFrames > 0 {
+		d.MeanCents = sum / float64(d.VoicedFrames)
+	}
+	return &d
+}
+
+// Cents returns the interval from a to b in cents.
+func Cents(a, b float64) float64 {
+	return 1200 * math.Log2(b/a)
+}
@@ -0,0 +1,127 @@
+package compare
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidLabel = errors.New("InvalidLabel")
+
+// labelUnitsPerSecond is the number of the time units of the labels per second, 100ns.
+const labelUnitsPerSecond = 1e7
+
+// Label is a line of the timing label, START END PHONEME.
+type Label struct {
+	// Start is the start time in seconds.
+	Start float64 `json:"start"`
+	// End is the end time in seconds.
+	End     float64 `json:"end"`
+	Phoneme string  `json:"phoneme"`
+}
+
+// ReadLabelFile reads the timing label of NEUTRINO.
+func ReadLabelFile(path string) ([]*Label, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeLabels(bytes.NewReader(b))
+}
+
+func DecodeLabels(r io.Reader) ([]*Label, error) {
+	var (
+		xs      []*Label
+		scanner = bufio.NewScanner(r)
+		lineNo  int
+	)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidLabel, lineNo, line)
+		}
+		start, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: start: %w", ErrInvalidLabel, lineNo, err)
+		}
+		end, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: end: %w", ErrInvalidLabel, lineNo, err)
+		}
+		xs = append(xs, &Label{
+			Start:   float64(start) / labelUnitsPerSecond,
+			End:     float64(end) / labelUnitsPerSecond,
+			Phoneme: strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return xs, nil
+}
+
+// TimingShift is the difference of the phoneme timings.
+type TimingShift struct {
+	// MeanShift is the mean of the absolute differences of the start times in seconds.
+	MeanShift float64 `json:"meanShift"`
+	// MaxShift is the max of the absolute differences of the start times in seconds.
+	MaxShift float64 `json:"maxShift"`
+	// Labels is the number of the labels compared.
+	Labels int `json:"labels"`
+	// PhonemeMismatch is true if the phoneme sequences differ.
+	PhonemeMismatch bool `json:"phonemeMismatch"`
+	// Changed are the pairs of the labels whose start times are shifted more than the tolerance.
+	Changed []*LabelShift `json:"changed,omitempty"`
+}
+
+// LabelShift is a pair of the labels at the same position.
+type LabelShift struct {
+	Index int    `json:"index"`
+	A     *Label `json:"a"`
+	B     *Label `json:"b"`
+	// Shift is the difference of the start times in seconds, b - a.
+	Shift float64 `json:"shift"`
+}
+
+// NewTimingShift compares the labels position by position up to the shorter one.
+// tolerance is the shift in seconds to be reported as changed.
+func NewTimingShift(a, b []*Label, tolerance float64) *TimingShift {
+	var (
+		s   = TimingShift{PhonemeMismatch: len(a) != len(b)}
+		sum float64
+	)
+	for i := range min(len(a), len(b)) {
+		x, y := a[i], b[i]
+		if x.Phoneme != y.Phoneme {
+			s.PhonemeMismatch = true
+		}
+		shift := y.Start - x.Start
+		d := math.Abs(shift)
+		sum += d
+		s.MaxShift = max(s.MaxShift, d)
+		s.Labels++
+		if d > tolerance || x.Phoneme != y.Phoneme {
+			s.Changed = append(s.Changed, &LabelShift{
+				Index: i,
+				A:     x,
+				B:     y,
+				Shift: shift,
+			})
+		}
+	}
+	if s.Labels > 0 {
+		s.MeanShift = sum / float64(s.Labels)
+	}
+	return &s
+}
@@ -0,0 +1,98 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const RegressionJSONFileName = "regress.json"
+
+// Target is the NEUTRINO installation and the singers to render with.
+type Target struct {
+	NeutrinoDir  string `json:"neutrinoDir"`
+	Model        string `json:"model"`
+	SupportModel string `json:"supportModel,omitempty"`
+}
+
+func (t Target) String() string {
+	s := t.NeutrinoDir + " " + t.Model
+	if t.SupportModel != "" {
+		s += "+" + t.SupportModel
+	}
+	return s
+}
+
+// Regression is the report of the renders of a corpus with the baseline and the candidate.
+type Regression struct {
+	Baseline  Target             `json:"baseline"`
+	Candidate Target             `json:"candidate"`
+	Threshold Threshold          `json:"threshold"`
+	Entries   []*RegressionEntry `json:"entries"`
+}
+
+// RegressionEntry is the comparison of a score.
+type RegressionEntry struct {
+	Score     string  `json:"score"`
+	Baseline  string  `json:"baseline,omitempty"`  // result directory
+	Candidate string  `json:"candidate,omitempty"` // result directory
+	Result    *Result `json:"result,omitempty"`
+	// Exceeded are the reasons why the result is beyond the threshold.
+	Exceeded []string `json:"exceeded,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Flagged returns true if the score failed to render or changed beyond the threshold.
+func (e RegressionEntry) Flagged() bool { return e.Error != "" || len(e.Exceeded) > 0 }
+
+// Flagged returns the number of the flagged entries.
+func (r Regression) Flagged() int {
+	var n int
+	for _, x := range r.Entries {
+		if x.Flagged() {
+			n++
+		}
+	}
+	return n
+}
+
+func (r Regression) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes a line per score, STATUS SCORE DURATION_DIFF F0_MEAN_CENTS SPECTRAL_DISTANCE TIMING_MAX_SHIFT REASONS,
+// separated by tab.
+func (r Regression) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "baseline: %s\ncandidate: %s\n", r.Baseline, r.Candidate); err != nil {
+		return err
+	}
+	for _, x := range r.Entries {
+		var line string
+		switch {
+		case x.Error != "":
+			line = fmt.Sprintf("ERROR\t%s\t-\t-\t-\t-\t%s", x.Score, strings.ReplaceAll(x.Error, "\n", ": "))
+		default:
+			status := "OK"
+			if x.Flagged() {
+				status = "CHANGED"
+			}
+			f0, timing := "-", "-"
+			if x.Result.F0 != nil {
+				f0 = fmt.Sprintf("%.1f", x.Result.F0.MeanCents)
+			}
+			if x.Result.Timing != nil {
+				timing = fmt.Sprintf("%.3f", x.Result.Timing.MaxShift)
+			}
+			line = fmt.Sprintf("%s\t%s\t%+.3f\t%s\t%.2f\t%s\t%s",
+				status, x.Score, x.Result.Duration.Diff, f0, x.Result.SpectralDistance, timing, strings.Join(x.Exceeded, ", "))
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, "\t")); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d scores flagged\n", r.Flagged(), len(r.Entries))
+	return err
+}
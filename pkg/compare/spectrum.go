@@ -0,0 +1,99 @@
+package compare
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+)
+
+const (
+	spectrumSize = 2048
+	spectrumHop  = 512
+	// spectrumFloor is the minimum power not to take the log of zero, -100dB.
+	spectrumFloor = 1e-10
+)
+
+// SpectralDistance returns the mean log-spectral distance in dB of the frames of the wavs.
+// The frames are compared up to the shorter wav, b is resampled to the sample rate of a.
+// Returns 0 if the wavs are shorter than a frame.
+func SpectralDistance(a, b *audio.Wav) float64 {
+	if b.Format.SampleRate != a.Format.SampleRate {
+		b = b.Clone()
+		b.Resample(a.Format.SampleRate)
+	}
+	var (
+		x      = a.Mono()
+		y      = b.Mono()
+		n      = min(len(x), len(y))
+		window = hann(spectrumSize)
+		sum    float64
+		frames int
+	)
+	for start := 0; start+spectrumSize <= n; start += spectrumHop {
+		px := powerSpectrum(x[start:start+spectrumSize], window)
+		py := powerSpectrum(y[start:start+spectrumSize], window)
+		var d float64
+		for i := range px {
+			v := 10 * math.Log10(max(px[i], spectrumFloor)/max(py[i], spectrumFloor))
+			d += v * v
+		}
+		sum += math.Sqrt(d / float64(len(px)))
+		frames++
+	}
+	if frames == 0 {
+		return 0
+	}
+	return sum / float64(frames)
+}
+
+func hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+	return w
+}
+
+// powerSpectrum returns the power of the bins from 0 to the nyquist frequency.
+// len(x) must be a power of 2.
+func powerSpectrum(x, window []float64) []float64 {
+	re := make([]float64, len(x))
+	im := make([]float64, len(x))
+	for i, v := range x {
+		re[i] = v * window[i]
+	}
+	fft(re, im)
+	p := make([]float64, len(x)/2+1)
+	for i := range p {
+		p[i] = re[i]*re[i] + im[i]*im[i]
+	}
+	return p
+}
+
+// fft is the in-place iterative radix-2 fast fourier transform.
+func fft(re, im []float64) {
+	n := len(re)
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range n {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		theta := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := range half {
+				wr, wi := math.Cos(theta*float64(k)), math.Sin(theta*float64(k))
+				i, j := start+k, start+k+half
+				tr := wr*re[j] - wi*im[j]
+				ti := wr*im[j] + wi*re[j]
+				re[j], im[j] = re[i]-tr, im[i]-ti
+				re[i], im[i] = re[i]+tr, im[i]+ti
+			}
+		}
+	}
+}
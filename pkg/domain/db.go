@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -8,6 +9,10 @@ type Process struct {
 	ID          int
 	RequestID   string
 	Status      ProcessStatus
+	Kind        ProcessKind
+	GroupID     *int // id of the ensemble process the voice belongs to
+	ParentID    *int // id of the process rerun by this process
+	Starred     bool // kept by the retention policies
 	DetailsID   int
 	StartedAt   *time.Time
 	CompletedAt *time.Time
@@ -23,6 +28,7 @@ const (
 	ProcessStatusRunning
 	ProcessStatusSucceed
 	ProcessStatusFailed
+	ProcessStatusCancelled
 )
 
 func (p ProcessStatus) String() string {
@@ -35,11 +41,23 @@ func (p ProcessStatus) String() string {
 		return "succeed"
 	case ProcessStatusFailed:
 		return "failed"
+	case ProcessStatusCancelled:
+		return "cancelled"
 	default:
 		return "unknown"
 	}
 }
 
+// Done returns true if the process will not run anymore.
+func (p ProcessStatus) Done() bool {
+	switch p {
+	case ProcessStatusSucceed, ProcessStatusFailed, ProcessStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
 func ProcessStatusFromString(s string) (ProcessStatus, bool) {
 	switch s {
 	case "pending":
@@ -50,15 +68,42 @@ func ProcessStatusFromString(s string) (ProcessStatus, bool) {
 		return ProcessStatusSucceed, true
 	case "failed":
 		return ProcessStatusFailed, true
+	case "cancelled":
+		return ProcessStatusCancelled, true
 	default:
 		return ProcessStatusPending, false
 	}
 }
 
+// master_process_kinds
+type ProcessKind int
+
+const (
+	ProcessKindRender ProcessKind = iota + 1
+	ProcessKindEnsemble
+	ProcessKindSweep
+)
+
+func (p ProcessKind) String() string {
+	switch p {
+	case ProcessKindRender:
+		return "render"
+	case ProcessKindEnsemble:
+		return "ensemble"
+	case ProcessKindSweep:
+		return "sweep"
+	default:
+		return "unknown"
+	}
+}
+
 type ProcessDetails struct {
 	ID             int
-	Command        *string // raw command
+	Command        *string         // raw command
+	Options        json.RawMessage // json of the options of the job, keyed by ctl.JobKeys
 	Title          string
+	Basename       string // original musicxml file name except extension, the results are named after it
+	Notes          string
 	ScoreObjectID  int  // score object
 	LogObjectID    *int // log object
 	ResultObjectID *int // result object
@@ -66,6 +111,13 @@ type ProcessDetails struct {
 	UpdatedAt      time.Time
 }
 
+type ProcessTag struct {
+	ID        int
+	ProcessID int
+	Name      string
+	CreatedAt time.Time
+}
+
 // master_object_types
 type ObjectType int
 
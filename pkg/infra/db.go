@@ -140,6 +140,23 @@ type Execer interface {
 var _ Execer = &Conn[int]{}
 
 func (c *Conn[T]) Exec(ctx context.Context, req *ExecRequest) (*ExecResponse, error) {
+	rs, err := c.ExecTx(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rs[0], nil
+}
+
+// TxExecer executes the requests in a single transaction.
+type TxExecer interface {
+	ExecTx(ctx context.Context, req ...*ExecRequest) ([]*ExecResponse, error)
+}
+
+var _ TxExecer = &Conn[int]{}
+
+// ExecTx executes the requests in order in a single transaction.
+// Rolls back all of them if any request fails.
+func (c *Conn[T]) ExecTx(ctx context.Context, req ...*ExecRequest) ([]*ExecResponse, error) {
 	conn, err := c.db.Conn(ctx)
 	if err != nil {
 		return nil, err
@@ -153,9 +170,25 @@ func (c *Conn[T]) Exec(ctx context.Context, req *ExecRequest) (*ExecResponse, er
 		return nil, err
 	}
 
+	responses := make([]*ExecResponse, len(req))
+	for i, r := range req {
+		response, err := c.exec(ctx, tx, r)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		responses[i] = response
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
+
+func (*Conn[T]) exec(ctx context.Context, tx *sql.Tx, req *ExecRequest) (*ExecResponse, error) {
 	result, err := tx.ExecContext(ctx, req.Query, req.Args...)
 	if err != nil {
-		_ = tx.Rollback()
 		return nil, err
 	}
 
@@ -171,12 +204,7 @@ func (c *Conn[T]) Exec(ctx context.Context, req *ExecRequest) (*ExecResponse, er
 	}
 
 	if err := req.assert(response); err != nil {
-		_ = tx.Rollback()
 		return nil, err
 	}
-	if err := tx.Commit(); err != nil {
-		return nil, err
-	}
-
 	return response, nil
 }
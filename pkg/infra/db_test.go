@@ -113,4 +113,58 @@ func TestConn(t *testing.T) {
 			Name: "first",
 		}, item)
 	})
+
+	t.Run("tx rollback", func(t *testing.T) {
+		_, err := conn.ExecTx(ctx,
+			&infra.ExecRequest{
+				Query: "delete from test where id = ?;",
+				Args: []any{
+					1,
+				},
+				AssertResponse: infra.AssertRowsAffected(1),
+			},
+			&infra.ExecRequest{
+				Query: "delete from test where id = ?;",
+				Args: []any{
+					2,
+				},
+				AssertResponse: infra.AssertRowsAffected(1),
+			},
+		)
+		assert.ErrorIs(t, err, infra.ErrRowsAffected)
+		got, err := conn.Query(ctx, &infra.QueryRequest[Table]{
+			Query: "select id, name from test;",
+			Scan:  scan,
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, len(got.Items), "rolled back")
+	})
+
+	t.Run("tx", func(t *testing.T) {
+		got, err := conn.ExecTx(ctx,
+			&infra.ExecRequest{
+				Query: "insert into test (id, name) values (?, ?);",
+				Args: []any{
+					2,
+					"second",
+				},
+			},
+			&infra.ExecRequest{
+				Query: "delete from test where id = ?;",
+				Args: []any{
+					1,
+				},
+			},
+		)
+		if !assert.Nil(t, err) {
+			return
+		}
+		if !assert.Equal(t, 2, len(got)) {
+			return
+		}
+		assert.Equal(t, int64(1), got[0].RowsAffected)
+		assert.Equal(t, int64(1), got[1].RowsAffected)
+	})
 }
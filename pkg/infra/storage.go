@@ -3,11 +3,15 @@ package infra
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/berquerant/pneutrinoutil/pkg/domain"
 	"github.com/berquerant/pneutrinoutil/pkg/pathx"
@@ -16,6 +20,8 @@ import (
 type Object interface {
 	ObjectCreator
 	ObjectGetter
+	ObjectDeleter
+	ObjectLister
 }
 
 type CreateObjectRequest struct {
@@ -39,6 +45,36 @@ type ObjectGetter interface {
 	GetObject(ctx context.Context, req *GetObjectRequest) (*domain.StorageObject, error)
 }
 
+type DeleteObjectRequest struct {
+	Bucket string
+	Path   string
+}
+
+type ObjectDeleter interface {
+	// DeleteObject deletes the object.
+	// Deleting the missing object is not an error.
+	DeleteObject(ctx context.Context, req *DeleteObjectRequest) error
+}
+
+type ListObjectsRequest struct {
+	Bucket string
+	Prefix string
+}
+
+type ListObjectsElement struct {
+	Path      string
+	SizeBytes uint64
+}
+
+type ListObjectsResponse struct {
+	Objects []*ListObjectsElement
+}
+
+type ObjectLister interface {
+	// ListObjects lists the objects whose paths start with the prefix.
+	ListObjects(ctx context.Context, req *ListObjectsRequest) (*ListObjectsResponse, error)
+}
+
 var (
 	_ Object = &S3{}
 )
@@ -100,6 +136,41 @@ func (s *S3) GetObject(ctx context.Context, req *GetObjectRequest) (*domain.Stor
 	}, nil
 }
 
+func (s *S3) DeleteObject(ctx context.Context, req *DeleteObjectRequest) error {
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: new(req.Bucket),
+		Key:    new(req.Path),
+	}); err != nil {
+		return fmt.Errorf("%w: s3 delete object bucket=%s, path=%s", err, req.Bucket, req.Path)
+	}
+	return nil
+}
+
+func (s *S3) ListObjects(ctx context.Context, req *ListObjectsRequest) (*ListObjectsResponse, error) {
+	var (
+		objects []*ListObjectsElement
+		pages   = s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+			Bucket: new(req.Bucket),
+			Prefix: new(req.Prefix),
+		})
+	)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: s3 list objects bucket=%s, prefix=%s", err, req.Bucket, req.Prefix)
+		}
+		for _, x := range page.Contents {
+			objects = append(objects, &ListObjectsElement{
+				Path:      aws.ToString(x.Key),
+				SizeBytes: uint64(aws.ToInt64(x.Size)),
+			})
+		}
+	}
+	return &ListObjectsResponse{
+		Objects: objects,
+	}, nil
+}
+
 var (
 	_ Object = &FileSystem{}
 )
@@ -154,3 +225,62 @@ func (f *FileSystem) CreateObject(_ context.Context, req *CreateObjectRequest) (
 		SizeBytes: uint64(sizeBytes),
 	}, nil
 }
+
+func (f *FileSystem) DeleteObject(_ context.Context, req *DeleteObjectRequest) error {
+	path := filepath.Join(f.rootDir, req.Bucket, req.Path)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: filesystem delete object bucket=%s, path=%s", err, req.Bucket, req.Path)
+	}
+	// remove the empty parent directories
+	bucketDir := filepath.Join(f.rootDir, req.Bucket)
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, bucketDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *FileSystem) ListObjects(_ context.Context, req *ListObjectsRequest) (*ListObjectsResponse, error) {
+	bucketDir := filepath.Join(f.rootDir, req.Bucket)
+	// walk the deepest directory containing the prefix
+	dir := filepath.Join(bucketDir, req.Prefix)
+	if req.Prefix != "" && !strings.HasSuffix(req.Prefix, "/") {
+		dir = filepath.Dir(dir)
+	}
+
+	var objects []*ListObjectsElement
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, req.Prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &ListObjectsElement{
+			Path:      rel,
+			SizeBytes: uint64(info.Size()),
+		})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("%w: filesystem list objects bucket=%s, prefix=%s", err, req.Bucket, req.Prefix)
+	}
+	return &ListObjectsResponse{
+		Objects: objects,
+	}, nil
+}
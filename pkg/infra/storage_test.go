@@ -115,4 +115,82 @@ func testObject(t *testing.T, ctx context.Context, s infra.Object) {
 		}
 		assert.Equal(t, content, string(buf))
 	})
+	t.Run("list", func(t *testing.T) {
+		for _, tc := range []struct {
+			title  string
+			prefix string
+			want   []*infra.ListObjectsElement
+		}{
+			{
+				title:  "path",
+				prefix: path,
+				want: []*infra.ListObjectsElement{
+					{
+						Path:      path,
+						SizeBytes: uint64(len([]byte(content))),
+					},
+				},
+			},
+			{
+				title:  "dir",
+				prefix: "dir1/",
+				want: []*infra.ListObjectsElement{
+					{
+						Path:      path,
+						SizeBytes: uint64(len([]byte(content))),
+					},
+				},
+			},
+			{
+				title:  "partial name",
+				prefix: "dir1/ob",
+				want: []*infra.ListObjectsElement{
+					{
+						Path:      path,
+						SizeBytes: uint64(len([]byte(content))),
+					},
+				},
+			},
+			{
+				title:  "missing",
+				prefix: "dir2/",
+			},
+		} {
+			t.Run(tc.title, func(t *testing.T) {
+				got, err := s.ListObjects(ctx, &infra.ListObjectsRequest{
+					Bucket: bucket,
+					Prefix: tc.prefix,
+				})
+				if !assert.Nil(t, err) {
+					return
+				}
+				assert.Equal(t, tc.want, got.Objects)
+			})
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.Nil(t, s.DeleteObject(ctx, &infra.DeleteObjectRequest{
+			Bucket: bucket,
+			Path:   path,
+		}))
+		_, err := s.GetObject(ctx, &infra.GetObjectRequest{
+			Bucket: bucket,
+			Path:   path,
+		})
+		assert.NotNil(t, err)
+		got, err := s.ListObjects(ctx, &infra.ListObjectsRequest{
+			Bucket: bucket,
+			Prefix: "dir1/",
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, got.Objects)
+	})
+	t.Run("delete missing", func(t *testing.T) {
+		assert.Nil(t, s.DeleteObject(ctx, &infra.DeleteObjectRequest{
+			Bucket: bucket,
+			Path:   path,
+		}))
+	})
 }
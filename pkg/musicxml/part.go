@@ -0,0 +1,138 @@
+package musicxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrInvalidScore = errors.New("InvalidScore")
+	ErrPartNotFound = errors.New("PartNotFound")
+)
+
+// Part is a score-part in the part-list.
+type Part struct {
+	ID   string `xml:"id,attr" json:"id"`
+	Name string `xml:"part-name" json:"name"`
+}
+
+type scorePartwise struct {
+	XMLName xml.Name
+	Parts   []*Part `xml:"part-list>score-part"`
+}
+
+// Parts returns the parts of the partwise score.
+func Parts(score []byte) ([]*Part, error) {
+	var s scorePartwise
+	if err := xml.Unmarshal(score, &s); err != nil {
+		return nil, errors.Join(ErrInvalidScore, err)
+	}
+	if s.XMLName.Local != "score-partwise" {
+		return nil, fmt.Errorf("%w: %s is not supported, only score-partwise", ErrInvalidScore, s.XMLName.Local)
+	}
+	return s.Parts, nil
+}
+
+// FindPart returns the part whose id or name is part.
+func FindPart(parts []*Part, part string) (*Part, error) {
+	for _, p := range parts {
+		if p.ID == part {
+			return p, nil
+		}
+	}
+	for _, p := range parts {
+		if p.Name == part {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPartNotFound, part)
+}
+
+// ExtractPart returns the score that contains only the part, specified by id or name.
+// Other bytes of the score are kept as they are.
+func ExtractPart(score []byte, part string) ([]byte, error) {
+	parts, err := Parts(score)
+	if err != nil {
+		return nil, err
+	}
+	target, err := FindPart(parts, part)
+	if err != nil {
+		return nil, err
+	}
+
+	type span struct {
+		start, end int64
+	}
+	var (
+		d     = xml.NewDecoder(bytes.NewReader(score))
+		drops []span
+		depth int
+	)
+	for {
+		offset := d.InputOffset()
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(ErrInvalidScore, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// score-partwise > part, score-partwise > part-list > score-part
+			isPart := depth == 2 && t.Name.Local == "part" || depth == 3 && t.Name.Local == "score-part"
+			if !isPart || attr(t, "id") == target.ID {
+				continue
+			}
+			if err := skip(d); err != nil {
+				return nil, errors.Join(ErrInvalidScore, err)
+			}
+			depth--
+			drops = append(drops, span{start: offset, end: d.InputOffset()})
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	var (
+		buf  bytes.Buffer
+		last int64
+	)
+	for _, x := range drops {
+		buf.Write(score[last:x.start])
+		last = x.end
+	}
+	buf.Write(score[last:])
+	return buf.Bytes(), nil
+}
+
+// skip reads tokens until the end of the current element.
+func skip(d *xml.Decoder) error {
+	depth := 1
+	for depth > 0 {
+		tok, err := d.RawToken()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package musicxml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/musicxml"
+	"github.com/stretchr/testify/assert"
+)
+
+const score = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">
+<score-partwise version="4.0">
+  <part-list>
+    <score-part id="P1"><part-name>Soprano</part-name></score-part>
+    <score-part id="P2"><part-name>Alto</part-name></score-part>
+  </part-list>
+  <part id="P1"><measure number="1"><note><pitch><step>E</step></pitch></note></measure></part>
+  <part id="P2"><measure number="1"><note><pitch><step>C</step></pitch></note></measure></part>
+</score-partwise>
+`
+
+func TestParts(t *testing.T) {
+	got, err := musicxml.Parts([]byte(score))
+	assert.Nil(t, err)
+	assert.Equal(t, []*musicxml.Part{
+		{ID: "P1", Name: "Soprano"},
+		{ID: "P2", Name: "Alto"},
+	}, got)
+
+	_, err = musicxml.Parts([]byte(`<score-timewise></score-timewise>`))
+	assert.ErrorIs(t, err, musicxml.ErrInvalidScore)
+}
+
+func TestExtractPart(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		part  string
+		keep  string
+		drop  string
+		err   error
+	}{
+		{title: "by id", part: "P2", keep: "<step>C</step>", drop: "<step>E</step>"},
+		{title: "by name", part: "Soprano", keep: "<step>E</step>", drop: "<step>C</step>"},
+		{title: "not found", part: "Tenor", err: musicxml.ErrPartNotFound},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := musicxml.ExtractPart([]byte(score), tc.part)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			s := string(got)
+			assert.Contains(t, s, tc.keep)
+			assert.NotContains(t, s, tc.drop)
+			assert.True(t, strings.HasPrefix(s, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE score-partwise`))
+
+			parts, err := musicxml.Parts(got)
+			assert.Nil(t, err)
+			assert.Len(t, parts, 1)
+		})
+	}
+}
@@ -80,3 +80,13 @@ func ParseResultElement(s string) (*ResultElement, error) {
 		Salt:      salt,
 	}, nil
 }
+
+// TimingLabelFileName returns the name of the timing label copied into the result directory.
+func TimingLabelFileName(basename string) string {
+	return basename + ".timing.lab"
+}
+
+// MixFileName returns the name of the wav mixed with the accompaniment.
+func MixFileName(basename string) string {
+	return basename + ".mix.wav"
+}
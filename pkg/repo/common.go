@@ -1,5 +1,10 @@
 package repo
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type Range[T any] struct {
 	Left  *T
 	Right *T
@@ -11,3 +16,18 @@ func NewRange[T any](left, right *T) Range[T] {
 		Right: right,
 	}
 }
+
+// nullJSON returns nil to store NULL if b is empty.
+func nullJSON(b json.RawMessage) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes the wildcards of LIKE in s.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+)
+
+type DeleteProcessesRequest struct {
+	ProcessIDs []int
+	DetailsIDs []int
+	ObjectIDs  []int
+	// PrunedProcessIDs are the processes whose objects are deleted.
+	PrunedProcessIDs []int
+}
+
+type ProcessDeleter interface {
+	// DeleteProcesses deletes the processes with their tags, the details and the objects in a single transaction.
+	// The children of the processes not deleted are unlinked from them.
+	// Any of them can be empty, e.g. deletes only the objects.
+	// The pruned processes are marked as pruned.
+	DeleteProcesses(ctx context.Context, req *DeleteProcessesRequest) error
+}
+
+var (
+	_ ProcessDeleter = &Deleter{}
+)
+
+func NewDeleter(exec infra.TxExecer) *Deleter {
+	return &Deleter{
+		exec: exec,
+	}
+}
+
+type Deleter struct {
+	exec infra.TxExecer
+}
+
+func (d *Deleter) DeleteProcesses(ctx context.Context, req *DeleteProcessesRequest) error {
+	var reqs []*infra.ExecRequest
+	if len(req.ProcessIDs) > 0 {
+		processIDs := joinIDs(req.ProcessIDs)
+		reqs = append(reqs,
+			&infra.ExecRequest{
+				Query: fmt.Sprintf("update processes set parent_id = null where parent_id in (%[1]s) and id not in (%[1]s);", processIDs),
+			},
+			&infra.ExecRequest{
+				Query: fmt.Sprintf("delete from process_tags where process_id in (%s);", processIDs),
+			},
+			&infra.ExecRequest{
+				// the members and the children have the larger ids than the groups and the parents
+				Query:          fmt.Sprintf("delete from processes where id in (%s) order by id desc;", processIDs),
+				AssertResponse: infra.AssertRowsAffected(int64(len(req.ProcessIDs))),
+			},
+		)
+	}
+	if len(req.DetailsIDs) > 0 {
+		reqs = append(reqs, &infra.ExecRequest{
+			Query:          fmt.Sprintf("delete from process_details where id in (%s);", joinIDs(req.DetailsIDs)),
+			AssertResponse: infra.AssertRowsAffected(int64(len(req.DetailsIDs))),
+		})
+	}
+	if len(req.ObjectIDs) > 0 {
+		reqs = append(reqs, &infra.ExecRequest{
+			Query:          fmt.Sprintf("delete from objects where id in (%s);", joinIDs(req.ObjectIDs)),
+			AssertResponse: infra.AssertRowsAffected(int64(len(req.ObjectIDs))),
+		})
+	}
+	if len(req.PrunedProcessIDs) > 0 {
+		reqs = append(reqs, &infra.ExecRequest{
+			Query: fmt.Sprintf("update processes set pruned_at = current_timestamp where id in (%s);", joinIDs(req.PrunedProcessIDs)),
+		})
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	if _, err := d.exec.ExecTx(ctx, reqs...); err != nil {
+		return fmt.Errorf("%w: delete processes: id=%v, details=%v, objects=%v", err, req.ProcessIDs, req.DetailsIDs, req.ObjectIDs)
+	}
+	return nil
+}
+
+func joinIDs(id []int) string {
+	xs := make([]string, len(id))
+	for i, v := range id {
+		xs[i] = fmt.Sprint(v)
+	}
+	return strings.Join(xs, ",")
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
@@ -27,6 +28,9 @@ type ObjectCreator interface {
 type ObjectGetter interface {
 	GetObject(ctx context.Context, id int) (*domain.Object, error)
 	GetObjectByPath(ctx context.Context, bucket, path string) (*domain.Object, error)
+	GetObjectList(ctx context.Context, id ...int) ([]*domain.Object, error)
+	// GetObjectListByDir returns the objects of the path and under the path.
+	GetObjectListByDir(ctx context.Context, bucket, path string) ([]*domain.Object, error)
 }
 
 func NewObject(query infra.Queryer[domain.Object], exec infra.Execer) *Object {
@@ -71,12 +75,10 @@ func (*Object) scan(f func(...any) error) (*domain.Object, error) {
 }
 
 func (s *Object) GetObjectByPath(ctx context.Context, bucket, path string) (*domain.Object, error) {
-	// hash := s.bucketPathSha256(bucket, path)
 	alog.L().Debug("GetObjectByPath", "bucket", bucket, "path", path)
 	r, err := s.query.Query(ctx, &infra.QueryRequest[domain.Object]{
-		Query: "select id, type_id, bucket, path, size_bytes, created_at, updated_at from objects where bucket = ? and path = ?;",
+		Query: "select id, type_id, bucket, path, size_bytes, created_at, updated_at from objects where bucket_path_sha256 = sha2(concat(?, '___', ?), 256);",
 		Args: []any{
-			// hash,
 			bucket,
 			path,
 		},
@@ -109,19 +111,56 @@ func (s *Object) GetObject(ctx context.Context, id int) (*domain.Object, error)
 	return r.Items[0], nil
 }
 
+func (s *Object) GetObjectList(ctx context.Context, id ...int) ([]*domain.Object, error) {
+	if len(id) == 0 {
+		return nil, nil
+	}
+
+	xs := make([]string, len(id))
+	for i, v := range id {
+		xs[i] = fmt.Sprint(v)
+	}
+	r, err := s.query.Query(ctx, &infra.QueryRequest[domain.Object]{
+		Query: fmt.Sprintf("select id, type_id, bucket, path, size_bytes, created_at, updated_at from objects where id in (%s);",
+			strings.Join(xs, ","),
+		),
+		Scan: s.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get object list: id=%v", err, id)
+	}
+	return r.Items, nil
+}
+
+func (s *Object) GetObjectListByDir(ctx context.Context, bucket, path string) ([]*domain.Object, error) {
+	r, err := s.query.Query(ctx, &infra.QueryRequest[domain.Object]{
+		Query: "select id, type_id, bucket, path, size_bytes, created_at, updated_at from objects where bucket = ? and (path = ? or path like ?) order by id;",
+		Args: []any{
+			bucket,
+			path,
+			escapeLike(path) + "/%",
+		},
+		Scan: s.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get object list by dir: bucket=%s, path=%s", err, bucket, path)
+	}
+	return r.Items, nil
+}
+
+// CteateObject creates the object, or updates the object of the same bucket and path.
+// Writing the same storage object twice, e.g. caching it by concurrent requests, leaves one object.
 func (s *Object) CteateObject(ctx context.Context, req *CreateObjectRequest) (*domain.Object, error) {
 	r, err := s.exec.Exec(ctx, &infra.ExecRequest{
-		Query: "insert into objects (type_id, bucket, path, size_bytes) values (?, ?, ?, ?);",
+		// last_insert_id(id) returns the id of the updated object
+		Query: "insert into objects (type_id, bucket, path, size_bytes) values (?, ?, ?, ?) as new on duplicate key update id = last_insert_id(objects.id), type_id = new.type_id, size_bytes = new.size_bytes;",
 		Args: []any{
 			int(req.Type),
 			req.Bucket,
 			req.Path,
 			req.SizeBytes,
 		},
-		AssertResponse: infra.AssertJoin(
-			infra.AssertRowsAffected(1),
-			infra.AssertLastInserted(),
-		),
+		AssertResponse: infra.AssertLastInserted(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: create object: bucket=%s, path=%s", err, req.Bucket, req.Path)
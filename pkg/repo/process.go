@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
@@ -18,6 +19,9 @@ const (
 type CreateProcessRequest struct {
 	RequestId   string
 	Status      domain.ProcessStatus
+	Kind        domain.ProcessKind
+	GroupId     *int
+	ParentId    *int
 	DetailsId   int
 	StartedAt   *time.Time
 	CompletedAt *time.Time
@@ -32,6 +36,10 @@ type UpdateProcessRequest struct {
 	Status      *domain.ProcessStatus
 	StartedAt   *time.Time
 	CompletedAt *time.Time
+	Starred     *bool
+	// FromStatus restricts the update to the process of the statuses if not empty.
+	// The update fails with infra.ErrRowsAffected if the process is not of them.
+	FromStatus []domain.ProcessStatus
 }
 
 type ProcessUpdater interface {
@@ -42,6 +50,17 @@ type ProcessGetter interface {
 	GetProcess(ctx context.Context, id int) (*domain.Process, error)
 	GetProcessByRequestId(ctx context.Context, rid string) (*domain.Process, error)
 	GetProcessByDetailsList(ctx context.Context, detailsID ...int) ([]*domain.Process, error)
+	GetProcessListByGroup(ctx context.Context, groupID int) ([]*domain.Process, error)
+	GetProcessListByParent(ctx context.Context, parentID int) ([]*domain.Process, error)
+	// GetProcessListForRetention returns the processes the retention policies can delete, the oldest first.
+	GetProcessListForRetention(ctx context.Context, req *GetProcessListForRetentionRequest) ([]*domain.Process, error)
+}
+
+type GetProcessListForRetentionRequest struct {
+	Status          []domain.ProcessStatus // completed statuses
+	CompletedBefore time.Time
+	ExcludePruned   bool
+	Limit           int
 }
 
 type ListProcessRequest struct {
@@ -85,10 +104,29 @@ func (p *Process) UpdateProcess(ctx context.Context, req *UpdateProcessRequest)
 		cols = append(cols, "completed_at = ?")
 		args = append(args, *x)
 	}
+	if x := req.Starred; x != nil {
+		cols = append(cols, "starred = ?")
+		args = append(args, *x)
+	}
+
+	var (
+		query  = fmt.Sprintf("update processes set %s where id = ?", strings.Join(cols, ","))
+		assert func(*infra.ExecResponse) error
+	)
+	args = append(args, req.ID)
+	if len(req.FromStatus) > 0 {
+		xs := make([]string, len(req.FromStatus))
+		for i, v := range req.FromStatus {
+			xs[i] = fmt.Sprint(int(v))
+		}
+		query += fmt.Sprintf(" and status_id in (%s)", strings.Join(xs, ","))
+		assert = infra.AssertRowsAffected(1)
+	}
 
 	if _, err := p.exec.Exec(ctx, &infra.ExecRequest{
-		Query: fmt.Sprintf("update processes set %s where id = ?;", strings.Join(cols, ",")),
-		Args:  append(args, req.ID),
+		Query:          query + ";",
+		Args:           args,
+		AssertResponse: assert,
 	}); err != nil {
 		return nil, fmt.Errorf("%w: update process: id=%d", err, req.ID)
 	}
@@ -98,10 +136,13 @@ func (p *Process) UpdateProcess(ctx context.Context, req *UpdateProcessRequest)
 
 func (p *Process) CreateProcess(ctx context.Context, req *CreateProcessRequest) (*domain.Process, error) {
 	r, err := p.exec.Exec(ctx, &infra.ExecRequest{
-		Query: "insert into processes (request_id, status_id, details_id, started_at, completed_at) values (?, ?, ?, ?, ?);",
+		Query: "insert into processes (request_id, status_id, kind_id, group_id, parent_id, details_id, started_at, completed_at) values (?, ?, ?, ?, ?, ?, ?, ?);",
 		Args: []any{
 			req.RequestId,
 			int(req.Status),
+			int(cmp.Or(req.Kind, domain.ProcessKindRender)),
+			req.GroupId,
+			req.ParentId,
 			req.DetailsId,
 			req.StartedAt,
 			req.CompletedAt,
@@ -124,23 +165,35 @@ func (*Process) scan(f func(...any) error) (*domain.Process, error) {
 		id          int
 		requestId   string
 		statusId    int
+		kindId      int
+		groupId     sql.NullInt64
+		parentId    sql.NullInt64
+		starred     bool
 		detailsId   int
 		startedAt   sql.NullTime
 		completedAt sql.NullTime
 		createdAt   time.Time
 		updatedAt   time.Time
 	)
-	if err := f(&id, &requestId, &statusId, &detailsId, &startedAt, &completedAt, &createdAt, &updatedAt); err != nil {
+	if err := f(&id, &requestId, &statusId, &kindId, &groupId, &parentId, &starred, &detailsId, &startedAt, &completedAt, &createdAt, &updatedAt); err != nil {
 		return nil, err
 	}
 	v := &domain.Process{
 		ID:        id,
 		RequestID: requestId,
 		Status:    domain.ProcessStatus(statusId),
+		Kind:      domain.ProcessKind(kindId),
+		Starred:   starred,
 		DetailsID: detailsId,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
+	if groupId.Valid {
+		v.GroupID = new(int(groupId.Int64))
+	}
+	if parentId.Valid {
+		v.ParentID = new(int(parentId.Int64))
+	}
 	if startedAt.Valid {
 		v.StartedAt = new(startedAt.Time)
 	}
@@ -152,7 +205,7 @@ func (*Process) scan(f func(...any) error) (*domain.Process, error) {
 
 func (p *Process) GetProcess(ctx context.Context, id int) (*domain.Process, error) {
 	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
-		Query: "select id, request_id, status_id, details_id, started_at, completed_at, created_at, updated_at from processes where id = ?;",
+		Query: "select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes where id = ?;",
 		Args: []any{
 			id,
 		},
@@ -169,7 +222,7 @@ func (p *Process) GetProcess(ctx context.Context, id int) (*domain.Process, erro
 
 func (p *Process) GetProcessByRequestId(ctx context.Context, rid string) (*domain.Process, error) {
 	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
-		Query: "select id, request_id, status_id, details_id, started_at, completed_at, created_at, updated_at from processes where request_id = ?;",
+		Query: "select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes where request_id = ?;",
 		Args: []any{
 			rid,
 		},
@@ -194,7 +247,7 @@ func (p *Process) GetProcessByDetailsList(ctx context.Context, detailsID ...int)
 		xs[i] = fmt.Sprint(v)
 	}
 	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
-		Query: fmt.Sprintf("select id, request_id, status_id, details_id, started_at, completed_at, created_at, updated_at from processes where details_id in (%s);",
+		Query: fmt.Sprintf("select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes where details_id in (%s);",
 			strings.Join(xs, ","),
 		),
 		Scan: p.scan,
@@ -204,3 +257,65 @@ func (p *Process) GetProcessByDetailsList(ctx context.Context, detailsID ...int)
 	}
 	return r.Items, nil
 }
+
+func (p *Process) GetProcessListByGroup(ctx context.Context, groupID int) ([]*domain.Process, error) {
+	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
+		Query: "select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes where group_id = ? order by id;",
+		Args: []any{
+			groupID,
+		},
+		Scan: p.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get process list by group: id=%d", err, groupID)
+	}
+	return r.Items, nil
+}
+
+func (p *Process) GetProcessListByParent(ctx context.Context, parentID int) ([]*domain.Process, error) {
+	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
+		Query: "select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes where parent_id = ? order by id;",
+		Args: []any{
+			parentID,
+		},
+		Scan: p.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get process list by parent: id=%d", err, parentID)
+	}
+	return r.Items, nil
+}
+
+// GetProcessListForRetention returns the completed processes except the members of the groups,
+// the starred processes and the groups of the starred or uncompleted members.
+func (p *Process) GetProcessListForRetention(ctx context.Context, req *GetProcessListForRetentionRequest) ([]*domain.Process, error) {
+	if len(req.Status) == 0 {
+		return nil, nil
+	}
+
+	xs := make([]string, len(req.Status))
+	for i, v := range req.Status {
+		xs[i] = fmt.Sprint(int(v))
+	}
+	var pruned string
+	if req.ExcludePruned {
+		pruned = " and p.pruned_at is null"
+	}
+	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.Process]{
+		Query: fmt.Sprintf(`select id, request_id, status_id, kind_id, group_id, parent_id, starred, details_id, started_at, completed_at, created_at, updated_at from processes p
+where p.group_id is null and not p.starred and p.status_id in (%s) and p.completed_at < ?%s
+and not exists (select 1 from processes m where m.group_id = p.id and (m.starred or m.status_id in (%d, %d)))
+order by p.completed_at limit ?;`,
+			strings.Join(xs, ","), pruned, int(domain.ProcessStatusPending), int(domain.ProcessStatusRunning),
+		),
+		Args: []any{
+			req.CompletedBefore,
+			req.Limit,
+		},
+		Scan: p.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get process list for retention: status=%v, completedBefore=%s", err, req.Status, req.CompletedBefore)
+	}
+	return r.Items, nil
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -17,7 +18,10 @@ const (
 
 type CreateProcessDetailsRequest struct {
 	Command        *string
+	Options        json.RawMessage
 	Title          string
+	Basename       string
+	Notes          string
 	ScoreObjectId  int
 	LogObjectId    *int
 	ResultObjectId *int
@@ -30,6 +34,8 @@ type ProcessDetailsCreator interface {
 type UpdateProcessDetailsRequest struct {
 	ID             int
 	Command        *string
+	Title          *string
+	Notes          *string
 	LogObjectId    *int
 	ResultObjectId *int
 }
@@ -46,6 +52,8 @@ type GetProcessDetailsByTitlePrefixOrderbyUpdatedAtDescRequest struct {
 type ProcessDetailsGetter interface {
 	GetProcessDetails(ctx context.Context, id int) (*domain.ProcessDetails, error)
 	GetProcessDetailsList(ctx context.Context, id ...int) ([]*domain.ProcessDetails, error)
+	// GetProcessDetailsListByObject returns the details referring to any of the objects.
+	GetProcessDetailsListByObject(ctx context.Context, objectID ...int) ([]*domain.ProcessDetails, error)
 }
 
 var (
@@ -75,6 +83,14 @@ func (p *ProcessDetails) UpdateProcessDetails(ctx context.Context, req *UpdatePr
 		cols = append(cols, "command = ?")
 		args = append(args, *x)
 	}
+	if x := req.Title; x != nil {
+		cols = append(cols, "title = ?")
+		args = append(args, *x)
+	}
+	if x := req.Notes; x != nil {
+		cols = append(cols, "notes = ?")
+		args = append(args, *x)
+	}
 	if x := req.LogObjectId; x != nil {
 		cols = append(cols, "log_object_id = ?")
 		args = append(args, *x)
@@ -96,10 +112,13 @@ func (p *ProcessDetails) UpdateProcessDetails(ctx context.Context, req *UpdatePr
 
 func (p *ProcessDetails) CreateProcessDetails(ctx context.Context, req *CreateProcessDetailsRequest) (*domain.ProcessDetails, error) {
 	r, err := p.exec.Exec(ctx, &infra.ExecRequest{
-		Query: "insert into process_details (command, title, score_object_id, log_object_id, result_object_id) values (?, ?, ?, ?, ?);",
+		Query: "insert into process_details (command, options, title, basename, notes, score_object_id, log_object_id, result_object_id) values (?, ?, ?, ?, ?, ?, ?, ?);",
 		Args: []any{
 			req.Command,
+			nullJSON(req.Options),
 			req.Title,
+			req.Basename,
+			req.Notes,
 			req.ScoreObjectId,
 			req.LogObjectId,
 			req.ResultObjectId,
@@ -121,19 +140,24 @@ func (*ProcessDetails) scan(f func(...any) error) (*domain.ProcessDetails, error
 	var (
 		id             int
 		command        sql.NullString
+		options        sql.NullString
 		title          string
+		basename       string
+		notes          string
 		scoreObjectId  int
 		logObjectId    sql.NullInt64
 		resultObjectId sql.NullInt64
 		createdAt      time.Time
 		updatedAt      time.Time
 	)
-	if err := f(&id, &command, &title, &scoreObjectId, &logObjectId, &resultObjectId, &createdAt, &updatedAt); err != nil {
+	if err := f(&id, &command, &options, &title, &basename, &notes, &scoreObjectId, &logObjectId, &resultObjectId, &createdAt, &updatedAt); err != nil {
 		return nil, err
 	}
 	v := &domain.ProcessDetails{
 		ID:            id,
 		Title:         title,
+		Basename:      basename,
+		Notes:         notes,
 		ScoreObjectID: scoreObjectId,
 		CreatedAt:     createdAt,
 		UpdatedAt:     updatedAt,
@@ -141,6 +165,9 @@ func (*ProcessDetails) scan(f func(...any) error) (*domain.ProcessDetails, error
 	if command.Valid {
 		v.Command = new(command.String)
 	}
+	if options.Valid {
+		v.Options = json.RawMessage(options.String)
+	}
 	if logObjectId.Valid {
 		v.LogObjectID = new(int(logObjectId.Int64))
 	}
@@ -152,7 +179,7 @@ func (*ProcessDetails) scan(f func(...any) error) (*domain.ProcessDetails, error
 
 func (p *ProcessDetails) GetProcessDetails(ctx context.Context, id int) (*domain.ProcessDetails, error) {
 	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.ProcessDetails]{
-		Query: "select id, command, title, score_object_id, log_object_id, result_object_id, created_at, updated_at from process_details where id = ?",
+		Query: "select id, command, options, title, basename, notes, score_object_id, log_object_id, result_object_id, created_at, updated_at from process_details where id = ?",
 		Args: []any{
 			id,
 		},
@@ -177,7 +204,7 @@ func (p *ProcessDetails) GetProcessDetailsList(ctx context.Context, id ...int) (
 		xs[i] = fmt.Sprint(v)
 	}
 	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.ProcessDetails]{
-		Query: fmt.Sprintf("select id, command, title, score_object_id, log_object_id, result_object_id, created_at, updated_at from process_details where id in (%s);",
+		Query: fmt.Sprintf("select id, command, options, title, basename, notes, score_object_id, log_object_id, result_object_id, created_at, updated_at from process_details where id in (%s);",
 			strings.Join(xs, ","),
 		),
 		Scan: p.scan,
@@ -187,3 +214,25 @@ func (p *ProcessDetails) GetProcessDetailsList(ctx context.Context, id ...int) (
 	}
 	return r.Items, nil
 }
+
+func (p *ProcessDetails) GetProcessDetailsListByObject(ctx context.Context, objectID ...int) ([]*domain.ProcessDetails, error) {
+	if len(objectID) == 0 {
+		return nil, nil
+	}
+
+	xs := make([]string, len(objectID))
+	for i, v := range objectID {
+		xs[i] = fmt.Sprint(v)
+	}
+	ids := strings.Join(xs, ",")
+	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.ProcessDetails]{
+		Query: fmt.Sprintf("select id, command, options, title, basename, notes, score_object_id, log_object_id, result_object_id, created_at, updated_at from process_details where score_object_id in (%[1]s) or log_object_id in (%[1]s) or result_object_id in (%[1]s);",
+			ids,
+		),
+		Scan: p.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get process details list by object: id=%v", err, objectID)
+	}
+	return r.Items, nil
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+)
+
+const (
+	ProcessTagsTable = "process_tags"
+)
+
+type ProcessTagsGetter interface {
+	// GetProcessTags returns the tags of the processes in order of name, keyed by the process id.
+	// The processes without tags are not in the result.
+	GetProcessTags(ctx context.Context, processID ...int) (map[int][]string, error)
+}
+
+type SetProcessTagsRequest struct {
+	ProcessID int
+	Tags      []string // unique
+}
+
+type ProcessTagsUpdater interface {
+	// SetProcessTags replaces the tags of the process in a single transaction.
+	SetProcessTags(ctx context.Context, req *SetProcessTagsRequest) error
+}
+
+var (
+	_ ProcessTagsGetter  = &ProcessTags{}
+	_ ProcessTagsUpdater = &ProcessTags{}
+)
+
+func NewProcessTags(query infra.Queryer[domain.ProcessTag], exec infra.TxExecer) *ProcessTags {
+	return &ProcessTags{
+		query: query,
+		exec:  exec,
+	}
+}
+
+type ProcessTags struct {
+	query infra.Queryer[domain.ProcessTag]
+	exec  infra.TxExecer
+}
+
+func (*ProcessTags) scan(f func(...any) error) (*domain.ProcessTag, error) {
+	var (
+		id        int
+		processId int
+		name      string
+		createdAt time.Time
+	)
+	if err := f(&id, &processId, &name, &createdAt); err != nil {
+		return nil, err
+	}
+	return &domain.ProcessTag{
+		ID:        id,
+		ProcessID: processId,
+		Name:      name,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+func (p *ProcessTags) GetProcessTags(ctx context.Context, processID ...int) (map[int][]string, error) {
+	if len(processID) == 0 {
+		return map[int][]string{}, nil
+	}
+
+	r, err := p.query.Query(ctx, &infra.QueryRequest[domain.ProcessTag]{
+		Query: fmt.Sprintf("select id, process_id, name, created_at from process_tags where process_id in (%s) order by process_id, name;",
+			joinIDs(processID),
+		),
+		Scan: p.scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: get process tags: id=%v", err, processID)
+	}
+	tags := map[int][]string{}
+	for _, x := range r.Items {
+		tags[x.ProcessID] = append(tags[x.ProcessID], x.Name)
+	}
+	return tags, nil
+}
+
+func (p *ProcessTags) SetProcessTags(ctx context.Context, req *SetProcessTagsRequest) error {
+	reqs := []*infra.ExecRequest{
+		{
+			Query: "delete from process_tags where process_id = ?;",
+			Args:  []any{req.ProcessID},
+		},
+	}
+	if len(req.Tags) > 0 {
+		args := make([]any, 0, len(req.Tags)*2)
+		for _, x := range req.Tags {
+			args = append(args, req.ProcessID, x)
+		}
+		reqs = append(reqs, &infra.ExecRequest{
+			Query:          fmt.Sprintf("insert into process_tags (process_id, name) values %s;", strings.TrimSuffix(strings.Repeat("(?, ?),", len(req.Tags)), ",")),
+			Args:           args,
+			AssertResponse: infra.AssertRowsAffected(int64(len(req.Tags))),
+		})
+	}
+
+	if _, err := p.exec.ExecTx(ctx, reqs...); err != nil {
+		return fmt.Errorf("%w: set process tags: id=%d, tags=%v", err, req.ProcessID, req.Tags)
+	}
+	return nil
+}
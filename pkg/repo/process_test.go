@@ -0,0 +1,93 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockQueryer records the requests and returns the items.
+type mockQueryer[T any] struct {
+	items    []*T
+	requests []*infra.QueryRequest[T]
+}
+
+func (m *mockQueryer[T]) Query(_ context.Context, req *infra.QueryRequest[T]) (*infra.QueryResponse[T], error) {
+	m.requests = append(m.requests, req)
+	return &infra.QueryResponse[T]{
+		Items: m.items,
+	}, nil
+}
+
+func TestGetProcessListForRetention(t *testing.T) {
+	completedBefore := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	// the conditions of the processes the retention policies should not touch
+	excluded := []string{
+		// the members of the groups are handled with the groups
+		"p.group_id is null",
+		"not p.starred",
+		// the groups of the starred or uncompleted members
+		"not exists (select 1 from processes m where m.group_id = p.id and (m.starred or m.status_id in (1, 2)))",
+	}
+
+	t.Run("no status", func(t *testing.T) {
+		q := &mockQueryer[domain.Process]{}
+		got, err := repo.NewProcess(q, nil).GetProcessListForRetention(t.Context(), &repo.GetProcessListForRetentionRequest{
+			CompletedBefore: completedBefore,
+			Limit:           10,
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+		assert.Empty(t, q.requests)
+	})
+
+	for _, tc := range []struct {
+		title         string
+		excludePruned bool
+	}{
+		{
+			title: "delete",
+		},
+		{
+			title:         "prune",
+			excludePruned: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			want := []*domain.Process{{ID: 1, RequestID: "rid1"}}
+			q := &mockQueryer[domain.Process]{
+				items: want,
+			}
+			got, err := repo.NewProcess(q, nil).GetProcessListForRetention(t.Context(), &repo.GetProcessListForRetentionRequest{
+				Status:          []domain.ProcessStatus{domain.ProcessStatusFailed, domain.ProcessStatusCancelled},
+				CompletedBefore: completedBefore,
+				ExcludePruned:   tc.excludePruned,
+				Limit:           10,
+			})
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, want, got)
+			if !assert.Len(t, q.requests, 1) {
+				return
+			}
+			req := q.requests[0]
+			for _, x := range excluded {
+				assert.Contains(t, req.Query, x)
+			}
+			assert.Contains(t, req.Query, "p.status_id in (4,5)")
+			assert.Contains(t, req.Query, "order by p.completed_at")
+			if tc.excludePruned {
+				assert.Contains(t, req.Query, "p.pruned_at is null")
+			} else {
+				assert.NotContains(t, req.Query, "pruned_at")
+			}
+			assert.Equal(t, []any{completedBefore, 10}, req.Args)
+		})
+	}
+}
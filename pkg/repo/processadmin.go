@@ -0,0 +1,456 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+)
+
+var (
+	ErrProcessNotFound = errors.New("ProcessNotFound")
+	ErrProcessNotDone  = errors.New("ProcessNotDone")
+	// ErrProcessMember means the member of the group is requested without the group.
+	ErrProcessMember = errors.New("ProcessMember")
+)
+
+type RemoveProcessesRequest struct {
+	RequestIDs []string
+	// DryRun reports what would be removed without removing anything.
+	DryRun bool
+}
+
+type RemoveProcessesResponse struct {
+	// RequestIDs are the removed processes including the members of the groups.
+	RequestIDs []string
+	// Objects is the number of the removed objects.
+	Objects int
+	// SizeBytes is the total size of the removed storage objects.
+	SizeBytes uint64
+}
+
+type ProcessRemover interface {
+	// RemoveProcesses deletes the processes and their objects from both the database and the storage.
+	// The members of the groups are also removed.
+	// The objects shared with the other processes, e.g. the score of the rerun, are kept.
+	//
+	// Returns ErrProcessNotFound if any process is missing,
+	// ErrProcessNotDone if any process is pending or running,
+	// ErrProcessMember if any process is the member of the group not requested.
+	RemoveProcesses(ctx context.Context, req *RemoveProcessesRequest) (*RemoveProcessesResponse, error)
+}
+
+type PruneProcessesRequest struct {
+	RequestIDs []string
+	// Keep is the patterns of the names of the result files to keep, see path.Match.
+	Keep []string
+	// DryRun reports what would be removed without removing anything.
+	DryRun bool
+}
+
+type ProcessPruner interface {
+	// PruneProcesses deletes the result files of the processes whose names do not match the patterns to keep.
+	// The members of the groups are also pruned.
+	// The processes, the objects referred by the details, e.g. the score and the log,
+	// and the files in the subdirectories, e.g. the accompaniment, are kept.
+	//
+	// Returns ErrProcessNotFound if any process is missing,
+	// ErrProcessNotDone if any process is pending or running,
+	// ErrProcessMember if any process is the member of the group not requested.
+	PruneProcesses(ctx context.Context, req *PruneProcessesRequest) (*RemoveProcessesResponse, error)
+}
+
+type ProcessAdminParams struct {
+	ProcessGetter  ProcessGetter
+	DetailsGetter  ProcessDetailsGetter
+	ObjectGetter   ObjectGetter
+	Deleter        ProcessDeleter
+	StorageDeleter infra.ObjectDeleter
+	StorageLister  infra.ObjectLister
+	// Bucket and BasePath are where the files of the processes are uploaded to.
+	Bucket   string
+	BasePath string
+}
+
+var (
+	_ ProcessRemover = &ProcessAdmin{}
+	_ ProcessPruner  = &ProcessAdmin{}
+)
+
+func NewProcessAdmin(params *ProcessAdminParams) *ProcessAdmin {
+	return &ProcessAdmin{
+		params,
+	}
+}
+
+type ProcessAdmin struct {
+	*ProcessAdminParams
+}
+
+// RemoveProcesses deletes the storage objects first and then the rows in a single transaction.
+// The processes remain if any step fails, so the removal can be retried.
+func (a *ProcessAdmin) RemoveProcesses(ctx context.Context, req *RemoveProcessesRequest) (*RemoveProcessesResponse, error) {
+	procs, err := a.getProcesses(ctx, req.RequestIDs)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		processIDs = make([]int, len(procs))
+		detailsIDs = make([]int, len(procs))
+		requestIDs = make([]string, len(procs))
+	)
+	for i, p := range procs {
+		processIDs[i] = p.ID
+		detailsIDs[i] = p.DetailsID
+		requestIDs[i] = p.RequestID
+	}
+
+	objects, err := a.getObjects(ctx, procs)
+	if err != nil {
+		return nil, err
+	}
+	objects, err = a.excludeShared(ctx, objects, detailsIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sizeBytes, err := a.deleteStorage(ctx, objects, req.DryRun, func(objectDir, string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	objectIDs := objects.itemIDs()
+	if !req.DryRun {
+		if err := a.Deleter.DeleteProcesses(ctx, &DeleteProcessesRequest{
+			ProcessIDs: processIDs,
+			DetailsIDs: detailsIDs,
+			ObjectIDs:  objectIDs,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	alog.L().Info("removed processes", "rid", requestIDs, "objects", len(objectIDs), "sizeBytes", sizeBytes, "dryRun", req.DryRun)
+	return &RemoveProcessesResponse{
+		RequestIDs: requestIDs,
+		Objects:    len(objectIDs),
+		SizeBytes:  sizeBytes,
+	}, nil
+}
+
+// PruneProcesses deletes the storage objects first and then the rows in a single transaction.
+// The result files remain if any step fails, so the pruning can be retried.
+func (a *ProcessAdmin) PruneProcesses(ctx context.Context, req *PruneProcessesRequest) (*RemoveProcessesResponse, error) {
+	procs, err := a.getProcesses(ctx, req.RequestIDs)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		processIDs = make([]int, len(procs))
+		requestIDs = make([]string, len(procs))
+	)
+	for i, p := range procs {
+		processIDs[i] = p.ID
+		requestIDs[i] = p.RequestID
+	}
+
+	objects, err := a.getObjects(ctx, procs)
+	if err != nil {
+		return nil, err
+	}
+	// keep all the objects referred by the details
+	objects, err = a.excludeShared(ctx, objects, nil)
+	if err != nil {
+		return nil, err
+	}
+	prunable := func(d objectDir, p string) bool {
+		name, ok := strings.CutPrefix(p, d.path+"/")
+		if !ok || strings.Contains(name, "/") {
+			// not a result file
+			return false
+		}
+		return !slices.ContainsFunc(req.Keep, func(pattern string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		})
+	}
+	objects = objects.filter(func(x *domain.Object) bool {
+		return x.Type == domain.ObjectTypeFile && slices.ContainsFunc(objects.dirs, func(d objectDir) bool {
+			return d.bucket == x.Bucket && prunable(d, x.Path)
+		})
+	})
+
+	sizeBytes, err := a.deleteStorage(ctx, objects, req.DryRun, prunable)
+	if err != nil {
+		return nil, err
+	}
+
+	objectIDs := objects.itemIDs()
+	if !req.DryRun {
+		if err := a.Deleter.DeleteProcesses(ctx, &DeleteProcessesRequest{
+			ObjectIDs:        objectIDs,
+			PrunedProcessIDs: processIDs,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	alog.L().Info("pruned processes", "rid", requestIDs, "objects", len(objectIDs), "sizeBytes", sizeBytes, "dryRun", req.DryRun)
+	return &RemoveProcessesResponse{
+		RequestIDs: requestIDs,
+		Objects:    len(objectIDs),
+		SizeBytes:  sizeBytes,
+	}, nil
+}
+
+// getProcesses returns the processes and the members of the groups, without duplicates.
+// The members are handled only with their groups, not to leave the groups without some members.
+func (a *ProcessAdmin) getProcesses(ctx context.Context, requestIDs []string) ([]*domain.Process, error) {
+	var (
+		procs   []*domain.Process
+		members []*domain.Process // requested members
+		seen    = map[int]bool{}
+		add     = func(p *domain.Process) error {
+			if seen[p.ID] {
+				return nil
+			}
+			if !p.Status.Done() {
+				return fmt.Errorf("%w: %s is %s", ErrProcessNotDone, p.RequestID, p.Status)
+			}
+			seen[p.ID] = true
+			procs = append(procs, p)
+			return nil
+		}
+	)
+	for _, rid := range requestIDs {
+		p, err := a.ProcessGetter.GetProcessByRequestId(ctx, rid)
+		if err != nil {
+			if errors.Is(err, infra.ErrAssertRows) {
+				return nil, fmt.Errorf("%w: %s", ErrProcessNotFound, rid)
+			}
+			return nil, err
+		}
+		if err := add(p); err != nil {
+			return nil, err
+		}
+		if p.GroupID != nil {
+			members = append(members, p)
+		}
+		if p.Kind == domain.ProcessKindRender {
+			continue
+		}
+		xs, err := a.ProcessGetter.GetProcessListByGroup(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range xs {
+			if err := add(x); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, m := range members {
+		if !seen[*m.GroupID] {
+			return nil, fmt.Errorf("%w: %s", ErrProcessMember, m.RequestID)
+		}
+	}
+	return procs, nil
+}
+
+type objectDir struct {
+	bucket string
+	path   string
+}
+
+// processObjects is the objects of the processes.
+type processObjects struct {
+	// items are the objects to be deleted.
+	items []*domain.Object
+	// shared are the objects to be kept.
+	shared []*domain.Object
+	// dirs contain the files of the processes.
+	dirs []objectDir
+}
+
+func (o *processObjects) add(x ...*domain.Object) {
+	for _, v := range x {
+		if !slices.ContainsFunc(o.items, func(y *domain.Object) bool { return y.ID == v.ID }) {
+			o.items = append(o.items, v)
+		}
+	}
+}
+
+func (o *processObjects) itemIDs() []int {
+	xs := make([]int, len(o.items))
+	for i, x := range o.items {
+		xs[i] = x.ID
+	}
+	return xs
+}
+
+// filter moves the items not satisfying f to the shared.
+func (o *processObjects) filter(f func(*domain.Object) bool) *processObjects {
+	r := processObjects{
+		shared: o.shared,
+		dirs:   o.dirs,
+	}
+	for _, x := range o.items {
+		if f(x) {
+			r.items = append(r.items, x)
+		} else {
+			r.shared = append(r.shared, x)
+		}
+	}
+	return &r
+}
+
+func (o *processObjects) addDir(d objectDir) {
+	if !slices.Contains(o.dirs, d) {
+		o.dirs = append(o.dirs, d)
+	}
+}
+
+// getObjects returns the objects referred by the details and the objects under the directories of the processes.
+func (a *ProcessAdmin) getObjects(ctx context.Context, procs []*domain.Process) (*processObjects, error) {
+	var r processObjects
+	for _, p := range procs {
+		// the score and the accompaniment are uploaded by the server
+		r.addDir(objectDir{
+			bucket: a.Bucket,
+			path:   filepath.Join(a.BasePath, p.RequestID),
+		})
+	}
+
+	detailsIDs := make([]int, len(procs))
+	for i, p := range procs {
+		detailsIDs[i] = p.DetailsID
+	}
+	details, err := a.DetailsGetter.GetProcessDetailsList(ctx, detailsIDs...)
+	if err != nil {
+		return nil, err
+	}
+	var objectIDs []int
+	for _, d := range details {
+		objectIDs = append(objectIDs, d.ScoreObjectID)
+		if x := d.LogObjectID; x != nil {
+			objectIDs = append(objectIDs, *x)
+		}
+		if x := d.ResultObjectID; x != nil {
+			objectIDs = append(objectIDs, *x)
+		}
+	}
+	objects, err := a.ObjectGetter.GetObjectList(ctx, objectIDs...)
+	if err != nil {
+		return nil, err
+	}
+	r.add(objects...)
+	for _, x := range objects {
+		if x.Type == domain.ObjectTypeDir {
+			// the results, the log and the transcoded files uploaded by the worker
+			r.addDir(objectDir{
+				bucket: x.Bucket,
+				path:   x.Path,
+			})
+		}
+	}
+
+	for _, d := range r.dirs {
+		objects, err := a.ObjectGetter.GetObjectListByDir(ctx, d.bucket, d.path)
+		if err != nil {
+			return nil, err
+		}
+		r.add(objects...)
+	}
+	return &r, nil
+}
+
+// excludeShared moves the objects referred by the details not in detailsIDs to the shared.
+func (a *ProcessAdmin) excludeShared(ctx context.Context, objects *processObjects, detailsIDs []int) (*processObjects, error) {
+	details, err := a.DetailsGetter.GetProcessDetailsListByObject(ctx, objects.itemIDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := map[int]bool{}
+	for _, d := range details {
+		if slices.Contains(detailsIDs, d.ID) {
+			continue
+		}
+		shared[d.ScoreObjectID] = true
+		if x := d.LogObjectID; x != nil {
+			shared[*x] = true
+		}
+		if x := d.ResultObjectID; x != nil {
+			shared[*x] = true
+		}
+	}
+	return objects.filter(func(x *domain.Object) bool { return !shared[x.ID] }), nil
+}
+
+// deleteStorage deletes the target files under the directories and the file objects, except the shared objects.
+// Returns the total size of the deleted files.
+func (a *ProcessAdmin) deleteStorage(ctx context.Context, objects *processObjects, dryRun bool, target func(objectDir, string) bool) (uint64, error) {
+	type key struct {
+		bucket string
+		path   string
+	}
+	var (
+		sizeBytes uint64
+		done      = map[key]bool{}
+	)
+	for _, x := range objects.shared {
+		done[key{bucket: x.Bucket, path: x.Path}] = true
+	}
+	deleteObject := func(bucket, path string, size uint64) error {
+		k := key{bucket: bucket, path: path}
+		if done[k] {
+			return nil
+		}
+		if !dryRun {
+			if err := a.StorageDeleter.DeleteObject(ctx, &infra.DeleteObjectRequest{
+				Bucket: bucket,
+				Path:   path,
+			}); err != nil {
+				return err
+			}
+		}
+		done[k] = true
+		sizeBytes += size
+		return nil
+	}
+
+	// the files not recorded as objects are also deleted
+	for _, d := range objects.dirs {
+		r, err := a.StorageLister.ListObjects(ctx, &infra.ListObjectsRequest{
+			Bucket: d.bucket,
+			Prefix: d.path + "/",
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, x := range r.Objects {
+			if !target(d, x.Path) {
+				continue
+			}
+			if err := deleteObject(d.bucket, x.Path, x.SizeBytes); err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, x := range objects.items {
+		if x.Type != domain.ObjectTypeFile {
+			continue
+		}
+		if err := deleteObject(x.Bucket, x.Path, x.SizeBytes); err != nil {
+			return 0, err
+		}
+	}
+	return sizeBytes, nil
+}
@@ -0,0 +1,488 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+type storageKey struct {
+	bucket string
+	path   string
+}
+
+// mockAdminStore is the database and the storage in memory.
+type mockAdminStore struct {
+	repo.ProcessGetter
+	repo.ObjectGetter
+	procs   []*domain.Process
+	details []*domain.ProcessDetails
+	objects []*domain.Object
+	storage map[storageKey]uint64
+	// deleteErr fails the next DeleteProcesses.
+	deleteErr error
+	deleted   []*repo.DeleteProcessesRequest
+}
+
+var errDeleteProcesses = errors.New("DeleteProcesses")
+
+func (m *mockAdminStore) GetProcessByRequestId(_ context.Context, requestID string) (*domain.Process, error) {
+	for _, p := range m.procs {
+		if p.RequestID == requestID {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", infra.ErrAssertRows, requestID)
+}
+
+func (m *mockAdminStore) GetProcessListByGroup(_ context.Context, groupID int) ([]*domain.Process, error) {
+	var r []*domain.Process
+	for _, p := range m.procs {
+		if x := p.GroupID; x != nil && *x == groupID {
+			r = append(r, p)
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) GetProcessDetails(_ context.Context, id int) (*domain.ProcessDetails, error) {
+	for _, d := range m.details {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, infra.ErrAssertRows
+}
+
+func (m *mockAdminStore) GetProcessDetailsList(_ context.Context, id ...int) ([]*domain.ProcessDetails, error) {
+	var r []*domain.ProcessDetails
+	for _, d := range m.details {
+		if slices.Contains(id, d.ID) {
+			r = append(r, d)
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) GetProcessDetailsListByObject(_ context.Context, objectID ...int) ([]*domain.ProcessDetails, error) {
+	var r []*domain.ProcessDetails
+	for _, d := range m.details {
+		if slices.Contains(objectID, d.ScoreObjectID) ||
+			(d.LogObjectID != nil && slices.Contains(objectID, *d.LogObjectID)) ||
+			(d.ResultObjectID != nil && slices.Contains(objectID, *d.ResultObjectID)) {
+			r = append(r, d)
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) GetObjectList(_ context.Context, id ...int) ([]*domain.Object, error) {
+	var r []*domain.Object
+	for _, x := range m.objects {
+		if slices.Contains(id, x.ID) {
+			r = append(r, x)
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) GetObjectListByDir(_ context.Context, bucket, path string) ([]*domain.Object, error) {
+	var r []*domain.Object
+	for _, x := range m.objects {
+		if x.Bucket == bucket && (x.Path == path || strings.HasPrefix(x.Path, path+"/")) {
+			r = append(r, x)
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) DeleteProcesses(_ context.Context, req *repo.DeleteProcessesRequest) error {
+	if err := m.deleteErr; err != nil {
+		m.deleteErr = nil
+		return err
+	}
+	m.deleted = append(m.deleted, req)
+	m.procs = slices.DeleteFunc(m.procs, func(x *domain.Process) bool { return slices.Contains(req.ProcessIDs, x.ID) })
+	m.details = slices.DeleteFunc(m.details, func(x *domain.ProcessDetails) bool { return slices.Contains(req.DetailsIDs, x.ID) })
+	m.objects = slices.DeleteFunc(m.objects, func(x *domain.Object) bool { return slices.Contains(req.ObjectIDs, x.ID) })
+	return nil
+}
+
+func (m *mockAdminStore) DeleteObject(_ context.Context, req *infra.DeleteObjectRequest) error {
+	delete(m.storage, storageKey{bucket: req.Bucket, path: req.Path})
+	return nil
+}
+
+func (m *mockAdminStore) ListObjects(_ context.Context, req *infra.ListObjectsRequest) (*infra.ListObjectsResponse, error) {
+	r := &infra.ListObjectsResponse{}
+	for _, k := range slices.SortedFunc(maps.Keys(m.storage), func(a, b storageKey) int { return strings.Compare(a.path, b.path) }) {
+		if k.bucket == req.Bucket && strings.HasPrefix(k.path, req.Prefix) {
+			r.Objects = append(r.Objects, &infra.ListObjectsElement{
+				Path:      k.path,
+				SizeBytes: m.storage[k],
+			})
+		}
+	}
+	return r, nil
+}
+
+func (m *mockAdminStore) paths() []string {
+	var r []string
+	for k := range m.storage {
+		r = append(r, k.bucket+":"+k.path)
+	}
+	slices.Sort(r)
+	return r
+}
+
+func (m *mockAdminStore) objectIDs() []int {
+	r := make([]int, len(m.objects))
+	for i, x := range m.objects {
+		r[i] = x.ID
+	}
+	return r
+}
+
+func (m *mockAdminStore) admin() *repo.ProcessAdmin {
+	return repo.NewProcessAdmin(&repo.ProcessAdminParams{
+		ProcessGetter:  m,
+		DetailsGetter:  m,
+		ObjectGetter:   m,
+		Deleter:        m,
+		StorageDeleter: m,
+		StorageLister:  m,
+		Bucket:         "server",
+		BasePath:       "base",
+	})
+}
+
+// addProcess adds the process that uploaded the score by the server and wrote the results by the worker.
+// The object ids are id*10+n.
+func (m *mockAdminStore) addProcess(id int, requestID string) {
+	var (
+		scoreID  = id*10 + 1
+		resultID = id*10 + 2
+		logID    = id*10 + 3
+		file     = func(id int, bucket, path string, size uint64) {
+			m.objects = append(m.objects, &domain.Object{
+				ID:        id,
+				Type:      domain.ObjectTypeFile,
+				Bucket:    bucket,
+				Path:      path,
+				SizeBytes: size,
+			})
+			m.storage[storageKey{bucket: bucket, path: path}] = size
+		}
+		resultDir = "results/" + requestID
+	)
+	file(scoreID, "server", "base/"+requestID+"/score.musicxml", 1)
+	m.objects = append(m.objects, &domain.Object{
+		ID:     resultID,
+		Type:   domain.ObjectTypeDir,
+		Bucket: "worker",
+		Path:   resultDir,
+	})
+	file(logID, "worker", resultDir+".log", 2)
+	file(id*10+4, "worker", resultDir+"/score.wav", 4)
+	file(id*10+5, "worker", resultDir+"/score.f0", 8)
+	file(id*10+6, "worker", resultDir+"/sub/score.f0", 16)
+	// not recorded as an object
+	m.storage[storageKey{bucket: "worker", path: resultDir + "/peaks_1024.json"}] = 32
+
+	m.procs = append(m.procs, &domain.Process{
+		ID:        id,
+		RequestID: requestID,
+		Status:    domain.ProcessStatusSucceed,
+		Kind:      domain.ProcessKindRender,
+		DetailsID: id,
+	})
+	m.details = append(m.details, &domain.ProcessDetails{
+		ID:             id,
+		ScoreObjectID:  scoreID,
+		LogObjectID:    &logID,
+		ResultObjectID: &resultID,
+	})
+}
+
+func newMockAdminStore() *mockAdminStore {
+	m := &mockAdminStore{
+		storage: map[storageKey]uint64{},
+	}
+	m.addProcess(1, "rid")
+	// the prefix of the directories is the request id of the other
+	m.addProcess(2, "rid-0")
+	return m
+}
+
+var rid0Paths = []string{
+	"server:base/rid-0/score.musicxml",
+	"worker:results/rid-0.log",
+	"worker:results/rid-0/peaks_1024.json",
+	"worker:results/rid-0/score.f0",
+	"worker:results/rid-0/score.wav",
+	"worker:results/rid-0/sub/score.f0",
+}
+
+func TestProcessAdminRemoveProcesses(t *testing.T) {
+	t.Run("remove", func(t *testing.T) {
+		m := newMockAdminStore()
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, &repo.RemoveProcessesResponse{
+			RequestIDs: []string{"rid"},
+			Objects:    6,
+			SizeBytes:  1 + 2 + 4 + 8 + 16 + 32,
+		}, got)
+		assert.Equal(t, rid0Paths, m.paths())
+		assert.Equal(t, []int{21, 22, 23, 24, 25, 26}, m.objectIDs())
+		if assert.Len(t, m.deleted, 1) {
+			assert.Equal(t, []int{1}, m.deleted[0].ProcessIDs)
+			assert.Equal(t, []int{1}, m.deleted[0].DetailsIDs)
+			assert.ElementsMatch(t, []int{11, 12, 13, 14, 15, 16}, m.deleted[0].ObjectIDs)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		m := newMockAdminStore()
+		paths := m.paths()
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+			DryRun:     true,
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, &repo.RemoveProcessesResponse{
+			RequestIDs: []string{"rid"},
+			Objects:    6,
+			SizeBytes:  1 + 2 + 4 + 8 + 16 + 32,
+		}, got)
+		assert.Equal(t, paths, m.paths())
+		assert.Empty(t, m.deleted)
+	})
+
+	t.Run("retry after failing to delete rows", func(t *testing.T) {
+		m := newMockAdminStore()
+		m.deleteErr = errDeleteProcesses
+		_, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+		})
+		assert.ErrorIs(t, err, errDeleteProcesses)
+		// the files are deleted but the rows remain
+		assert.Equal(t, rid0Paths, m.paths())
+		assert.Len(t, m.procs, 2)
+		assert.Len(t, m.objects, 12)
+
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"rid"}, got.RequestIDs)
+		assert.Equal(t, 6, got.Objects)
+		assert.Equal(t, rid0Paths, m.paths())
+		assert.Equal(t, []int{21, 22, 23, 24, 25, 26}, m.objectIDs())
+		_, err = m.GetProcessByRequestId(t.Context(), "rid")
+		assert.ErrorIs(t, err, infra.ErrAssertRows)
+	})
+
+	t.Run("keep the score shared with the rerun", func(t *testing.T) {
+		m := newMockAdminStore()
+		// rid-1 reruns rid with the same score
+		parentID := 1
+		m.procs = append(m.procs, &domain.Process{
+			ID:        3,
+			RequestID: "rid-1",
+			Status:    domain.ProcessStatusFailed,
+			Kind:      domain.ProcessKindRender,
+			ParentID:  &parentID,
+			DetailsID: 3,
+		})
+		m.details = append(m.details, &domain.ProcessDetails{
+			ID:            3,
+			ScoreObjectID: 11,
+		})
+
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 5, got.Objects)
+		assert.Equal(t, uint64(2+4+8+16+32), got.SizeBytes)
+		assert.ElementsMatch(t, append([]string{"server:base/rid/score.musicxml"}, rid0Paths...), m.paths())
+		assert.Contains(t, m.objectIDs(), 11)
+		if assert.Len(t, m.deleted, 1) {
+			assert.NotContains(t, m.deleted[0].ObjectIDs, 11)
+		}
+	})
+
+	t.Run("members of the group", func(t *testing.T) {
+		m := newMockAdminStore()
+		groupID := 2
+		m.procs[1].Kind = domain.ProcessKindEnsemble
+		m.procs[0].GroupID = &groupID
+
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid-0"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"rid-0", "rid"}, got.RequestIDs)
+		assert.Equal(t, 12, got.Objects)
+		assert.Empty(t, m.paths())
+		assert.Empty(t, m.procs)
+	})
+
+	t.Run("member with the group", func(t *testing.T) {
+		m := newMockAdminStore()
+		groupID := 2
+		m.procs[1].Kind = domain.ProcessKindEnsemble
+		m.procs[0].GroupID = &groupID
+
+		got, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid", "rid-0"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"rid", "rid-0"}, got.RequestIDs)
+		assert.Empty(t, m.procs)
+	})
+
+	t.Run("member without the group", func(t *testing.T) {
+		m := newMockAdminStore()
+		groupID := 2
+		m.procs[1].Kind = domain.ProcessKindEnsemble
+		m.procs[0].GroupID = &groupID
+
+		_, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid"},
+		})
+		assert.ErrorIs(t, err, repo.ErrProcessMember)
+		assert.Len(t, m.paths(), 12)
+		assert.Len(t, m.procs, 2)
+		assert.Empty(t, m.deleted)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := newMockAdminStore()
+		_, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid", "rid-2"},
+		})
+		assert.ErrorIs(t, err, repo.ErrProcessNotFound)
+		assert.Len(t, m.paths(), 12)
+	})
+
+	t.Run("not done", func(t *testing.T) {
+		m := newMockAdminStore()
+		m.procs[1].Status = domain.ProcessStatusRunning
+		_, err := m.admin().RemoveProcesses(t.Context(), &repo.RemoveProcessesRequest{
+			RequestIDs: []string{"rid", "rid-0"},
+		})
+		assert.ErrorIs(t, err, repo.ErrProcessNotDone)
+		assert.Len(t, m.paths(), 12)
+	})
+}
+
+func TestProcessAdminPruneProcesses(t *testing.T) {
+	t.Run("prune", func(t *testing.T) {
+		m := newMockAdminStore()
+		got, err := m.admin().PruneProcesses(t.Context(), &repo.PruneProcessesRequest{
+			RequestIDs: []string{"rid"},
+			Keep:       []string{"*.wav"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, &repo.RemoveProcessesResponse{
+			RequestIDs: []string{"rid"},
+			Objects:    1,
+			SizeBytes:  8 + 32,
+		}, got)
+		// the score, the log, the kept wav and the files in the subdirectory remain
+		assert.ElementsMatch(t, append([]string{
+			"server:base/rid/score.musicxml",
+			"worker:results/rid.log",
+			"worker:results/rid/score.wav",
+			"worker:results/rid/sub/score.f0",
+		}, rid0Paths...), m.paths())
+		assert.NotContains(t, m.objectIDs(), 15)
+		if assert.Len(t, m.deleted, 1) {
+			assert.Equal(t, &repo.DeleteProcessesRequest{
+				ObjectIDs:        []int{15},
+				PrunedProcessIDs: []int{1},
+			}, m.deleted[0])
+		}
+		assert.Len(t, m.procs, 2)
+	})
+
+	t.Run("keep patterns", func(t *testing.T) {
+		m := newMockAdminStore()
+		got, err := m.admin().PruneProcesses(t.Context(), &repo.PruneProcessesRequest{
+			RequestIDs: []string{"rid"},
+			Keep:       []string{"*.f0", "peaks_*.json"},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, got.Objects)
+		assert.Equal(t, uint64(4), got.SizeBytes)
+		assert.NotContains(t, m.paths(), "worker:results/rid/score.wav")
+		assert.Contains(t, m.paths(), "worker:results/rid/score.f0")
+		assert.Contains(t, m.paths(), "worker:results/rid/peaks_1024.json")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		m := newMockAdminStore()
+		paths := m.paths()
+		got, err := m.admin().PruneProcesses(t.Context(), &repo.PruneProcessesRequest{
+			RequestIDs: []string{"rid"},
+			Keep:       []string{"*.wav"},
+			DryRun:     true,
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, got.Objects)
+		assert.Equal(t, uint64(8+32), got.SizeBytes)
+		assert.Equal(t, paths, m.paths())
+		assert.Empty(t, m.deleted)
+	})
+
+	t.Run("retry after failing to delete rows", func(t *testing.T) {
+		m := newMockAdminStore()
+		m.deleteErr = errDeleteProcesses
+		req := &repo.PruneProcessesRequest{
+			RequestIDs: []string{"rid"},
+			Keep:       []string{"*.wav"},
+		}
+		_, err := m.admin().PruneProcesses(t.Context(), req)
+		assert.ErrorIs(t, err, errDeleteProcesses)
+		assert.Contains(t, m.objectIDs(), 15)
+
+		got, err := m.admin().PruneProcesses(t.Context(), req)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, got.Objects)
+		assert.NotContains(t, m.objectIDs(), 15)
+		assert.NotContains(t, m.paths(), "worker:results/rid/score.f0")
+	})
+}
@@ -3,7 +3,10 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,20 +14,83 @@ import (
 	"github.com/berquerant/pneutrinoutil/pkg/infra"
 )
 
+var ErrInvalidCursor = errors.New("InvalidCursor")
+
+// SearchSort is the sort key of the search.
+type SearchSort int
+
+const (
+	SearchSortCreated   SearchSort = iota // created_at
+	SearchSortCompleted                   // completed_at, only the completed processes
+	SearchSortDuration                    // completed_at - started_at, only the started and completed processes
+	SearchSortTitle                       // title
+)
+
+var searchSortNames = []string{"created", "completed", "duration", "title"}
+
+func (s SearchSort) String() string {
+	if int(s) < 0 || int(s) >= len(searchSortNames) {
+		return "unknown"
+	}
+	return searchSortNames[s]
+}
+
+func SearchSortFromString(s string) (SearchSort, bool) {
+	for i, x := range searchSortNames {
+		if x == s {
+			return SearchSort(i), true
+		}
+	}
+	return 0, false
+}
+
+// SearchCursor is the position of the last item of the page.
+// The next page starts after it in the order of the sort key and then the process id.
+type SearchCursor struct {
+	// Key is the value of the sort key of the item,
+	// RFC3339Nano for created and completed, microseconds for duration, title for title.
+	Key string
+	ID  int
+}
+
 type SearchProcessRequest struct {
-	Limit       int
-	Status      *domain.ProcessStatus
-	TitlePrefix *string
-	CreatedAt   Range[time.Time]
+	Limit         int
+	RequestIDs    []string
+	Status        *domain.ProcessStatus
+	TitlePrefix   *string
+	TitleContains *string // case-insensitive
+	Model         *string
+	SupportModel  *string
+	Transpose     *int
+	CreatedAt     Range[time.Time]
+	CompletedAt   Range[time.Time]
+	// Duration is the inclusive range of completed_at - started_at.
+	Duration Range[time.Duration]
+	// HasWav restricts the processes to the ones with or without the wav of the result.
+	HasWav *bool
+	// Tags restricts the processes to the ones with all the tags.
+	Tags  []string
+	Sort  SearchSort
+	Asc   bool          // descending if false
+	After *SearchCursor // the first page if nil
+	Total bool          // count the processes matched regardless of the cursor
 }
 
 type SearchProcessResult struct {
 	Items []*SearchProcessResultElement
+	// Next is the cursor of the next page, nil if the last page.
+	Next *SearchCursor
+	// Total is the number of the processes matched, nil if not requested.
+	Total *int
 }
 
 type SearchProcessResultElement struct {
 	Process *domain.Process
 	Details *domain.ProcessDetails
+	// ParentRequestID is the request id of the parent of Process if any.
+	ParentRequestID *string
+	// Tags are the tags of Process in order of name.
+	Tags []string
 }
 
 type ProcessSearcher interface {
@@ -33,14 +99,16 @@ type ProcessSearcher interface {
 
 var _ ProcessSearcher = &Searcher{}
 
-func NewSearcher(query infra.Queryer[SearchProcessResultElement]) *Searcher {
+func NewSearcher(query infra.Queryer[SearchProcessResultElement], count infra.Queryer[int]) *Searcher {
 	return &Searcher{
 		query: query,
+		count: count,
 	}
 }
 
 type Searcher struct {
 	query infra.Queryer[SearchProcessResultElement]
+	count infra.Queryer[int]
 }
 
 func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchProcessResultElement, error) {
@@ -48,6 +116,10 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 		processId        int
 		requestId        string
 		statusId         int
+		kindId           int
+		groupId          sql.NullInt64
+		parentId         sql.NullInt64
+		starred          bool
 		detailsId        int
 		startedAt        sql.NullTime
 		completedAt      sql.NullTime
@@ -55,16 +127,22 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 		processUpdatedAt time.Time
 		__detailsId      int
 		command          sql.NullString
+		options          sql.NullString
 		title            string
+		basename         string
+		notes            string
 		scoreObjectId    int
 		logObjectId      sql.NullInt64
 		resultObjectId   sql.NullInt64
 		detailsCreatedAt time.Time
 		detailsUpdatedAt time.Time
+		parentRequestId  sql.NullString
+		tags             sql.NullString
 	)
 	if err := f(
-		&processId, &requestId, &statusId, &detailsId, &startedAt, &completedAt, &processCreatedAt, &processUpdatedAt,
-		&__detailsId, &command, &title, &scoreObjectId, &logObjectId, &resultObjectId, &detailsCreatedAt, &detailsUpdatedAt,
+		&processId, &requestId, &statusId, &kindId, &groupId, &parentId, &starred, &detailsId, &startedAt, &completedAt, &processCreatedAt, &processUpdatedAt,
+		&__detailsId, &command, &options, &title, &basename, &notes, &scoreObjectId, &logObjectId, &resultObjectId, &detailsCreatedAt, &detailsUpdatedAt,
+		&parentRequestId, &tags,
 	); err != nil {
 		return nil, err
 	}
@@ -73,10 +151,18 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 		ID:        processId,
 		RequestID: requestId,
 		Status:    domain.ProcessStatus(statusId),
+		Kind:      domain.ProcessKind(kindId),
+		Starred:   starred,
 		DetailsID: detailsId,
 		CreatedAt: processCreatedAt,
 		UpdatedAt: processUpdatedAt,
 	}
+	if groupId.Valid {
+		p.GroupID = new(int(groupId.Int64))
+	}
+	if parentId.Valid {
+		p.ParentID = new(int(parentId.Int64))
+	}
 	if startedAt.Valid {
 		p.StartedAt = new(startedAt.Time)
 	}
@@ -87,6 +173,8 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 	d := &domain.ProcessDetails{
 		ID:            detailsId,
 		Title:         title,
+		Basename:      basename,
+		Notes:         notes,
 		ScoreObjectID: scoreObjectId,
 		CreatedAt:     detailsCreatedAt,
 		UpdatedAt:     detailsUpdatedAt,
@@ -94,6 +182,9 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 	if command.Valid {
 		d.Command = new(command.String)
 	}
+	if options.Valid {
+		d.Options = json.RawMessage(options.String)
+	}
 	if logObjectId.Valid {
 		d.LogObjectID = new(int(logObjectId.Int64))
 	}
@@ -101,17 +192,28 @@ func (*Searcher) scanSearchProcessResultElement(f func(...any) error) (*SearchPr
 		d.ResultObjectID = new(int(resultObjectId.Int64))
 	}
 
-	return &SearchProcessResultElement{
+	r := &SearchProcessResultElement{
 		Process: p,
 		Details: d,
-	}, nil
+	}
+	if parentRequestId.Valid {
+		r.ParentRequestID = new(parentRequestId.String)
+	}
+	if tags.Valid {
+		// the tags do not contain the separator
+		r.Tags = strings.Split(tags.String, ",")
+	}
+	return r, nil
 }
 
 func (s *Searcher) SearchProcess(ctx context.Context, req *SearchProcessRequest) (*SearchProcessResult, error) {
 	const baseQuery = `select
-p.id, p.request_id, p.status_id, p.details_id, p.started_at, p.completed_at, p.created_at, p.updated_at,
-d.id, d.command, d.title, d.score_object_id, d.log_object_id, d.result_object_id, d.created_at, d.updated_at
-from process_details d inner join processes p on d.id = p.details_id`
+p.id, p.request_id, p.status_id, p.kind_id, p.group_id, p.parent_id, p.starred, p.details_id, p.started_at, p.completed_at, p.created_at, p.updated_at,
+d.id, d.command, d.options, d.title, d.basename, d.notes, d.score_object_id, d.log_object_id, d.result_object_id, d.created_at, d.updated_at,
+pp.request_id,
+(select group_concat(t.name order by t.name separator ',') from process_tags t where t.process_id = p.id)
+from process_details d inner join processes p on d.id = p.details_id
+left join processes pp on p.parent_id = pp.id`
 	var (
 		conditions []string
 		args       []any
@@ -132,15 +234,100 @@ from process_details d inner join processes p on d.id = p.details_id`
 		conditions = append(conditions, "p.created_at < ?")
 		args = append(args, *x)
 	}
+	if len(req.RequestIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("p.request_id in (%s)", strings.TrimSuffix(strings.Repeat("?,", len(req.RequestIDs)), ",")))
+		for _, x := range req.RequestIDs {
+			args = append(args, x)
+		}
+	}
+	if x := req.TitleContains; x != nil {
+		conditions = append(conditions, "lower(d.title) like lower(?)")
+		args = append(args, "%"+escapeLike(*x)+"%")
+	}
+	if x := req.Model; x != nil {
+		conditions = append(conditions, "d.model = ?")
+		args = append(args, *x)
+	}
+	if x := req.SupportModel; x != nil {
+		conditions = append(conditions, "d.support_model = ?")
+		args = append(args, *x)
+	}
+	if x := req.Transpose; x != nil {
+		conditions = append(conditions, "d.transpose = ?")
+		args = append(args, *x)
+	}
+	if x := req.CompletedAt.Left; x != nil {
+		conditions = append(conditions, "p.completed_at > ?")
+		args = append(args, *x)
+	}
+	if x := req.CompletedAt.Right; x != nil {
+		conditions = append(conditions, "p.completed_at < ?")
+		args = append(args, *x)
+	}
+	if x := req.Duration.Left; x != nil {
+		conditions = append(conditions, "timestampdiff(microsecond, p.started_at, p.completed_at) >= ?")
+		args = append(args, x.Microseconds())
+	}
+	if x := req.Duration.Right; x != nil {
+		conditions = append(conditions, "timestampdiff(microsecond, p.started_at, p.completed_at) <= ?")
+		args = append(args, x.Microseconds())
+	}
+	if x := req.HasWav; x != nil {
+		// the wav is named after the basename in the result directory
+		var not string
+		if !*x {
+			not = "not "
+		}
+		conditions = append(conditions, not+`exists (select 1 from objects r inner join objects w on w.bucket = r.bucket and w.path = concat(r.path, '/', d.basename, '.wav') where r.id = d.result_object_id)`)
+	}
+	for _, x := range req.Tags {
+		conditions = append(conditions, "exists (select 1 from process_tags t where t.process_id = p.id and t.name = ?)")
+		args = append(args, x)
+	}
+	switch req.Sort {
+	case SearchSortCompleted:
+		conditions = append(conditions, "p.completed_at is not null")
+	case SearchSortDuration:
+		conditions = append(conditions, "p.started_at is not null and p.completed_at is not null")
+	}
+
+	r := &SearchProcessResult{}
+	if req.Total {
+		total, err := s.countProcess(ctx, conditions, args)
+		if err != nil {
+			return nil, err
+		}
+		r.Total = &total
+	}
+
+	var (
+		sortKey   = req.Sort.column()
+		direction = "desc"
+		operator  = "<"
+	)
+	if req.Asc {
+		direction = "asc"
+		operator = ">"
+	}
+	if x := req.After; x != nil {
+		key, err := req.Sort.parseKey(x.Key)
+		if err != nil {
+			return nil, err
+		}
+		// the ids break the ties of the keys to make the pages stable
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? or (%[1]s = ? and p.id %[2]s ?))", sortKey, operator))
+		args = append(args, key, key, x.ID)
+	}
 
 	query := baseQuery
 	if len(conditions) > 0 {
 		query += " where " + strings.Join(conditions, " and ")
 	}
-	query += " order by p.created_at desc limit ?;"
-	args = append(args, req.Limit)
+	query += fmt.Sprintf(" order by %[1]s %[2]s, p.id %[2]s limit ?;", sortKey, direction)
+	// fetch one more item to know if the next page exists
+	args = append(args, req.Limit+1)
 
-	r, err := s.query.Query(ctx, &infra.QueryRequest[SearchProcessResultElement]{
+	xs, err := s.query.Query(ctx, &infra.QueryRequest[SearchProcessResultElement]{
 		Query: query,
 		Args:  args,
 		Scan:  s.scanSearchProcessResultElement,
@@ -148,7 +335,86 @@ from process_details d inner join processes p on d.id = p.details_id`
 	if err != nil {
 		return nil, fmt.Errorf("%w: search process", err)
 	}
-	return &SearchProcessResult{
-		Items: r.Items,
-	}, nil
+	r.Items = xs.Items
+	if len(r.Items) > req.Limit {
+		r.Items = r.Items[:req.Limit]
+		last := r.Items[len(r.Items)-1]
+		r.Next = &SearchCursor{
+			Key: req.Sort.key(last),
+			ID:  last.Process.ID,
+		}
+	}
+	return r, nil
+}
+
+func (s *Searcher) countProcess(ctx context.Context, conditions []string, args []any) (int, error) {
+	query := "select count(*) from process_details d inner join processes p on d.id = p.details_id"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	r, err := s.count.Query(ctx, &infra.QueryRequest[int]{
+		Query: query + ";",
+		Args:  args,
+		Scan: func(f func(...any) error) (*int, error) {
+			var x int
+			if err := f(&x); err != nil {
+				return nil, err
+			}
+			return &x, nil
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: count process", err)
+	}
+	if err := r.AssertRows(1); err != nil {
+		return 0, fmt.Errorf("%w: count process", err)
+	}
+	return *r.Items[0], nil
+}
+
+func (s SearchSort) column() string {
+	switch s {
+	case SearchSortCompleted:
+		return "p.completed_at"
+	case SearchSortDuration:
+		return "timestampdiff(microsecond, p.started_at, p.completed_at)"
+	case SearchSortTitle:
+		return "d.title"
+	default:
+		return "p.created_at"
+	}
+}
+
+// key returns the value of the sort key of the item as SearchCursor.Key.
+func (s SearchSort) key(x *SearchProcessResultElement) string {
+	switch s {
+	case SearchSortCompleted:
+		return x.Process.CompletedAt.Format(time.RFC3339Nano)
+	case SearchSortDuration:
+		return strconv.FormatInt(x.Process.CompletedAt.Sub(*x.Process.StartedAt).Microseconds(), 10)
+	case SearchSortTitle:
+		return x.Details.Title
+	default:
+		return x.Process.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// parseKey returns the value of SearchCursor.Key as the query argument.
+func (s SearchSort) parseKey(key string) (any, error) {
+	switch s {
+	case SearchSortDuration:
+		v, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s key %q: %w", ErrInvalidCursor, s, key, err)
+		}
+		return v, nil
+	case SearchSortTitle:
+		return key, nil
+	default:
+		v, err := time.Parse(time.RFC3339Nano, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s key %q: %w", ErrInvalidCursor, s, key, err)
+		}
+		return v, nil
+	}
 }
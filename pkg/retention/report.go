@@ -0,0 +1,32 @@
+package retention
+
+import "time"
+
+// Report is what the retention policy reclaimed, or would reclaim if dry run.
+type Report struct {
+	DryRun    bool          `json:"dry_run"`
+	StartedAt time.Time     `json:"started_at"`
+	Rules     []*RuleReport `json:"rules"`
+	Processes int           `json:"processes"`  // number of the deleted or pruned processes
+	Objects   int           `json:"objects"`    // number of the deleted objects
+	SizeBytes uint64        `json:"size_bytes"` // total size of the deleted files
+	Errors    int           `json:"errors"`     // number of the processes failed to be deleted or pruned
+}
+
+type RuleReport struct {
+	Name      string   `json:"name"`
+	Action    Action   `json:"action"`
+	Processes []string `json:"processes"` // request ids of the deleted or pruned processes
+	Objects   int      `json:"objects"`
+	SizeBytes uint64   `json:"size_bytes"`
+	Errors    int      `json:"errors"`
+}
+
+// Add adds the report of the rule.
+func (r *Report) Add(x *RuleReport) {
+	r.Rules = append(r.Rules, x)
+	r.Processes += len(x.Processes)
+	r.Objects += x.Objects
+	r.SizeBytes += x.SizeBytes
+	r.Errors += x.Errors
+}
@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/goccy/go-yaml"
+)
+
+var ErrInvalidPolicy = errors.New("InvalidPolicy")
+
+type Action string
+
+const (
+	// ActionDelete deletes the processes and their objects.
+	ActionDelete Action = "delete"
+	// ActionPrune deletes the result files except the ones to keep.
+	ActionPrune Action = "prune"
+)
+
+// DefaultKeep is the patterns of the result files the prune keeps by default,
+// the files served by the server except the transcoded wavs, which are generated again on demand.
+var DefaultKeep = []string{
+	"*.wav",
+	"*.musicxml",
+	"config.yml",
+	"peaks_*.json",
+	"index.json",
+	"index.html",
+}
+
+// Policy is the retention rules applied in order, e.g.
+//
+//	rules:
+//	  - name: failed
+//	    status: [failed, cancelled]
+//	    olderThanDays: 7
+//	    action: delete
+//	  - name: artifacts
+//	    olderThanDays: 30
+//	    action: prune
+//	    keep: ["*.wav"]
+//
+// The process matched by a rule is not matched by the following rules.
+// The starred processes and the groups of the starred members are kept forever.
+type Policy struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+type Rule struct {
+	Name string `json:"name" yaml:"name"`
+	// Status is the statuses of the processes, all the completed statuses if empty.
+	Status []string `json:"status,omitempty" yaml:"status"`
+	// OlderThanDays is the days since the processes completed.
+	OlderThanDays int    `json:"olderThanDays" yaml:"olderThanDays"`
+	Action        Action `json:"action" yaml:"action"`
+	// Keep is the patterns of the names of the result files the prune keeps, see path.Match.
+	// DefaultKeep if empty.
+	Keep []string `json:"keep,omitempty" yaml:"keep"`
+}
+
+// Parse parses yaml or json policy.
+func Parse(b []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.UnmarshalWithOptions(b, &p, yaml.DisallowUnknownField()); err != nil {
+		return nil, errors.Join(ErrInvalidPolicy, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Read reads the policy file.
+func Read(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+func (p *Policy) Validate() error {
+	names := map[string]bool{}
+	for i, r := range p.Rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%w: rule %d", err, i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("%w: rule %d: duplicated name %s", ErrInvalidPolicy, i, r.Name)
+		}
+		names[r.Name] = true
+	}
+	return nil
+}
+
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: require name", ErrInvalidPolicy)
+	}
+	if r.OlderThanDays < 1 {
+		return fmt.Errorf("%w: %s: olderThanDays should be positive", ErrInvalidPolicy, r.Name)
+	}
+	switch r.Action {
+	case ActionDelete, ActionPrune:
+	default:
+		return fmt.Errorf("%w: %s: unknown action %q", ErrInvalidPolicy, r.Name, r.Action)
+	}
+	for _, s := range r.Status {
+		x, ok := domain.ProcessStatusFromString(s)
+		if !ok || !x.Done() {
+			return fmt.Errorf("%w: %s: status should be completed: %s", ErrInvalidPolicy, r.Name, s)
+		}
+	}
+	for _, k := range r.Keep {
+		if _, err := path.Match(k, ""); err != nil {
+			return fmt.Errorf("%w: %s: keep %q: %w", ErrInvalidPolicy, r.Name, k, err)
+		}
+	}
+	return nil
+}
+
+// Statuses returns the statuses of the processes the rule applies to.
+func (r *Rule) Statuses() []domain.ProcessStatus {
+	if len(r.Status) == 0 {
+		return []domain.ProcessStatus{
+			domain.ProcessStatusSucceed,
+			domain.ProcessStatusFailed,
+			domain.ProcessStatusCancelled,
+		}
+	}
+	xs := make([]domain.ProcessStatus, len(r.Status))
+	for i, s := range r.Status {
+		xs[i], _ = domain.ProcessStatusFromString(s)
+	}
+	return xs
+}
+
+// CompletedBefore returns the time the processes the rule applies to completed before.
+func (r *Rule) CompletedBefore(now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.OlderThanDays)
+}
+
+// KeepPatterns returns the patterns of the result files the prune keeps.
+func (r *Rule) KeepPatterns() []string {
+	if len(r.Keep) == 0 {
+		return DefaultKeep
+	}
+	return r.Keep
+}
@@ -0,0 +1,189 @@
+package retention_test
+
+import (
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		input string
+		want  *retention.Policy
+		err   bool
+	}{
+		{
+			title: "empty",
+			input: `rules: []`,
+			want: &retention.Policy{
+				Rules: []*retention.Rule{},
+			},
+		},
+		{
+			title: "rules",
+			input: `rules:
+  - name: failed
+    status: [failed, cancelled]
+    olderThanDays: 7
+    action: delete
+  - name: artifacts
+    olderThanDays: 30
+    action: prune
+    keep: ["*.wav"]
+`,
+			want: &retention.Policy{
+				Rules: []*retention.Rule{
+					{
+						Name:          "failed",
+						Status:        []string{"failed", "cancelled"},
+						OlderThanDays: 7,
+						Action:        retention.ActionDelete,
+					},
+					{
+						Name:          "artifacts",
+						OlderThanDays: 30,
+						Action:        retention.ActionPrune,
+						Keep:          []string{"*.wav"},
+					},
+				},
+			},
+		},
+		{
+			title: "json",
+			input: `{"rules":[{"name":"old","olderThanDays":90,"action":"delete"}]}`,
+			want: &retention.Policy{
+				Rules: []*retention.Rule{
+					{
+						Name:          "old",
+						OlderThanDays: 90,
+						Action:        retention.ActionDelete,
+					},
+				},
+			},
+		},
+		{
+			title: "unknown field",
+			input: `rules: [{name: old, olderThanDays: 90, action: delete, days: 1}]`,
+			err:   true,
+		},
+		{
+			title: "no name",
+			input: `rules: [{olderThanDays: 90, action: delete}]`,
+			err:   true,
+		},
+		{
+			title: "duplicated name",
+			input: `rules: [{name: old, olderThanDays: 90, action: delete}, {name: old, olderThanDays: 9, action: prune}]`,
+			err:   true,
+		},
+		{
+			title: "no days",
+			input: `rules: [{name: old, action: delete}]`,
+			err:   true,
+		},
+		{
+			title: "unknown action",
+			input: `rules: [{name: old, olderThanDays: 90, action: archive}]`,
+			err:   true,
+		},
+		{
+			title: "running",
+			input: `rules: [{name: old, status: [running], olderThanDays: 90, action: delete}]`,
+			err:   true,
+		},
+		{
+			title: "bad keep",
+			input: `rules: [{name: old, olderThanDays: 90, action: prune, keep: ["["]}]`,
+			err:   true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := retention.Parse([]byte(tc.input))
+			if tc.err {
+				assert.ErrorIs(t, err, retention.ErrInvalidPolicy)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRule(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		r := &retention.Rule{
+			Name:          "r",
+			OlderThanDays: 7,
+			Action:        retention.ActionPrune,
+		}
+		assert.Equal(t, []domain.ProcessStatus{
+			domain.ProcessStatusSucceed,
+			domain.ProcessStatusFailed,
+			domain.ProcessStatusCancelled,
+		}, r.Statuses())
+		assert.Equal(t, retention.DefaultKeep, r.KeepPatterns())
+		now := time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), r.CompletedBefore(now))
+	})
+
+	t.Run("specified", func(t *testing.T) {
+		r := &retention.Rule{
+			Name:          "r",
+			Status:        []string{"failed"},
+			OlderThanDays: 7,
+			Action:        retention.ActionPrune,
+			Keep:          []string{"*.flac"},
+		}
+		assert.Equal(t, []domain.ProcessStatus{domain.ProcessStatusFailed}, r.Statuses())
+		assert.Equal(t, []string{"*.flac"}, r.KeepPatterns())
+	})
+}
+
+func TestDefaultKeep(t *testing.T) {
+	keep := func(name string) bool {
+		return slices.ContainsFunc(retention.DefaultKeep, func(pattern string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		})
+	}
+
+	t.Run("served", func(t *testing.T) {
+		for _, name := range []string{
+			"score.wav",
+			"score.stem0.wav",
+			"score.musicxml",
+			"config.yml",
+			audio.PeaksFileName(256),
+			audio.PeaksFileName(1024),
+			audio.PeaksFileName(4096),
+			sweep.IndexJSONFileName,
+			sweep.IndexHTMLFileName,
+		} {
+			assert.True(t, keep(name), name)
+		}
+	})
+
+	t.Run("pruned", func(t *testing.T) {
+		for _, name := range []string{
+			"score.f0",
+			"score.mgc",
+			"score.bap",
+			"score.lab",
+			"score.mp3",
+			"score.flac",
+			"peaks.json",
+		} {
+			assert.False(t, keep(name), name)
+		}
+	})
+}
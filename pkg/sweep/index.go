@@ -0,0 +1,45 @@
+package sweep
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"io"
+)
+
+const (
+	IndexJSONFileName = "index.json"
+	IndexHTMLFileName = "index.html"
+)
+
+// Index is the list of the results of a sweep.
+type Index struct {
+	Basename string   `json:"basename"`
+	Entries  []*Entry `json:"entries"`
+}
+
+// Entry is a result of a combination.
+type Entry struct {
+	Combination
+	ID     string `json:"id"`               // request id or name of the result
+	OK     bool   `json:"ok"`               // true if rendered successfully
+	Wav    string `json:"wav,omitempty"`    // path or url of the wav
+	Config string `json:"config,omitempty"` // path or url of the config.yml
+	Error  string `json:"error,omitempty"`
+}
+
+func (x Index) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(x)
+}
+
+//go:embed index.html.tmpl
+var indexHTML string
+
+var indexHTMLTemplate = template.Must(template.New("index").Parse(indexHTML))
+
+// WriteHTML writes a page that lists the wavs with the config.yml.
+func (x Index) WriteHTML(w io.Writer) error {
+	return indexHTMLTemplate.Execute(w, x)
+}
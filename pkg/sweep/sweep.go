@@ -0,0 +1,102 @@
+package sweep
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// MaxCombinations is the upper limit of the number of the combinations of a sweep.
+const MaxCombinations = 64
+
+var ErrInvalidSpec = errors.New("InvalidSpec")
+
+// Spec is the value lists to sweep.
+// An empty list keeps the base value.
+type Spec struct {
+	Model        []string `json:"model,omitempty" yaml:"model,omitempty"`
+	SupportModel []string `json:"supportModel,omitempty" yaml:"supportModel,omitempty"`
+	Transpose    []int    `json:"transpose,omitempty" yaml:"transpose,omitempty"`
+}
+
+// ParseSpec parses yaml or json spec.
+func ParseSpec(b []byte) (*Spec, error) {
+	var s Spec
+	if err := yaml.UnmarshalWithOptions(b, &s, yaml.DisallowUnknownField()); err != nil {
+		return nil, errors.Join(ErrInvalidSpec, err)
+	}
+	return &s, nil
+}
+
+// Merge returns a new spec whose lists are overridden by the non-empty lists of other.
+func (s Spec) Merge(other Spec) Spec {
+	if len(other.Model) > 0 {
+		s.Model = other.Model
+	}
+	if len(other.SupportModel) > 0 {
+		s.SupportModel = other.SupportModel
+	}
+	if len(other.Transpose) > 0 {
+		s.Transpose = other.Transpose
+	}
+	return s
+}
+
+// Len returns the number of the combinations.
+func (s Spec) Len() int {
+	return max(len(s.Model), 1) * max(len(s.SupportModel), 1) * max(len(s.Transpose), 1)
+}
+
+func (s Spec) Validate() error {
+	if n := s.Len(); n > MaxCombinations {
+		return fmt.Errorf("%w: %d combinations exceed %d", ErrInvalidSpec, n, MaxCombinations)
+	}
+	return nil
+}
+
+// Combinations returns the cartesian product of the lists.
+// Values of empty lists are taken from base.
+func (s Spec) Combinations(base Combination) []*Combination {
+	or := func(xs []string, v string) []string {
+		if len(xs) == 0 {
+			return []string{v}
+		}
+		return xs
+	}
+	transposes := s.Transpose
+	if len(transposes) == 0 {
+		transposes = []int{base.Transpose}
+	}
+
+	var r []*Combination
+	for _, model := range or(s.Model, base.Model) {
+		for _, supportModel := range or(s.SupportModel, base.SupportModel) {
+			for _, transpose := range transposes {
+				r = append(r, &Combination{
+					Model:        model,
+					SupportModel: supportModel,
+					Transpose:    transpose,
+				})
+			}
+		}
+	}
+	return r
+}
+
+// Combination is a set of values of a sweep.
+type Combination struct {
+	Model        string `json:"model"`
+	SupportModel string `json:"supportModel,omitempty"`
+	Transpose    int    `json:"transpose"`
+}
+
+// String returns a label of the combination, e.g. MERROW+KIRITAN_-12.
+func (c Combination) String() string {
+	xs := []string{c.Model}
+	if c.SupportModel != "" {
+		xs = append(xs, c.SupportModel)
+	}
+	return fmt.Sprintf("%s_%d", strings.Join(xs, "+"), c.Transpose)
+}
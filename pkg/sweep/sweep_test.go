@@ -0,0 +1,103 @@
+package sweep_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec(t *testing.T) {
+	base := sweep.Combination{Model: "MERROW", Transpose: 0}
+
+	t.Run("parse", func(t *testing.T) {
+		got, err := sweep.ParseSpec([]byte(`model: [A, B]
+transpose: [0, -12]
+`))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, &sweep.Spec{Model: []string{"A", "B"}, Transpose: []int{0, -12}}, got)
+
+		_, err = sweep.ParseSpec([]byte(`models: [A]`))
+		assert.ErrorIs(t, err, sweep.ErrInvalidSpec)
+	})
+
+	for _, tc := range []struct {
+		title string
+		spec  sweep.Spec
+		want  []*sweep.Combination
+	}{
+		{
+			title: "empty",
+			want:  []*sweep.Combination{{Model: "MERROW"}},
+		},
+		{
+			title: "product",
+			spec: sweep.Spec{
+				Model:        []string{"A", "B"},
+				SupportModel: []string{"", "S"},
+				Transpose:    []int{3},
+			},
+			want: []*sweep.Combination{
+				{Model: "A", Transpose: 3},
+				{Model: "A", SupportModel: "S", Transpose: 3},
+				{Model: "B", Transpose: 3},
+				{Model: "B", SupportModel: "S", Transpose: 3},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got := tc.spec.Combinations(base)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, len(tc.want), tc.spec.Len())
+		})
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		s := sweep.Spec{Model: []string{"A"}, Transpose: []int{1}}
+		got := s.Merge(sweep.Spec{Transpose: []int{2, 3}})
+		assert.Equal(t, sweep.Spec{Model: []string{"A"}, Transpose: []int{2, 3}}, got)
+	})
+
+	t.Run("too many", func(t *testing.T) {
+		s := sweep.Spec{Transpose: make([]int, sweep.MaxCombinations+1)}
+		assert.ErrorIs(t, s.Validate(), sweep.ErrInvalidSpec)
+	})
+}
+
+func TestCombination(t *testing.T) {
+	c := sweep.Combination{Model: "A", SupportModel: "S", Transpose: -12}
+	assert.Equal(t, "A+S_-12", c.String())
+}
+
+func TestIndex(t *testing.T) {
+	x := sweep.Index{
+		Basename: "score",
+		Entries: []*sweep.Entry{
+			{Combination: sweep.Combination{Model: "A"}, ID: "a", OK: true, Wav: "a/score.wav", Config: "a/config.yml"},
+			{Combination: sweep.Combination{Model: "<B>"}, ID: "b", Error: "exit status 1"},
+		},
+	}
+
+	var j bytes.Buffer
+	if !assert.Nil(t, x.WriteJSON(&j)) {
+		return
+	}
+	var got sweep.Index
+	assert.Nil(t, json.Unmarshal(j.Bytes(), &got))
+	assert.Equal(t, x, got)
+
+	var h bytes.Buffer
+	if !assert.Nil(t, x.WriteHTML(&h)) {
+		return
+	}
+	html := h.String()
+	assert.True(t, strings.Contains(html, `src="a/score.wav"`))
+	assert.True(t, strings.Contains(html, `href="a/config.yml"`))
+	assert.True(t, strings.Contains(html, "&lt;B&gt;"))
+	assert.True(t, strings.Contains(html, "failed: exit status 1"))
+}
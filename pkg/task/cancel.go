@@ -0,0 +1,112 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+)
+
+var ErrProcessDone = errors.New("ProcessDone")
+
+// Inspector deletes the tasks in the queue and cancels the running tasks, e.g. asynq.Inspector.
+type Inspector interface {
+	DeleteTask(queue, id string) error
+	CancelProcessing(id string) error
+}
+
+type CancellerParams struct {
+	Inspector      Inspector
+	Webhooker      infra.Webhooker // optional
+	Enqueuer       Enqueuer        // enqueues the task to complete the group
+	ProcessGetter  repo.ProcessGetter
+	ProcessUpdater repo.ProcessUpdater
+}
+
+func NewCanceller(params *CancellerParams) *Canceller {
+	return &Canceller{
+		params,
+	}
+}
+
+// Canceller cancels the pending or running processes.
+type Canceller struct {
+	*CancellerParams
+}
+
+// Cancel makes the process cancelled, deletes the task of it if pending and kills it if running.
+// The members of the group are also cancelled.
+// Returns ErrProcessDone if the process is already completed.
+func (c *Canceller) Cancel(ctx context.Context, proc *domain.Process) error {
+	if err := c.cancel(ctx, proc); err != nil {
+		return err
+	}
+
+	if proc.Kind != domain.ProcessKindRender {
+		members, err := c.ProcessGetter.GetProcessListByGroup(ctx, proc.ID)
+		if err != nil {
+			return fmt.Errorf("%w: get members of %s", err, proc.RequestID)
+		}
+		for _, m := range members {
+			if m.Status.Done() {
+				continue
+			}
+			if err := c.cancel(ctx, m); err != nil && !errors.Is(err, ErrProcessDone) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if groupID := proc.GroupID; groupID != nil {
+		// the group completes without this member
+		if err := EnqueueGroup(ctx, c.ProcessGetter, c.Enqueuer, *groupID); err != nil {
+			return fmt.Errorf("%w: enqueue group(%d)", err, *groupID)
+		}
+	}
+	return nil
+}
+
+func (c *Canceller) cancel(ctx context.Context, proc *domain.Process) error {
+	typ, err := ProcessTaskType(proc.Kind)
+	if err != nil {
+		return err
+	}
+	if _, err := c.ProcessUpdater.UpdateProcess(ctx, &repo.UpdateProcessRequest{
+		ID:          proc.ID,
+		Status:      new(domain.ProcessStatusCancelled),
+		CompletedAt: new(time.Now()),
+		FromStatus:  []domain.ProcessStatus{domain.ProcessStatusPending, domain.ProcessStatusRunning},
+	}); err != nil {
+		if errors.Is(err, infra.ErrRowsAffected) {
+			return fmt.Errorf("%w: %s", ErrProcessDone, proc.RequestID)
+		}
+		return err
+	}
+
+	logAttrs := []any{"type", typ, "rid", proc.RequestID}
+	id := TaskID(typ, proc.RequestID)
+	// the task is not in the queue if running, or not enqueued yet if the group is waiting for the members
+	if err := c.Inspector.DeleteTask(DefaultQueue, id); err != nil {
+		alog.L().Debug("delete task", append(logAttrs, logx.Err(err))...)
+		// the worker kills the process if running
+		if err := c.Inspector.CancelProcessing(id); err != nil {
+			alog.L().Warn("cancel task", append(logAttrs, logx.Err(err))...)
+		}
+	}
+	alog.L().Info("cancelled", logAttrs...)
+
+	if c.Webhooker == nil {
+		return nil
+	}
+	if err := c.Webhooker.Webhook(ctx, NewPneutrinoutilStartWebhookParams(typ, proc.RequestID, domain.ProcessStatusCancelled)); err != nil {
+		alog.L().Error("webhook failed", append(logAttrs, logx.Err(err))...)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/goccy/go-yaml"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypePneutrinoutilEnsemble = "pneutrinoutil:ensemble"
+)
+
+// StemFileName returns the name of the wav of the index-th voice in the results of the ensemble.
+func StemFileName(basename string, index int) string {
+	return fmt.Sprintf("%s.stem%d.wav", basename, index)
+}
+
+// ProcessEnsemble mixes the voices of the ensemble.
+func (p *PneutrinoutilProcessor) ProcessEnsemble(ctx context.Context, t *asynq.Task) error {
+	return p.processGroup(ctx, t, func(ctx context.Context, g *groupProcess) error {
+		var (
+			basename  = g.details.Basename
+			resultDir = filepath.Join(g.workDir, "result")
+			attrs     = func(v ...any) []any { return append(g.logAttrs, v...) }
+		)
+		if err := pathx.EnsureDir(resultDir); err != nil {
+			return err
+		}
+
+		tracks := make([]*audio.Track, len(g.members))
+		for i, v := range g.members {
+			alog.L().Info("get voice", attrs("voice", v.RequestID, "status", v.Status.String())...)
+			if v.Status != domain.ProcessStatusSucceed {
+				return fmt.Errorf("voice %s is %s", v.RequestID, v.Status)
+			}
+			track, err := p.downloadVoice(ctx, v, filepath.Join(resultDir, StemFileName(basename, i)))
+			if err != nil {
+				return fmt.Errorf("%w: failed to get voice %s", err, v.RequestID)
+			}
+			tracks[i] = track
+		}
+
+		alog.L().Info("mix voices", attrs("voices", len(tracks))...)
+		mix, err := audio.Mix(tracks...)
+		if err != nil {
+			return fmt.Errorf("%w: failed to mix voices", err)
+		}
+		if err := audio.WriteFile(filepath.Join(resultDir, basename+".wav"), mix); err != nil {
+			return fmt.Errorf("%w: failed to write mix", err)
+		}
+		p.writePeaks(resultDir, basename, attrs)
+		scorePath, err := p.downloadObject(ctx, g.details.ScoreObjectID, g.workDir)
+		if err != nil {
+			return fmt.Errorf("%w: create local score file from object(%d)", err, g.details.ScoreObjectID)
+		}
+		if err := os.Rename(scorePath, filepath.Join(resultDir, basename+".musicxml")); err != nil {
+			return fmt.Errorf("%w: failed to move score", err)
+		}
+		return p.uploadGroupResults(ctx, g, resultDir)
+	})
+}
+
+// downloadVoice writes the wav of the voice into stemPath and returns the track to mix.
+func (p *PneutrinoutilProcessor) downloadVoice(ctx context.Context, voice *domain.Process, stemPath string) (*audio.Track, error) {
+	details, err := p.ProcessDetailsGetter.GetProcessDetails(ctx, voice.DetailsID)
+	if err != nil {
+		return nil, err
+	}
+	if details.ResultObjectID == nil {
+		return nil, errors.New("no results")
+	}
+
+	b, err := p.readResultFile(ctx, *details.ResultObjectID, "config.yml")
+	if err != nil {
+		return nil, fmt.Errorf("%w: read config", err)
+	}
+	var c ctl.Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal config", err)
+	}
+
+	b, err = p.readResultFile(ctx, *details.ResultObjectID, details.Basename+".wav")
+	if err != nil {
+		return nil, fmt.Errorf("%w: read wav", err)
+	}
+	if err := os.WriteFile(stemPath, b, 0644); err != nil {
+		return nil, err
+	}
+	w, err := audio.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return &audio.Track{
+		Wav:  w,
+		Gain: c.VocalGain,
+		Pan:  c.VocalPan,
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup makes cmd kill the process group of it when the context is done,
+// not to leave the NEUTRINO processes run by pneutrinoutil.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin)
+
+package task
+
+import "os/exec"
+
+// killProcessGroup kills only the process of cmd when the context is done.
+func killProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,210 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/hibiken/asynq"
+)
+
+// GroupMemberRequestID returns the request id of the index-th member of the group, e.g. the voice of the ensemble.
+func GroupMemberRequestID(requestID string, index int) string {
+	return fmt.Sprintf("%s-%d", requestID, index)
+}
+
+// PneutrinoutilGroupPayload is the payload of the task to complete the group, enqueued when all the members are completed.
+type PneutrinoutilGroupPayload struct {
+	RequestID string `json:"rid"`
+}
+
+func NewPneutrinoutilGroup(typ string, p PneutrinoutilGroupPayload) (*asynq.Task, error) {
+	alog.L().Debug("NewPneutrinoutilGroup", slog.String("type", typ), slog.String("rid", p.RequestID))
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(typ, payload), nil
+}
+
+type Enqueuer interface {
+	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+// enqueueGroup enqueues the task to complete the group when all the members are completed.
+func (p *PneutrinoutilProcessor) enqueueGroup(ctx context.Context, groupID int) error {
+	return EnqueueGroup(ctx, p.ProcessGetter, p.Enqueuer, groupID)
+}
+
+// EnqueueGroup enqueues the task to complete the group when all the members are completed.
+func EnqueueGroup(ctx context.Context, getter repo.ProcessGetter, enqueuer Enqueuer, groupID int) error {
+	members, err := getter.GetProcessListByGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, v := range members {
+		if !v.Status.Done() {
+			return nil
+		}
+	}
+	group, err := getter.GetProcess(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	typ, err := ProcessTaskType(group.Kind)
+	if err != nil {
+		return err
+	}
+	if typ == TypePneutrinoutilStart {
+		return fmt.Errorf("process(%d) of kind %s is not a group", groupID, group.Kind)
+	}
+	t, err := NewPneutrinoutilGroup(typ, PneutrinoutilGroupPayload{
+		RequestID: group.RequestID,
+	})
+	if err != nil {
+		return err
+	}
+	// the last members may be completed at the same time
+	_, err = enqueuer.EnqueueContext(ctx, t, asynq.TaskID(TaskID(typ, group.RequestID)))
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+type groupProcess struct {
+	proc     *domain.Process
+	details  *domain.ProcessDetails
+	members  []*domain.Process
+	workDir  string
+	logAttrs []any
+}
+
+// processGroup runs f to complete the group and updates the status of the group.
+func (p *PneutrinoutilProcessor) processGroup(ctx context.Context, t *asynq.Task, f func(context.Context, *groupProcess) error) error {
+	var (
+		logAttrs = []any{"type", t.Type()}
+		attrs    = func(v ...any) []any { return append(logAttrs, v...) }
+		baseErr  = fmt.Errorf("%w: %s", ErrTask, t.Type())
+	)
+
+	alog.L().Info("got task", attrs()...)
+	var payload PneutrinoutilGroupPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return errors.Join(baseErr, err)
+	}
+
+	logAttrs = append(logAttrs, "rid", payload.RequestID)
+	baseErr = fmt.Errorf("%w: rid=%s", baseErr, payload.RequestID)
+	withBaseErr := func(err error, format string, v ...any) error {
+		verr := fmt.Errorf("%w: %s", errors.Join(baseErr, err), fmt.Sprintf(format, v...))
+		alog.L().Error("got error", attrs(logx.Err(verr))...)
+		return verr
+	}
+
+	workDir := filepath.Join(p.WorkDir, payload.RequestID)
+	alog.L().Info("create work dir", attrs("dir", workDir)...)
+	if err := pathx.EnsureDir(workDir); err != nil {
+		return withBaseErr(err, "failed to create work dir")
+	}
+
+	alog.L().Info("get process", attrs()...)
+	proc, err := p.ProcessGetter.GetProcessByRequestId(ctx, payload.RequestID)
+	if err != nil {
+		return withBaseErr(err, "failed to get process")
+	}
+	if ok, err := p.startProcess(ctx, proc); err != nil {
+		return withBaseErr(err, "failed to update process(%d)", proc.ID)
+	} else if !ok {
+		alog.L().Info("cancelled", attrs()...)
+		return nil
+	}
+
+	var processSucceed bool
+	defer func() {
+		// the context is done if the task is cancelled or timed out
+		p.completeProcess(context.WithoutCancel(ctx), t.Type(), proc, processSucceed, attrs)
+	}()
+
+	alog.L().Info("get process details", attrs("id", proc.DetailsID)...)
+	details, err := p.ProcessDetailsGetter.GetProcessDetails(ctx, proc.DetailsID)
+	if err != nil {
+		return withBaseErr(err, "process_details(%d) not found", proc.DetailsID)
+	}
+
+	alog.L().Info("get members", attrs("id", proc.ID)...)
+	members, err := p.ProcessGetter.GetProcessListByGroup(ctx, proc.ID)
+	if err != nil {
+		return withBaseErr(err, "failed to get members")
+	}
+	if len(members) == 0 {
+		return withBaseErr(errors.New("no members"), "failed to get members")
+	}
+
+	if err := f(ctx, &groupProcess{
+		proc:     proc,
+		details:  details,
+		members:  members,
+		workDir:  workDir,
+		logAttrs: logAttrs,
+	}); err != nil {
+		return withBaseErr(err, "failed to complete group")
+	}
+
+	alog.L().Info("succeed", attrs()...)
+	processSucceed = true
+	return nil
+}
+
+// uploadGroupResults uploads the files in resultDir as the results of the group.
+func (p *PneutrinoutilProcessor) uploadGroupResults(ctx context.Context, g *groupProcess, resultDir string) error {
+	resultObjectPath := filepath.Join(p.BasePath, g.proc.RequestID)
+	alog.L().Info("upload results", append(g.logAttrs, "from", resultDir, "to", resultObjectPath)...)
+	resultObjectId, err := p.uploadResults(ctx, resultDir, resultObjectPath, "")
+	if err != nil {
+		return fmt.Errorf("%w: failed to upload results", err)
+	}
+	alog.L().Info("update results in process details", append(g.logAttrs, "id", g.details.ID)...)
+	if _, err := p.ProcessDetailsUpdater.UpdateProcessDetails(ctx, &repo.UpdateProcessDetailsRequest{
+		ID:             g.details.ID,
+		ResultObjectId: &resultObjectId,
+	}); err != nil {
+		return fmt.Errorf("%w: failed to update process_details(%d)", err, g.details.ID)
+	}
+	return nil
+}
+
+// readObjectFile returns the content of the file object of the path.
+func (p *PneutrinoutilProcessor) readObjectFile(ctx context.Context, bucket, path string) ([]byte, error) {
+	obj, err := p.ObjectGetter.GetObjectByPath(ctx, bucket, path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := p.ObjectReader.ReadObject(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	stor, ok := r.Storage()
+	if !ok {
+		return nil, fmt.Errorf("object(%d) is not a file", obj.ID)
+	}
+	return io.ReadAll(stor.Blob)
+}
+
+// readResultFile returns the content of the file in the result directory object.
+func (p *PneutrinoutilProcessor) readResultFile(ctx context.Context, resultObjectID int, name string) ([]byte, error) {
+	dir, err := p.ObjectReader.ReadObject(ctx, resultObjectID)
+	if err != nil {
+		return nil, err
+	}
+	return p.readObjectFile(ctx, dir.Object().Bucket, filepath.Join(dir.Object().Path, name))
+}
@@ -0,0 +1,275 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypePneutrinoutilRetention = "pneutrinoutil:retention"
+)
+
+// RetentionPayload is the retention the worker registers to the scheduler.
+// The server reads it to report what the retention would reclaim.
+type RetentionPayload struct {
+	Policy *retention.Policy `json:"policy"`
+	// Limit is the max number of the processes a rule handles at once.
+	Limit int `json:"limit"`
+}
+
+// NewPneutrinoutilRetention returns the task to apply the retention policy, enqueued periodically by the scheduler.
+func NewPneutrinoutilRetention(p *RetentionPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypePneutrinoutilRetention, payload), nil
+}
+
+var ErrRetentionConflict = errors.New("RetentionConflict")
+
+// SchedulerEntryLister lists the tasks registered to the running schedulers, e.g. asynq.Inspector.
+type SchedulerEntryLister interface {
+	SchedulerEntries() ([]*asynq.SchedulerEntry, error)
+}
+
+// RegisteredRetention returns the retention registered by the workers, nil if the retention is disabled.
+// Returns ErrRetentionConflict if the workers registered different retentions.
+func RegisteredRetention(lister SchedulerEntryLister) (*RetentionPayload, error) {
+	entries, err := lister.SchedulerEntries()
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	for _, e := range entries {
+		if e.Task.Type() != TypePneutrinoutilRetention {
+			continue
+		}
+		if payload != nil && !bytes.Equal(payload, e.Task.Payload()) {
+			return nil, fmt.Errorf("%w: scheduler entry %s", ErrRetentionConflict, e.ID)
+		}
+		payload = e.Task.Payload()
+	}
+	if payload == nil {
+		return nil, nil
+	}
+	var x RetentionPayload
+	if err := json.Unmarshal(payload, &x); err != nil {
+		return nil, fmt.Errorf("%w: retention payload", err)
+	}
+	if x.Policy == nil {
+		return nil, fmt.Errorf("%w: no policy", retention.ErrInvalidPolicy)
+	}
+	return &x, nil
+}
+
+// retentionMetrics are the counters of what the retention reclaimed by rule, exported by expvar, e.g.
+//
+//	"retention": {"failed": {"errors": 0, "objects": 12, "processes": 3, "size_bytes": 1024}}
+var (
+	retentionMetrics   = expvar.NewMap("retention")
+	retentionMetricsMu sync.Mutex
+)
+
+func addRetentionMetrics(x *retention.RuleReport) {
+	retentionMetricsMu.Lock()
+	defer retentionMetricsMu.Unlock()
+	m, ok := retentionMetrics.Get(x.Name).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map).Init()
+		retentionMetrics.Set(x.Name, m)
+	}
+	m.Add("processes", int64(len(x.Processes)))
+	m.Add("objects", int64(x.Objects))
+	m.Add("size_bytes", int64(x.SizeBytes))
+	m.Add("errors", int64(x.Errors))
+}
+
+type RetentionParams struct {
+	Policy         *retention.Policy
+	ProcessGetter  repo.ProcessGetter
+	ProcessRemover repo.ProcessRemover
+	ProcessPruner  repo.ProcessPruner
+	// Limit is the max number of the processes a rule handles at once.
+	Limit int
+}
+
+func NewRetention(params *RetentionParams) *Retention {
+	return &Retention{
+		params,
+	}
+}
+
+// Retention applies the retention policy.
+type Retention struct {
+	*RetentionParams
+}
+
+// With returns the retention applying the registered policy.
+func (r *Retention) With(x *RetentionPayload) *Retention {
+	params := *r.RetentionParams
+	params.Policy = x.Policy
+	params.Limit = x.Limit
+	return NewRetention(&params)
+}
+
+// Apply applies the rules of the policy in order.
+// The process matched by a rule is not handled by the following rules.
+// The metrics are added unless dry run.
+func (r *Retention) Apply(ctx context.Context, now time.Time, dryRun bool) (*retention.Report, error) {
+	report := &retention.Report{
+		DryRun:    dryRun,
+		StartedAt: now,
+		Rules:     []*retention.RuleReport{},
+	}
+	handled := map[int]bool{}
+	for _, rule := range r.Policy.Rules {
+		x, err := r.apply(ctx, rule, now, dryRun, handled)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %s", err, rule.Name)
+		}
+		report.Add(x)
+		if !dryRun {
+			addRetentionMetrics(x)
+		}
+	}
+	return report, nil
+}
+
+func (r *Retention) apply(ctx context.Context, rule *retention.Rule, now time.Time, dryRun bool, handled map[int]bool) (*retention.RuleReport, error) {
+	procs, err := r.ProcessGetter.GetProcessListForRetention(ctx, &repo.GetProcessListForRetentionRequest{
+		Status:          rule.Statuses(),
+		CompletedBefore: rule.CompletedBefore(now),
+		ExcludePruned:   rule.Action == retention.ActionPrune,
+		Limit:           r.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &retention.RuleReport{
+		Name:      rule.Name,
+		Action:    rule.Action,
+		Processes: []string{},
+	}
+	for _, p := range procs {
+		if handled[p.ID] {
+			continue
+		}
+		handled[p.ID] = true
+
+		var (
+			res *repo.RemoveProcessesResponse
+			err error
+		)
+		switch rule.Action {
+		case retention.ActionDelete:
+			res, err = r.ProcessRemover.RemoveProcesses(ctx, &repo.RemoveProcessesRequest{
+				RequestIDs: []string{p.RequestID},
+				DryRun:     dryRun,
+			})
+		case retention.ActionPrune:
+			res, err = r.ProcessPruner.PruneProcesses(ctx, &repo.PruneProcessesRequest{
+				RequestIDs: []string{p.RequestID},
+				Keep:       rule.KeepPatterns(),
+				DryRun:     dryRun,
+			})
+		}
+		if err != nil {
+			// the process will be retried by the next run
+			alog.L().Error("retention", "rule", rule.Name, "action", rule.Action, "rid", p.RequestID, logx.Err(err))
+			report.Errors++
+			continue
+		}
+		report.Processes = append(report.Processes, p.RequestID)
+		report.Objects += res.Objects
+		report.SizeBytes += res.SizeBytes
+	}
+	return report, nil
+}
+
+// ProcessRetention applies the retention policy of the task and writes the report as the result of the task.
+func (r *Retention) ProcessRetention(ctx context.Context, t *asynq.Task) error {
+	x := r
+	if len(t.Payload()) > 0 {
+		// the task may be registered by the other worker
+		var p RetentionPayload
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("%w: retention payload", err)
+		}
+		if p.Policy != nil {
+			x = r.With(&p)
+		}
+	}
+	report, err := x.Apply(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	alog.L().Info("retention",
+		"processes", report.Processes,
+		"objects", report.Objects,
+		"size_bytes", report.SizeBytes,
+		"errors", report.Errors,
+	)
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if _, err := t.ResultWriter().Write(b); err != nil {
+		return fmt.Errorf("%w: write retention report", err)
+	}
+	return nil
+}
+
+// CompletedTaskLister lists the completed tasks retained in the queue, e.g. asynq.Inspector.
+type CompletedTaskLister interface {
+	ListCompletedTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+}
+
+// listCompletedTasksPageSize is the page size to list the completed tasks.
+const listCompletedTasksPageSize = 100
+
+// ListRetentionReports returns the reports of the retention tasks completed recently, the latest first.
+func ListRetentionReports(lister CompletedTaskLister, size int) ([]*retention.Report, error) {
+	reports := []*retention.Report{}
+	// the completed tasks are listed in order of the expiration, the oldest first
+	for page := 1; ; page++ {
+		tasks, err := lister.ListCompletedTasks(DefaultQueue, asynq.Page(page), asynq.PageSize(listCompletedTasksPageSize))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			if t.Type != TypePneutrinoutilRetention || len(t.Result) == 0 {
+				continue
+			}
+			var x retention.Report
+			if err := json.Unmarshal(t.Result, &x); err != nil {
+				return nil, fmt.Errorf("%w: retention report %s", err, t.ID)
+			}
+			reports = append(reports, &x)
+		}
+		if len(tasks) < listCompletedTasksPageSize {
+			break
+		}
+	}
+	slices.SortFunc(reports, func(a, b *retention.Report) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	if len(reports) > size {
+		reports = reports[:size]
+	}
+	return reports, nil
+}
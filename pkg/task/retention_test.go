@@ -0,0 +1,393 @@
+package task_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockCompletedTaskLister returns the pages in order, ignoring the options.
+type mockCompletedTaskLister struct {
+	pages [][]*asynq.TaskInfo
+	calls int
+}
+
+func (m *mockCompletedTaskLister) ListCompletedTasks(_ string, _ ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	m.calls++
+	if m.calls > len(m.pages) {
+		return []*asynq.TaskInfo{}, nil
+	}
+	return m.pages[m.calls-1], nil
+}
+
+func TestListRetentionReports(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	retentionTask := func(t *testing.T, day int) *asynq.TaskInfo {
+		b, err := json.Marshal(&retention.Report{
+			StartedAt: base.AddDate(0, 0, day),
+			Rules:     []*retention.RuleReport{},
+		})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return &asynq.TaskInfo{
+			ID:     fmt.Sprint(day),
+			Type:   task.TypePneutrinoutilRetention,
+			Result: b,
+		}
+	}
+	otherTask := func(id int) *asynq.TaskInfo {
+		return &asynq.TaskInfo{
+			ID:     fmt.Sprintf("other%d", id),
+			Type:   task.TypePneutrinoutilStart,
+			Result: []byte(`{}`),
+		}
+	}
+	startedAt := func(xs []*retention.Report) []time.Time {
+		r := make([]time.Time, len(xs))
+		for i, x := range xs {
+			r[i] = x.StartedAt
+		}
+		return r
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		got, err := task.ListRetentionReports(&mockCompletedTaskLister{}, 3)
+		assert.Nil(t, err)
+		assert.Equal(t, []*retention.Report{}, got)
+	})
+
+	t.Run("latest of all pages", func(t *testing.T) {
+		// the oldest first, a full page and the last page
+		first := []*asynq.TaskInfo{retentionTask(t, 0), retentionTask(t, 1)}
+		for i := range 98 {
+			first = append(first, otherTask(i))
+		}
+		lister := &mockCompletedTaskLister{
+			pages: [][]*asynq.TaskInfo{
+				first,
+				{retentionTask(t, 2), otherTask(100), retentionTask(t, 3), retentionTask(t, 4)},
+			},
+		}
+		got, err := task.ListRetentionReports(lister, 3)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2, lister.calls)
+		assert.Equal(t, []time.Time{
+			base.AddDate(0, 0, 4),
+			base.AddDate(0, 0, 3),
+			base.AddDate(0, 0, 2),
+		}, startedAt(got))
+	})
+
+	t.Run("less than size", func(t *testing.T) {
+		lister := &mockCompletedTaskLister{
+			pages: [][]*asynq.TaskInfo{
+				{retentionTask(t, 0), otherTask(0), retentionTask(t, 1)},
+			},
+		}
+		got, err := task.ListRetentionReports(lister, 3)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, lister.calls)
+		assert.Equal(t, []time.Time{
+			base.AddDate(0, 0, 1),
+			base,
+		}, startedAt(got))
+	})
+}
+
+// mockRetentionGetter returns the processes of the rule, keyed by the first status of the request.
+type mockRetentionGetter struct {
+	repo.ProcessGetter
+	procs    map[domain.ProcessStatus][]*domain.Process
+	err      error
+	requests []*repo.GetProcessListForRetentionRequest
+}
+
+func (m *mockRetentionGetter) GetProcessListForRetention(_ context.Context, req *repo.GetProcessListForRetentionRequest) ([]*domain.Process, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.procs[req.Status[0]], nil
+}
+
+// mockRetentionAdmin records the removed and the pruned processes in order.
+type mockRetentionAdmin struct {
+	calls  []string
+	failed map[string]bool // request ids to fail
+	dryRun []bool
+	keep   [][]string
+}
+
+var errRetention = errors.New("Retention")
+
+func (m *mockRetentionAdmin) call(action, rid string, dryRun bool) (*repo.RemoveProcessesResponse, error) {
+	m.calls = append(m.calls, action+" "+rid)
+	m.dryRun = append(m.dryRun, dryRun)
+	if m.failed[rid] {
+		return nil, errRetention
+	}
+	return &repo.RemoveProcessesResponse{
+		RequestIDs: []string{rid},
+		Objects:    2,
+		SizeBytes:  10,
+	}, nil
+}
+
+func (m *mockRetentionAdmin) RemoveProcesses(_ context.Context, req *repo.RemoveProcessesRequest) (*repo.RemoveProcessesResponse, error) {
+	return m.call("delete", req.RequestIDs[0], req.DryRun)
+}
+
+func (m *mockRetentionAdmin) PruneProcesses(_ context.Context, req *repo.PruneProcessesRequest) (*repo.RemoveProcessesResponse, error) {
+	m.keep = append(m.keep, req.Keep)
+	return m.call("prune", req.RequestIDs[0], req.DryRun)
+}
+
+func TestRetentionApply(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	proc := func(id int) *domain.Process {
+		return &domain.Process{
+			ID:        id,
+			RequestID: fmt.Sprintf("rid%d", id),
+		}
+	}
+	policy := &retention.Policy{
+		Rules: []*retention.Rule{
+			{
+				Name:          "failed",
+				Status:        []string{"failed", "cancelled"},
+				OlderThanDays: 7,
+				Action:        retention.ActionDelete,
+			},
+			{
+				Name:          "artifacts",
+				Status:        []string{"succeed", "failed"},
+				OlderThanDays: 30,
+				Action:        retention.ActionPrune,
+				Keep:          []string{"*.wav"},
+			},
+		},
+	}
+	newRetention := func(getter *mockRetentionGetter, admin *mockRetentionAdmin) *task.Retention {
+		return task.NewRetention(&task.RetentionParams{
+			Policy:         policy,
+			ProcessGetter:  getter,
+			ProcessRemover: admin,
+			ProcessPruner:  admin,
+			Limit:          100,
+		})
+	}
+
+	metric := func(rule, key string) int64 {
+		m, ok := expvar.Get("retention").(*expvar.Map).Get(rule).(*expvar.Map)
+		if !ok {
+			return 0
+		}
+		return m.Get(key).(*expvar.Int).Value()
+	}
+
+	t.Run("rules in order", func(t *testing.T) {
+		var (
+			processes = metric("failed", "processes")
+			objects   = metric("failed", "objects")
+			sizeBytes = metric("artifacts", "size_bytes")
+		)
+		getter := &mockRetentionGetter{
+			procs: map[domain.ProcessStatus][]*domain.Process{
+				domain.ProcessStatusFailed: {proc(1), proc(2)},
+				// rid1 is deleted by the failed rule
+				domain.ProcessStatusSucceed: {proc(1), proc(3)},
+			},
+		}
+		admin := &mockRetentionAdmin{}
+		got, err := newRetention(getter, admin).Apply(t.Context(), now, false)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		assert.Equal(t, []*repo.GetProcessListForRetentionRequest{
+			{
+				Status:          []domain.ProcessStatus{domain.ProcessStatusFailed, domain.ProcessStatusCancelled},
+				CompletedBefore: time.Date(2025, 3, 24, 12, 0, 0, 0, time.UTC),
+				ExcludePruned:   false,
+				Limit:           100,
+			},
+			{
+				Status:          []domain.ProcessStatus{domain.ProcessStatusSucceed, domain.ProcessStatusFailed},
+				CompletedBefore: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+				ExcludePruned:   true,
+				Limit:           100,
+			},
+		}, getter.requests)
+		assert.Equal(t, []string{"delete rid1", "delete rid2", "prune rid3"}, admin.calls)
+		assert.Equal(t, []bool{false, false, false}, admin.dryRun)
+		assert.Equal(t, [][]string{{"*.wav"}}, admin.keep)
+		assert.Equal(t, &retention.Report{
+			DryRun:    false,
+			StartedAt: now,
+			Rules: []*retention.RuleReport{
+				{
+					Name:      "failed",
+					Action:    retention.ActionDelete,
+					Processes: []string{"rid1", "rid2"},
+					Objects:   4,
+					SizeBytes: 20,
+				},
+				{
+					Name:      "artifacts",
+					Action:    retention.ActionPrune,
+					Processes: []string{"rid3"},
+					Objects:   2,
+					SizeBytes: 10,
+				},
+			},
+			Processes: 3,
+			Objects:   6,
+			SizeBytes: 30,
+		}, got)
+		assert.Equal(t, processes+2, metric("failed", "processes"))
+		assert.Equal(t, objects+4, metric("failed", "objects"))
+		assert.Equal(t, sizeBytes+10, metric("artifacts", "size_bytes"))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		getter := &mockRetentionGetter{
+			procs: map[domain.ProcessStatus][]*domain.Process{
+				domain.ProcessStatusFailed:  {proc(1)},
+				domain.ProcessStatusSucceed: {proc(2)},
+			},
+		}
+		admin := &mockRetentionAdmin{}
+		processes := metric("failed", "processes")
+		got, err := newRetention(getter, admin).Apply(t.Context(), now, true)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"delete rid1", "prune rid2"}, admin.calls)
+		assert.Equal(t, []bool{true, true}, admin.dryRun)
+		assert.True(t, got.DryRun)
+		assert.Equal(t, 2, got.Processes)
+		assert.Equal(t, processes, metric("failed", "processes"), "dry run is not counted")
+	})
+
+	t.Run("failed process", func(t *testing.T) {
+		getter := &mockRetentionGetter{
+			procs: map[domain.ProcessStatus][]*domain.Process{
+				domain.ProcessStatusFailed: {proc(1), proc(2)},
+				// rid1 is not retried by the following rule
+				domain.ProcessStatusSucceed: {proc(1)},
+			},
+		}
+		admin := &mockRetentionAdmin{
+			failed: map[string]bool{"rid1": true},
+		}
+		errs := metric("failed", "errors")
+		got, err := newRetention(getter, admin).Apply(t.Context(), now, false)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"delete rid1", "delete rid2"}, admin.calls)
+		assert.Equal(t, []string{"rid2"}, got.Rules[0].Processes)
+		assert.Equal(t, 1, got.Rules[0].Errors)
+		assert.Equal(t, []string{}, got.Rules[1].Processes)
+		assert.Equal(t, 1, got.Processes)
+		assert.Equal(t, 1, got.Errors)
+		assert.Equal(t, errs+1, metric("failed", "errors"))
+	})
+
+	t.Run("getter error", func(t *testing.T) {
+		getter := &mockRetentionGetter{
+			err: errRetention,
+		}
+		admin := &mockRetentionAdmin{}
+		_, err := newRetention(getter, admin).Apply(t.Context(), now, false)
+		assert.ErrorIs(t, err, errRetention)
+		assert.Empty(t, admin.calls)
+	})
+}
+
+// mockSchedulerEntryLister returns the entries.
+type mockSchedulerEntryLister struct {
+	entries []*asynq.SchedulerEntry
+}
+
+func (m *mockSchedulerEntryLister) SchedulerEntries() ([]*asynq.SchedulerEntry, error) {
+	return m.entries, nil
+}
+
+func TestRegisteredRetention(t *testing.T) {
+	newPayload := func(days int) *task.RetentionPayload {
+		return &task.RetentionPayload{
+			Policy: &retention.Policy{
+				Rules: []*retention.Rule{
+					{
+						Name:          "failed",
+						Status:        []string{"failed"},
+						OlderThanDays: days,
+						Action:        retention.ActionDelete,
+					},
+				},
+			},
+			Limit: 10,
+		}
+	}
+	entry := func(t *testing.T, id string, p *task.RetentionPayload) *asynq.SchedulerEntry {
+		x, err := task.NewPneutrinoutilRetention(p)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return &asynq.SchedulerEntry{
+			ID:   id,
+			Task: x,
+		}
+	}
+	other := &asynq.SchedulerEntry{
+		ID:   "other",
+		Task: asynq.NewTask(task.TypePneutrinoutilStart, nil),
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, err := task.RegisteredRetention(&mockSchedulerEntryLister{
+			entries: []*asynq.SchedulerEntry{other},
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("registered by workers", func(t *testing.T) {
+		got, err := task.RegisteredRetention(&mockSchedulerEntryLister{
+			entries: []*asynq.SchedulerEntry{
+				other,
+				entry(t, "worker1", newPayload(7)),
+				entry(t, "worker2", newPayload(7)),
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, newPayload(7), got)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := task.RegisteredRetention(&mockSchedulerEntryLister{
+			entries: []*asynq.SchedulerEntry{
+				entry(t, "worker1", newPayload(7)),
+				entry(t, "worker2", newPayload(30)),
+			},
+		})
+		assert.ErrorIs(t, err, task.ErrRetentionConflict)
+	})
+}
@@ -0,0 +1,81 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypePneutrinoutilSweep = "pneutrinoutil:sweep"
+)
+
+// SweepIndexObjectPath returns the path of the index of the sweep created when the sweep is requested.
+// The entries are in the order of the members.
+func SweepIndexObjectPath(basePath, requestID string) string {
+	return filepath.Join(basePath, requestID, "sweep", sweep.IndexJSONFileName)
+}
+
+// ProcessSweep writes the index of the results of the members of the sweep.
+// The sweep fails if any member failed, but the index is available.
+func (p *PneutrinoutilProcessor) ProcessSweep(ctx context.Context, t *asynq.Task) error {
+	return p.processGroup(ctx, t, func(ctx context.Context, g *groupProcess) error {
+		resultDir := filepath.Join(g.workDir, "result")
+		if err := pathx.EnsureDir(resultDir); err != nil {
+			return err
+		}
+
+		b, err := p.readObjectFile(ctx, p.Bucket, SweepIndexObjectPath(p.BasePath, g.proc.RequestID))
+		if err != nil {
+			return fmt.Errorf("%w: read index", err)
+		}
+		var index sweep.Index
+		if err := json.Unmarshal(b, &index); err != nil {
+			return fmt.Errorf("%w: unmarshal index", err)
+		}
+		if len(index.Entries) != len(g.members) {
+			return fmt.Errorf("index has %d entries but got %d members", len(index.Entries), len(g.members))
+		}
+
+		var failed int
+		for i, m := range g.members {
+			x := index.Entries[i]
+			x.OK = m.Status == domain.ProcessStatusSucceed
+			if !x.OK {
+				x.Error = m.Status.String()
+				failed++
+			}
+		}
+
+		for _, x := range []struct {
+			name  string
+			write func(*bytes.Buffer) error
+		}{
+			{name: sweep.IndexJSONFileName, write: func(w *bytes.Buffer) error { return index.WriteJSON(w) }},
+			{name: sweep.IndexHTMLFileName, write: func(w *bytes.Buffer) error { return index.WriteHTML(w) }},
+		} {
+			var buf bytes.Buffer
+			if err := x.write(&buf); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(resultDir, x.name), buf.Bytes(), 0644); err != nil {
+				return err
+			}
+		}
+		if err := p.uploadGroupResults(ctx, g, resultDir); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d members failed", failed, len(g.members))
+		}
+		return nil
+	})
+}
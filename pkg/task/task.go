@@ -14,7 +14,9 @@ import (
 	"time"
 
 	"al.essio.dev/pkg/shellescape"
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
 	"github.com/berquerant/pneutrinoutil/pkg/domain"
 	"github.com/berquerant/pneutrinoutil/pkg/infra"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
@@ -27,13 +29,38 @@ const (
 	TypePneutrinoutilStart = "pneutrinoutil:start"
 )
 
+// DefaultQueue is the queue of the tasks.
+const DefaultQueue = "default"
+
+// TaskID returns the id of the task of the process, to delete or cancel the task of the process.
+func TaskID(typ, requestID string) string {
+	return typ + ":" + requestID
+}
+
+// ProcessTaskType returns the type of the task that runs the process of the kind.
+func ProcessTaskType(kind domain.ProcessKind) (string, error) {
+	switch kind {
+	case domain.ProcessKindRender:
+		return TypePneutrinoutilStart, nil
+	case domain.ProcessKindEnsemble:
+		return TypePneutrinoutilEnsemble, nil
+	case domain.ProcessKindSweep:
+		return TypePneutrinoutilSweep, nil
+	default:
+		return "", fmt.Errorf("%w: unknown process kind %d", ErrTask, kind)
+	}
+}
+
 type PneutrinoutilStartPayload struct {
-	RequestID string   `json:"rid"`
-	Args      []string `json:"args"`
+	RequestID string `json:"rid"`
+	// Config is the config of the job validated by the server.
+	// The score and the accompaniment are given by the worker.
+	Config                *ctl.Config `json:"config"`
+	AccompanimentObjectID *int        `json:"accompaniment,omitempty"`
 }
 
 func NewPneutrinoutilStart(p PneutrinoutilStartPayload) (*asynq.Task, error) {
-	alog.L().Debug("NewPneutrinoutilStart", slog.String("rid", p.RequestID), slog.Any("args", p.Args))
+	alog.L().Debug("NewPneutrinoutilStart", slog.String("rid", p.RequestID), slog.Any("config", p.Config))
 	payload, err := json.Marshal(p)
 	if err != nil {
 		return nil, err
@@ -45,6 +72,16 @@ type PneutrinoutilStartWebhookParams struct {
 	Type      string `json:"type"`
 	RequestID string `json:"rid"`
 	OK        bool   `json:"ok"`
+	Status    string `json:"status"` // succeed, failed or cancelled
+}
+
+func NewPneutrinoutilStartWebhookParams(typ, requestID string, status domain.ProcessStatus) *PneutrinoutilStartWebhookParams {
+	return &PneutrinoutilStartWebhookParams{
+		Type:      typ,
+		RequestID: requestID,
+		OK:        status == domain.ProcessStatusSucceed,
+		Status:    status.String(),
+	}
 }
 
 type PneutrinoutilProcessorParams struct {
@@ -57,7 +94,9 @@ type PneutrinoutilProcessorParams struct {
 	Env           []string
 
 	Webhooker             infra.Webhooker // optional
+	Enqueuer              Enqueuer        // enqueues the task to complete the group
 	ObjectReader          repo.ObjectReader
+	ObjectGetter          repo.ObjectGetter
 	ObjectWriter          repo.ObjectWriter
 	ProcessDetailsGetter  repo.ProcessDetailsGetter
 	ProcessDetailsUpdater repo.ProcessDetailsUpdater
@@ -101,6 +140,10 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 		return verr
 	}
 
+	if payload.Config == nil {
+		return fmt.Errorf("%w: no config", baseErr)
+	}
+
 	alog.L().Info("create work dir", attrs("dir", p.WorkDir)...)
 	workDir := filepath.Join(p.WorkDir, payload.RequestID)
 	if err := pathx.EnsureDir(workDir); err != nil {
@@ -112,26 +155,22 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 	if err != nil {
 		return withBaseErr(err, "failed to get process")
 	}
-	if proc.Status != domain.ProcessStatusPending {
-		return fmt.Errorf("%w: status is not pending", baseErr)
-	}
-	if _, err := p.ProcessUpdater.UpdateProcess(ctx, &repo.UpdateProcessRequest{
-		ID:        proc.ID,
-		Status:    new(domain.ProcessStatusRunning),
-		StartedAt: new(time.Now()),
-	}); err != nil {
+	if ok, err := p.startProcess(ctx, proc); err != nil {
 		return withBaseErr(err, "failed to update process(%d)", proc.ID)
+	} else if !ok {
+		alog.L().Info("cancelled", attrs()...)
+		return nil
 	}
 
 	var processSucceed bool
 	defer func() {
-		if err := p.updateProcessStatus(ctx, proc.ID, time.Now(), processSucceed); err != nil {
-			alog.L().Error("update process status", attrs("succeed", processSucceed, logx.Err(err))...)
-		} else {
-			alog.L().Info("update process status", attrs("succeed", processSucceed)...)
-		}
-		if err := p.webhook(ctx, payload.RequestID, processSucceed); err != nil {
-			alog.L().Error("webhook failed", attrs(logx.Err(err))...)
+		// the context is done if the task is cancelled or timed out
+		ctx := context.WithoutCancel(ctx)
+		p.completeProcess(ctx, TypePneutrinoutilStart, proc, processSucceed, attrs)
+		if groupID := proc.GroupID; groupID != nil {
+			if err := p.enqueueGroup(ctx, *groupID); err != nil {
+				alog.L().Error("enqueue group failed", attrs("group", *groupID, logx.Err(err))...)
+			}
 		}
 	}()
 
@@ -141,27 +180,20 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 		return withBaseErr(err, "process_details(%d) not found", proc.DetailsID)
 	}
 	alog.L().Info("get score object", attrs("id", details.ScoreObjectID)...)
-	scoreObject, err := p.ObjectReader.ReadObject(ctx, details.ScoreObjectID)
+	scorePath, err := p.downloadObject(ctx, details.ScoreObjectID, workDir)
 	if err != nil {
-		return withBaseErr(err, "score object(%d) not found", details.ScoreObjectID)
-	}
-	if scoreObject.Object().Type != domain.ObjectTypeFile {
-		return fmt.Errorf("%w: score object(%d) is not a file", baseErr, details.ScoreObjectID)
+		return withBaseErr(err, "create local score file from object(%d)", details.ScoreObjectID)
 	}
+	alog.L().Info("created local score file", attrs("path", scorePath)...)
 
-	score, _ := scoreObject.Storage()
-	scorePath := filepath.Join(workDir, filepath.Base(score.Path))
-	alog.L().Info("create local score file", attrs("path", scorePath)...)
-	if err := func() error {
-		f, err := os.Create(scorePath)
+	var accompanimentPath string
+	if objectID := payload.AccompanimentObjectID; objectID != nil {
+		alog.L().Info("get accompaniment object", attrs("id", *objectID)...)
+		accompanimentPath, err = p.downloadObject(ctx, *objectID, filepath.Join(workDir, "accompaniment"))
 		if err != nil {
-			return err
+			return withBaseErr(err, "create local accompaniment file from object(%d)", *objectID)
 		}
-		defer func() { _ = f.Close() }()
-		_, err = io.Copy(f, score.Blob)
-		return err
-	}(); err != nil {
-		return withBaseErr(err, "create local score file")
+		alog.L().Info("created local accompaniment file", attrs("path", accompanimentPath)...)
 	}
 
 	logPath := filepath.Join(workDir, "process.log")
@@ -171,11 +203,18 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 		return withBaseErr(err, "failed to create local log file")
 	}
 
-	args := p.generateArgs(&payload, workDir, scorePath)
+	configPath := filepath.Join(workDir, "config.json")
+	alog.L().Info("create local config file", attrs("path", configPath)...)
+	if err := p.writeConfig(&payload, configPath, scorePath, accompanimentPath); err != nil {
+		_ = logFile.Close()
+		return withBaseErr(err, "failed to create local config file")
+	}
+
+	args := p.generateArgs(workDir, configPath)
 	alog.L().Info("start pneutrinoutil", attrs("args", args)...)
 	if _, err := p.ProcessDetailsUpdater.UpdateProcessDetails(ctx, &repo.UpdateProcessDetailsRequest{
 		ID:      details.ID,
-		Command: new(strings.Join(args, " ")),
+		Command: new(commandLine(p.Pneutrinoutil, args)),
 	}); err != nil {
 		_ = logFile.Close()
 		return withBaseErr(err, "failed to update process details(%d) command", details.ID)
@@ -195,8 +234,12 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
 	cmd.Env = p.Env
+	killProcessGroup(cmd)
 	err = cmd.Run()
 	_ = logFile.Close()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return withBaseErr(errors.Join(ctxErr, err), "pneutrinoutil process is cancelled or timed out")
+	}
 	if err != nil {
 		addErr(withBaseErr(err, "failed to run pneutrinoutil process"))
 	}
@@ -220,8 +263,9 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 			addErr(withBaseErr(err, "failed to find local results directory"))
 			return nil
 		}
+		p.writePeaks(resultDir, pathx.Basename(scorePath), attrs)
 		alog.L().Info("upload results", attrs("from", resultDir, "to", resultObjectPath)...)
-		resultObjectId, err := p.uploadResults(ctx, resultDir, resultObjectPath, filepath.Base(score.Path))
+		resultObjectId, err := p.uploadResults(ctx, resultDir, resultObjectPath, filepath.Base(scorePath))
 		if err != nil {
 			addErr(withBaseErr(err, "failed to upload results"))
 			return nil
@@ -247,20 +291,96 @@ func (p *PneutrinoutilProcessor) ProcessStart(ctx context.Context, t *asynq.Task
 	return nil
 }
 
-func (p *PneutrinoutilProcessor) generateArgs(payload *PneutrinoutilStartPayload, workDir, scorePath string) []string {
-	args := append([]string{
-		"--desc", payload.RequestID,
+// writeConfig writes the config of the job with the local files into path.
+func (p *PneutrinoutilProcessor) writeConfig(payload *PneutrinoutilStartPayload, path, scorePath, accompanimentPath string) error {
+	c := *payload.Config
+	c.Score = scorePath
+	c.Accompaniment = accompanimentPath
+	if c.Description == "" {
+		c.Description = payload.RequestID
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// generateArgs returns the arguments for pneutrinoutil, the job is in the config file.
+func (p *PneutrinoutilProcessor) generateArgs(workDir, configPath string) []string {
+	return []string{
 		"--neutrinoDir", p.NeutrinoDir,
 		"--workDir", workDir,
-		"--score", scorePath,
 		"--env", "all",
 		"--shell", p.Shell,
-	}, payload.Args...)
-	escaped := make([]string, len(args))
+		configPath,
+	}
+}
+
+// commandLine returns the command line to be recorded.
+func commandLine(name string, args []string) string {
+	xs := make([]string, len(args)+1)
+	xs[0] = shellescape.Quote(name)
 	for i, x := range args {
-		escaped[i] = shellescape.Quote(x)
+		xs[i+1] = shellescape.Quote(x)
 	}
-	return escaped
+	return strings.Join(xs, " ")
+}
+
+// downloadObject writes the file object into dir and returns the path of the local file.
+func (p *PneutrinoutilProcessor) downloadObject(ctx context.Context, objectID int, dir string) (string, error) {
+	obj, err := p.ObjectReader.ReadObject(ctx, objectID)
+	if err != nil {
+		return "", err
+	}
+	stor, ok := obj.Storage()
+	if !ok {
+		return "", fmt.Errorf("object(%d) is not a file", objectID)
+	}
+	if err := pathx.EnsureDir(dir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filepath.Base(stor.Path))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(f, stor.Blob); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writePeaks generates the peaks of the wav in resultDir, logging the error if any.
+func (p *PneutrinoutilProcessor) writePeaks(resultDir, basename string, attrs func(...any) []any) {
+	alog.L().Info("generate peaks", attrs("dir", resultDir)...)
+	if err := p.generatePeaks(resultDir, basename); err != nil {
+		// peaks are optional, the result is still available without them
+		alog.L().Warn("failed to generate peaks", attrs(logx.Err(err))...)
+	}
+}
+
+// generatePeaks writes waveform peaks of the generated wav into resultDir.
+func (p *PneutrinoutilProcessor) generatePeaks(resultDir, basename string) error {
+	w, err := audio.ReadFile(filepath.Join(resultDir, basename+".wav"))
+	if err != nil {
+		return err
+	}
+	for _, spp := range audio.PeaksResolutions {
+		peaks, err := audio.NewPeaks(w, spp, 8)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(peaks)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(resultDir, audio.PeaksFileName(spp)), b, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (p *PneutrinoutilProcessor) uploadLog(ctx context.Context, logPath, resultObjectPath string) (int, error) {
@@ -321,7 +441,6 @@ func (p *PneutrinoutilProcessor) writeObject(ctx context.Context, path string, b
 		Blob:   blob,
 	})
 	logAttrs := []any{
-		"task", TypePneutrinoutilStart,
 		"bucket", p.Bucket,
 		"path", path,
 	}
@@ -349,26 +468,62 @@ func (p *PneutrinoutilProcessor) findResultDir(workDir string) (string, error) {
 	return filepath.Join(workDir, "result", d.Name()), nil
 }
 
-func (p *PneutrinoutilProcessor) updateProcessStatus(ctx context.Context, processID int, now time.Time, processSucceed bool) error {
-	status := new(domain.ProcessStatusFailed)
+// startProcess makes the pending process running.
+// Returns false if the process is not pending, e.g. cancelled.
+func (p *PneutrinoutilProcessor) startProcess(ctx context.Context, proc *domain.Process) (bool, error) {
+	if proc.Status == domain.ProcessStatusCancelled {
+		return false, nil
+	}
+	if proc.Status != domain.ProcessStatusPending {
+		return false, errors.New("status is not pending")
+	}
+	_, err := p.ProcessUpdater.UpdateProcess(ctx, &repo.UpdateProcessRequest{
+		ID:         proc.ID,
+		Status:     new(domain.ProcessStatusRunning),
+		StartedAt:  new(time.Now()),
+		FromStatus: []domain.ProcessStatus{domain.ProcessStatusPending},
+	})
+	switch {
+	case errors.Is(err, infra.ErrRowsAffected):
+		// cancelled after got
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// completeProcess updates the status of the running process and calls the webhook.
+// Does nothing if the process is cancelled, the canceller has done them.
+func (p *PneutrinoutilProcessor) completeProcess(ctx context.Context, typ string, proc *domain.Process, processSucceed bool, attrs func(...any) []any) {
+	status := domain.ProcessStatusFailed
 	if processSucceed {
-		status = new(domain.ProcessStatusSucceed)
+		status = domain.ProcessStatusSucceed
 	}
 	_, err := p.ProcessUpdater.UpdateProcess(ctx, &repo.UpdateProcessRequest{
-		ID:          processID,
-		Status:      status,
-		CompletedAt: new(now),
+		ID:          proc.ID,
+		Status:      &status,
+		CompletedAt: new(time.Now()),
+		FromStatus:  []domain.ProcessStatus{domain.ProcessStatusRunning},
 	})
-	return err
+	switch {
+	case errors.Is(err, infra.ErrRowsAffected):
+		alog.L().Info("process is cancelled", attrs()...)
+		return
+	case err != nil:
+		alog.L().Error("update process status", attrs("succeed", processSucceed, logx.Err(err))...)
+	default:
+		alog.L().Info("update process status", attrs("succeed", processSucceed)...)
+	}
+	if err := p.webhook(ctx, typ, proc.RequestID, status); err != nil {
+		alog.L().Error("webhook failed", attrs(logx.Err(err))...)
+	}
 }
 
-func (p *PneutrinoutilProcessor) webhook(ctx context.Context, requestID string, processSucceed bool) error {
+func (p *PneutrinoutilProcessor) webhook(ctx context.Context, typ, requestID string, status domain.ProcessStatus) error {
 	if p.Webhooker == nil {
 		return nil
 	}
-	return p.Webhooker.Webhook(ctx, &PneutrinoutilStartWebhookParams{
-		Type:      TypePneutrinoutilStart,
-		RequestID: requestID,
-		OK:        processSucceed,
-	})
+	return p.Webhooker.Webhook(ctx, NewPneutrinoutilStartWebhookParams(typ, requestID, status))
 }
@@ -38,6 +38,9 @@ type Config struct {
 	StorageDir                  string `name:"storageDir" usage:"local storage directory; $HOME/.pneutrinoutil-worker/storage or .pneutrinoutil-worker/storage if no $HOME"`
 	StorageBucket               string `name:"storageBucket" default:"pneutrinoutil-worker" usage:"storage bucket"`
 	StoragePath                 string `name:"storagePath" usage:"storage base path"`
+	NeutrinoDir                 string `name:"neutrinoDir" usage:"NEUTRINO directory to restrict model and supportModel to the installed models; not restricted if empty"`
+	Webhook                     string `name:"webhook" usage:"webhook endpoint to notify task cancellation"`
+	WebhookTimeoutSeconds       int    `name:"webhookTimeoutSeconds" default:"10" usage:"duration webhook timeout"`
 }
 
 func (c Config) Addr() string { return fmt.Sprintf("%s:%d", c.Host, c.Port) }
@@ -50,6 +53,21 @@ func (c Config) NewAsynqClient() (*asynq.Client, error) {
 	return asynq.NewClient(opt), nil
 }
 
+func (c Config) NewAsynqInspector() (*asynq.Inspector, error) {
+	opt, err := asynq.ParseRedisURI(c.RedisDSN)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewInspector(opt), nil
+}
+
+func (c Config) NewWebhook() *infra.Webhook {
+	if c.Webhook == "" {
+		return nil
+	}
+	return infra.NewWebhook(c.Webhook, time.Duration(c.WebhookTimeoutSeconds)*time.Second)
+}
+
 func (c Config) NewStorage(ctx context.Context) (infra.Object, error) {
 	return infra.NewStorage(ctx, &infra.StorageParam{
 		UseS3:   c.StorageS3,
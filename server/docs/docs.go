@@ -51,7 +51,11 @@ const docTemplate = `{
         },
         "/proc": {
             "post": {
-                "description": "start a pneutrinoutil process with given arguments",
+                "description": "start a pneutrinoutil process with given config.\nthe config is the json of the options keyed like config.yml, see /schema/config; the options below are available.\nthe form values of the options override the config.\nto render the score of another process, send handler.StartRequest as application/json instead of the form.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -64,6 +68,42 @@ const docTemplate = `{
                         "in": "formData",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "json of the options of the job, e.g. {\"model\": \"MERROW\", \"thread\": 2}",
+                        "name": "config",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "title of the process; default: the score file name except extension",
+                        "name": "title",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "notes of the process",
+                        "name": "notes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "tags of the process separated by comma, case-insensitive",
+                        "name": "tags",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "description of the config of the job",
+                        "name": "desc",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of parallel in session; default: 4",
+                        "name": "thread",
+                        "in": "formData"
+                    },
                     {
                         "type": "string",
                         "description": "default: MERROW",
@@ -72,15 +112,1018 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "support singer library",
-                        "name": "supportModel",
-                        "in": "formData"
+                        "description": "support singer library",
+                        "name": "supportModel",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default: 0",
+                        "name": "transpose",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or name of the part to render, the whole score if empty",
+                        "name": "part",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "peak",
+                            "loudness"
+                        ],
+                        "type": "string",
+                        "description": "normalize the wav; peak or loudness (EBU R128), disabled if empty",
+                        "name": "normalize",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "target level of peak normalization, also the peak ceiling of loudness normalization, in dBFS; default: -1",
+                        "name": "peakLevel",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "target level of loudness normalization in LUFS; default: -23",
+                        "name": "loudnessLevel",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "trim or pad leading and trailing silence to leadingSilence and trailingSilence",
+                        "name": "trimSilence",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "level regarded as silence in dBFS; default: -60",
+                        "name": "silenceThreshold",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "seconds of leading silence after trimming",
+                        "name": "leadingSilence",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "seconds of trailing silence after trimming",
+                        "name": "trailingSilence",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "seconds of fade in",
+                        "name": "fadeIn",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "seconds of fade out",
+                        "name": "fadeOut",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "accompaniment wav to mix with the vocal",
+                        "name": "accompaniment",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "seconds to delay the vocal against the accompaniment, negative value delays the accompaniment",
+                        "name": "mixOffset",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gain of the vocal in the mix in dB",
+                        "name": "vocalGain",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "pan of the vocal in the mix; -1 (left) to 1 (right)",
+                        "name": "vocalPan",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gain of the accompaniment in the mix in dB",
+                        "name": "accompanimentGain",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "pan of the accompaniment in the mix; -1 (left) to 1 (right)",
+                        "name": "accompanimentPan",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "additional output formats separated by comma; codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac,wav:48000:24",
+                        "name": "formats",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "new process started",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        },
+                        "headers": {
+                            "string x-request-id": {
+                                "type": "string",
+                                "description": "request id, or just id"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad score, accompaniment or config",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "no process of the score of handler.StartRequest",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "too big score or accompaniment",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/delete": {
+            "post": {
+                "description": "delete the completed processes in the same way as DELETE /proc/{id}.\nnothing is deleted if any process is missing, not completed or a member without its group.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "delete processes",
+                "parameters": [
+                    {
+                        "description": "processes to delete",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-handler_DeleteResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "process is pending or running, or a member of the group",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/ensemble": {
+            "post": {
+                "description": "render each voice of the score as a process and mix them into one wav.\nvoices are available as /proc/{id}-{index}, stems of the mix as /proc/{id}/stem/{index}.\nother form values and config are the same as /proc and applied to all voices.\nthe title is also of the voices, the notes and the tags are only of the ensemble.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "start an ensemble process",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "musicxml",
+                        "name": "score",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json array of voices; [{part, model, supportModel, transpose, gain, pan}], part is id or name of the part of the score",
+                        "name": "voices",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "new process started",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        },
+                        "headers": {
+                            "string x-request-id": {
+                                "type": "string",
+                                "description": "request id, or just id"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad score or voices",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "too big score",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/search": {
+            "get": {
+                "description": "search processes by the conditions below, all the conditions should be met.\nthe pages are ordered by the sort key and then the process id, so they are stable under the concurrent inserts.\npass next of the response as cursor with the same conditions to get the next page.\nsorting by completed excludes the uncompleted processes, by duration excludes the processes not started or uncompleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "search processes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "query limit; default: 5",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "request ids",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "title prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "title substring, case-insensitive",
+                        "name": "contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "process status; (pending|running|succeed|failed|cancelled)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "singer",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "support singer",
+                        "name": "supportModel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "transpose",
+                        "name": "transpose",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "completed_at",
+                        "name": "completedStart",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "completed_at",
+                        "name": "completedEnd",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "min seconds from started_at to completed_at",
+                        "name": "minDuration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max seconds from started_at to completed_at",
+                        "name": "maxDuration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "whether the wav of the result exists",
+                        "name": "wav",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "tags, all of them should be attached, case-insensitive",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort key; (created|completed|duration|title); default: created",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort order; (asc|desc); default: desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "count the processes matched",
+                        "name": "total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SearchProcessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/sweep": {
+            "post": {
+                "description": "render every combination of singers and transpose values as a process.\nruns are available as /proc/{id}-{index}, the index of them as /proc/{id}/sweep.\nvalues of the lists that are not specified are taken from model, supportModel and transpose.\nother form values and config are the same as /proc and applied to all runs.\nthe title is also of the runs, the notes and the tags are only of the sweep.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "start a sweep process",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "musicxml",
+                        "name": "score",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "yaml or json of the value lists to sweep; {model: [], supportModel: [], transpose: []}",
+                        "name": "spec",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "singers to sweep separated by comma, override the spec",
+                        "name": "models",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "support singers to sweep separated by comma, override the spec",
+                        "name": "supportModels",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "transpose values to sweep separated by comma, override the spec",
+                        "name": "transposes",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "new process started",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        },
+                        "headers": {
+                            "string x-request-id": {
+                                "type": "string",
+                                "description": "request id, or just id"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad score or spec",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "too big score",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}": {
+            "delete": {
+                "description": "delete the completed process and its score, log and results from both the database and the storage.\nthe members of the ensemble or the sweep are also deleted.\nthe member cannot be deleted alone, delete the group.\nthe score shared with the other processes, e.g. the reruns, is kept.\nthe reruns of the process are kept and unlinked from it.\nthe deletion can be retried if it failed in the middle.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "delete a process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-handler_DeleteResponseData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "process is pending or running, or a member of the group",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "edit the title, the notes and the tags of the process.\nthe fields not given are kept, the empty title resets it to the basename, the tags replace all of the tags.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "edit a process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "fields to edit",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.EditRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "edited",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid title, notes or tags",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/cancel": {
+            "post": {
+                "description": "cancel the pending or running process.\nthe pending process is deleted from the queue, the running process is killed.\nthe members of the ensemble or the sweep are also cancelled.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "cancel a process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "process is already completed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/config": {
+            "get": {
+                "description": "download pneutrinoutil config as json",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "download config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-ctl_Config"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/detail": {
+            "get": {
+                "description": "get process info",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "get process info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-handler_GetDetailResponseData"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/diff/{other}": {
+            "get": {
+                "description": "align the wav of the other process to the process, then compare the RMS, the f0 per note and the phoneme timings",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "compare processes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "request id to compare with",
+                        "name": "other",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "shift of a label in seconds to be reported as changed; default: 0.01",
+                        "name": "timingTolerance",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-compare_Diff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/diff/{other}/wav": {
+            "get": {
+                "description": "download the wav of the process minus the aligned wav of the other process",
+                "summary": "download residual wav",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "request id to compare with",
+                        "name": "other",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/label": {
+            "get": {
+                "description": "download the timing label of the wav generated by NEUTRINO",
+                "summary": "download timing label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/log": {
+            "get": {
+                "description": "download process log file",
+                "summary": "download log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/manifest": {
+            "get": {
+                "description": "download the manifest that records what is required to reproduce the render",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "download manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/mix": {
+            "get": {
+                "description": "download wav mixed with the accompaniment, available if the accompaniment is specified",
+                "summary": "download mix",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/musicxml": {
+            "get": {
+                "description": "download musicxml file",
+                "summary": "download musicxml",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/peaks": {
+            "get": {
+                "description": "download min/max waveform peaks of the wav, compatible with audiowaveform json format",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "download peaks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "samples per pixel; (256|1024|4096), default: 1024",
+                        "name": "resolution",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audio.Peaks"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/proc/{id}/rerun": {
+            "post": {
+                "description": "start a process with the score and the options of the process.\nthe options are overridden by the config of handler.RerunRequest, the body is optional.\nthe accompaniment is not reused.\nthe new process is linked to the process as the parent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "rerun a process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "default: 0",
-                        "name": "transpose",
-                        "in": "formData"
+                        "description": "options to override",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RerunRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -97,13 +1140,19 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "bad score",
+                        "description": "bad config or the process is not a render",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
-                    "413": {
-                        "description": "too big score",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "options of the process are not stored",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
@@ -117,62 +1166,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/proc/search": {
-            "get": {
-                "description": "search processes by status, created_at, title prefix, order by created_at desc",
+        "/proc/{id}/star": {
+            "put": {
+                "description": "star the process to keep it from the retention policies forever.\nthe ensemble or the sweep is also kept if any member is starred.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "search processes",
+                "summary": "star a process",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "query limit; default: 5",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "title prefix",
-                        "name": "prefix",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "process status; (pending|running|succeed|failed)",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "created_at",
-                        "name": "start",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "created_at",
-                        "name": "end",
-                        "in": "query"
+                        "description": "request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "starred",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/handler.SuccessResponse-handler_SearchProcessResponseData"
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/proc/{id}/config": {
-            "get": {
-                "description": "download pneutrinoutil config as json",
+            },
+            "delete": {
+                "description": "unstar the process to apply the retention policies to it.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "download config",
+                "summary": "unstar a process",
                 "parameters": [
                     {
                         "type": "string",
@@ -184,9 +1220,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "unstarred",
                         "schema": {
-                            "$ref": "#/definitions/handler.SuccessResponse-ctl_Config"
+                            "$ref": "#/definitions/handler.SuccessResponse-string"
                         }
                     },
                     "404": {
@@ -194,17 +1230,20 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/proc/{id}/detail": {
+        "/proc/{id}/stem/{index}": {
             "get": {
-                "description": "get process info",
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "get process info",
+                "description": "download wav of the voice of the ensemble, mixed into the wav of the ensemble",
+                "summary": "download stem",
                 "parameters": [
                     {
                         "type": "string",
@@ -212,13 +1251,26 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "index of the voice",
+                        "name": "index",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.SuccessResponse-handler_GetDetailResponseData"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "404": {
@@ -230,10 +1282,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/proc/{id}/log": {
+        "/proc/{id}/sweep": {
             "get": {
-                "description": "download process log file",
-                "summary": "download log",
+                "description": "download the index of the runs of the sweep",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "download sweep index",
                 "parameters": [
                     {
                         "type": "string",
@@ -247,7 +1302,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/sweep.Index"
                         }
                     },
                     "404": {
@@ -259,10 +1314,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/proc/{id}/musicxml": {
+        "/proc/{id}/sweep/html": {
             "get": {
-                "description": "download musicxml file",
-                "summary": "download musicxml",
+                "description": "download the html that lists the wavs of the runs of the sweep with the config",
+                "produces": [
+                    "text/html"
+                ],
+                "summary": "download sweep index page",
                 "parameters": [
                     {
                         "type": "string",
@@ -290,7 +1348,7 @@ const docTemplate = `{
         },
         "/proc/{id}/wav": {
             "get": {
-                "description": "download wav file generated by pneutrinoutil",
+                "description": "download wav file generated by pneutrinoutil, or transcoded one if format is specified",
                 "summary": "download wav",
                 "parameters": [
                     {
@@ -299,6 +1357,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac, wav:48000:24",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -308,6 +1372,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -317,6 +1387,83 @@ const docTemplate = `{
                 }
             }
         },
+        "/retention/report": {
+            "get": {
+                "description": "apply the retention policy registered by the worker in dry run mode, nothing is deleted.\nthe starred processes are never reclaimed.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "report what the retention would reclaim",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-retention_Report"
+                        }
+                    },
+                    "404": {
+                        "description": "retention is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/retention/runs": {
+            "get": {
+                "description": "list what the retention of the worker reclaimed, the latest first.\nthe reports are kept for the retentionReportDays of the worker.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "list the reports of the retention runs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "max number of the reports; default: 30",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SuccessResponse-handler_RetentionRunsResponseData"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schema/config": {
+            "get": {
+                "description": "get the JSON Schema of config.yml, also applied to the config of the processes",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "get config schema",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ctl.Schema"
+                        }
+                    }
+                }
+            }
+        },
         "/version": {
             "get": {
                 "description": "get server version",
@@ -333,15 +1480,211 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        }
+    },
+    "definitions": {
+        "audio.Peaks": {
+            "type": "object",
+            "properties": {
+                "bits": {
+                    "type": "integer"
+                },
+                "channels": {
+                    "type": "integer"
+                },
+                "data": {
+                    "description": "min and max per channel per pixel",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "length": {
+                    "description": "number of pixels",
+                    "type": "integer"
+                },
+                "sample_rate": {
+                    "type": "integer"
+                },
+                "samples_per_pixel": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "compare.Diff": {
+            "type": "object",
+            "properties": {
+                "lag": {
+                    "description": "Lag is the offset of b against a in seconds to align the wavs, positive means b is delayed.",
+                    "type": "number"
+                },
+                "notes": {
+                    "description": "Notes are the f0 differences per note, nil if the f0 or the labels are not available.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/compare.NoteDiff"
+                    }
+                },
+                "rms": {
+                    "$ref": "#/definitions/compare.RMSDiff"
+                },
+                "timing": {
+                    "description": "Timing is the difference of the labels, nil if the labels of either are not available.\nThe labels of b are shifted by the lag.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/compare.TimingShift"
+                        }
+                    ]
+                }
+            }
+        },
+        "compare.Label": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "description": "End is the end time in seconds.",
+                    "type": "number"
+                },
+                "phoneme": {
+                    "type": "string"
+                },
+                "start": {
+                    "description": "Start is the start time in seconds.",
+                    "type": "number"
+                }
+            }
+        },
+        "compare.LabelShift": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "$ref": "#/definitions/compare.Label"
+                },
+                "b": {
+                    "$ref": "#/definitions/compare.Label"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "shift": {
+                    "description": "Shift is the difference of the start times in seconds, b - a.",
+                    "type": "number"
+                }
+            }
+        },
+        "compare.NoteDiff": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "description": "A is the mean f0 of a in Hz, 0 if unvoiced.",
+                    "type": "number"
+                },
+                "b": {
+                    "description": "B is the mean f0 of b in Hz, 0 if unvoiced.",
+                    "type": "number"
+                },
+                "cents": {
+                    "description": "Cents is the interval from A to B, 0 if either is unvoiced.",
+                    "type": "number"
+                },
+                "end": {
+                    "description": "End is the end time of the note of a in seconds.",
+                    "type": "number"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "lyric": {
+                    "description": "Lyric is the phonemes of the note.",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "Start is the start time of the note of a in seconds.",
+                    "type": "number"
+                }
+            }
+        },
+        "compare.RMSDiff": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "number"
+                },
+                "b": {
+                    "type": "number"
+                },
+                "diff": {
+                    "type": "number"
+                }
+            }
+        },
+        "compare.TimingShift": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "description": "Changed are the pairs of the labels whose start times are shifted more than the tolerance.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/compare.LabelShift"
+                    }
+                },
+                "labels": {
+                    "description": "Labels is the number of the labels compared.",
+                    "type": "integer"
+                },
+                "maxShift": {
+                    "description": "MaxShift is the max of the absolute differences of the start times in seconds.",
+                    "type": "number"
+                },
+                "meanShift": {
+                    "description": "MeanShift is the mean of the absolute differences of the start times in seconds.",
+                    "type": "number"
+                },
+                "phonemeMismatch": {
+                    "description": "PhonemeMismatch is true if the phoneme sequences differ.",
+                    "type": "boolean"
+                }
+            }
+        },
         "ctl.Config": {
             "type": "object",
             "properties": {
+                "accompaniment": {
+                    "description": "Mix",
+                    "type": "string"
+                },
+                "accompanimentGain": {
+                    "type": "number"
+                },
+                "accompanimentPan": {
+                    "type": "number"
+                },
                 "desc": {
                     "type": "string"
                 },
+                "fadeIn": {
+                    "type": "number"
+                },
+                "fadeOut": {
+                    "type": "number"
+                },
+                "formats": {
+                    "description": "Transcode",
+                    "type": "string"
+                },
+                "leadingSilence": {
+                    "type": "number"
+                },
+                "loudnessLevel": {
+                    "type": "number",
+                    "default": -23
+                },
+                "mixOffset": {
+                    "type": "number"
+                },
                 "model": {
                     "description": "NEUTRINO",
                     "type": "string",
@@ -352,10 +1695,25 @@ const docTemplate = `{
                     "description": "Info",
                     "type": "string"
                 },
+                "normalize": {
+                    "description": "Post-process",
+                    "type": "string"
+                },
+                "part": {
+                    "type": "string"
+                },
+                "peakLevel": {
+                    "type": "number",
+                    "default": -1
+                },
                 "score": {
                     "description": "Project settings",
                     "type": "string"
                 },
+                "silenceThreshold": {
+                    "type": "number",
+                    "default": -60
+                },
                 "supportModel": {
                     "type": "string"
                 },
@@ -364,9 +1722,66 @@ const docTemplate = `{
                     "type": "integer",
                     "default": 4
                 },
+                "trailingSilence": {
+                    "type": "number"
+                },
                 "transpose": {
                     "type": "integer",
                     "default": 0
+                },
+                "trimSilence": {
+                    "type": "boolean"
+                },
+                "vocalGain": {
+                    "type": "number"
+                },
+                "vocalPan": {
+                    "type": "number"
+                }
+            }
+        },
+        "ctl.Schema": {
+            "type": "object",
+            "properties": {
+                "$schema": {
+                    "type": "string"
+                },
+                "additionalProperties": {
+                    "type": "boolean"
+                },
+                "properties": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/ctl.SchemaProperty"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "ctl.SchemaProperty": {
+            "type": "object",
+            "properties": {
+                "default": {},
+                "description": {
+                    "type": "string"
+                },
+                "enum": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "readOnly": {
+                    "description": "ReadOnly is true if the value is set by pneutrinoutil, not by the config.",
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         },
@@ -376,6 +1791,57 @@ const docTemplate = `{
                 "routes": {}
             }
         },
+        "handler.DeleteRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "description": "IDs are the request ids of the processes.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handler.DeleteResponseData": {
+            "type": "object",
+            "properties": {
+                "objects": {
+                    "description": "number of the deleted objects",
+                    "type": "integer"
+                },
+                "processes": {
+                    "description": "request ids of the deleted processes, including the members of the groups",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "size_bytes": {
+                    "description": "total size of the deleted files",
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.EditRequest": {
+            "type": "object",
+            "properties": {
+                "notes": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags replace the tags of the process, empty removes all of them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title is the title of the process, empty resets it to the basename.",
+                    "type": "string"
+                }
+            }
+        },
         "handler.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -404,15 +1870,100 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "group": {
+                    "description": "request id of the ensemble the voice belongs to",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "render or ensemble",
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options are the options of the job, keyed like config.yml.",
+                    "type": "object"
+                },
+                "parent": {
+                    "description": "request id of the process rerun by this process",
+                    "type": "string"
+                },
+                "reruns": {
+                    "description": "request ids of the processes that rerun this process",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "rid": {
                     "description": "request id, or just id",
                     "type": "string"
                 },
+                "runs": {
+                    "description": "request ids of the runs of the sweep",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "starred": {
+                    "description": "kept by the retention policies",
+                    "type": "boolean"
+                },
                 "started_at": {
                     "type": "string"
                 },
                 "status": {
                     "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "voices": {
+                    "description": "request ids of the voices of the ensemble",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handler.RerunRequest": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "description": "Config overrides the options of the process, keyed by ctl.JobKeys.",
+                    "type": "object"
+                }
+            }
+        },
+        "handler.SearchProcessResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.SearchProcessResponseDataElement"
+                    }
+                },
+                "next": {
+                    "description": "cursor of the next page, empty if the last page",
+                    "type": "string"
+                },
+                "ok": {
+                    "description": "true",
+                    "type": "boolean"
+                },
+                "total": {
+                    "description": "number of the processes matched if requested",
+                    "type": "integer"
                 }
             }
         },
@@ -428,16 +1979,33 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "notes": {
+                    "type": "string"
+                },
+                "parent": {
+                    "description": "request id of the process rerun by this process",
+                    "type": "string"
+                },
                 "request_id": {
                     "description": "request id, or just id",
                     "type": "string"
                 },
+                "starred": {
+                    "description": "kept by the retention policies",
+                    "type": "boolean"
+                },
                 "started_at": {
                     "type": "string"
                 },
                 "status": {
                     "type": "string"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "title": {
                     "type": "string"
                 },
@@ -446,6 +2014,18 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.SuccessResponse-compare_Diff": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/compare.Diff"
+                },
+                "ok": {
+                    "description": "true",
+                    "type": "boolean"
+                }
+            }
+        },
         "handler.SuccessResponse-ctl_Config": {
             "type": "object",
             "properties": {
@@ -470,6 +2050,18 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.SuccessResponse-handler_DeleteResponseData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handler.DeleteResponseData"
+                },
+                "ok": {
+                    "description": "true",
+                    "type": "boolean"
+                }
+            }
+        },
         "handler.SuccessResponse-handler_GetDetailResponseData": {
             "type": "object",
             "properties": {
@@ -482,13 +2074,13 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.SuccessResponse-handler_SearchProcessResponseData": {
+        "handler.SuccessResponse-handler_RetentionRunsResponseData": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handler.SearchProcessResponseDataElement"
+                        "$ref": "#/definitions/retention.Report"
                     }
                 },
                 "ok": {
@@ -509,6 +2101,18 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.SuccessResponse-retention_Report": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/retention.Report"
+                },
+                "ok": {
+                    "description": "true",
+                    "type": "boolean"
+                }
+            }
+        },
         "handler.SuccessResponse-string": {
             "type": "object",
             "properties": {
@@ -533,6 +2137,128 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "retention.Action": {
+            "type": "string",
+            "enum": [
+                "delete",
+                "prune"
+            ],
+            "x-enum-comments": {
+                "ActionDelete": "deletes the processes and their objects.",
+                "ActionPrune": "deletes the result files except the ones to keep."
+            },
+            "x-enum-varnames": [
+                "ActionDelete",
+                "ActionPrune"
+            ]
+        },
+        "retention.Report": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "number of the processes failed to be deleted or pruned",
+                    "type": "integer"
+                },
+                "objects": {
+                    "description": "number of the deleted objects",
+                    "type": "integer"
+                },
+                "processes": {
+                    "description": "number of the deleted or pruned processes",
+                    "type": "integer"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/retention.RuleReport"
+                    }
+                },
+                "size_bytes": {
+                    "description": "total size of the deleted files",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "retention.RuleReport": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/retention.Action"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "objects": {
+                    "type": "integer"
+                },
+                "processes": {
+                    "description": "request ids of the deleted or pruned processes",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "size_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "sweep.Entry": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "description": "path or url of the config.yml",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "request id or name of the result",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "ok": {
+                    "description": "true if rendered successfully",
+                    "type": "boolean"
+                },
+                "supportModel": {
+                    "type": "string"
+                },
+                "transpose": {
+                    "type": "integer"
+                },
+                "wav": {
+                    "description": "path or url of the wav",
+                    "type": "string"
+                }
+            }
+        },
+        "sweep.Index": {
+            "type": "object",
+            "properties": {
+                "basename": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/sweep.Entry"
+                    }
+                }
+            }
         }
     }
 }`
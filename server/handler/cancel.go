@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/labstack/echo/v5"
+)
+
+func NewCancel(processGetter repo.ProcessGetter, canceller *task.Canceller) *Cancel {
+	return &Cancel{
+		processGetter: processGetter,
+		canceller:     canceller,
+	}
+}
+
+type Cancel struct {
+	processGetter repo.ProcessGetter
+	canceller     *task.Canceller
+}
+
+// Cancel a process.
+//
+// @summary cancel a process
+// @description cancel the pending or running process.
+// @description the pending process is deleted from the queue, the running process is killed.
+// @description the members of the ensemble or the sweep are also cancelled.
+// @param id path string true "request id"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[string] "cancelled"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 409 {object} handler.ErrorResponse "process is already completed"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id}/cancel [post]
+func (h *Cancel) Handler(c *echo.Context) error {
+	var p GetParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+
+	proc, err := h.processGetter.GetProcessByRequestId(c.Request().Context(), p.RequestID)
+	if err != nil {
+		alog.L().Error("missing process", slog.String("id", echox.RequestID(c)), slog.String("param_id", p.RequestID), logx.Err(err))
+		return Error(c, http.StatusNotFound, "not found")
+	}
+
+	if err := h.canceller.Cancel(c.Request().Context(), proc); err != nil {
+		if errors.Is(err, task.ErrProcessDone) {
+			return Error(c, http.StatusConflict, "process is already completed")
+		}
+		alog.L().Error("cancel", slog.String("id", echox.RequestID(c)), slog.String("param_id", p.RequestID), logx.Err(err))
+		return Error(c, http.StatusInternalServerError, "failed to cancel")
+	}
+	return Success(c, http.StatusOK, "cancelled")
+}
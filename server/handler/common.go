@@ -12,7 +12,8 @@ import (
 )
 
 const (
-	uploadMaxSizeBytes = 1 << 20 // 1 MiB
+	uploadMaxSizeBytes              = 1 << 20  // 1 MiB
+	accompanimentUploadMaxSizeBytes = 64 << 20 // 64 MiB
 )
 
 type ErrorResponse struct {
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/labstack/echo/v5"
+)
+
+func NewDelete(remover repo.ProcessRemover) *Delete {
+	return &Delete{
+		remover: remover,
+	}
+}
+
+type Delete struct {
+	remover repo.ProcessRemover
+}
+
+// DeleteRequest is the json body to delete processes.
+type DeleteRequest struct {
+	// IDs are the request ids of the processes.
+	IDs []string `json:"ids"`
+}
+
+type DeleteResponseData struct {
+	Processes []string `json:"processes"`  // request ids of the deleted processes, including the members of the groups
+	Objects   int      `json:"objects"`    // number of the deleted objects
+	SizeBytes uint64   `json:"size_bytes"` // total size of the deleted files
+}
+
+// Delete a process.
+//
+// @summary delete a process
+// @description delete the completed process and its score, log and results from both the database and the storage.
+// @description the members of the ensemble or the sweep are also deleted.
+// @description the member cannot be deleted alone, delete the group.
+// @description the score shared with the other processes, e.g. the reruns, is kept.
+// @description the reruns of the process are kept and unlinked from it.
+// @description the deletion can be retried if it failed in the middle.
+// @param id path string true "request id"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[handler.DeleteResponseData] "deleted"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 409 {object} handler.ErrorResponse "process is pending or running, or a member of the group"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id} [delete]
+func (h *Delete) Handler(c *echo.Context) error {
+	var p GetParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+	return h.remove(c, []string{p.RequestID})
+}
+
+// Delete processes.
+//
+// @summary delete processes
+// @description delete the completed processes in the same way as DELETE /proc/{id}.
+// @description nothing is deleted if any process is missing, not completed or a member without its group.
+// @accept json
+// @param request body handler.DeleteRequest true "processes to delete"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[handler.DeleteResponseData] "deleted"
+// @failure 400 {object} handler.ErrorResponse
+// @failure 404 {object} handler.ErrorResponse
+// @failure 409 {object} handler.ErrorResponse "process is pending or running, or a member of the group"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/delete [post]
+func (h *Delete) Bulk(c *echo.Context) error {
+	var req DeleteRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return Error(c, http.StatusBadRequest, "invalid body")
+	}
+	if len(req.IDs) == 0 {
+		return Error(c, http.StatusBadRequest, "require ids")
+	}
+	return h.remove(c, req.IDs)
+}
+
+func (h *Delete) remove(c *echo.Context, requestIDs []string) error {
+	r, err := h.remover.RemoveProcesses(c.Request().Context(), &repo.RemoveProcessesRequest{
+		RequestIDs: requestIDs,
+	})
+	if err != nil {
+		alog.L().Error("delete", slog.String("id", echox.RequestID(c)), slog.Any("param_ids", requestIDs), logx.Err(err))
+		switch {
+		case errors.Is(err, repo.ErrProcessNotFound):
+			return Error(c, http.StatusNotFound, "not found")
+		case errors.Is(err, repo.ErrProcessNotDone):
+			return Error(c, http.StatusConflict, "process is pending or running")
+		case errors.Is(err, repo.ErrProcessMember):
+			return Error(c, http.StatusConflict, "process is a member of the group, delete the group")
+		default:
+			return Error(c, http.StatusInternalServerError, "failed to delete")
+		}
+	}
+	return Success(c, http.StatusOK, &DeleteResponseData{
+		Processes: r.RequestIDs,
+		Objects:   r.Objects,
+		SizeBytes: r.SizeBytes,
+	})
+}
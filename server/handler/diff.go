@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/labstack/echo/v5"
+)
+
+const defaultTimingTolerance = 0.01
+
+type GetDiffParam struct {
+	RequestID       string  `param:"id" validate:"required"`
+	Other           string  `param:"other" validate:"required"`
+	TimingTolerance float64 `query:"timingTolerance"` // seconds; default: 0.01
+}
+
+// Compare the audio of the processes.
+//
+// @summary compare processes
+// @description align the wav of the other process to the process, then compare the RMS, the f0 per note and the phoneme timings
+// @param id path string true "request id"
+// @param other path string true "request id to compare with"
+// @param timingTolerance query number false "shift of a label in seconds to be reported as changed; default: 0.01"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[compare.Diff]
+// @failure 400 {object} handler.ErrorResponse
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/diff/{other} [get]
+func (g *Get) Diff(c *echo.Context) error {
+	return g.withDiff(func(c *echo.Context, d *compare.Diff, _ *audio.Wav) error {
+		return Success(c, http.StatusOK, d)
+	})(c)
+}
+
+// Download the residual wav of the processes.
+//
+// @summary download residual wav
+// @description download the wav of the process minus the aligned wav of the other process
+// @param id path string true "request id"
+// @param other path string true "request id to compare with"
+// @success 200 {string} file
+// @failure 400 {object} handler.ErrorResponse
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/diff/{other}/wav [get]
+func (g *Get) DiffWav(c *echo.Context) error {
+	return g.withDiff(func(c *echo.Context, _ *compare.Diff, residual *audio.Wav) error {
+		var buf bytes.Buffer
+		if err := residual.Encode(&buf); err != nil {
+			alog.L().Error("failed to encode residual wav", slog.String("id", echox.RequestID(c)), logx.Err(err))
+			return Error(c, http.StatusInternalServerError, "encode wav")
+		}
+		c.Response().Header().Set("Content-Disposition", `attachment; filename="diff.wav"`)
+		return c.Blob(http.StatusOK, "audio/wav", buf.Bytes())
+	})(c)
+}
+
+func (g *Get) withDiff(f func(*echo.Context, *compare.Diff, *audio.Wav) error) func(*echo.Context) error {
+	return func(c *echo.Context) error {
+		var p GetDiffParam
+		if err := c.Bind(&p); err != nil || p.RequestID == "" || p.Other == "" || p.TimingTolerance < 0 {
+			return Error(c, http.StatusBadRequest, "bad request")
+		}
+		if p.TimingTolerance == 0 {
+			p.TimingTolerance = defaultTimingTolerance
+		}
+
+		renders := make([]*compare.Render, 2)
+		for i, rid := range []string{p.RequestID, p.Other} {
+			r, sErr := g.readRender(c.Request().Context(), rid)
+			if sErr != nil {
+				alog.L().Error("failed to read render", slog.String("id", echox.RequestID(c)), slog.String("rid", rid), logx.Err(sErr))
+				return sErr.Respond(c)
+			}
+			renders[i] = r
+		}
+		d, residual := compare.NewDiff(renders[0], renders[1], p.TimingTolerance)
+		return f(c, d, residual)
+	}
+}
+
+// readRender reads the wav, the f0 and the timing label of the result of the process.
+// The f0 and the timing label are optional.
+func (g *Get) readRender(ctx context.Context, requestID string) (*compare.Render, *StatusError) {
+	proc, err := g.processGetter.GetProcessByRequestId(ctx, requestID)
+	if err != nil {
+		return nil, NewStatusError(http.StatusNotFound, err, "not found")
+	}
+	details, err := g.detailsGetter.GetProcessDetails(ctx, proc.DetailsID)
+	if err != nil {
+		return nil, NewStatusError(http.StatusNotFound, err, "not found")
+	}
+	if details.ResultObjectID == nil {
+		return nil, NewStatusError(http.StatusNotFound, fmt.Errorf("no result: %s", requestID), "not found")
+	}
+	dir, err := g.objectReader.ReadObject(ctx, *details.ResultObjectID)
+	if err != nil {
+		return nil, NewStatusError(http.StatusNotFound, err, "not found")
+	}
+	read := func(name string) ([]byte, error) {
+		obj, err := g.objectGetter.GetObjectByPath(ctx, dir.Object().Bucket, filepath.Join(dir.Object().Path, name))
+		if err != nil {
+			return nil, err
+		}
+		r, err := g.objectReader.ReadObject(ctx, obj.ID)
+		if err != nil {
+			return nil, err
+		}
+		stor, ok := r.Storage()
+		if !ok {
+			return nil, fmt.Errorf("object(%d) is not a file", obj.ID)
+		}
+		return io.ReadAll(stor.Blob)
+	}
+
+	var r compare.Render
+	b, err := read(details.Basename + ".wav")
+	if err != nil {
+		return nil, NewStatusError(http.StatusNotFound, err, "wav not found")
+	}
+	if r.Wav, err = audio.Decode(bytes.NewReader(b)); err != nil {
+		return nil, NewStatusError(http.StatusInternalServerError, err, "decode wav")
+	}
+	if b, err := read(details.Basename + ".f0"); err == nil {
+		if r.F0, err = compare.DecodeF0(b); err != nil {
+			return nil, NewStatusError(http.StatusInternalServerError, err, "decode f0")
+		}
+	}
+	if b, err := read(pathx.TimingLabelFileName(details.Basename)); err == nil {
+		if r.Labels, err = compare.DecodeLabels(bytes.NewReader(b)); err != nil {
+			return nil, NewStatusError(http.StatusInternalServerError, err, "decode labels")
+		}
+	}
+	return &r, nil
+}
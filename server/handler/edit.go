@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/labstack/echo/v5"
+)
+
+func NewEdit(
+	processGetter repo.ProcessGetter,
+	detailsGetter repo.ProcessDetailsGetter,
+	detailsUpdater repo.ProcessDetailsUpdater,
+	tagsUpdater repo.ProcessTagsUpdater,
+) *Edit {
+	return &Edit{
+		processGetter:  processGetter,
+		detailsGetter:  detailsGetter,
+		detailsUpdater: detailsUpdater,
+		tagsUpdater:    tagsUpdater,
+	}
+}
+
+type Edit struct {
+	processGetter  repo.ProcessGetter
+	detailsGetter  repo.ProcessDetailsGetter
+	detailsUpdater repo.ProcessDetailsUpdater
+	tagsUpdater    repo.ProcessTagsUpdater
+}
+
+// EditRequest is the json body to edit a process.
+// The fields not given are kept.
+type EditRequest struct {
+	// Title is the title of the process, empty resets it to the basename.
+	Title *string `json:"title,omitempty"`
+	Notes *string `json:"notes,omitempty"`
+	// Tags replace the tags of the process, empty removes all of them.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// Edit a process.
+//
+// @summary edit a process
+// @description edit the title, the notes and the tags of the process.
+// @description the fields not given are kept, the empty title resets it to the basename, the tags replace all of the tags.
+// @accept json
+// @param id path string true "request id"
+// @param request body handler.EditRequest true "fields to edit"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[string] "edited"
+// @failure 400 {object} handler.ErrorResponse "invalid title, notes or tags"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id} [patch]
+func (h *Edit) Handler(c *echo.Context) error {
+	if err := h.edit(c); err != nil {
+		alog.L().Error("failed to edit process", slog.String("id", echox.RequestID(c)), logx.Err(err))
+		return err.Respond(c)
+	}
+	return Success(c, http.StatusOK, "edited")
+}
+
+func (h *Edit) edit(c *echo.Context) *StatusError {
+	var p GetParam
+	// not Bind, the body is the request
+	if err := echo.BindPathValues(c, &p); err != nil {
+		return NewStatusError(http.StatusBadRequest, err, "bad request")
+	}
+	var req EditRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return NewStatusError(http.StatusBadRequest, err, "invalid body")
+	}
+
+	update := &repo.UpdateProcessDetailsRequest{}
+	if x := req.Title; x != nil {
+		title, err := NormalizeTitle(*x)
+		if err != nil {
+			return invalidLabelsError(err)
+		}
+		update.Title = &title
+	}
+	if x := req.Notes; x != nil {
+		notes, err := NormalizeNotes(*x)
+		if err != nil {
+			return invalidLabelsError(err)
+		}
+		update.Notes = &notes
+	}
+	var tags []string
+	if x := req.Tags; x != nil {
+		var err error
+		if tags, err = NormalizeTags(*x); err != nil {
+			return invalidLabelsError(err)
+		}
+	}
+
+	ctx := c.Request().Context()
+	proc, err := h.processGetter.GetProcessByRequestId(ctx, p.RequestID)
+	if err != nil {
+		return NewStatusError(http.StatusNotFound, err, "not found")
+	}
+	if update.Title != nil || update.Notes != nil {
+		details, err := h.detailsGetter.GetProcessDetails(ctx, proc.DetailsID)
+		if err != nil {
+			return NewStatusError(http.StatusNotFound, err, "not found")
+		}
+		if x := update.Title; x != nil && *x == "" {
+			update.Title = &details.Basename
+		}
+		update.ID = details.ID
+		if _, err := h.detailsUpdater.UpdateProcessDetails(ctx, update); err != nil {
+			return NewStatusError(http.StatusInternalServerError, err, "failed to edit process")
+		}
+	}
+	if req.Tags != nil {
+		if err := h.tagsUpdater.SetProcessTags(ctx, &repo.SetProcessTagsRequest{
+			ProcessID: proc.ID,
+			Tags:      tags,
+		}); err != nil {
+			return NewStatusError(http.StatusInternalServerError, err, "failed to tag process")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/musicxml"
+	"github.com/labstack/echo/v5"
+)
+
+const maxEnsembleVoices = 16
+
+// EnsembleVoice is a voice of the ensemble.
+type EnsembleVoice struct {
+	Part         string  `json:"part,omitempty"`         // id or name of the part, the whole score if empty
+	Model        string  `json:"model,omitempty"`        // default: MERROW
+	SupportModel string  `json:"supportModel,omitempty"` // support singer library
+	Transpose    *int    `json:"transpose,omitempty"`
+	Gain         float64 `json:"gain,omitempty"` // gain in the mix in dB
+	Pan          float64 `json:"pan,omitempty"`  // pan in the mix; -1 (left) to 1 (right)
+}
+
+// Apply sets the voice to the config to render the voice.
+func (v EnsembleVoice) Apply(c *ctl.Config) {
+	c.VocalGain = v.Gain
+	c.VocalPan = v.Pan
+	if v.Part != "" {
+		c.Part = v.Part
+	}
+	if v.Transpose != nil {
+		c.Transpose = *v.Transpose
+	}
+	if v.Model != "" {
+		c.ModelDir = v.Model
+	}
+	if v.SupportModel != "" {
+		c.SupportModelDir = v.SupportModel
+	}
+}
+
+func (v EnsembleVoice) validate(parts []*musicxml.Part) error {
+	if v.Pan < -1 || v.Pan > 1 {
+		return fmt.Errorf("pan %f out of range [-1, 1]", v.Pan)
+	}
+	if v.Part == "" {
+		return nil
+	}
+	_, err := musicxml.FindPart(parts, v.Part)
+	return err
+}
+
+// Start an ensemble process.
+//
+// @summary start an ensemble process
+// @description render each voice of the score as a process and mix them into one wav.
+// @description voices are available as /proc/{id}-{index}, stems of the mix as /proc/{id}/stem/{index}.
+// @description other form values and config are the same as /proc and applied to all voices.
+// @description the title is also of the voices, the notes and the tags are only of the ensemble.
+// @param score formData file true "musicxml"
+// @param voices formData string true "json array of voices; [{part, model, supportModel, transpose, gain, pan}], part is id or name of the part of the score"
+// @produce json
+// @success 202 {object} handler.SuccessResponse[string] "new process started"
+// @header 202 {string} string x-request-id "request id, or just id"
+// @failure 400 {object} handler.ErrorResponse "bad score or voices"
+// @failure 413 {object} handler.ErrorResponse "too big score"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/ensemble [post]
+func (e *Ensemble) Handler(c *echo.Context) error {
+	err := e.NewProcess(c)
+	if err != nil {
+		rid := echox.RequestID(c)
+		alog.L().Error("failed to start ensemble process", slog.String("id", rid), logx.Err(err))
+		return err.Respond(c)
+	}
+
+	return Success(c, http.StatusAccepted, "accepted")
+}
+
+// GetFormVoices reads the voices from the form value `voices` and validates them against the score.
+func (Ensemble) GetFormVoices(c *echo.Context, score []byte) ([]*EnsembleVoice, *StatusError) {
+	var voices []*EnsembleVoice
+	if err := json.Unmarshal([]byte(c.FormValue("voices")), &voices); err != nil {
+		return nil, NewStatusError(http.StatusBadRequest, err, "invalid voices")
+	}
+	if len(voices) == 0 || len(voices) > maxEnsembleVoices {
+		return nil, NewStatusError(http.StatusBadRequest,
+			fmt.Errorf("got %d voices", len(voices)),
+			fmt.Sprintf("voices should be 1 to %d", maxEnsembleVoices),
+		)
+	}
+	parts, err := musicxml.Parts(score)
+	if err != nil {
+		return nil, NewStatusError(http.StatusBadRequest, err, "invalid score")
+	}
+	for i, v := range voices {
+		if err := v.validate(parts); err != nil {
+			return nil, NewStatusError(http.StatusBadRequest, err, fmt.Sprintf("invalid voice %d", i))
+		}
+	}
+	return voices, nil
+}
+
+func NewEnsemble(group *Group) *Ensemble {
+	return &Ensemble{
+		group: group,
+	}
+}
+
+type Ensemble struct {
+	group *Group
+}
+
+func (e *Ensemble) NewProcess(c *echo.Context) *StatusError {
+	score, fErr := (Start{}).GetFormFile(c)
+	if fErr != nil {
+		return fErr
+	}
+	voices, fErr := e.GetFormVoices(c, score.Blob)
+	if fErr != nil {
+		return fErr
+	}
+	members := make([]Member, len(voices))
+	for i, v := range voices {
+		members[i] = v.Apply
+	}
+	return e.group.NewProcess(c, domain.ProcessKindEnsemble, score, members)
+}
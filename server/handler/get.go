@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,11 +11,16 @@ import (
 	"time"
 
 	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/manifest"
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
 	"github.com/berquerant/pneutrinoutil/pkg/domain"
 	"github.com/berquerant/pneutrinoutil/pkg/echox"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
 	"github.com/goccy/go-yaml"
 	"github.com/labstack/echo/v5"
 )
@@ -23,12 +30,16 @@ func NewGet(
 	detailsGetter repo.ProcessDetailsGetter,
 	objectReader repo.ObjectReader,
 	objectGetter repo.ObjectGetter,
+	objectWriter repo.ObjectWriter,
+	tagsGetter repo.ProcessTagsGetter,
 ) *Get {
 	return &Get{
 		processGetter: processGetter,
 		detailsGetter: detailsGetter,
+		tagsGetter:    tagsGetter,
 		objectReader:  objectReader,
 		objectGetter:  objectGetter,
+		objectWriter:  objectWriter,
 	}
 }
 
@@ -39,8 +50,10 @@ type GetParam struct {
 type Get struct {
 	processGetter repo.ProcessGetter
 	detailsGetter repo.ProcessDetailsGetter
+	tagsGetter    repo.ProcessTagsGetter
 	objectReader  repo.ObjectReader
 	objectGetter  repo.ObjectGetter
+	objectWriter  repo.ObjectWriter
 }
 
 func (*Get) bind(c *echo.Context) (*GetParam, *StatusError) {
@@ -52,9 +65,17 @@ func (*Get) bind(c *echo.Context) (*GetParam, *StatusError) {
 }
 
 type result struct {
+	processID      int
+	kind           domain.ProcessKind
+	groupID        *int
+	parentID       *int
+	starred        bool
 	requestID      string
+	title          string
 	basename       string
+	notes          string
 	command        *string
+	options        json.RawMessage
 	statusID       domain.ProcessStatus
 	createdAt      time.Time
 	startedAt      *time.Time
@@ -85,9 +106,17 @@ func (g *Get) withResult(
 		}
 
 		r := &result{
+			processID:      proc.ID,
+			kind:           proc.Kind,
+			groupID:        proc.GroupID,
+			parentID:       proc.ParentID,
+			starred:        proc.Starred,
 			requestID:      proc.RequestID,
-			basename:       details.Title,
+			title:          details.Title,
+			basename:       details.Basename,
+			notes:          details.Notes,
 			command:        details.Command,
+			options:        details.Options,
 			statusID:       proc.Status,
 			createdAt:      proc.CreatedAt,
 			startedAt:      proc.StartedAt,
@@ -153,13 +182,25 @@ func (g *Get) withResultObjectFileBlob(objectID int, contentType, path string) f
 }
 
 type GetDetailResponseData struct {
-	RequestID   string `json:"rid"`                // request id, or just id
-	Basename    string `json:"basename,omitempty"` // original musicxml file name except extension
-	Command     string `json:"command,omitempty"`
-	Status      string `json:"status"`
-	CreatedAt   string `json:"created_at,omitempty"`
-	StartedAt   string `json:"started_at,omitempty"`
-	CompletedAt string `json:"completed_at,omitempty"`
+	RequestID string   `json:"rid"` // request id, or just id
+	Title     string   `json:"title"`
+	Basename  string   `json:"basename,omitempty"` // original musicxml file name except extension
+	Notes     string   `json:"notes,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Command   string   `json:"command,omitempty"`
+	// Options are the options of the job, keyed like config.yml.
+	Options     json.RawMessage `json:"options,omitempty" swaggertype:"object"`
+	Status      string          `json:"status"`
+	Kind        string          `json:"kind"`             // render or ensemble
+	Group       string          `json:"group,omitempty"`  // request id of the ensemble the voice belongs to
+	Voices      []string        `json:"voices,omitempty"` // request ids of the voices of the ensemble
+	Runs        []string        `json:"runs,omitempty"`   // request ids of the runs of the sweep
+	Parent      string          `json:"parent,omitempty"` // request id of the process rerun by this process
+	Reruns      []string        `json:"reruns,omitempty"` // request ids of the processes that rerun this process
+	Starred     bool            `json:"starred"`          // kept by the retention policies
+	CreatedAt   string          `json:"created_at,omitempty"`
+	StartedAt   string          `json:"started_at,omitempty"`
+	CompletedAt string          `json:"completed_at,omitempty"`
 }
 
 // Get process info.
@@ -175,13 +216,64 @@ func (g *Get) Detail(c *echo.Context) error {
 	return g.withResult(func(c *echo.Context, r *result) error {
 		v := GetDetailResponseData{
 			RequestID: r.requestID,
+			Title:     r.title,
 			Basename:  r.basename,
+			Notes:     r.notes,
 			Status:    r.statusID.String(),
+			Kind:      r.kind.String(),
+			Starred:   r.starred,
 			CreatedAt: r.createdAt.Format(time.DateTime),
 		}
+		if x := r.groupID; x != nil {
+			group, err := g.processGetter.GetProcess(c.Request().Context(), *x)
+			if err != nil {
+				alog.L().Error("missing group", slog.String("id", echox.RequestID(c)), slog.Int("groupID", *x), logx.Err(err))
+				return Error(c, http.StatusInternalServerError, "missing group")
+			}
+			v.Group = group.RequestID
+		}
+		if x := r.parentID; x != nil {
+			parent, err := g.processGetter.GetProcess(c.Request().Context(), *x)
+			if err != nil {
+				alog.L().Error("missing parent", slog.String("id", echox.RequestID(c)), slog.Int("parentID", *x), logx.Err(err))
+				return Error(c, http.StatusInternalServerError, "missing parent")
+			}
+			v.Parent = parent.RequestID
+		}
+		reruns, err := g.processGetter.GetProcessListByParent(c.Request().Context(), r.processID)
+		if err != nil {
+			alog.L().Error("missing reruns", slog.String("id", echox.RequestID(c)), slog.Int("processID", r.processID), logx.Err(err))
+			return Error(c, http.StatusInternalServerError, "missing reruns")
+		}
+		for _, x := range reruns {
+			v.Reruns = append(v.Reruns, x.RequestID)
+		}
+		tags, err := g.tagsGetter.GetProcessTags(c.Request().Context(), r.processID)
+		if err != nil {
+			alog.L().Error("missing tags", slog.String("id", echox.RequestID(c)), slog.Int("processID", r.processID), logx.Err(err))
+			return Error(c, http.StatusInternalServerError, "missing tags")
+		}
+		v.Tags = tags[r.processID]
+		if r.kind == domain.ProcessKindEnsemble || r.kind == domain.ProcessKindSweep {
+			members, err := g.processGetter.GetProcessListByGroup(c.Request().Context(), r.processID)
+			if err != nil {
+				alog.L().Error("missing members", slog.String("id", echox.RequestID(c)), slog.Int("processID", r.processID), logx.Err(err))
+				return Error(c, http.StatusInternalServerError, "missing members")
+			}
+			rids := make([]string, len(members))
+			for i, x := range members {
+				rids[i] = x.RequestID
+			}
+			if r.kind == domain.ProcessKindEnsemble {
+				v.Voices = rids
+			} else {
+				v.Runs = rids
+			}
+		}
 		if x := r.command; x != nil {
 			v.Command = *x
 		}
+		v.Options = r.options
 		if x := r.startedAt; x != nil {
 			v.StartedAt = x.Format(time.DateTime)
 		}
@@ -209,6 +301,43 @@ func (g *Get) Log(c *echo.Context) error {
 	})(c)
 }
 
+// Download the timing label.
+//
+// @summary download timing label
+// @description download the timing label of the wav generated by NEUTRINO
+// @param id path string true "request id"
+// @success 200 {string} file
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/label [get]
+func (g *Get) Label(c *echo.Context) error {
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil {
+			name := pathx.TimingLabelFileName(r.basename)
+			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+			return g.withResultObjectFileBlob(*objectID, "text/plain", name)(c)
+		}
+		return Error(c, http.StatusNotFound, "not found")
+	})(c)
+}
+
+// Download the manifest.
+//
+// @summary download manifest
+// @description download the manifest that records what is required to reproduce the render
+// @param id path string true "request id"
+// @produce json
+// @success 200 {string} file
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/manifest [get]
+func (g *Get) Manifest(c *echo.Context) error {
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil {
+			return g.withResultObjectFileBlob(*objectID, "application/json", manifest.FileName)(c)
+		}
+		return Error(c, http.StatusNotFound, "not found")
+	})(c)
+}
+
 // Download pneutrinoutil config file.
 //
 // @summary download config
@@ -260,20 +389,203 @@ func (g *Get) MusicXML(c *echo.Context) error {
 	})(c)
 }
 
+type GetWavParam struct {
+	Format string `query:"format"` // codec[:sampleRate[:bitDepth]]
+}
+
 // Download wav file generated by pneutrinoutil.
 //
 // @summary download wav
-// @description download wav file generated by pneutrinoutil
+// @description download wav file generated by pneutrinoutil, or transcoded one if format is specified
 // @param id path string true "request id"
+// @param format query string false "codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac, wav:48000:24"
 // @success 200 {string} file
+// @failure 400 {object} handler.ErrorResponse
 // @failure 404 {object} handler.ErrorResponse
 // @router /proc/{id}/wav [get]
 func (g *Get) Wav(c *echo.Context) error {
+	var p GetWavParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+	var format *audio.OutputFormat
+	if p.Format != "" {
+		f, err := audio.ParseOutputFormat(p.Format)
+		if err != nil {
+			return Error(c, http.StatusBadRequest, "invalid format")
+		}
+		format = f
+	}
+
 	return g.withResult(func(c *echo.Context, r *result) error {
-		if objectID := r.resultObjectID; objectID != nil {
+		objectID := r.resultObjectID
+		if objectID == nil {
+			return Error(c, http.StatusNotFound, "not found")
+		}
+		if format == nil {
 			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.wav"`, r.basename))
 			return g.withResultObjectFileBlob(*objectID, "audio/wav", r.basename+".wav")(c)
 		}
+		return g.withStorageObject(*objectID, func(c *echo.Context, dir repo.ReadObjectResponse) error {
+			name := format.FileName(r.basename)
+			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+			path := filepath.Join(dir.Object().Path, name)
+			if got, err := g.objectGetter.GetObjectByPath(c.Request().Context(), dir.Object().Bucket, path); err == nil {
+				// generated by pneutrinoutil or cached
+				return g.withStorageObjectFileBlob(got.ID, format.ContentType())(c)
+			}
+			return g.withResultObjectFile(*objectID, "audio/wav", r.basename+".wav", func(wavID int, _ string) func(*echo.Context) error {
+				return g.transcode(wavID, dir.Object().Bucket, path, format)
+			})(c)
+		})(c)
+	})(c)
+}
+
+// transcode converts the wav object into the format and caches the result as the object of the path.
+func (g *Get) transcode(wavObjectID int, bucket, path string, format *audio.OutputFormat) func(*echo.Context) error {
+	return g.withStorageObjectFile(wavObjectID, func(c *echo.Context, r repo.ReadObjectResponse) error {
+		storage, _ := r.Storage()
+		w, err := audio.Decode(storage.Blob)
+		if err != nil {
+			alog.L().Error("failed to decode wav", slog.String("id", echox.RequestID(c)), slog.Int("objectID", wavObjectID), logx.Err(err))
+			return Error(c, http.StatusInternalServerError, "decode wav")
+		}
+		var buf bytes.Buffer
+		if err := format.Encode(&buf, w); err != nil {
+			alog.L().Error("failed to transcode wav", slog.String("id", echox.RequestID(c)), slog.Int("objectID", wavObjectID), slog.String("format", format.String()), logx.Err(err))
+			return Error(c, http.StatusInternalServerError, "transcode wav")
+		}
+		blob := buf.Bytes()
+		if _, err := g.objectWriter.WriteObject(c.Request().Context(), &repo.WriteObjectRequest{
+			Type:   domain.ObjectTypeFile,
+			Bucket: bucket,
+			Path:   path,
+			Blob:   bytes.NewReader(blob),
+		}); err != nil {
+			// the transcoded wav is still available
+			alog.L().Warn("failed to cache transcoded wav", slog.String("id", echox.RequestID(c)), slog.String("bucket", bucket), slog.String("path", path), logx.Err(err))
+		}
+		return c.Blob(http.StatusOK, format.ContentType(), blob)
+	})
+}
+
+// Download wav mixed with the accompaniment.
+//
+// @summary download mix
+// @description download wav mixed with the accompaniment, available if the accompaniment is specified
+// @param id path string true "request id"
+// @success 200 {string} file
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/mix [get]
+func (g *Get) Mix(c *echo.Context) error {
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil {
+			name := pathx.MixFileName(r.basename)
+			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+			return g.withResultObjectFileBlob(*objectID, "audio/wav", name)(c)
+		}
+		return Error(c, http.StatusNotFound, "not found")
+	})(c)
+}
+
+const defaultPeaksResolution = 1024
+
+type GetPeaksParam struct {
+	Resolution int `query:"resolution"` // samples per pixel; default: 1024
+}
+
+// Download waveform peaks.
+//
+// @summary download peaks
+// @description download min/max waveform peaks of the wav, compatible with audiowaveform json format
+// @param id path string true "request id"
+// @param resolution query int false "samples per pixel; (256|1024|4096), default: 1024"
+// @produce json
+// @success 200 {object} audio.Peaks
+// @failure 400 {object} handler.ErrorResponse
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/peaks [get]
+func (g *Get) Peaks(c *echo.Context) error {
+	var p GetPeaksParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+	switch {
+	case p.Resolution == 0:
+		p.Resolution = defaultPeaksResolution
+	case p.Resolution < 0:
+		return Error(c, http.StatusBadRequest, "invalid resolution")
+	}
+
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil {
+			return g.withResultObjectFileBlob(*objectID, "application/json", audio.PeaksFileName(p.Resolution))(c)
+		}
+		return Error(c, http.StatusNotFound, "not found")
+	})(c)
+}
+
+type GetStemParam struct {
+	Index int `param:"index"`
+}
+
+// Download a stem of the ensemble.
+//
+// @summary download stem
+// @description download wav of the voice of the ensemble, mixed into the wav of the ensemble
+// @param id path string true "request id"
+// @param index path int true "index of the voice"
+// @success 200 {string} file
+// @failure 400 {object} handler.ErrorResponse
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/stem/{index} [get]
+func (g *Get) Stem(c *echo.Context) error {
+	var p GetStemParam
+	if err := c.Bind(&p); err != nil || p.Index < 0 {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil && r.kind == domain.ProcessKindEnsemble {
+			name := task.StemFileName(r.basename, p.Index)
+			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+			return g.withResultObjectFileBlob(*objectID, "audio/wav", name)(c)
+		}
+		return Error(c, http.StatusNotFound, "not found")
+	})(c)
+}
+
+// Download the index of the sweep.
+//
+// @summary download sweep index
+// @description download the index of the runs of the sweep
+// @param id path string true "request id"
+// @produce json
+// @success 200 {object} sweep.Index
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/sweep [get]
+func (g *Get) SweepIndex(c *echo.Context) error {
+	return g.sweepIndex(c, "application/json", sweep.IndexJSONFileName)
+}
+
+// Download the index page of the sweep.
+//
+// @summary download sweep index page
+// @description download the html that lists the wavs of the runs of the sweep with the config
+// @param id path string true "request id"
+// @produce html
+// @success 200 {string} file
+// @failure 404 {object} handler.ErrorResponse
+// @router /proc/{id}/sweep/html [get]
+func (g *Get) SweepIndexHTML(c *echo.Context) error {
+	return g.sweepIndex(c, "text/html; charset=utf-8", sweep.IndexHTMLFileName)
+}
+
+func (g *Get) sweepIndex(c *echo.Context, contentType, name string) error {
+	return g.withResult(func(c *echo.Context, r *result) error {
+		if objectID := r.resultObjectID; objectID != nil && r.kind == domain.ProcessKindSweep {
+			return g.withResultObjectFileBlob(*objectID, contentType, name)(c)
+		}
 		return Error(c, http.StatusNotFound, "not found")
 	})(c)
 }
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v5"
+)
+
+func NewGroup(
+	client task.Enqueuer,
+	inspector task.Inspector,
+	processTimeout time.Duration,
+	bucket string,
+	path string,
+	objectWriter repo.ObjectWriter,
+	detailsCreator repo.ProcessDetailsCreator,
+	processCreator repo.ProcessCreator,
+	processUpdater repo.ProcessUpdater,
+	tagsUpdater repo.ProcessTagsUpdater,
+	schema *ctl.Schema,
+) *Group {
+	return &Group{
+		client:         client,
+		inspector:      inspector,
+		processTimeout: processTimeout,
+		bucket:         bucket,
+		path:           path,
+		objectWriter:   objectWriter,
+		detailsCreator: detailsCreator,
+		processCreator: processCreator,
+		processUpdater: processUpdater,
+		tagsUpdater:    tagsUpdater,
+		schema:         schema,
+	}
+}
+
+// Group starts a process that consists of render processes of the same score.
+type Group struct {
+	client         task.Enqueuer
+	inspector      task.Inspector
+	processTimeout time.Duration
+	objectWriter   repo.ObjectWriter
+	detailsCreator repo.ProcessDetailsCreator
+	processCreator repo.ProcessCreator
+	processUpdater repo.ProcessUpdater
+	tagsUpdater    repo.ProcessTagsUpdater
+	schema         *ctl.Schema
+	bucket         string
+	path           string
+}
+
+// Member modifies the config of the member of the group.
+type Member func(*ctl.Config)
+
+// NewProcess creates the group process and enqueues the members.
+// members override the config of the form values.
+// The title of the form values is also the title of the members, the notes and the tags are only of the group.
+func (g *Group) NewProcess(c *echo.Context, kind domain.ProcessKind, score *ReadFromFileResult, members []Member) *StatusError {
+	rid := echox.RequestID(c)
+	base, fErr := GetFormConfig(c, g.schema)
+	if fErr != nil {
+		return fErr
+	}
+	memberConfigs, fErr := g.MemberConfigs(c, members)
+	if fErr != nil {
+		return fErr
+	}
+	labels, fErr := getFormLabels(c)
+	if fErr != nil {
+		return fErr
+	}
+
+	obj, err := g.objectWriter.WriteObject(c.Request().Context(), &repo.WriteObjectRequest{
+		Type:   domain.ObjectTypeFile,
+		Bucket: g.bucket,
+		Path:   filepath.Join(g.path, rid, score.Name),
+		Blob:   bytes.NewReader(score.Blob),
+	})
+	if err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to upload score")
+	}
+
+	var (
+		basename = pathx.Basename(score.Name)
+		title    = labels.titleOr(basename)
+	)
+	newProcess := func(requestID string, kind domain.ProcessKind, groupID *int, config *ctl.Config, notes string) (*domain.Process, error) {
+		options, err := json.Marshal(config.JobOptions())
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to marshal config", err)
+		}
+		details, err := g.detailsCreator.CreateProcessDetails(c.Request().Context(), &repo.CreateProcessDetailsRequest{
+			Title:         title,
+			Basename:      basename,
+			Notes:         notes,
+			ScoreObjectId: obj.Object().ID,
+			Options:       options,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to create process details", err)
+		}
+		return g.processCreator.CreateProcess(c.Request().Context(), &repo.CreateProcessRequest{
+			RequestId: requestID,
+			DetailsId: details.ID,
+			Status:    domain.ProcessStatusPending,
+			Kind:      kind,
+			GroupId:   groupID,
+		})
+	}
+
+	// the tasks are created first not to leave the processes without the tasks
+	var (
+		memberRids = make([]string, len(memberConfigs))
+		tasks      = make([]*asynq.Task, len(memberConfigs))
+	)
+	for i, config := range memberConfigs {
+		memberRids[i] = task.GroupMemberRequestID(rid, i)
+		atask, err := task.NewPneutrinoutilStart(task.PneutrinoutilStartPayload{
+			RequestID: memberRids[i],
+			Config:    config,
+		})
+		if err != nil {
+			return NewStatusError(http.StatusInternalServerError, err, "failed to create task")
+		}
+		tasks[i] = atask
+	}
+
+	group, err := newProcess(rid, kind, nil, base, labels.notes)
+	if err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to create process")
+	}
+	procs := []*domain.Process{group}
+	if err := labels.setTags(c.Request().Context(), g.tagsUpdater, group.ID); err != nil {
+		g.abort(c, procs, nil)
+		return NewStatusError(http.StatusInternalServerError, err, "failed to tag process")
+	}
+
+	for i, config := range memberConfigs {
+		proc, err := newProcess(memberRids[i], domain.ProcessKindRender, &group.ID, config, "")
+		if err != nil {
+			g.abort(c, procs, nil)
+			return NewStatusError(http.StatusInternalServerError, err, fmt.Sprintf("failed to create process of member %d", i))
+		}
+		procs = append(procs, proc)
+	}
+
+	var enqueued []string
+	for i, atask := range tasks {
+		taskID := task.TaskID(task.TypePneutrinoutilStart, memberRids[i])
+		info, err := g.client.EnqueueContext(c.Request().Context(), atask,
+			asynq.Timeout(g.processTimeout), asynq.TaskID(taskID),
+		)
+		if err != nil {
+			g.abort(c, procs, enqueued)
+			return NewStatusError(http.StatusInternalServerError, err, "failed to enqueue task")
+		}
+		enqueued = append(enqueued, taskID)
+		alog.L().Info("new task enqueued",
+			slog.String("id", rid), slog.String("member", memberRids[i]), slog.Int("processID", procs[i+1].ID), slog.String("taskID", info.ID),
+		)
+	}
+
+	return nil
+}
+
+// abort makes the group and the created members failed and deletes the enqueued tasks of the members
+// not to run a part of the group.
+func (g *Group) abort(c *echo.Context, procs []*domain.Process, taskIDs []string) {
+	var (
+		ctx = context.WithoutCancel(c.Request().Context())
+		rid = echox.RequestID(c)
+	)
+	for _, proc := range procs {
+		if _, err := g.processUpdater.UpdateProcess(ctx, &repo.UpdateProcessRequest{
+			ID:          proc.ID,
+			Status:      new(domain.ProcessStatusFailed),
+			CompletedAt: new(time.Now()),
+			// the worker may have started the member
+			FromStatus: []domain.ProcessStatus{domain.ProcessStatusPending, domain.ProcessStatusRunning},
+		}); err != nil {
+			alog.L().Error("failed to abort process", slog.String("id", rid), slog.String("rid", proc.RequestID), logx.Err(err))
+		}
+	}
+	for _, id := range taskIDs {
+		if err := g.inspector.DeleteTask(task.DefaultQueue, id); err != nil {
+			if err := g.inspector.CancelProcessing(id); err != nil {
+				alog.L().Error("failed to delete task", slog.String("id", rid), slog.String("taskID", id), logx.Err(err))
+			}
+		}
+	}
+}
+
+// MemberConfigs returns the validated configs of the members, the config of the form values overridden by members.
+func (g *Group) MemberConfigs(c *echo.Context, members []Member) ([]*ctl.Config, *StatusError) {
+	base, fErr := GetFormConfig(c, g.schema)
+	if fErr != nil {
+		return nil, fErr
+	}
+	configs := make([]*ctl.Config, len(members))
+	for i, f := range members {
+		x := *base
+		f(&x)
+		if fErr := validateConfig(g.schema, &x); fErr != nil {
+			return nil, fErr.AppendMessageToErr(fmt.Sprintf("member %d", i))
+		}
+		configs[i] = &x
+	}
+	return configs, nil
+}
+
+// WriteFile uploads the file of the group to the path.
+func (g *Group) WriteFile(c *echo.Context, path func(basePath, requestID string) string, blob []byte) *StatusError {
+	if _, err := g.objectWriter.WriteObject(c.Request().Context(), &repo.WriteObjectRequest{
+		Type:   domain.ObjectTypeFile,
+		Bucket: g.bucket,
+		Path:   path(g.path, echox.RequestID(c)),
+		Blob:   bytes.NewReader(blob),
+	}); err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to upload file")
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/infra"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/berquerant/pneutrinoutil/server/handler"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var errGroup = errors.New("Group")
+
+type mockObject struct {
+	obj *domain.Object
+}
+
+func (m mockObject) Object() *domain.Object { return m.obj }
+
+// mockGroupStore is the database of the processes of the group.
+type mockGroupStore struct {
+	procs   []*domain.Process
+	failRid string // request id of the process not to be created
+}
+
+func (*mockGroupStore) WriteObject(_ context.Context, req *repo.WriteObjectRequest) (repo.WriteObjectResponse, error) {
+	return mockObject{
+		obj: &domain.Object{ID: 1, Bucket: req.Bucket, Path: req.Path},
+	}, nil
+}
+
+func (*mockGroupStore) CreateProcessDetails(_ context.Context, _ *repo.CreateProcessDetailsRequest) (*domain.ProcessDetails, error) {
+	return &domain.ProcessDetails{ID: 1}, nil
+}
+
+func (m *mockGroupStore) CreateProcess(_ context.Context, req *repo.CreateProcessRequest) (*domain.Process, error) {
+	if req.RequestId == m.failRid {
+		return nil, errGroup
+	}
+	p := &domain.Process{
+		ID:        len(m.procs) + 1,
+		RequestID: req.RequestId,
+		Status:    req.Status,
+		Kind:      req.Kind,
+		GroupID:   req.GroupId,
+		DetailsID: req.DetailsId,
+	}
+	m.procs = append(m.procs, p)
+	return p, nil
+}
+
+func (m *mockGroupStore) UpdateProcess(_ context.Context, req *repo.UpdateProcessRequest) (*domain.Process, error) {
+	i := slices.IndexFunc(m.procs, func(p *domain.Process) bool { return p.ID == req.ID })
+	if i < 0 || !slices.Contains(req.FromStatus, m.procs[i].Status) {
+		return nil, infra.ErrRowsAffected
+	}
+	m.procs[i].Status = *req.Status
+	return m.procs[i], nil
+}
+
+func (m *mockGroupStore) statuses() map[string]domain.ProcessStatus {
+	r := map[string]domain.ProcessStatus{}
+	for _, p := range m.procs {
+		r[p.RequestID] = p.Status
+	}
+	return r
+}
+
+// mockGroupQueue is the queue of the tasks of the members.
+type mockGroupQueue struct {
+	tasks   []string
+	deleted []string
+	failRid string // request id of the task not to be enqueued
+}
+
+func (m *mockGroupQueue) EnqueueContext(_ context.Context, t *asynq.Task, _ ...asynq.Option) (*asynq.TaskInfo, error) {
+	var p task.PneutrinoutilStartPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return nil, err
+	}
+	if p.RequestID == m.failRid {
+		return nil, errGroup
+	}
+	id := task.TaskID(t.Type(), p.RequestID)
+	m.tasks = append(m.tasks, id)
+	return &asynq.TaskInfo{ID: id}, nil
+}
+
+func (m *mockGroupQueue) DeleteTask(_, id string) error {
+	i := slices.Index(m.tasks, id)
+	if i < 0 {
+		return errGroup
+	}
+	m.tasks = slices.Delete(m.tasks, i, i+1)
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (*mockGroupQueue) CancelProcessing(_ string) error {
+	return errGroup
+}
+
+func TestGroupNewProcess(t *testing.T) {
+	schema, err := ctl.NewSchema([]string{"MERROW"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	const rid = "rid"
+	members := []handler.Member{
+		func(c *ctl.Config) { c.Transpose = 0 },
+		func(c *ctl.Config) { c.Transpose = 12 },
+		func(c *ctl.Config) { c.Transpose = -12 },
+	}
+	taskID := func(rid string) string {
+		return task.TaskID(task.TypePneutrinoutilStart, rid)
+	}
+
+	for _, tc := range []struct {
+		title        string
+		failProcess  string
+		failEnqueue  string
+		wantStatuses map[string]domain.ProcessStatus
+		wantTasks    []string
+		wantDeleted  []string
+	}{
+		{
+			title: "all members",
+			wantStatuses: map[string]domain.ProcessStatus{
+				"rid":   domain.ProcessStatusPending,
+				"rid-0": domain.ProcessStatusPending,
+				"rid-1": domain.ProcessStatusPending,
+				"rid-2": domain.ProcessStatusPending,
+			},
+			wantTasks: []string{taskID("rid-0"), taskID("rid-1"), taskID("rid-2")},
+		},
+		{
+			title:       "failed to create member",
+			failProcess: "rid-1",
+			wantStatuses: map[string]domain.ProcessStatus{
+				"rid":   domain.ProcessStatusFailed,
+				"rid-0": domain.ProcessStatusFailed,
+			},
+		},
+		{
+			title:       "failed to enqueue member",
+			failEnqueue: "rid-2",
+			wantStatuses: map[string]domain.ProcessStatus{
+				"rid":   domain.ProcessStatusFailed,
+				"rid-0": domain.ProcessStatusFailed,
+				"rid-1": domain.ProcessStatusFailed,
+				"rid-2": domain.ProcessStatusFailed,
+			},
+			wantDeleted: []string{taskID("rid-0"), taskID("rid-1")},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			var (
+				store = &mockGroupStore{failRid: tc.failProcess}
+				queue = &mockGroupQueue{failRid: tc.failEnqueue}
+				g     = handler.NewGroup(queue, queue, 0, "bucket", "path", store, store, store, store, nil, schema)
+				req   = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+				c     = echo.New().NewContext(req, httptest.NewRecorder())
+			)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			c.Response().Header().Set(echo.HeaderXRequestID, rid)
+
+			sErr := g.NewProcess(c, domain.ProcessKindSweep, &handler.ReadFromFileResult{
+				Name: "score.musicxml",
+				Blob: []byte("<score/>"),
+			}, members)
+			if tc.failProcess == "" && tc.failEnqueue == "" {
+				assert.Nil(t, sErr)
+			} else if assert.NotNil(t, sErr) {
+				assert.Equal(t, http.StatusInternalServerError, sErr.Status)
+				assert.ErrorIs(t, sErr, errGroup)
+			}
+			assert.Equal(t, tc.wantStatuses, store.statuses())
+			assert.ElementsMatch(t, tc.wantTasks, queue.tasks)
+			assert.Equal(t, tc.wantDeleted, queue.deleted)
+		})
+	}
+}
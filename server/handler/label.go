@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	maxTitleLength = 255  // runes
+	maxNotesLength = 4096 // runes
+	maxTagLength   = 64   // runes
+	maxTags        = 32
+)
+
+var (
+	ErrInvalidTitle = errors.New("InvalidTitle")
+	ErrInvalidNotes = errors.New("InvalidNotes")
+	ErrInvalidTag   = errors.New("InvalidTag")
+)
+
+// NormalizeTitle returns the trimmed title, empty means the basename of the score.
+func NormalizeTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if n := utf8.RuneCountInString(title); n > maxTitleLength {
+		return "", fmt.Errorf("%w: %d characters, want at most %d", ErrInvalidTitle, n, maxTitleLength)
+	}
+	return title, nil
+}
+
+// NormalizeNotes returns the trimmed notes.
+func NormalizeNotes(notes string) (string, error) {
+	notes = strings.TrimSpace(notes)
+	if n := utf8.RuneCountInString(notes); n > maxNotesLength {
+		return "", fmt.Errorf("%w: %d characters, want at most %d", ErrInvalidNotes, n, maxNotesLength)
+	}
+	return notes, nil
+}
+
+// NormalizeTags returns the trimmed, lowercased, deduplicated and sorted tags.
+// The empty tags are ignored.
+// A tag should not contain comma and spaces, which separate the tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	r := []string{}
+	for _, x := range tags {
+		x = strings.ToLower(strings.TrimSpace(x))
+		if x == "" {
+			continue
+		}
+		if n := utf8.RuneCountInString(x); n > maxTagLength {
+			return nil, fmt.Errorf("%w: %s has %d characters, want at most %d", ErrInvalidTag, x, n, maxTagLength)
+		}
+		if strings.ContainsFunc(x, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			return nil, fmt.Errorf("%w: %s contains comma or spaces", ErrInvalidTag, x)
+		}
+		r = append(r, x)
+	}
+	slices.Sort(r)
+	r = slices.Compact(r)
+	if len(r) > maxTags {
+		return nil, fmt.Errorf("%w: %d tags, want at most %d", ErrInvalidTag, len(r), maxTags)
+	}
+	return r, nil
+}
+
+// SplitTags returns the tags separated by comma.
+func SplitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// labels are the title, the notes and the tags of a process given by the user.
+type labels struct {
+	title string // the default title if empty
+	notes string
+	tags  []string
+}
+
+func newLabels(title, notes string, tags []string) (*labels, *StatusError) {
+	var (
+		r   labels
+		err error
+	)
+	if r.title, err = NormalizeTitle(title); err != nil {
+		return nil, invalidLabelsError(err)
+	}
+	if r.notes, err = NormalizeNotes(notes); err != nil {
+		return nil, invalidLabelsError(err)
+	}
+	if r.tags, err = NormalizeTags(tags); err != nil {
+		return nil, invalidLabelsError(err)
+	}
+	return &r, nil
+}
+
+func invalidLabelsError(err error) *StatusError {
+	return NewStatusError(http.StatusBadRequest, err, "invalid labels: "+err.Error())
+}
+
+// getFormLabels returns the labels of the process from the form values `title`, `notes` and `tags`.
+func getFormLabels(c *echo.Context) (*labels, *StatusError) {
+	return newLabels(c.FormValue("title"), c.FormValue("notes"), SplitTags(c.FormValue("tags")))
+}
+
+// titleOr returns the title of the labels, or title if not given.
+func (l *labels) titleOr(title string) string {
+	if l.title != "" {
+		return l.title
+	}
+	return title
+}
+
+// setTags tags the process if any tags.
+func (l *labels) setTags(ctx context.Context, updater repo.ProcessTagsUpdater, processID int) error {
+	if len(l.tags) == 0 {
+		return nil
+	}
+	return updater.SetProcessTags(ctx, &repo.SetProcessTagsRequest{
+		ProcessID: processID,
+		Tags:      l.tags,
+	})
+}
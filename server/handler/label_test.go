@@ -0,0 +1,86 @@
+package handler_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/server/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		tags  []string
+		want  []string
+		err   error
+	}{
+		{
+			title: "nil",
+			want:  []string{},
+		},
+		{
+			title: "trim, sort and deduplicate",
+			tags:  []string{" solo", "draft ", "", "solo"},
+			want:  []string{"draft", "solo"},
+		},
+		{
+			title: "ignore case",
+			tags:  []string{"Solo", "solo", "SOLO", "Draft"},
+			want:  []string{"draft", "solo"},
+		},
+		{
+			title: "split",
+			tags:  handler.SplitTags("draft, solo,,"),
+			want:  []string{"draft", "solo"},
+		},
+		{
+			title: "spaces",
+			tags:  []string{"first draft"},
+			err:   handler.ErrInvalidTag,
+		},
+		{
+			title: "too long",
+			tags:  []string{strings.Repeat("a", 65)},
+			err:   handler.ErrInvalidTag,
+		},
+		{
+			title: "duplicates are not too many",
+			tags:  strings.Split(strings.Repeat("a,", 40)+"b", ","),
+			want:  []string{"a", "b"},
+		},
+		{
+			title: "too many",
+			tags: func() []string {
+				r := make([]string, 33)
+				for i := range r {
+					r[i] = fmt.Sprintf("t%d", i)
+				}
+				return r
+			}(),
+			err: handler.ErrInvalidTag,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := handler.NormalizeTags(tc.tags)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			if !assert.Nil(t, err, "%v", err) {
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	got, err := handler.NormalizeTitle("  title ")
+	assert.Nil(t, err)
+	assert.Equal(t, "title", got)
+
+	_, err = handler.NormalizeTitle(strings.Repeat("あ", 256))
+	assert.ErrorIs(t, err, handler.ErrInvalidTitle)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/labstack/echo/v5"
+)
+
+// NewRetention returns the retention handler.
+// r applies the policy registered by the worker, found by entries.
+func NewRetention(r *task.Retention, lister task.CompletedTaskLister, entries task.SchedulerEntryLister) *Retention {
+	return &Retention{
+		retention: r,
+		lister:    lister,
+		entries:   entries,
+	}
+}
+
+type Retention struct {
+	retention *task.Retention
+	lister    task.CompletedTaskLister
+	entries   task.SchedulerEntryLister
+}
+
+// Report what the retention would reclaim.
+//
+// @summary report what the retention would reclaim
+// @description apply the retention policy registered by the worker in dry run mode, nothing is deleted.
+// @description the starred processes are never reclaimed.
+// @produce json
+// @success 200 {object} handler.SuccessResponse[retention.Report]
+// @failure 404 {object} handler.ErrorResponse "retention is disabled"
+// @failure 500 {object} handler.ErrorResponse
+// @router /retention/report [get]
+func (h *Retention) Report(c *echo.Context) error {
+	x, err := task.RegisteredRetention(h.entries)
+	if err != nil {
+		alog.L().Error("retention report", slog.String("id", echox.RequestID(c)), logx.Err(err))
+		return Error(c, http.StatusInternalServerError, "failed to get retention")
+	}
+	if x == nil {
+		return Error(c, http.StatusNotFound, "retention is disabled")
+	}
+	r, err := h.retention.With(x).Apply(c.Request().Context(), time.Now(), true)
+	if err != nil {
+		alog.L().Error("retention report", slog.String("id", echox.RequestID(c)), logx.Err(err))
+		return Error(c, http.StatusInternalServerError, "failed to report")
+	}
+	return Success(c, http.StatusOK, r)
+}
+
+type RetentionRunsParam struct {
+	Limit int `query:"limit"` // default: 30
+}
+
+type RetentionRunsResponseData []*retention.Report
+
+// List the reports of the retention runs.
+//
+// @summary list the reports of the retention runs
+// @description list what the retention of the worker reclaimed, the latest first.
+// @description the reports are kept for the retentionReportDays of the worker.
+// @param limit query int false "max number of the reports; default: 30"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[RetentionRunsResponseData]
+// @failure 500 {object} handler.ErrorResponse
+// @router /retention/runs [get]
+func (h *Retention) Runs(c *echo.Context) error {
+	var p RetentionRunsParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+	if p.Limit <= 0 {
+		p.Limit = 30
+	}
+	xs, err := task.ListRetentionReports(h.lister, p.Limit)
+	if err != nil {
+		alog.L().Error("retention runs", slog.String("id", echox.RequestID(c)), logx.Err(err))
+		return Error(c, http.StatusInternalServerError, "failed to list")
+	}
+	return Success(c, http.StatusOK, RetentionRunsResponseData(xs))
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/cli/info"
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/labstack/echo/v5"
+)
+
+// NewConfigSchema returns the schema of the config.
+// Models are discovered from neutrinoDir, not restricted if neutrinoDir is empty.
+func NewConfigSchema(neutrinoDir string) (*ctl.Schema, error) {
+	var models []string
+	if neutrinoDir != "" {
+		xs, err := info.ModelIDs(neutrinoDir)
+		if err != nil {
+			return nil, err
+		}
+		models = xs
+	}
+	alog.L().Info("config schema", slog.Any("models", models))
+	return ctl.NewSchema(models)
+}
+
+func NewSchema(config *ctl.Schema) *Schema {
+	return &Schema{
+		config: config,
+	}
+}
+
+type Schema struct {
+	config *ctl.Schema
+}
+
+// Get the JSON Schema of the config.
+//
+// @summary get config schema
+// @description get the JSON Schema of config.yml, also applied to the config of the processes
+// @produce json
+// @success 200 {object} ctl.Schema
+// @router /schema/config [get]
+func (s *Schema) Config(c *echo.Context) error {
+	return c.JSON(http.StatusOK, s.config)
+}
+
+// newJobConfig returns the config of the job from the options and the values, validated by the schema.
+func newJobConfig(schema *ctl.Schema, options []byte, values map[string]string) (*ctl.Config, *StatusError) {
+	c, err := schema.NewJobConfig(options, values)
+	if err != nil {
+		return nil, invalidConfigError(err)
+	}
+	return c, nil
+}
+
+// validateConfig validates the config of the job by the schema.
+func validateConfig(schema *ctl.Schema, c *ctl.Config) *StatusError {
+	if err := schema.ValidateConfig(c); err != nil {
+		return invalidConfigError(err)
+	}
+	return nil
+}
+
+func invalidConfigError(err error) *StatusError {
+	// joined errors are multiline
+	return NewStatusError(http.StatusBadRequest, err, "invalid config: "+strings.ReplaceAll(err.Error(), "\n", "; "))
+}
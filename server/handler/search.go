@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -20,14 +24,29 @@ func NewSearch(processSearcher repo.ProcessSearcher) *Search {
 }
 
 type SearchProcessParam struct {
-	Limit  int         `query:"limit"`  // default: 5
-	Status string      `query:"status"` // (pending|running|succeed|failed)
-	Prefix string      `query:"prefix"` // title prefix
-	Start  *CustomTime `query:"start"`  // created_at; RFC3339 or timestamp
-	End    *CustomTime `query:"end"`    // created_at; RFC3339 or timestamp
+	Limit          int           `query:"limit"`          // default: 5
+	IDs            []string      `query:"ids"`            // request ids
+	Status         string        `query:"status"`         // (pending|running|succeed|failed|cancelled)
+	Prefix         string        `query:"prefix"`         // title prefix
+	Contains       string        `query:"contains"`       // title substring, case-insensitive
+	Model          string        `query:"model"`          // singer
+	SupportModel   string        `query:"supportModel"`   // support singer
+	Transpose      *int          `query:"transpose"`      // transpose
+	Start          *CustomTime   `query:"start"`          // created_at; RFC3339 or timestamp
+	End            *CustomTime   `query:"end"`            // created_at; RFC3339 or timestamp
+	CompletedStart *CustomTime   `query:"completedStart"` // completed_at; RFC3339 or timestamp
+	CompletedEnd   *CustomTime   `query:"completedEnd"`   // completed_at; RFC3339 or timestamp
+	MinDuration    int           `query:"minDuration"`    // seconds from started_at to completed_at, inclusive
+	MaxDuration    int           `query:"maxDuration"`    // seconds from started_at to completed_at, inclusive
+	Wav            *bool         `query:"wav"`            // whether the wav of the result exists
+	Tags           []string      `query:"tags"`           // all the tags should be attached
+	Sort           string        `query:"sort"`           // (created|completed|duration|title); default: created
+	Order          string        `query:"order"`          // (asc|desc); default: desc
+	Cursor         *SearchCursor `query:"cursor"`         // next of the previous page
+	Total          bool          `query:"total"`          // count the processes matched
 }
 
-func (p SearchProcessParam) intoRequest() *repo.SearchProcessRequest {
+func (p SearchProcessParam) intoRequest() (*repo.SearchProcessRequest, error) {
 	var r repo.SearchProcessRequest
 	if x := p.Limit; x > 0 {
 		r.Limit = x
@@ -50,7 +69,94 @@ func (p SearchProcessParam) intoRequest() *repo.SearchProcessRequest {
 		end = new(time.Time(*x))
 	}
 	r.CreatedAt = repo.NewRange(start, end)
-	return &r
+	r.RequestIDs = p.IDs
+	if x := p.Contains; x != "" {
+		r.TitleContains = &x
+	}
+	if x := p.Model; x != "" {
+		r.Model = &x
+	}
+	if x := p.SupportModel; x != "" {
+		r.SupportModel = &x
+	}
+	r.Transpose = p.Transpose
+	var (
+		completedStart, completedEnd *time.Time
+	)
+	if x := p.CompletedStart; x != nil {
+		completedStart = new(time.Time(*x))
+	}
+	if x := p.CompletedEnd; x != nil {
+		completedEnd = new(time.Time(*x))
+	}
+	r.CompletedAt = repo.NewRange(completedStart, completedEnd)
+	var (
+		minDuration, maxDuration *time.Duration
+	)
+	if x := p.MinDuration; x > 0 {
+		minDuration = new(time.Duration(x) * time.Second)
+	}
+	if x := p.MaxDuration; x > 0 {
+		maxDuration = new(time.Duration(x) * time.Second)
+	}
+	r.Duration = repo.NewRange(minDuration, maxDuration)
+	r.HasWav = p.Wav
+	tags, err := NormalizeTags(p.Tags)
+	if err != nil {
+		return nil, err
+	}
+	r.Tags = tags
+
+	if p.Sort == "" {
+		p.Sort = repo.SearchSortCreated.String()
+	}
+	sort, ok := repo.SearchSortFromString(p.Sort)
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %s", p.Sort)
+	}
+	r.Sort = sort
+	switch p.Order {
+	case "", "desc":
+		p.Order = "desc"
+	case "asc":
+		r.Asc = true
+	default:
+		return nil, fmt.Errorf("unknown order %s", p.Order)
+	}
+	if x := p.Cursor; x != nil {
+		if x.Sort != p.Sort || x.Order != p.Order {
+			return nil, errors.New("cursor does not match sort and order")
+		}
+		r.After = &repo.SearchCursor{
+			Key: x.Key,
+			ID:  x.ID,
+		}
+	}
+	r.Total = p.Total
+	return &r, nil
+}
+
+// SearchCursor is the opaque cursor of the search, the position of the last item of the page.
+type SearchCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"`
+	ID    int    `json:"i"`
+}
+
+var _ echo.BindUnmarshaler = new(SearchCursor)
+
+func (c SearchCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (c *SearchCursor) UnmarshalParam(param string) error {
+	b, err := base64.RawURLEncoding.DecodeString(param)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, c)
 }
 
 type SearchProcessResponseDataElement struct {
@@ -62,30 +168,68 @@ type SearchProcessResponseDataElement struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	Command     string    `json:"command,omitempty"`
 	Title       string    `json:"title"`
+	Notes       string    `json:"notes,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Parent      string    `json:"parent,omitempty"` // request id of the process rerun by this process
+	Starred     bool      `json:"starred"`          // kept by the retention policies
 }
 
 type SearchProcessResponseData []*SearchProcessResponseDataElement
 
+// SearchProcessResponse is SuccessResponse[SearchProcessResponseData] with the pagination.
+type SearchProcessResponse struct {
+	OK    bool                      `json:"ok"` // true
+	Data  SearchProcessResponseData `json:"data"`
+	Next  string                    `json:"next,omitempty"`  // cursor of the next page, empty if the last page
+	Total *int                      `json:"total,omitempty"` // number of the processes matched if requested
+}
+
 // Search processes.
 //
 // @summary search processes
-// @description search processes by status, created_at, title prefix, order by created_at desc
+// @description search processes by the conditions below, all the conditions should be met.
+// @description the pages are ordered by the sort key and then the process id, so they are stable under the concurrent inserts.
+// @description pass next of the response as cursor with the same conditions to get the next page.
+// @description sorting by completed excludes the uncompleted processes, by duration excludes the processes not started or uncompleted.
 // @param limit query int false "query limit; default: 5"
+// @param ids query []string false "request ids" collectionFormat(multi)
 // @param prefix query string false "title prefix"
-// @param status query string false "process status; (pending|running|succeed|failed)"
+// @param contains query string false "title substring, case-insensitive"
+// @param status query string false "process status; (pending|running|succeed|failed|cancelled)"
+// @param model query string false "singer"
+// @param supportModel query string false "support singer"
+// @param transpose query int false "transpose"
 // @param start query string false "created_at"
 // @param end query string false "created_at"
+// @param completedStart query string false "completed_at"
+// @param completedEnd query string false "completed_at"
+// @param minDuration query int false "min seconds from started_at to completed_at"
+// @param maxDuration query int false "max seconds from started_at to completed_at"
+// @param wav query bool false "whether the wav of the result exists"
+// @param tags query []string false "tags, all of them should be attached, case-insensitive" collectionFormat(multi)
+// @param sort query string false "sort key; (created|completed|duration|title); default: created"
+// @param order query string false "sort order; (asc|desc); default: desc"
+// @param cursor query string false "next of the previous page"
+// @param total query bool false "count the processes matched"
 // @produce json
-// @success 200 {object} handler.SuccessResponse[SearchProcessResponseData]
+// @success 200 {object} handler.SearchProcessResponse
+// @failure 400 {object} handler.ErrorResponse
 // @router /proc/search [get]
 func (s *Search) SearchProcess(c *echo.Context) error {
 	var p SearchProcessParam
 	if err := c.Bind(&p); err != nil {
-		return err
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+	req, err := p.intoRequest()
+	if err != nil {
+		return Error(c, http.StatusBadRequest, err.Error())
 	}
 
-	xs, err := s.processSearcher.SearchProcess(c.Request().Context(), p.intoRequest())
+	xs, err := s.processSearcher.SearchProcess(c.Request().Context(), req)
 	if err != nil {
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			return Error(c, http.StatusBadRequest, "invalid cursor")
+		}
 		return err
 	}
 
@@ -97,6 +241,9 @@ func (s *Search) SearchProcess(c *echo.Context) error {
 			CreatedAt: x.Process.CreatedAt,
 			UpdatedAt: x.Process.UpdatedAt,
 			Title:     x.Details.Title,
+			Notes:     x.Details.Notes,
+			Tags:      x.Tags,
+			Starred:   x.Process.Starred,
 		}
 		if v := x.Process.StartedAt; v != nil {
 			y.StartedAt = *v
@@ -107,8 +254,28 @@ func (s *Search) SearchProcess(c *echo.Context) error {
 		if v := x.Details.Command; v != nil {
 			y.Command = *v
 		}
+		if v := x.ParentRequestID; v != nil {
+			y.Parent = *v
+		}
 		data[i] = y
 	}
 
-	return Success(c, http.StatusOK, SearchProcessResponseData(data))
+	r := &SearchProcessResponse{
+		OK:    true,
+		Data:  data,
+		Total: xs.Total,
+	}
+	if x := xs.Next; x != nil {
+		order := "desc"
+		if req.Asc {
+			order = "asc"
+		}
+		r.Next = SearchCursor{
+			Sort:  req.Sort.String(),
+			Order: order,
+			Key:   x.Key,
+			ID:    x.ID,
+		}.String()
+	}
+	return c.JSON(http.StatusOK, r)
 }
@@ -0,0 +1,52 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/berquerant/pneutrinoutil/server/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCursor(t *testing.T) {
+	t.Run("roundtrip", func(t *testing.T) {
+		for _, tc := range []struct {
+			title  string
+			cursor handler.SearchCursor
+		}{
+			{
+				title: "created",
+				cursor: handler.SearchCursor{
+					Sort:  "created",
+					Order: "desc",
+					Key:   "2025-11-15T15:33:34+09:00",
+					ID:    10,
+				},
+			},
+			{
+				title: "title",
+				cursor: handler.SearchCursor{
+					Sort:  "title",
+					Order: "asc",
+					Key:   "a/b?c&d=e",
+					ID:    1,
+				},
+			},
+		} {
+			t.Run(tc.title, func(t *testing.T) {
+				param := tc.cursor.String()
+				assert.NotContains(t, param, "=")
+				var got handler.SearchCursor
+				if assert.Nil(t, got.UnmarshalParam(param)) {
+					assert.Equal(t, tc.cursor, got)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, param := range []string{"!", "bm90IGpzb24"} {
+			var got handler.SearchCursor
+			assert.NotNil(t, got.UnmarshalParam(param), param)
+		}
+	})
+}
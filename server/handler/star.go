@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/labstack/echo/v5"
+)
+
+func NewStar(processGetter repo.ProcessGetter, processUpdater repo.ProcessUpdater) *Star {
+	return &Star{
+		processGetter:  processGetter,
+		processUpdater: processUpdater,
+	}
+}
+
+type Star struct {
+	processGetter  repo.ProcessGetter
+	processUpdater repo.ProcessUpdater
+}
+
+// Star a process.
+//
+// @summary star a process
+// @description star the process to keep it from the retention policies forever.
+// @description the ensemble or the sweep is also kept if any member is starred.
+// @param id path string true "request id"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[string] "starred"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id}/star [put]
+func (h *Star) Star(c *echo.Context) error {
+	return h.star(c, true)
+}
+
+// Unstar a process.
+//
+// @summary unstar a process
+// @description unstar the process to apply the retention policies to it.
+// @param id path string true "request id"
+// @produce json
+// @success 200 {object} handler.SuccessResponse[string] "unstarred"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id}/star [delete]
+func (h *Star) Unstar(c *echo.Context) error {
+	return h.star(c, false)
+}
+
+func (h *Star) star(c *echo.Context, starred bool) error {
+	var p GetParam
+	if err := c.Bind(&p); err != nil {
+		return Error(c, http.StatusBadRequest, "bad request")
+	}
+
+	proc, err := h.processGetter.GetProcessByRequestId(c.Request().Context(), p.RequestID)
+	if err != nil {
+		alog.L().Error("missing process", slog.String("id", echox.RequestID(c)), slog.String("param_id", p.RequestID), logx.Err(err))
+		return Error(c, http.StatusNotFound, "not found")
+	}
+
+	if _, err := h.processUpdater.UpdateProcess(c.Request().Context(), &repo.UpdateProcessRequest{
+		ID:      proc.ID,
+		Starred: &starred,
+	}); err != nil {
+		alog.L().Error("star", slog.String("id", echox.RequestID(c)), slog.String("param_id", p.RequestID), slog.Bool("starred", starred), logx.Err(err))
+		return Error(c, http.StatusInternalServerError, "failed to star")
+	}
+	if starred {
+		return Success(c, http.StatusOK, "starred")
+	}
+	return Success(c, http.StatusOK, "unstarred")
+}
@@ -2,17 +2,24 @@ package handler
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
-	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
 	"github.com/berquerant/pneutrinoutil/pkg/domain"
 	"github.com/berquerant/pneutrinoutil/pkg/echox"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
 	"github.com/berquerant/pneutrinoutil/pkg/repo"
 	"github.com/berquerant/pneutrinoutil/pkg/task"
 	"github.com/hibiken/asynq"
@@ -22,16 +29,45 @@ import (
 // Start a process.
 //
 // @summary start a process
-// @description start a pneutrinoutil process with given arguments
+// @description start a pneutrinoutil process with given config.
+// @description the config is the json of the options keyed like config.yml, see /schema/config; the options below are available.
+// @description the form values of the options override the config.
+// @description to render the score of another process, send handler.StartRequest as application/json instead of the form.
+// @accept multipart/form-data
+// @accept json
 // @param score formData file true "musicxml"
+// @param config formData string false "json of the options of the job, e.g. {\"model\": \"MERROW\", \"thread\": 2}"
+// @param title formData string false "title of the process; default: the score file name except extension"
+// @param notes formData string false "notes of the process"
+// @param tags formData string false "tags of the process separated by comma, case-insensitive"
+// @param desc formData string false "description of the config of the job"
+// @param thread formData integer false "number of parallel in session; default: 4"
 // @param model formData string false "default: MERROW"
 // @param supportModel formData string false "support singer library"
 // @param transpose formData integer false "default: 0"
+// @param part formData string false "id or name of the part to render, the whole score if empty"
+// @param normalize formData string false "normalize the wav; peak or loudness (EBU R128), disabled if empty" Enums(peak, loudness)
+// @param peakLevel formData number false "target level of peak normalization, also the peak ceiling of loudness normalization, in dBFS; default: -1"
+// @param loudnessLevel formData number false "target level of loudness normalization in LUFS; default: -23"
+// @param trimSilence formData boolean false "trim or pad leading and trailing silence to leadingSilence and trailingSilence"
+// @param silenceThreshold formData number false "level regarded as silence in dBFS; default: -60"
+// @param leadingSilence formData number false "seconds of leading silence after trimming"
+// @param trailingSilence formData number false "seconds of trailing silence after trimming"
+// @param fadeIn formData number false "seconds of fade in"
+// @param fadeOut formData number false "seconds of fade out"
+// @param accompaniment formData file false "accompaniment wav to mix with the vocal"
+// @param mixOffset formData number false "seconds to delay the vocal against the accompaniment, negative value delays the accompaniment"
+// @param vocalGain formData number false "gain of the vocal in the mix in dB"
+// @param vocalPan formData number false "pan of the vocal in the mix; -1 (left) to 1 (right)"
+// @param accompanimentGain formData number false "gain of the accompaniment in the mix in dB"
+// @param accompanimentPan formData number false "pan of the accompaniment in the mix; -1 (left) to 1 (right)"
+// @param formats formData string false "additional output formats separated by comma; codec[:sampleRate[:bitDepth]], codec is wav or flac, e.g. flac,wav:48000:24"
 // @produce json
 // @success 202 {object} handler.SuccessResponse[string] "new process started"
 // @header 202 {string} string x-request-id "request id, or just id"
-// @failure 400 {object} handler.ErrorResponse "bad score"
-// @failure 413 {object} handler.ErrorResponse "too big score"
+// @failure 400 {object} handler.ErrorResponse "bad score, accompaniment or config"
+// @failure 404 {object} handler.ErrorResponse "no process of the score of handler.StartRequest"
+// @failure 413 {object} handler.ErrorResponse "too big score or accompaniment"
 // @failure 500 {object} handler.ErrorResponse
 // @router /proc [post]
 func (s *Start) Handler(c *echo.Context) error {
@@ -45,20 +81,15 @@ func (s *Start) Handler(c *echo.Context) error {
 	return Success(c, http.StatusAccepted, "accepted")
 }
 
-// GetFormArgs extracts arguments for pneutrinoutil from the form values.
-func (Start) GetFormArgs(c *echo.Context) map[string]string {
-	keys := []string{
-		"model",
-		"supportModel",
-		"transpose",
-	}
-	d := map[string]string{}
-	for _, k := range keys {
+// GetFormConfig returns the config of the job from the form value `config` overridden by the other form values keyed by ctl.JobKeys.
+func GetFormConfig(c *echo.Context, schema *ctl.Schema) (*ctl.Config, *StatusError) {
+	values := map[string]string{}
+	for _, k := range ctl.JobKeys() {
 		if v := c.FormValue(k); v != "" {
-			d[k] = v
+			values[k] = v
 		}
 	}
-	return d
+	return newJobConfig(schema, []byte(c.FormValue("config")), values)
 }
 
 // GetFormFile reads a musicxml file from the form file `score`.
@@ -71,6 +102,22 @@ func (s Start) GetFormFile(c *echo.Context) (*ReadFromFileResult, *StatusError)
 	return r, nil
 }
 
+// GetFormAccompaniment reads a wav file from the optional form file `accompaniment`.
+// Returns nil if not given.
+func (Start) GetFormAccompaniment(c *echo.Context) (*ReadFromFileResult, *StatusError) {
+	if _, err := c.FormFile("accompaniment"); errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	r, err := ReadFormFile(c, "accompaniment", accompanimentUploadMaxSizeBytes)
+	if err != nil {
+		return nil, err.AppendMessageToErr("failed to read accompaniment file from form")
+	}
+	if _, err := audio.Decode(bytes.NewReader(r.Blob)); err != nil {
+		return nil, NewStatusError(http.StatusBadRequest, err, "accompaniment is not a valid wav")
+	}
+	return r, nil
+}
+
 func NewStart(
 	client *asynq.Client,
 	processTimeout time.Duration,
@@ -78,7 +125,11 @@ func NewStart(
 	path string,
 	objectWriter repo.ObjectWriter,
 	detailsCreator repo.ProcessDetailsCreator,
+	detailsGetter repo.ProcessDetailsGetter,
 	processCreator repo.ProcessCreator,
+	processGetter repo.ProcessGetter,
+	tagsUpdater repo.ProcessTagsUpdater,
+	schema *ctl.Schema,
 ) *Start {
 	return &Start{
 		client:         client,
@@ -87,7 +138,11 @@ func NewStart(
 		path:           path,
 		objectWriter:   objectWriter,
 		detailsCreator: detailsCreator,
+		detailsGetter:  detailsGetter,
 		processCreator: processCreator,
+		processGetter:  processGetter,
+		tagsUpdater:    tagsUpdater,
+		schema:         schema,
 	}
 }
 
@@ -96,17 +151,66 @@ type Start struct {
 	processTimeout time.Duration
 	objectWriter   repo.ObjectWriter
 	detailsCreator repo.ProcessDetailsCreator
+	detailsGetter  repo.ProcessDetailsGetter
 	processCreator repo.ProcessCreator
+	processGetter  repo.ProcessGetter
+	tagsUpdater    repo.ProcessTagsUpdater
+	schema         *ctl.Schema
 	bucket         string
 	path           string
 }
 
+// StartRequest is the json body to start a process with the score of another process.
+type StartRequest struct {
+	// Score is the request id of the process whose score is rendered.
+	Score string `json:"score"`
+	// Config is the options of the job keyed by ctl.JobKeys, the same as the form value config.
+	Config json.RawMessage `json:"config,omitempty" swaggertype:"object"`
+	// Title is the title of the process, the title of the process of the score if empty.
+	Title string   `json:"title,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// RerunRequest is the json body to rerun a process.
+type RerunRequest struct {
+	// Config overrides the options of the process, keyed by ctl.JobKeys.
+	Config json.RawMessage `json:"config,omitempty" swaggertype:"object"`
+}
+
+// startJob is a process to start.
+type startJob struct {
+	title                 string
+	basename              string
+	labels                *labels
+	scoreObjectID         int
+	accompanimentObjectID *int
+	parentID              *int
+	config                *ctl.Config
+}
+
 func (s *Start) NewProcess(c *echo.Context) *StatusError {
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return s.newProcessFromJSON(c)
+	}
+
 	rid := echox.RequestID(c)
+	config, fErr := GetFormConfig(c, s.schema)
+	if fErr != nil {
+		return fErr
+	}
 	score, fErr := s.GetFormFile(c)
 	if fErr != nil {
 		return fErr
 	}
+	accompaniment, fErr := s.GetFormAccompaniment(c)
+	if fErr != nil {
+		return fErr
+	}
+	labels, fErr := getFormLabels(c)
+	if fErr != nil {
+		return fErr
+	}
 
 	obj, err := s.objectWriter.WriteObject(c.Request().Context(), &repo.WriteObjectRequest{
 		Type:   domain.ObjectTypeFile,
@@ -118,13 +222,175 @@ func (s *Start) NewProcess(c *echo.Context) *StatusError {
 		return NewStatusError(http.StatusInternalServerError, err, "failed to upload score")
 	}
 
-	title := score.Name
-	if ext := path.Ext(title); ext != "" {
-		title = title[:len(title)-len(ext)]
+	var accompanimentObjectID *int
+	if accompaniment != nil {
+		obj, err := s.objectWriter.WriteObject(c.Request().Context(), &repo.WriteObjectRequest{
+			Type:   domain.ObjectTypeFile,
+			Bucket: s.bucket,
+			Path:   filepath.Join(s.path, rid, "accompaniment", accompaniment.Name),
+			Blob:   bytes.NewReader(accompaniment.Blob),
+		})
+		if err != nil {
+			return NewStatusError(http.StatusInternalServerError, err, "failed to upload accompaniment")
+		}
+		accompanimentObjectID = &obj.Object().ID
+	}
+
+	basename := pathx.Basename(score.Name)
+	return s.start(c, &startJob{
+		title:                 labels.titleOr(basename),
+		basename:              basename,
+		labels:                labels,
+		scoreObjectID:         obj.Object().ID,
+		accompanimentObjectID: accompanimentObjectID,
+		config:                config,
+	})
+}
+
+func (s *Start) newProcessFromJSON(c *echo.Context) *StatusError {
+	var req StartRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return NewStatusError(http.StatusBadRequest, err, "invalid body")
+	}
+	if req.Score == "" {
+		return NewStatusError(http.StatusBadRequest, errors.New("no score"), "require score")
+	}
+	config, fErr := newJobConfig(s.schema, req.Config, nil)
+	if fErr != nil {
+		return fErr
+	}
+	labels, fErr := newLabels(req.Title, req.Notes, req.Tags)
+	if fErr != nil {
+		return fErr
+	}
+	proc, details, fErr := s.getParent(c, req.Score, "score not found")
+	if fErr != nil {
+		return fErr
+	}
+	return s.start(c, &startJob{
+		title:         labels.titleOr(details.Title),
+		basename:      details.Basename,
+		labels:        labels,
+		scoreObjectID: details.ScoreObjectID,
+		parentID:      &proc.ID,
+		config:        config,
+	})
+}
+
+// getParent returns the process whose score is reused.
+func (s *Start) getParent(c *echo.Context, requestID, notFound string) (*domain.Process, *domain.ProcessDetails, *StatusError) {
+	proc, err := s.processGetter.GetProcessByRequestId(c.Request().Context(), requestID)
+	if err != nil {
+		return nil, nil, NewStatusError(http.StatusNotFound, err, notFound)
+	}
+	details, err := s.detailsGetter.GetProcessDetails(c.Request().Context(), proc.DetailsID)
+	if err != nil {
+		return nil, nil, NewStatusError(http.StatusNotFound, err, notFound)
+	}
+	return proc, details, nil
+}
+
+// Rerun a process.
+//
+// @summary rerun a process
+// @description start a process with the score and the options of the process.
+// @description the options are overridden by the config of handler.RerunRequest, the body is optional.
+// @description the accompaniment is not reused.
+// @description the new process is linked to the process as the parent.
+// @accept json
+// @param id path string true "request id"
+// @param request body handler.RerunRequest false "options to override"
+// @produce json
+// @success 202 {object} handler.SuccessResponse[string] "new process started"
+// @header 202 {string} string x-request-id "request id, or just id"
+// @failure 400 {object} handler.ErrorResponse "bad config or the process is not a render"
+// @failure 404 {object} handler.ErrorResponse
+// @failure 409 {object} handler.ErrorResponse "options of the process are not stored"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/{id}/rerun [post]
+func (s *Start) Rerun(c *echo.Context) error {
+	if err := s.rerun(c); err != nil {
+		rid := echox.RequestID(c)
+		alog.L().Error("failed to rerun process", slog.String("id", rid), logx.Err(err))
+		return err.Respond(c)
+	}
+	return Success(c, http.StatusAccepted, "accepted")
+}
+
+func (s *Start) rerun(c *echo.Context) *StatusError {
+	var p GetParam
+	// not Bind, the body is the request
+	if err := echo.BindPathValues(c, &p); err != nil {
+		return NewStatusError(http.StatusBadRequest, err, "bad request")
+	}
+	var req RerunRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return NewStatusError(http.StatusBadRequest, err, "invalid body")
+	}
+
+	proc, details, fErr := s.getParent(c, p.RequestID, "not found")
+	if fErr != nil {
+		return fErr
+	}
+	if proc.Kind != domain.ProcessKindRender {
+		return NewStatusError(http.StatusBadRequest,
+			fmt.Errorf("rerun %s process", proc.Kind),
+			fmt.Sprintf("%s process cannot be rerun, rerun the members instead", proc.Kind),
+		)
+	}
+	if len(details.Options) == 0 {
+		// created before the options were stored, the defaults are not what the process used
+		return NewStatusError(http.StatusConflict,
+			errors.New("no options"),
+			"options of the process are not stored, start a new process instead",
+		)
+	}
+	options, err := mergeOptions(details.Options, req.Config)
+	if err != nil {
+		return invalidConfigError(err)
+	}
+	config, fErr := newJobConfig(s.schema, options, nil)
+	if fErr != nil {
+		return fErr
+	}
+	return s.start(c, &startJob{
+		title:         details.Title,
+		basename:      details.Basename,
+		labels:        &labels{},
+		scoreObjectID: details.ScoreObjectID,
+		parentID:      &proc.ID,
+		config:        config,
+	})
+}
+
+// mergeOptions returns the json object of base overridden by the keys of override.
+func mergeOptions(base, override json.RawMessage) (json.RawMessage, error) {
+	m := map[string]json.RawMessage{}
+	for _, b := range []json.RawMessage{base, override} {
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		var x map[string]json.RawMessage
+		if err := json.Unmarshal(b, &x); err != nil {
+			return nil, fmt.Errorf("%w: options should be a json object", err)
+		}
+		maps.Copy(m, x)
+	}
+	return json.Marshal(m)
+}
+
+func (s *Start) start(c *echo.Context, job *startJob) *StatusError {
+	rid := echox.RequestID(c)
+	options, err := json.Marshal(job.config.JobOptions())
+	if err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to marshal config")
 	}
 	details, err := s.detailsCreator.CreateProcessDetails(c.Request().Context(), &repo.CreateProcessDetailsRequest{
-		Title:         title,
-		ScoreObjectId: obj.Object().ID,
+		Title:         job.title,
+		Basename:      job.basename,
+		Notes:         job.labels.notes,
+		ScoreObjectId: job.scoreObjectID,
+		Options:       options,
 	})
 	if err != nil {
 		return NewStatusError(http.StatusInternalServerError, err, "failed to create process details")
@@ -134,24 +400,27 @@ func (s *Start) NewProcess(c *echo.Context) *StatusError {
 		RequestId: rid,
 		DetailsId: details.ID,
 		Status:    domain.ProcessStatusPending,
+		ParentId:  job.parentID,
 	})
 	if err != nil {
 		return NewStatusError(http.StatusInternalServerError, err, "failed to create process")
 	}
-
-	var args []string
-	for k, v := range s.GetFormArgs(c) {
-		args = append(args, fmt.Sprintf("--%s", k), v)
+	if err := job.labels.setTags(c.Request().Context(), s.tagsUpdater, proc.ID); err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to tag process")
 	}
+
 	atask, err := task.NewPneutrinoutilStart(task.PneutrinoutilStartPayload{
-		RequestID: rid,
-		Args:      args,
+		RequestID:             rid,
+		Config:                job.config,
+		AccompanimentObjectID: job.accompanimentObjectID,
 	})
 	if err != nil {
 		return NewStatusError(http.StatusInternalServerError, err, "failed to create task")
 	}
 
-	info, err := s.client.EnqueueContext(c.Request().Context(), atask, asynq.Timeout(s.processTimeout))
+	info, err := s.client.EnqueueContext(c.Request().Context(), atask,
+		asynq.Timeout(s.processTimeout), asynq.TaskID(task.TaskID(task.TypePneutrinoutilStart, rid)),
+	)
 	if err != nil {
 		return NewStatusError(http.StatusInternalServerError, err, "failed to enqueue task")
 	}
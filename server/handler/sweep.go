@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/alog"
+	"github.com/berquerant/pneutrinoutil/pkg/domain"
+	"github.com/berquerant/pneutrinoutil/pkg/echox"
+	"github.com/berquerant/pneutrinoutil/pkg/logx"
+	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
+	"github.com/labstack/echo/v5"
+)
+
+// Start a sweep process.
+//
+// @summary start a sweep process
+// @description render every combination of singers and transpose values as a process.
+// @description runs are available as /proc/{id}-{index}, the index of them as /proc/{id}/sweep.
+// @description values of the lists that are not specified are taken from model, supportModel and transpose.
+// @description other form values and config are the same as /proc and applied to all runs.
+// @description the title is also of the runs, the notes and the tags are only of the sweep.
+// @param score formData file true "musicxml"
+// @param spec formData string false "yaml or json of the value lists to sweep; {model: [], supportModel: [], transpose: []}"
+// @param models formData string false "singers to sweep separated by comma, override the spec"
+// @param supportModels formData string false "support singers to sweep separated by comma, override the spec"
+// @param transposes formData string false "transpose values to sweep separated by comma, override the spec"
+// @produce json
+// @success 202 {object} handler.SuccessResponse[string] "new process started"
+// @header 202 {string} string x-request-id "request id, or just id"
+// @failure 400 {object} handler.ErrorResponse "bad score or spec"
+// @failure 413 {object} handler.ErrorResponse "too big score"
+// @failure 500 {object} handler.ErrorResponse
+// @router /proc/sweep [post]
+func (s *Sweep) Handler(c *echo.Context) error {
+	err := s.NewProcess(c)
+	if err != nil {
+		rid := echox.RequestID(c)
+		alog.L().Error("failed to start sweep process", slog.String("id", rid), logx.Err(err))
+		return err.Respond(c)
+	}
+
+	return Success(c, http.StatusAccepted, "accepted")
+}
+
+// GetFormSpec reads the spec from the form value `spec` overridden by `models`, `supportModels` and `transposes`.
+func (Sweep) GetFormSpec(c *echo.Context) (*sweep.Spec, *StatusError) {
+	spec := &sweep.Spec{}
+	if x := c.FormValue("spec"); x != "" {
+		s, err := sweep.ParseSpec([]byte(x))
+		if err != nil {
+			return nil, NewStatusError(http.StatusBadRequest, err, "invalid spec")
+		}
+		spec = s
+	}
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		xs := strings.Split(s, ",")
+		for i, x := range xs {
+			xs[i] = strings.TrimSpace(x)
+		}
+		return xs
+	}
+	flagSpec := sweep.Spec{
+		Model:        split(c.FormValue("models")),
+		SupportModel: split(c.FormValue("supportModels")),
+	}
+	for _, x := range split(c.FormValue("transposes")) {
+		v, err := strconv.Atoi(x)
+		if err != nil {
+			return nil, NewStatusError(http.StatusBadRequest, err, "invalid transposes")
+		}
+		flagSpec.Transpose = append(flagSpec.Transpose, v)
+	}
+	merged := spec.Merge(flagSpec)
+	if err := merged.Validate(); err != nil {
+		return nil, NewStatusError(http.StatusBadRequest, err, "invalid spec")
+	}
+	return &merged, nil
+}
+
+// GetFormBase returns the values of the lists not to sweep from the config of the form values.
+func (s Sweep) GetFormBase(c *echo.Context) (*sweep.Combination, *StatusError) {
+	cfg, fErr := GetFormConfig(c, s.group.schema)
+	if fErr != nil {
+		return nil, fErr
+	}
+	return &sweep.Combination{
+		Model:        cfg.ModelDir,
+		SupportModel: cfg.SupportModelDir,
+		Transpose:    cfg.Transpose,
+	}, nil
+}
+
+func NewSweep(group *Group) *Sweep {
+	return &Sweep{
+		group: group,
+	}
+}
+
+type Sweep struct {
+	group *Group
+}
+
+func (s *Sweep) NewProcess(c *echo.Context) *StatusError {
+	rid := echox.RequestID(c)
+	score, fErr := (Start{}).GetFormFile(c)
+	if fErr != nil {
+		return fErr
+	}
+	spec, fErr := s.GetFormSpec(c)
+	if fErr != nil {
+		return fErr
+	}
+	base, fErr := s.GetFormBase(c)
+	if fErr != nil {
+		return fErr
+	}
+
+	var (
+		combinations = spec.Combinations(*base)
+		members      = make([]Member, len(combinations))
+		index        = sweep.Index{
+			Basename: pathx.Basename(score.Name),
+			Entries:  make([]*sweep.Entry, len(combinations)),
+		}
+	)
+	for i, x := range combinations {
+		members[i] = func(c *ctl.Config) {
+			c.ModelDir = x.Model
+			c.SupportModelDir = x.SupportModel
+			c.Transpose = x.Transpose
+		}
+		memberRid := task.GroupMemberRequestID(rid, i)
+		index.Entries[i] = &sweep.Entry{
+			Combination: *x,
+			ID:          memberRid,
+			Wav:         fmt.Sprintf("/v1/proc/%s/wav", memberRid),
+			Config:      fmt.Sprintf("/v1/proc/%s/config", memberRid),
+		}
+	}
+	if _, fErr := s.group.MemberConfigs(c, members); fErr != nil {
+		return fErr
+	}
+	// the index should be available before the runs are completed
+	var buf bytes.Buffer
+	if err := index.WriteJSON(&buf); err != nil {
+		return NewStatusError(http.StatusInternalServerError, err, "failed to create index")
+	}
+	if err := s.group.WriteFile(c, task.SweepIndexObjectPath, buf.Bytes()); err != nil {
+		return err
+	}
+
+	return s.group.NewProcess(c, domain.ProcessKindSweep, score, members)
+}
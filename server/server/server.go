@@ -13,6 +13,7 @@ import (
 	"github.com/berquerant/pneutrinoutil/pkg/infra"
 	"github.com/berquerant/pneutrinoutil/pkg/logx"
 	"github.com/berquerant/pneutrinoutil/pkg/repo"
+	"github.com/berquerant/pneutrinoutil/pkg/task"
 	"github.com/berquerant/pneutrinoutil/server/config"
 	"github.com/berquerant/pneutrinoutil/server/handler"
 	"github.com/hibiken/asynq"
@@ -23,16 +24,17 @@ import (
 )
 
 type Server struct {
-	e      *echo.Echo
-	c      *config.Config
-	db     *sql.DB
-	client *asynq.Client
+	e         *echo.Echo
+	c         *config.Config
+	db        *sql.DB
+	client    *asynq.Client
+	inspector *asynq.Inspector
 }
 
 func (s *Server) Echo() *echo.Echo { return s.e }
 
 func (s *Server) Close() error {
-	return errors.Join(s.db.Close(), s.client.Close())
+	return errors.Join(s.db.Close(), s.client.Close(), s.inspector.Close())
 }
 
 func New(ctx context.Context, cfg *config.Config) (*Server, error) {
@@ -54,6 +56,21 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 		return nil, err
 	}
 
+	inspector, err := cfg.NewAsynqInspector()
+	if err != nil {
+		_ = db.Close()
+		_ = client.Close()
+		return nil, err
+	}
+
+	configSchema, err := handler.NewConfigSchema(cfg.NeutrinoDir)
+	if err != nil {
+		_ = db.Close()
+		_ = client.Close()
+		_ = inspector.Close()
+		return nil, err
+	}
+
 	var (
 		objectConn   = infra.NewConn[domain.Object](db)
 		objects      = repo.NewObject(objectConn, objectConn)
@@ -62,8 +79,27 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 		details      = repo.NewProcessDetails(detailConn, detailConn)
 		processConn  = infra.NewConn[domain.Process](db)
 		processes    = repo.NewProcess(processConn, processConn)
+		tagConn      = infra.NewConn[domain.ProcessTag](db)
+		tags         = repo.NewProcessTags(tagConn, tagConn)
+		processAdmin = repo.NewProcessAdmin(&repo.ProcessAdminParams{
+			ProcessGetter:  processes,
+			DetailsGetter:  details,
+			ObjectGetter:   objects,
+			Deleter:        repo.NewDeleter(processConn),
+			StorageDeleter: objectStorage,
+			StorageLister:  objectStorage,
+			Bucket:         cfg.StorageBucket,
+			BasePath:       cfg.StoragePath,
+		})
 		searcherConn = infra.NewConn[repo.SearchProcessResultElement](db)
-		searcher     = repo.NewSearcher(searcherConn)
+		searcher     = repo.NewSearcher(searcherConn, infra.NewConn[int](db))
+		canceller    = task.NewCanceller(&task.CancellerParams{
+			Inspector:      inspector,
+			Webhooker:      cfg.NewWebhook(),
+			Enqueuer:       client,
+			ProcessGetter:  processes,
+			ProcessUpdater: processes,
+		})
 	)
 
 	//
@@ -128,12 +164,13 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	r3 := v1.GET("/debug", handler.Debug)
 	r3.Name = "debug"
 	v1.GET("/swagger/*", echoSwagger.WrapHandler)
-	r4 := v1.POST("/proc", handler.NewStart(client, cfg.ProcessTimeout(), cfg.StorageBucket, cfg.StoragePath, objectAdmin, details, processes).Handler)
+	startHandler := handler.NewStart(client, cfg.ProcessTimeout(), cfg.StorageBucket, cfg.StoragePath, objectAdmin, details, details, processes, processes, tags, configSchema)
+	r4 := v1.POST("/proc", startHandler.Handler)
 	r4.Name = "createProcess"
 	r5 := v1.GET("/proc/search", handler.NewSearch(searcher).SearchProcess)
 	r5.Name = "searchProcess"
 	getGroup := v1.Group("/proc/:id")
-	getHandler := handler.NewGet(processes, details, objectAdmin, objects)
+	getHandler := handler.NewGet(processes, details, objectAdmin, objects, objectAdmin, tags)
 	r6 := getGroup.GET("/detail", getHandler.Detail)
 	r6.Name = "getDetail"
 	r7 := getGroup.GET("/config", getHandler.Config)
@@ -144,12 +181,67 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	r9.Name = "getWav"
 	r10 := getGroup.GET("/log", getHandler.Log)
 	r10.Name = "getLog"
+	r11 := getGroup.GET("/peaks", getHandler.Peaks)
+	r11.Name = "getPeaks"
+	groupHandler := handler.NewGroup(client, inspector, cfg.ProcessTimeout(), cfg.StorageBucket, cfg.StoragePath, objectAdmin, details, processes, processes, tags, configSchema)
+	r12 := v1.POST("/proc/ensemble", handler.NewEnsemble(groupHandler).Handler)
+	r12.Name = "createEnsemble"
+	r13 := getGroup.GET("/stem/:index", getHandler.Stem)
+	r13.Name = "getStem"
+	r14 := v1.POST("/proc/sweep", handler.NewSweep(groupHandler).Handler)
+	r14.Name = "createSweep"
+	r15 := getGroup.GET("/sweep", getHandler.SweepIndex)
+	r15.Name = "getSweepIndex"
+	r16 := getGroup.GET("/sweep/html", getHandler.SweepIndexHTML)
+	r16.Name = "getSweepIndexHTML"
+	r17 := v1.GET("/schema/config", handler.NewSchema(configSchema).Config)
+	r17.Name = "getConfigSchema"
+	r18 := getGroup.GET("/diff/:other", getHandler.Diff)
+	r18.Name = "getDiff"
+	r19 := getGroup.GET("/diff/:other/wav", getHandler.DiffWav)
+	r19.Name = "getDiffWav"
+	r20 := getGroup.POST("/cancel", handler.NewCancel(processes, canceller).Handler)
+	r20.Name = "cancelProcess"
+	r21 := getGroup.POST("/rerun", startHandler.Rerun)
+	r21.Name = "rerunProcess"
+	deleteHandler := handler.NewDelete(processAdmin)
+	r22 := v1.DELETE("/proc/:id", deleteHandler.Handler)
+	r22.Name = "deleteProcess"
+	r23 := v1.POST("/proc/delete", deleteHandler.Bulk)
+	r23.Name = "deleteProcesses"
+
+	starHandler := handler.NewStar(processes, processes)
+	r24 := v1.PUT("/proc/:id/star", starHandler.Star)
+	r24.Name = "starProcess"
+	r25 := v1.DELETE("/proc/:id/star", starHandler.Unstar)
+	r25.Name = "unstarProcess"
+
+	// the policy is registered by the worker
+	retention := task.NewRetention(&task.RetentionParams{
+		ProcessGetter:  processes,
+		ProcessRemover: processAdmin,
+		ProcessPruner:  processAdmin,
+	})
+	retentionHandler := handler.NewRetention(retention, inspector, inspector)
+	r26 := v1.GET("/retention/report", retentionHandler.Report)
+	r26.Name = "retentionReport"
+	r27 := v1.GET("/retention/runs", retentionHandler.Runs)
+	r27.Name = "retentionRuns"
+	r28 := v1.PATCH("/proc/:id", handler.NewEdit(processes, details, details, tags).Handler)
+	r28.Name = "editProcess"
+	r29 := getGroup.GET("/mix", getHandler.Mix)
+	r29.Name = "getMix"
+	r30 := getGroup.GET("/label", getHandler.Label)
+	r30.Name = "getLabel"
+	r31 := getGroup.GET("/manifest", getHandler.Manifest)
+	r31.Name = "getManifest"
 
 	return &Server{
-		e:      e,
-		c:      cfg,
-		db:     db,
-		client: client,
+		e:         e,
+		c:         cfg,
+		db:        db,
+		client:    client,
+		inspector: inspector,
 	}, nil
 }
 
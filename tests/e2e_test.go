@@ -3,7 +3,11 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
@@ -13,11 +17,16 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/berquerant/pneutrinoutil/cli/ctl"
+	"github.com/berquerant/pneutrinoutil/pkg/audio"
+	"github.com/berquerant/pneutrinoutil/pkg/client"
+	"github.com/berquerant/pneutrinoutil/pkg/compare"
+	"github.com/berquerant/pneutrinoutil/pkg/sweep"
 	"github.com/berquerant/pneutrinoutil/server/handler"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,41 +41,56 @@ func assertNil[T any](t assert.TestingT, v T) bool {
 	return assert.Nil(t, v, "%v should be nil", v)
 }
 
-func assertAndGet[T any](t *testing.T, url string) (T, bool) {
-	var (
-		d    T
-		retb bool
-	)
-	t.Run("assertAndGet:"+url, func(t *testing.T) {
-		r, err := http.Get(url)
-		if !assertNil(t, err) {
-			return
-		}
-		defer r.Body.Close()
-		if !assert.Equal(t, http.StatusOK, r.StatusCode) {
-			return
-		}
-		b, err := io.ReadAll(r.Body)
-		if !assertNil(t, err) {
-			return
-		}
-		var body handler.SuccessResponse[T]
-		if !assertNil(t, json.Unmarshal(b, &body)) {
-			return
-		}
-		if !assert.True(t, body.OK) {
-			return
-		}
-		d = body.Data
-		retb = true
-	})
-	return d, retb
-}
-
 func eventually(t *testing.T, condition func(c *assert.CollectT), msgAndArgs ...any) bool {
 	return assert.EventuallyWithT(t, condition, eventuallyWaitForMax, eventuallyTick, msgAndArgs...)
 }
 
+// wait waits for the process to succeed.
+func wait(t *testing.T, c *client.Client, rid string) bool {
+	ctx, cancel := context.WithTimeout(t.Context(), eventuallyWaitForMax)
+	defer cancel()
+	d, err := c.Wait(ctx, rid)
+	if !assertNil(t, err) {
+		return false
+	}
+	return assert.Equal(t, "succeed", d.Status)
+}
+
+// readAll reads the downloaded content.
+func readAll(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// readFLACFormat reads the format from the STREAMINFO of the FLAC stream.
+func readFLACFormat(r io.Reader) (*audio.Wav, error) {
+	b := make([]byte, 4+4+34) // marker, metadata block header and STREAMINFO
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	if string(b[:4]) != "fLaC" || b[4]&0x7f != 0 {
+		return nil, errors.New("not a flac stream")
+	}
+	// sample rate (20 bits), channels - 1 (3 bits) and bits per sample - 1 (5 bits)
+	// after the block sizes and the frame sizes
+	x := binary.BigEndian.Uint32(b[8+10:])
+	return audio.NewWav(audio.Format{
+		SampleRate: int(x >> 12),
+		Channels:   int(x>>9&0x7) + 1,
+		BitDepth:   int(x>>4&0x1f) + 1,
+	}, 0), nil
+}
+
+func newFile(name, content string) *client.File {
+	return &client.File{
+		Name:    name,
+		Content: strings.NewReader(content),
+	}
+}
+
 func generateData(content string, basename ...string) (map[string]string, error) {
 	// basename to rid
 	d := map[string]string{}
@@ -101,35 +125,39 @@ func generateData(content string, basename ...string) (map[string]string, error)
 }
 
 func TestE2E(t *testing.T) {
-	newUrl := func(path string) string {
-		return fmt.Sprintf("%s%s", os.Getenv("SERVER_URI"), path)
-	}
+	var (
+		ctx = t.Context()
+		c   = client.New(os.Getenv("SERVER_URI"))
+	)
 
-	eventually(t, func(c *assert.CollectT) {
-		r, err := http.Get(newUrl("/health"))
-		if !assertNil(c, err) {
-			return
-		}
-		defer r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
+	eventually(t, func(ct *assert.CollectT) {
+		assertNil(ct, c.Health(ctx))
 	}, "healthcheck")
 
 	t.Run("version", func(t *testing.T) {
-		r, err := http.Get(newUrl("/version"))
-		if !assertNil(t, err) {
-			return
-		}
-		defer r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
+		_, err := c.Version(ctx)
+		assertNil(t, err)
 	})
 
 	t.Run("debug", func(t *testing.T) {
-		r, err := http.Get(newUrl("/debug"))
+		_, err := c.Debug(ctx)
+		assertNil(t, err)
+	})
+
+	t.Run("config schema", func(t *testing.T) {
+		schema, err := c.ConfigSchema(ctx)
 		if !assertNil(t, err) {
 			return
 		}
-		defer r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
+		assert.Equal(t, "integer", schema.Properties["transpose"].Type)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, err := c.Start(ctx, &client.StartRequest{
+			Score:  newFile("score", "score content"),
+			Values: map[string]string{"transpose": "high"},
+		})
+		assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
 	})
 
 	const (
@@ -150,59 +178,9 @@ func TestE2E(t *testing.T) {
 		return
 	}
 
-	t.Run("details", func(t *testing.T) {
-		got, ok := assertAndGet[handler.GetDetailResponseData](t, newUrl("/proc/"+newRid+"/detail"))
-		assert.True(t, ok)
-		assert.Equal(t, "succeed", got.Status)
-		assert.Equal(t, newRid, got.RequestID)
-		assert.Equal(t, basename, got.Basename)
-	})
-
-	t.Run("download config", func(t *testing.T) {
-		got, ok := assertAndGet[ctl.Config](t, newUrl("/proc/"+newRid+"/config"))
-		if !assert.True(t, ok) {
-			return
-		}
-		assert.Equal(t, newRid, got.Description)
-		assert.Equal(t, scoreFileName, filepath.Base(got.Score))
-		assert.Equal(t, basename, got.Basename())
-	})
-
-	t.Run("download log", func(t *testing.T) {
-		r, err := http.Get(newUrl("/proc/" + newRid + "/log"))
-		if !assertNil(t, err) {
-			return
-		}
-		r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
-	})
-
-	t.Run("download musicxml", func(t *testing.T) {
-		r, err := http.Get(newUrl("/proc/" + newRid + "/musicxml"))
-		if !assertNil(t, err) {
-			return
-		}
-		defer r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
-		body, err := io.ReadAll(r.Body)
-		if !assertNil(t, err) {
-			return
-		}
-		assert.Equal(t, scoreContent, string(body))
-	})
-
-	t.Run("download wav", func(t *testing.T) {
-		r, err := http.Get(newUrl("/proc/" + newRid + "/wav"))
-		if !assertNil(t, err) {
-			return
-		}
-		r.Body.Close()
-		assert.Equal(t, http.StatusOK, r.StatusCode)
-	})
-
 	t.Run("search", func(t *testing.T) {
-		r, ok := assertAndGet[handler.SearchProcessResponseData](t, newUrl("/proc/search"))
-		if !ok {
+		r, err := c.Search(ctx, nil)
+		if !assertNil(t, err) {
 			return
 		}
 		if !assert.Len(t, r, 1) {
@@ -273,10 +251,8 @@ func TestE2E(t *testing.T) {
 		}
 
 		t.Run("order by craeted_at desc", func(t *testing.T) {
-			r, ok := assertAndGet[handler.SearchProcessResponseData](t,
-				newUrl("/proc/search?limit=2"),
-			)
-			if !ok {
+			r, err := c.Search(ctx, &client.SearchRequest{Limit: 2})
+			if !assertNil(t, err) {
 				return
 			}
 			if !assert.Len(t, r, 2) {
@@ -287,10 +263,8 @@ func TestE2E(t *testing.T) {
 		})
 
 		t.Run("show all", func(t *testing.T) {
-			r, ok := assertAndGet[handler.SearchProcessResponseData](t,
-				newUrl("/proc/search?limit=100"),
-			)
-			if !ok {
+			r, err := c.Search(ctx, &client.SearchRequest{Limit: 100})
+			if !assertNil(t, err) {
 				return
 			}
 			t.Logf("%v", d)
@@ -301,41 +275,57 @@ func TestE2E(t *testing.T) {
 
 		for _, tc := range []struct {
 			title     string
-			q         string
+			req       *client.SearchRequest
 			basenames []string
 		}{
 			{
 				title:     "all",
-				q:         "limit=100",
+				req:       &client.SearchRequest{Limit: 100},
 				basenames: slices.Collect(maps.Keys(d)),
 			},
 			{
 				title: "no running",
-				q:     "status=running",
+				req:   &client.SearchRequest{Status: "running"},
 			},
 			{
 				title:     "prefix",
-				q:         "prefix=a",
+				req:       &client.SearchRequest{Prefix: "a"},
 				basenames: []string{"a1", "a2", "a3"},
 			},
 			{
 				title:     "title and start",
-				q:         fmt.Sprintf("prefix=a&start=%d", start2.Unix()),
+				req:       &client.SearchRequest{Prefix: "a", Start: &start2},
 				basenames: []string{"a3"},
 			},
 			{
 				title:     "time range",
-				q:         fmt.Sprintf("start=%d&end=%d", start2.Unix(), start3.Unix()),
+				req:       &client.SearchRequest{Start: &start2, End: &start3},
 				basenames: []string{"b1", "b2", "a3"},
 			},
+			{
+				title:     "ids",
+				req:       &client.SearchRequest{IDs: []string{d["a1"], d["c1"]}},
+				basenames: []string{"a1", "c1"},
+			},
+			{
+				title:     "contains",
+				req:       &client.SearchRequest{Contains: "1"},
+				basenames: []string{"a1", "b1", "c1", "d1"},
+			},
+			{
+				title:     "contains ignoring case",
+				req:       &client.SearchRequest{Contains: "B"},
+				basenames: []string{"b1", "b2"},
+			},
+			{
+				title:     "ids and prefix",
+				req:       &client.SearchRequest{IDs: []string{d["a1"], d["c1"]}, Prefix: "c"},
+				basenames: []string{"c1"},
+			},
 		} {
 			t.Run(tc.title, func(t *testing.T) {
-				u := newUrl("/proc/search")
-				if tc.q != "" {
-					u += "?" + tc.q
-				}
-				r, ok := assertAndGet[handler.SearchProcessResponseData](t, u)
-				if !ok {
+				r, err := c.Search(ctx, tc.req)
+				if !assertNil(t, err) {
 					return
 				}
 				got := map[string]string{}
@@ -349,5 +339,718 @@ func TestE2E(t *testing.T) {
 				assert.Equal(t, want, got)
 			})
 		}
+
+		collectPages := func(t *testing.T, req *client.SearchRequest) ([]*handler.SearchProcessResponse, bool) {
+			var pages []*handler.SearchProcessResponse
+			for {
+				r, err := c.SearchPage(ctx, req)
+				if !assertNil(t, err) {
+					return nil, false
+				}
+				pages = append(pages, r)
+				if r.Next == "" || len(pages) > len(d) {
+					return pages, true
+				}
+				req.Cursor = r.Next
+			}
+		}
+
+		t.Run("pages", func(t *testing.T) {
+			all, err := c.Search(ctx, &client.SearchRequest{Limit: 100})
+			if !assertNil(t, err) {
+				return
+			}
+			pages, ok := collectPages(t, &client.SearchRequest{Limit: 3, Total: true})
+			if !ok {
+				return
+			}
+			var got []*handler.SearchProcessResponseDataElement
+			for _, p := range pages {
+				if assert.NotNil(t, p.Total) {
+					assert.Equal(t, len(d), *p.Total)
+				}
+				got = append(got, p.Data...)
+			}
+			assert.Len(t, pages, (len(d)+2)/3)
+			assert.Equal(t, all, handler.SearchProcessResponseData(got))
+		})
+
+		t.Run("sort by title", func(t *testing.T) {
+			pages, ok := collectPages(t, &client.SearchRequest{Limit: 2, Prefix: "a", Sort: "title", Order: "asc"})
+			if !ok {
+				return
+			}
+			var got []string
+			for _, p := range pages {
+				for _, x := range p.Data {
+					got = append(got, x.Title)
+				}
+			}
+			assert.Equal(t, []string{"a1", "a2", "a3"}, got)
+		})
+
+		t.Run("invalid cursor", func(t *testing.T) {
+			_, err := c.SearchPage(ctx, &client.SearchRequest{Cursor: "x"})
+			assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
+			r, err := c.SearchPage(ctx, &client.SearchRequest{Limit: 1})
+			if !assertNil(t, err) {
+				return
+			}
+			_, err = c.SearchPage(ctx, &client.SearchRequest{Limit: 1, Sort: "title", Cursor: r.Next})
+			assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
+		})
+	})
+
+	t.Run("invalid accompaniment", func(t *testing.T) {
+		_, err := c.Start(ctx, &client.StartRequest{
+			Score:         newFile("score", scoreContent),
+			Accompaniment: newFile("accompaniment", "not a wav"),
+		})
+		assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
+	})
+
+	t.Run("accompaniment", func(t *testing.T) {
+		// 2 seconds of silence, longer than the vocal
+		var accompaniment bytes.Buffer
+		if !assertNil(t, audio.NewWav(audio.Format{
+			SampleRate: 44100,
+			BitDepth:   16,
+			Channels:   1,
+		}, 88200).Encode(&accompaniment)) {
+			return
+		}
+		rid, err := c.Start(ctx, &client.StartRequest{
+			Score: newFile(scoreFileName, scoreContent),
+			Accompaniment: &client.File{
+				Name:    "accompaniment.wav",
+				Content: &accompaniment,
+			},
+		})
+		if !assertNil(t, err) || !wait(t, c, rid) {
+			return
+		}
+		b, err := readAll(c.Mix(ctx, rid))
+		if !assertNil(t, err) {
+			return
+		}
+		w, err := audio.Decode(bytes.NewReader(b))
+		if assertNil(t, err) {
+			assert.Equal(t, 48000, w.Format.SampleRate)
+			assert.Equal(t, 2, w.Format.Channels)
+			assert.InDelta(t, 2.0, w.Duration(), 0.01)
+		}
+
+		_, err = c.Mix(ctx, newRid)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Run("invalid config", func(t *testing.T) {
+			for _, config := range []map[string]any{
+				{"score": "/etc/passwd"},
+				{"stages": []any{map[string]any{"name": "x", "command": "true"}}},
+				{"neutrinoVersion": "v"},
+				{"thread": "many"},
+				{"unknown": 1},
+			} {
+				_, err := c.Start(ctx, &client.StartRequest{
+					Score:  newFile(scoreFileName, scoreContent),
+					Config: config,
+				})
+				assert.Equal(t, http.StatusBadRequest, client.StatusCode(err), config)
+			}
+		})
+
+		rid, err := c.Start(ctx, &client.StartRequest{
+			Score:  newFile(scoreFileName, scoreContent),
+			Config: map[string]any{"thread": 2, "desc": "configured", "transpose": -12},
+			Values: map[string]string{"transpose": "12"},
+		})
+		if !assertNil(t, err) || !wait(t, c, rid) {
+			return
+		}
+		config, err := c.Config(ctx, rid)
+		if assertNil(t, err) {
+			assert.Equal(t, 2, config.NumThreads)
+			assert.Equal(t, "configured", config.Description)
+			assert.Equal(t, 12, config.Transpose)
+		}
+		got, err := c.Detail(ctx, rid)
+		if assertNil(t, err) {
+			var options map[string]any
+			if assertNil(t, json.Unmarshal(got.Options, &options)) {
+				assert.Equal(t, 2.0, options["thread"])
+			}
+		}
+
+		t.Run("score of another process", func(t *testing.T) {
+			_, err := c.StartFromScore(ctx, "unknown", nil)
+			assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+
+			rid, err := c.StartFromScore(ctx, newRid, map[string]any{"transpose": 5})
+			if !assertNil(t, err) || !wait(t, c, rid) {
+				return
+			}
+			config, err := c.Config(ctx, rid)
+			if assertNil(t, err) {
+				assert.Equal(t, 5, config.Transpose)
+				assert.Equal(t, basename, config.Basename())
+			}
+			got, err := c.Detail(ctx, rid)
+			if assertNil(t, err) {
+				assert.Equal(t, newRid, got.Parent)
+			}
+		})
+
+		t.Run("rerun", func(t *testing.T) {
+			_, err := c.Rerun(ctx, "unknown", nil)
+			assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+			_, err = c.Rerun(ctx, rid, map[string]any{"thread": "many"})
+			assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
+
+			child, err := c.Rerun(ctx, rid, map[string]any{"transpose": 3})
+			if !assertNil(t, err) || !wait(t, c, child) {
+				return
+			}
+			config, err := c.Config(ctx, child)
+			if assertNil(t, err) {
+				assert.Equal(t, 2, config.NumThreads)
+				assert.Equal(t, "configured", config.Description)
+				assert.Equal(t, 3, config.Transpose)
+			}
+			got, err := c.Detail(ctx, child)
+			if assertNil(t, err) {
+				assert.Equal(t, rid, got.Parent)
+			}
+			got, err = c.Detail(ctx, rid)
+			if assertNil(t, err) {
+				assert.Contains(t, got.Reruns, child)
+			}
+			r, err := c.Search(ctx, &client.SearchRequest{Limit: 1})
+			if assertNil(t, err) && assert.Len(t, r, 1) {
+				assert.Equal(t, child, r[0].RequestID)
+				assert.Equal(t, rid, r[0].Parent)
+			}
+
+			// the process created before the options were stored
+			db, err := sql.Open("mysql", os.Getenv("TEST_MYSQL_DSN"))
+			if !assertNil(t, err) {
+				return
+			}
+			defer db.Close()
+			_, err = db.ExecContext(ctx,
+				"update process_details d join processes p on p.details_id = d.id set d.options = null where p.request_id = ?",
+				child,
+			)
+			if !assertNil(t, err) {
+				return
+			}
+			_, err = c.Rerun(ctx, child, nil)
+			assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+		})
+	})
+
+	t.Run("ensemble", func(t *testing.T) {
+		const ensembleScore = `<?xml version="1.0" encoding="UTF-8"?>
+<score-partwise version="4.0">
+  <part-list>
+    <score-part id="P1"><part-name>Soprano</part-name></score-part>
+    <score-part id="P2"><part-name>Alto</part-name></score-part>
+  </part-list>
+  <part id="P1"><measure number="1"/></part>
+  <part id="P2"><measure number="1"/></part>
+</score-partwise>`
+		startEnsemble := func(voices []*handler.EnsembleVoice) (string, error) {
+			return c.StartEnsemble(ctx, &client.EnsembleRequest{
+				Score:  newFile("ensemble_sample.musicxml", ensembleScore),
+				Voices: voices,
+			})
+		}
+
+		t.Run("invalid voices", func(t *testing.T) {
+			for _, voices := range [][]*handler.EnsembleVoice{
+				nil,
+				{},
+				{{Part: "P3"}},
+				{{Part: "P1", Pan: 2}},
+			} {
+				_, err := startEnsemble(voices)
+				assert.Equal(t, http.StatusBadRequest, client.StatusCode(err), voices)
+			}
+		})
+
+		rid, err := startEnsemble([]*handler.EnsembleVoice{
+			{Part: "P1", Pan: -0.5},
+			{Part: "Alto", Transpose: new(-12), Gain: -3, Pan: 0.5},
+		})
+		if !assertNil(t, err) || !wait(t, c, rid) {
+			return
+		}
+
+		got, err := c.Detail(ctx, rid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, "ensemble", got.Kind)
+		assert.Equal(t, []string{rid + "-0", rid + "-1"}, got.Voices)
+
+		voice, err := c.Detail(ctx, rid+"-1")
+		if assertNil(t, err) {
+			assert.Equal(t, "render", voice.Kind)
+			assert.Equal(t, rid, voice.Group)
+		}
+		// the voice is deleted only with the ensemble
+		_, err = c.Delete(ctx, rid+"-1")
+		assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+		config, err := c.Config(ctx, rid+"-1")
+		if assertNil(t, err) {
+			assert.Equal(t, "Alto", config.Part)
+			assert.Equal(t, -12, config.Transpose)
+		}
+
+		for title, download := range map[string]func() (io.ReadCloser, error){
+			"wav":    func() (io.ReadCloser, error) { return c.Wav(ctx, rid, "") },
+			"stem 0": func() (io.ReadCloser, error) { return c.Stem(ctx, rid, 0) },
+			"stem 1": func() (io.ReadCloser, error) { return c.Stem(ctx, rid, 1) },
+		} {
+			b, err := readAll(download())
+			if !assertNil(t, err) {
+				return
+			}
+			w, err := audio.Decode(bytes.NewReader(b))
+			if assertNil(t, err) {
+				assert.Equal(t, 48000, w.Format.SampleRate, title)
+			}
+		}
+		_, err = c.Stem(ctx, rid, 2)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		startSweep := func(values map[string]string) (string, error) {
+			return c.StartSweep(ctx, &client.StartRequest{
+				Score:  newFile(scoreFileName, scoreContent),
+				Values: values,
+			})
+		}
+
+		t.Run("invalid spec", func(t *testing.T) {
+			for _, values := range []map[string]string{
+				{"spec": "models: [A]"},
+				{"transposes": "a"},
+				{"transposes": strings.Repeat("0,", 65) + "0"},
+			} {
+				_, err := startSweep(values)
+				assert.Equal(t, http.StatusBadRequest, client.StatusCode(err), values)
+			}
+		})
+
+		rid, err := startSweep(map[string]string{
+			"spec":       "model: [X, Y]\ntranspose: [1]",
+			"models":     "A,B",
+			"transposes": "0,-12",
+		})
+		if !assertNil(t, err) || !wait(t, c, rid) {
+			return
+		}
+
+		got, err := c.Detail(ctx, rid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, "sweep", got.Kind)
+		assert.Len(t, got.Runs, 4)
+
+		config, err := c.Config(ctx, rid+"-1")
+		if assertNil(t, err) {
+			assert.Equal(t, "A", config.ModelDir)
+			assert.Equal(t, -12, config.Transpose)
+		}
+
+		index, err := c.SweepIndex(ctx, rid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, basename, index.Basename)
+		if assert.Len(t, index.Entries, 4) {
+			want := []sweep.Combination{
+				{Model: "A", Transpose: 0},
+				{Model: "A", Transpose: -12},
+				{Model: "B", Transpose: 0},
+				{Model: "B", Transpose: -12},
+			}
+			for i, x := range index.Entries {
+				assert.Equal(t, want[i], x.Combination)
+				assert.True(t, x.OK)
+				assert.Equal(t, fmt.Sprintf("%s-%d", rid, i), x.ID)
+			}
+		}
+
+		_, err = readAll(c.SweepIndexHTML(ctx, rid))
+		assertNil(t, err)
+	})
+
+	t.Run("details", func(t *testing.T) {
+		got, err := c.Detail(ctx, newRid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, "succeed", got.Status)
+		assert.Equal(t, newRid, got.RequestID)
+		assert.Equal(t, basename, got.Basename)
+	})
+
+	t.Run("download config", func(t *testing.T) {
+		got, err := c.Config(ctx, newRid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, newRid, got.Description)
+		assert.Equal(t, scoreFileName, filepath.Base(got.Score))
+		assert.Equal(t, basename, got.Basename())
+	})
+
+	t.Run("download log", func(t *testing.T) {
+		_, err := readAll(c.Log(ctx, newRid))
+		assertNil(t, err)
+	})
+
+	t.Run("download musicxml", func(t *testing.T) {
+		body, err := readAll(c.MusicXML(ctx, newRid))
+		if assertNil(t, err) {
+			assert.Equal(t, scoreContent, string(body))
+		}
+	})
+
+	t.Run("download wav", func(t *testing.T) {
+		_, err := readAll(c.Wav(ctx, newRid, ""))
+		assertNil(t, err)
+	})
+
+	t.Run("download transcoded wav", func(t *testing.T) {
+		db, err := sql.Open("mysql", os.Getenv("TEST_MYSQL_DSN"))
+		if !assertNil(t, err) {
+			return
+		}
+		defer db.Close()
+		// countCacheObjects returns the number of the objects of the transcoded wav in the result directory
+		countCacheObjects := func(t *testing.T, format string) int {
+			f, err := audio.ParseOutputFormat(format)
+			if !assertNil(t, err) {
+				t.FailNow()
+			}
+			var n int
+			if !assertNil(t, db.QueryRowContext(ctx,
+				"select count(*) from objects where path like ?",
+				"%/"+newRid+"/"+f.FileName(basename),
+			).Scan(&n)) {
+				t.FailNow()
+			}
+			return n
+		}
+
+		for _, tc := range []struct {
+			title       string
+			format      string
+			concurrency int // 1 if 0
+			status      int
+			want        audio.Format
+			flac        bool
+		}{
+			{
+				title:  "flac",
+				format: "flac",
+				status: http.StatusOK,
+				want:   audio.Format{SampleRate: 48000, BitDepth: 16, Channels: 1},
+				flac:   true,
+			},
+			{
+				title:  "resampled wav",
+				format: "wav:48000:24",
+				status: http.StatusOK,
+				want:   audio.Format{SampleRate: 48000, BitDepth: 24, Channels: 1},
+			},
+			{
+				title:  "cached",
+				format: "wav:48000:24",
+				status: http.StatusOK,
+				want:   audio.Format{SampleRate: 48000, BitDepth: 24, Channels: 1},
+			},
+			{
+				title:       "concurrent",
+				format:      "wav:44100:16",
+				concurrency: 4,
+				status:      http.StatusOK,
+				want:        audio.Format{SampleRate: 44100, BitDepth: 16, Channels: 1},
+			},
+			{
+				title:  "unknown codec",
+				format: "mp3",
+				status: http.StatusBadRequest,
+			},
+		} {
+			t.Run(tc.title, func(t *testing.T) {
+				download := func() (*audio.Wav, error) {
+					r, err := c.Wav(ctx, newRid, tc.format)
+					if err != nil {
+						return nil, err
+					}
+					defer r.Close()
+					if tc.flac {
+						return readFLACFormat(r)
+					}
+					return audio.Decode(r)
+				}
+				if tc.status != http.StatusOK {
+					_, err := download()
+					assert.Equal(t, tc.status, client.StatusCode(err))
+					return
+				}
+
+				var (
+					results = make([]*audio.Wav, max(tc.concurrency, 1))
+					errs    = make([]error, len(results))
+					wg      sync.WaitGroup
+				)
+				for i := range results {
+					wg.Go(func() {
+						results[i], errs[i] = download()
+					})
+				}
+				wg.Wait()
+				for i, got := range results {
+					if assertNil(t, errs[i]) {
+						assert.Equal(t, tc.want, got.Format)
+					}
+				}
+				// cached once by the first request
+				assert.Equal(t, 1, countCacheObjects(t, tc.format))
+			})
+		}
+	})
+
+	t.Run("download peaks", func(t *testing.T) {
+		for _, tc := range []struct {
+			title      string
+			resolution int
+			status     int
+			want       int
+		}{
+			{
+				title:  "default",
+				status: http.StatusOK,
+				want:   1024,
+			},
+			{
+				title:      "resolution",
+				resolution: 256,
+				status:     http.StatusOK,
+				want:       256,
+			},
+			{
+				title:      "unknown resolution",
+				resolution: 100,
+				status:     http.StatusNotFound,
+			},
+		} {
+			t.Run(tc.title, func(t *testing.T) {
+				got, err := c.Peaks(ctx, newRid, tc.resolution)
+				if tc.status != http.StatusOK {
+					assert.Equal(t, tc.status, client.StatusCode(err))
+					return
+				}
+				if !assertNil(t, err) {
+					return
+				}
+				assert.Equal(t, tc.want, got.SamplesPerPixel)
+				assert.Equal(t, 1, got.Channels)
+				assert.Len(t, got.Data, got.Length*2)
+			})
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		got, err := c.Diff(ctx, newRid, newRid, 0)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, 0.0, got.Lag)
+		if assert.Len(t, got.Notes, 1) {
+			assert.Equal(t, "a", got.Notes[0].Lyric)
+			assert.Equal(t, 0.0, got.Notes[0].Cents)
+		}
+		if assert.NotNil(t, got.Timing) {
+			assert.Empty(t, got.Timing.Changed)
+		}
+
+		if b, err := readAll(c.DiffWav(ctx, newRid, newRid)); assertNil(t, err) {
+			w, err := audio.Decode(bytes.NewReader(b))
+			if assertNil(t, err) {
+				assert.Equal(t, 0.0, compare.RMS(w.Data[0]))
+			}
+		}
+
+		_, err = c.Diff(ctx, newRid, "unknown", 0)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		err := c.Cancel(ctx, "unknown")
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+		err = c.Cancel(ctx, newRid)
+		assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+
+		rid, err := c.Start(ctx, &client.StartRequest{
+			Score: newFile(scoreFileName, scoreContent),
+		})
+		if !assertNil(t, err) {
+			return
+		}
+		// the process may be completed before the cancellation
+		if err := c.Cancel(ctx, rid); err != nil {
+			assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+			return
+		}
+		d, err := c.Wait(ctx, rid)
+		if assertNil(t, err) {
+			assert.Equal(t, "cancelled", d.Status)
+		}
+		err = c.Cancel(ctx, rid)
+		assert.Equal(t, http.StatusConflict, client.StatusCode(err))
+		// the worker does not overwrite the status
+		time.Sleep(time.Second)
+		d, err = c.Detail(ctx, rid)
+		if assertNil(t, err) {
+			assert.Equal(t, "cancelled", d.Status)
+		}
+	})
+
+	t.Run("star", func(t *testing.T) {
+		err := c.Star(ctx, "unknown", true)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+
+		for _, starred := range []bool{true, false} {
+			if !assertNil(t, c.Star(ctx, newRid, starred)) {
+				return
+			}
+			d, err := c.Detail(ctx, newRid)
+			if assertNil(t, err) {
+				assert.Equal(t, starred, d.Starred)
+			}
+		}
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		_, err := c.Start(ctx, &client.StartRequest{
+			Score:  newFile(scoreFileName, scoreContent),
+			Values: map[string]string{"tags": "first draft"},
+		})
+		assert.Equal(t, http.StatusBadRequest, client.StatusCode(err))
+
+		rid, err := c.Start(ctx, &client.StartRequest{
+			Score: newFile(scoreFileName, scoreContent),
+			Values: map[string]string{
+				"title": "labeled",
+				"notes": "first take",
+				"tags":  "Solo,draft,SOLO",
+			},
+		})
+		if !assertNil(t, err) || !wait(t, c, rid) {
+			return
+		}
+		d, err := c.Detail(ctx, rid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, "labeled", d.Title)
+		assert.Equal(t, basename, d.Basename)
+		assert.Equal(t, "first take", d.Notes)
+		assert.Equal(t, []string{"draft", "solo"}, d.Tags)
+		w, err := c.Wav(ctx, rid, "")
+		if assertNil(t, err) {
+			_ = w.Close()
+		}
+
+		r, err := c.Search(ctx, &client.SearchRequest{Tags: []string{"SOLO", "draft"}})
+		if assertNil(t, err) && assert.Len(t, r, 1) {
+			assert.Equal(t, rid, r[0].RequestID)
+			assert.Equal(t, []string{"draft", "solo"}, r[0].Tags)
+		}
+
+		err = c.Edit(ctx, "unknown", &handler.EditRequest{Notes: new("x")})
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+		if !assertNil(t, c.Edit(ctx, rid, &handler.EditRequest{
+			Title: new(""),
+			Tags:  &[]string{"final"},
+		})) {
+			return
+		}
+		d, err = c.Detail(ctx, rid)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, basename, d.Title)
+		assert.Equal(t, "first take", d.Notes)
+		assert.Equal(t, []string{"final"}, d.Tags)
+		r, err = c.Search(ctx, &client.SearchRequest{Tags: []string{"solo"}})
+		if assertNil(t, err) {
+			assert.Empty(t, r)
+		}
+	})
+
+	t.Run("retention report", func(t *testing.T) {
+		// the worker registers no retention
+		_, err := c.RetentionReport(ctx)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+	})
+
+	t.Run("retention runs", func(t *testing.T) {
+		_, err := c.RetentionRuns(ctx, 0)
+		assertNil(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := c.Delete(ctx, "unknown")
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+
+		parent, err := c.Start(ctx, &client.StartRequest{
+			Score: newFile(scoreFileName, scoreContent),
+		})
+		if !assertNil(t, err) || !wait(t, c, parent) {
+			return
+		}
+		child, err := c.Rerun(ctx, parent, nil)
+		if !assertNil(t, err) || !wait(t, c, child) {
+			return
+		}
+
+		got, err := c.Delete(ctx, parent)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{parent}, got.Processes)
+		assert.NotZero(t, got.Objects)
+		assert.NotZero(t, got.SizeBytes)
+		_, err = c.Detail(ctx, parent)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+		// the score is shared with the rerun
+		d, err := c.Detail(ctx, child)
+		if assertNil(t, err) {
+			assert.Empty(t, d.Parent)
+		}
+		body, err := readAll(c.MusicXML(ctx, child))
+		if assertNil(t, err) {
+			assert.Equal(t, scoreContent, string(body))
+		}
+
+		_, err = c.DeleteList(ctx, child, "unknown")
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
+		got, err = c.DeleteList(ctx, child)
+		if !assertNil(t, err) {
+			return
+		}
+		assert.Equal(t, []string{child}, got.Processes)
+		_, err = c.Detail(ctx, child)
+		assert.Equal(t, http.StatusNotFound, client.StatusCode(err))
 	})
 }
@@ -84,6 +84,7 @@ export default function Search() {
         <option value="pending">Pending</option>
         <option value="succeed">Succeed</option>
         <option value="failed">Failed</option>
+        <option value="cancelled">Cancelled</option>
       </select>
       <input
         className="form-control me-2"
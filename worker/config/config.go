@@ -12,6 +12,7 @@ import (
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
 	"github.com/berquerant/pneutrinoutil/pkg/infra"
 	"github.com/berquerant/pneutrinoutil/pkg/pathx"
+	"github.com/berquerant/pneutrinoutil/pkg/retention"
 	"github.com/berquerant/structconfig"
 	"github.com/hibiken/asynq"
 	"github.com/spf13/pflag"
@@ -70,6 +71,23 @@ type Config struct {
 	Debug                       bool   `name:"debug" usage:"enable debug logs"`
 	Webhook                     string `name:"webhook" usage:"webhook endpoint to notify task completion"`
 	WebhookTimeoutSeconds       int    `name:"webhookTimeoutSeconds" default:"10" usage:"duration webhook timeout"`
+	Retention                   string `name:"retention" usage:"retention policy file, yaml or json; disable the retention if empty"`
+	RetentionSchedule           string `name:"retentionSchedule" default:"@daily" usage:"cron spec to apply the retention policy"`
+	RetentionLimit              int    `name:"retentionLimit" default:"100" usage:"max number of the processes a retention rule handles at once"`
+	RetentionReportDays         int    `name:"retentionReportDays" default:"30" usage:"days to keep the reports of the retention"`
+	MetricsAddr                 string `name:"metricsAddr" usage:"address to serve the metrics at /debug/vars, e.g. :9102; disable the metrics if empty"`
+}
+
+// NewRetentionPolicy returns the retention policy, nil if disabled.
+func (c Config) NewRetentionPolicy() (*retention.Policy, error) {
+	if c.Retention == "" {
+		return nil, nil
+	}
+	return retention.Read(c.Retention)
+}
+
+func (c Config) RetentionReportPeriod() time.Duration {
+	return time.Duration(c.RetentionReportDays) * 24 * time.Hour
 }
 
 func (c Config) NewWebhook() *infra.Webhook {
@@ -118,6 +136,9 @@ func (c Config) Validate() error {
 	if _, err := exec.LookPath(c.Pneutrinoutil); err != nil {
 		return fmt.Errorf("%w: look path %s", err, c.Pneutrinoutil)
 	}
+	if _, err := c.NewRetentionPolicy(); err != nil {
+		return fmt.Errorf("%w: retention %s", err, c.Retention)
+	}
 	return nil
 }
 
@@ -3,9 +3,13 @@ package worker
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"expvar"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/berquerant/pneutrinoutil/pkg/alog"
 	"github.com/berquerant/pneutrinoutil/pkg/domain"
@@ -26,11 +30,16 @@ func New(c *config.Config) *Server {
 type Server struct {
 	c              *config.Config
 	srv            *asynq.Server
+	client         *asynq.Client
+	scheduler      *asynq.Scheduler // nil if the retention is disabled
 	db             *sql.DB
 	objects        *repo.ObjectAdmin
+	objectTable    *repo.Object
 	processDetails *repo.ProcessDetails
 	processes      *repo.Process
 	webhook        *infra.Webhook
+	retention      *task.Retention // nil if disabled
+	metrics        *http.Server    // nil if disabled
 }
 
 func (s *Server) Run(ctx context.Context) error {
@@ -61,8 +70,8 @@ func (s *Server) init(ctx context.Context) error {
 		return err
 	}
 	objectConn := infra.NewConn[domain.Object](db)
-	objectTable := repo.NewObject(objectConn, objectConn)
-	s.objects = repo.NewObjectAdmin(objectTable, objectTable, storageObjects, storageObjects)
+	s.objectTable = repo.NewObject(objectConn, objectConn)
+	s.objects = repo.NewObjectAdmin(s.objectTable, s.objectTable, storageObjects, storageObjects)
 
 	processDetailsConn := infra.NewConn[domain.ProcessDetails](db)
 	s.processDetails = repo.NewProcessDetails(processDetailsConn, processDetailsConn)
@@ -70,20 +79,47 @@ func (s *Server) init(ctx context.Context) error {
 	processConn := infra.NewConn[domain.Process](db)
 	s.processes = repo.NewProcess(processConn, processConn)
 
+	policy, err := s.c.NewRetentionPolicy()
+	if err != nil {
+		_ = s.db.Close()
+		return err
+	}
+	if policy != nil {
+		processAdmin := repo.NewProcessAdmin(&repo.ProcessAdminParams{
+			ProcessGetter:  s.processes,
+			DetailsGetter:  s.processDetails,
+			ObjectGetter:   s.objectTable,
+			Deleter:        repo.NewDeleter(processConn),
+			StorageDeleter: storageObjects,
+			StorageLister:  storageObjects,
+			Bucket:         s.c.StorageBucket,
+			BasePath:       s.c.StoragePath,
+		})
+		s.retention = task.NewRetention(&task.RetentionParams{
+			Policy:         policy,
+			ProcessGetter:  s.processes,
+			ProcessRemover: processAdmin,
+			ProcessPruner:  processAdmin,
+			Limit:          s.c.RetentionLimit,
+		})
+	}
+
 	if x := s.c.NewWebhook(); x != nil {
 		s.webhook = x
 	}
 
 	redisOpt, err := asynq.ParseRedisURI(s.c.RedisDSN)
 	if err != nil {
+		_ = s.db.Close()
 		return err
 	}
+	s.client = asynq.NewClient(redisOpt)
 	s.srv = asynq.NewServer(
 		redisOpt,
 		asynq.Config{
 			Concurrency: s.c.Concurrency,
 			Queues: map[string]int{
-				"default": 10,
+				task.DefaultQueue: 10,
 			},
 			ShutdownTimeout: s.c.ShutdownPeriod(),
 			Logger:          NewAsynqLogger(alog.L()),
@@ -91,6 +127,45 @@ func (s *Server) init(ctx context.Context) error {
 		},
 	)
 
+	if s.retention != nil {
+		s.scheduler = asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{
+			Logger:   NewAsynqLogger(alog.L()),
+			LogLevel: s.c.AsynqLogLevel(),
+		})
+		retentionTask, err := task.NewPneutrinoutilRetention(&task.RetentionPayload{
+			Policy: policy,
+			Limit:  s.c.RetentionLimit,
+		})
+		if err != nil {
+			_ = s.db.Close()
+			_ = s.client.Close()
+			return err
+		}
+		if _, err := s.scheduler.Register(
+			s.c.RetentionSchedule,
+			retentionTask,
+			asynq.Queue(task.DefaultQueue),
+			// keep the report as the result of the task
+			asynq.Retention(s.c.RetentionReportPeriod()),
+			// the schedulers of the workers enqueue the task at the same time
+			asynq.Unique(time.Minute),
+		); err != nil {
+			_ = s.db.Close()
+			_ = s.client.Close()
+			return fmt.Errorf("%w: register retention %s", err, s.c.RetentionSchedule)
+		}
+	}
+
+	if addr := s.c.MetricsAddr; addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		s.metrics = &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+
 	return nil
 }
 
@@ -108,7 +183,9 @@ func (s *Server) newServeMux() *asynq.ServeMux {
 			fmt.Sprintf("PWD=%s", os.Getenv("PWD")),
 		},
 		Webhooker:             s.webhook,
+		Enqueuer:              s.client,
 		ObjectReader:          s.objects,
+		ObjectGetter:          s.objectTable,
 		ObjectWriter:          s.objects,
 		ProcessDetailsGetter:  s.processDetails,
 		ProcessDetailsUpdater: s.processDetails,
@@ -116,14 +193,40 @@ func (s *Server) newServeMux() *asynq.ServeMux {
 		ProcessUpdater:        s.processes,
 	})
 	mux.HandleFunc(task.TypePneutrinoutilStart, pneutrinoutilProcessor.ProcessStart)
+	mux.HandleFunc(task.TypePneutrinoutilEnsemble, pneutrinoutilProcessor.ProcessEnsemble)
+	mux.HandleFunc(task.TypePneutrinoutilSweep, pneutrinoutilProcessor.ProcessSweep)
+	if s.retention != nil {
+		mux.HandleFunc(task.TypePneutrinoutilRetention, s.retention.ProcessRetention)
+	}
 	return mux
 }
 
 func (s *Server) run() error {
 	mux := s.newServeMux()
+	if s.scheduler != nil {
+		if err := s.scheduler.Start(); err != nil {
+			return err
+		}
+		alog.L().Info("start retention scheduler", "schedule", s.c.RetentionSchedule)
+	}
+	if s.metrics != nil {
+		go func() {
+			if err := s.metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				alog.L().Error("metrics", logx.Err(err))
+			}
+		}()
+		alog.L().Info("start metrics", "addr", s.metrics.Addr)
+	}
 	return s.srv.Run(mux)
 }
 
 func (s *Server) close() error {
-	return s.db.Close()
+	if s.scheduler != nil {
+		s.scheduler.Shutdown()
+	}
+	var err error
+	if s.metrics != nil {
+		err = s.metrics.Close()
+	}
+	return errors.Join(err, s.db.Close(), s.client.Close())
 }